@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+var branchCmd = &cobra.Command{
+	Use:   "branch",
+	Short: "branch subcommand list local branches with their upstream.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		branches, err := repo(cmd).Branches(cmd.Context())
+		if err != nil {
+			Error(cmd, args, err)
+		}
+		render(cmd, branches, func(w io.Writer) {
+			fmt.Fprintln(w, "\tBRANCH\tCOMMIT\tUPSTREAM\tTRACK\tSUBJECT")
+			for _, b := range branches {
+				current, track := "", ""
+				if b.Current {
+					current = "*"
+				}
+				switch {
+				case b.Gone:
+					track = "gone"
+				case b.Upstream != "":
+					track = fmt.Sprintf("+%d -%d", b.Ahead, b.Behind)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%.7s\t%s\t%s\t%s\n", current, b.Name, b.Hash, b.Upstream, track, b.Subject)
+			}
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(branchCmd)
+}
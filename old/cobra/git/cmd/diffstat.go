@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var diffStatCached bool
+
+var diffStatCmd = &cobra.Command{
+	Use:   "diff-stat [revisions...] [-- paths...]",
+	Short: "diff-stat subcommand show added and deleted lines per file.",
+	Args:  cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		gitArgs := args
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			gitArgs = append(append(append([]string{}, args[:dash]...), "--"), args[dash:]...)
+		}
+		if diffStatCached {
+			gitArgs = append([]string{"--cached"}, gitArgs...)
+		}
+		stats, err := repo(cmd).DiffStat(cmd.Context(), gitArgs...)
+		if err != nil {
+			Error(cmd, args, err)
+		}
+		render(cmd, stats, func(w io.Writer) {
+			var added, deleted int
+			fmt.Fprintln(w, "ADDED\tDELETED\tPATH")
+			for _, s := range stats {
+				path := strconv.Quote(s.Path)
+				if s.OldPath != "" {
+					path = strconv.Quote(s.OldPath) + " -> " + path
+				}
+				if s.Binary {
+					fmt.Fprintf(w, "-\t-\t%s\n", path)
+					continue
+				}
+				added += s.Added
+				deleted += s.Deleted
+				fmt.Fprintf(w, "%d\t%d\t%s\n", s.Added, s.Deleted, path)
+			}
+			fmt.Fprintf(w, "%d\t%d\t%d files changed\n", added, deleted, len(stats))
+		})
+	},
+}
+
+func init() {
+	diffStatCmd.Flags().BoolVar(&diffStatCached, "cached", false, "compare the index with HEAD")
+	rootCmd.AddCommand(diffStatCmd)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"testGo/cobra/git/porcelain"
+)
+
+var logMax int
+
+var logCmd = &cobra.Command{
+	Use:   "log [revision range] [-- paths...]",
+	Short: "log subcommand show commit logs.",
+	Args:  cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := porcelain.LogOptions{Max: logMax}
+		revs, paths := args, []string(nil)
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			revs, paths = args[:dash], args[dash:]
+		}
+		if len(revs) > 1 {
+			Error(cmd, args, fmt.Errorf("at most one revision range, got %v", revs))
+		}
+		if len(revs) == 1 {
+			opts.Rev = revs[0]
+		}
+		opts.Paths = paths
+
+		commits, err := repo(cmd).Log(cmd.Context(), opts)
+		if err != nil {
+			Error(cmd, args, err)
+		}
+		render(cmd, commits, func(w io.Writer) {
+			fmt.Fprintln(w, "COMMIT\tDATE\tAUTHOR\tSUBJECT")
+			for _, c := range commits {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.ShortHash, c.Time.Format("2006-01-02 15:04"), c.Author, c.Subject)
+			}
+		})
+	},
+}
+
+func init() {
+	logCmd.Flags().IntVarP(&logMax, "max-count", "n", 20, "limit the number of commits, 0 for all")
+	rootCmd.AddCommand(logCmd)
+}
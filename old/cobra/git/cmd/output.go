@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"testGo/cobra/git/porcelain"
+)
+
+// repo 按 -C 打开仓库
+func repo(cmd *cobra.Command) *porcelain.Repo {
+	r, err := porcelain.Open(cmd.Context(), repoDir)
+	if err != nil {
+		Error(cmd, nil, err)
+	}
+	return r
+}
+
+// render 按 -o 输出: json 直接编码 v, table 调用 table 写入对齐的列
+func render(cmd *cobra.Command, v any, table func(w io.Writer)) {
+	switch outputFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			Error(cmd, nil, err)
+		}
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		table(w)
+		w.Flush()
+	default:
+		Error(cmd, nil, fmt.Errorf("unknown output format %q, want table or json", outputFormat))
+	}
+}
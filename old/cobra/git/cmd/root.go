@@ -17,6 +17,16 @@ with speed and efficiency.`,
 	},
 }
 
+var (
+	repoDir      string // -C 仓库目录
+	outputFormat string // table 或 json
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&repoDir, "dir", "C", "", "run as if git was started in this directory")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "table", "output format: table or json")
+}
+
 func Execute() {
 	_ = rootCmd.Execute()
 }
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var statusIgnored bool
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "status subcommand show the working tree status.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		status, err := repo(cmd).Status(cmd.Context(), statusIgnored)
+		if err != nil {
+			Error(cmd, args, err)
+		}
+		render(cmd, status, func(w io.Writer) {
+			b := status.Branch
+			fmt.Fprintf(w, "branch\t%s\t%s", b.Head, b.OID)
+			if b.Upstream != "" {
+				fmt.Fprintf(w, "\t%s +%d -%d", b.Upstream, b.Ahead, b.Behind)
+			}
+			fmt.Fprintln(w)
+			if status.Clean() {
+				fmt.Fprintln(w, "clean")
+				return
+			}
+			fmt.Fprintln(w, "XY\tKIND\tPATH")
+			for _, e := range status.Entries {
+				path := strconv.Quote(e.Path)
+				if e.OrigPath != "" {
+					path = strconv.Quote(e.OrigPath) + " -> " + path
+				}
+				fmt.Fprintf(w, "%s%s\t%s\t%s\n", e.Index, e.Worktree, e.Kind, path)
+			}
+		})
+	},
+}
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusIgnored, "ignored", false, "show ignored files")
+	rootCmd.AddCommand(statusCmd)
+}
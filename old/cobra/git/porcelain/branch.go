@@ -0,0 +1,85 @@
+package porcelain
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Branch 本地分支
+type Branch struct {
+	Name     string    `json:"name"`
+	Hash     string    `json:"hash"`
+	Current  bool      `json:"current"`
+	Upstream string    `json:"upstream,omitempty"`
+	Gone     bool      `json:"gone,omitempty"` // 上游分支已被删除
+	Ahead    int       `json:"ahead"`
+	Behind   int       `json:"behind"`
+	Time     time.Time `json:"time"` // 最后一次提交的时间
+	Subject  string    `json:"subject"`
+}
+
+const branchFormat = "%(refname:short)%00%(objectname)%00%(HEAD)%00%(upstream:short)%00%(upstream:track,nobracket)%00%(committerdate:unix)%00%(contents:subject)%00"
+
+// Branches 执行 git for-each-ref refs/heads, 按名字排序
+func (r *Repo) Branches(ctx context.Context) ([]Branch, error) {
+	out, err := r.Run(ctx, "for-each-ref", "--format="+branchFormat, "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	// 每个分支 7 个字段, for-each-ref 在每条记录后还会输出一个换行
+	fields := strings.Split(string(out), "\x00")
+	branches := []Branch{}
+	for len(fields) >= 7 {
+		f := fields[:7]
+		fields = fields[7:]
+		b := Branch{
+			Name:     strings.TrimPrefix(f[0], "\n"),
+			Hash:     f[1],
+			Current:  f[2] == "*",
+			Upstream: f[3],
+			Subject:  f[6],
+		}
+		if err := b.parseTrack(f[4]); err != nil {
+			return nil, fmt.Errorf("parse branch %s: %w", b.Name, err)
+		}
+		if f[5] != "" {
+			unix, err := strconv.ParseInt(f[5], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parse branch %s: commit time %q: %w", b.Name, f[5], err)
+			}
+			b.Time = time.Unix(unix, 0)
+		}
+		branches = append(branches, b)
+	}
+	return branches, nil
+}
+
+// parseTrack ahead 1, behind 2 / gone / 空
+func (b *Branch) parseTrack(track string) error {
+	if track == "gone" {
+		b.Gone = true
+		return nil
+	}
+	for _, part := range strings.Split(track, ", ") {
+		if part == "" {
+			continue
+		}
+		name, n, _ := strings.Cut(part, " ")
+		count, err := strconv.Atoi(n)
+		if err != nil {
+			return fmt.Errorf("track %q: %w", track, err)
+		}
+		switch name {
+		case "ahead":
+			b.Ahead = count
+		case "behind":
+			b.Behind = count
+		default:
+			return fmt.Errorf("unknown track %q", track)
+		}
+	}
+	return nil
+}
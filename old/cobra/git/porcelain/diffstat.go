@@ -0,0 +1,57 @@
+package porcelain
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FileStat 一个文件的增删行数, 二进制文件 Binary 为 true, 行数为 0
+type FileStat struct {
+	Path    string `json:"path"`
+	OldPath string `json:"old_path,omitempty"` // 重命名前的路径
+	Added   int    `json:"added"`
+	Deleted int    `json:"deleted"`
+	Binary  bool   `json:"binary,omitempty"`
+}
+
+// DiffStat 执行 git diff --numstat -z, args 为 git diff 的版本和选项, 如 --cached, HEAD~1, main..feature
+func (r *Repo) DiffStat(ctx context.Context, args ...string) ([]FileStat, error) {
+	out, err := r.Run(ctx, append([]string{"diff", "--numstat", "-z", "-M"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseNumstat(splitZ(out))
+}
+
+// parseNumstat 每条为 "added\tdeleted\tpath"; 重命名时 path 为空, 后面两条记录是原路径和新路径
+func parseNumstat(records []string) ([]FileStat, error) {
+	stats := []FileStat{}
+	for i := 0; i < len(records); i++ {
+		f := strings.SplitN(records[i], "\t", 3)
+		if len(f) != 3 {
+			return nil, fmt.Errorf("parse numstat: unexpected record %q", records[i])
+		}
+		st := FileStat{Path: f[2]}
+		if f[0] == "-" && f[1] == "-" {
+			st.Binary = true
+		} else {
+			var err1, err2 error
+			st.Added, err1 = strconv.Atoi(f[0])
+			st.Deleted, err2 = strconv.Atoi(f[1])
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("parse numstat: unexpected record %q", records[i])
+			}
+		}
+		if st.Path == "" {
+			if i+2 >= len(records) {
+				return nil, fmt.Errorf("parse numstat: truncated rename record")
+			}
+			st.OldPath, st.Path = records[i+1], records[i+2]
+			i += 2
+		}
+		stats = append(stats, st)
+	}
+	return stats, nil
+}
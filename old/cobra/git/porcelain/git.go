@@ -0,0 +1,89 @@
+// Package porcelain 调用 git 命令, 把 porcelain / --format 输出解析为结构体
+//
+// 只依赖 git 可执行文件, 所有命令都使用机器可读的格式和 -z, 文件名里的空格、换行不会影响解析
+package porcelain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ExitError git 以非 0 状态退出, Stderr 是 git 的错误输出, 不包含标准输出
+type ExitError struct {
+	Args   []string
+	Code   int
+	Stderr string
+}
+
+func (e *ExitError) Error() string {
+	msg := strings.TrimSpace(e.Stderr)
+	if msg == "" {
+		msg = "no error output"
+	}
+	return fmt.Sprintf("git %s: exit status %d: %s", strings.Join(e.Args, " "), e.Code, msg)
+}
+
+// ErrNotRepository 目录不在 git 仓库中
+var ErrNotRepository = errors.New("not a git repository")
+
+// Is 使 errors.Is(err, ErrNotRepository) 可以判断
+func (e *ExitError) Is(target error) bool {
+	return target == ErrNotRepository && strings.Contains(e.Stderr, "not a git repository")
+}
+
+// Repo 一个工作目录
+type Repo struct {
+	// Dir 工作目录, 为空时使用当前目录
+	Dir string
+	// Git 可执行文件, 默认 git
+	Git string
+	// Env 额外的环境变量, 追加在当前进程的环境变量之后
+	Env []string
+}
+
+// Open 检查 dir 在 git 仓库中
+func Open(ctx context.Context, dir string) (*Repo, error) {
+	r := &Repo{Dir: dir}
+	if _, err := r.Run(ctx, "rev-parse", "--git-dir"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Run 执行 git 命令, 返回标准输出; 非 0 退出时返回 *ExitError
+func (r *Repo) Run(ctx context.Context, args ...string) ([]byte, error) {
+	git := r.Git
+	if git == "" {
+		git = "git"
+	}
+	cmd := exec.CommandContext(ctx, git, args...)
+	cmd.Dir = r.Dir
+	// 固定语言和不分页, 保证输出格式稳定
+	cmd.Env = append(os.Environ(), "LC_ALL=C", "GIT_PAGER=cat", "GIT_OPTIONAL_LOCKS=0")
+	cmd.Env = append(cmd.Env, r.Env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil, &ExitError{Args: args, Code: exitErr.ExitCode(), Stderr: stderr.String()}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// splitZ 按 NUL 切割, 去掉最后一个空段
+func splitZ(out []byte) []string {
+	s := strings.TrimSuffix(string(out), "\x00")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\x00")
+}
@@ -0,0 +1,73 @@
+package porcelain
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Commit 一个提交
+type Commit struct {
+	Hash      string    `json:"hash"`
+	ShortHash string    `json:"short_hash"`
+	Author    string    `json:"author"`
+	Email     string    `json:"email"`
+	Time      time.Time `json:"time"`
+	Parents   []string  `json:"parents"`
+	Subject   string    `json:"subject"`
+}
+
+// LogOptions git log 参数
+type LogOptions struct {
+	Max   int      // 最多返回多少个提交, 0 表示不限制
+	Rev   string   // 版本范围, 如 main..feature, 为空时为 HEAD; 不能以 - 开头
+	Paths []string // 只看这些路径的提交
+}
+
+// 字段用 \x1f 分隔, 提交之间用 -z 的 NUL 分隔
+const logFormat = "%H%x1f%h%x1f%an%x1f%ae%x1f%at%x1f%P%x1f%s"
+
+// Log 执行 git log --format
+func (r *Repo) Log(ctx context.Context, opts LogOptions) ([]Commit, error) {
+	// 以 - 开头的版本会被 git 当成选项, 如 --output=<file> 会写文件
+	if strings.HasPrefix(opts.Rev, "-") {
+		return nil, fmt.Errorf("invalid revision %q", opts.Rev)
+	}
+	args := []string{"log", "-z", "--format=" + logFormat}
+	if opts.Max > 0 {
+		args = append(args, "-n", strconv.Itoa(opts.Max))
+	}
+	if opts.Rev != "" {
+		args = append(args, opts.Rev)
+	}
+	args = append(args, "--")
+	args = append(args, opts.Paths...)
+	out, err := r.Run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []Commit{}
+	for _, rec := range splitZ(out) {
+		f := strings.Split(strings.TrimPrefix(rec, "\n"), "\x1f")
+		if len(f) != 7 {
+			return nil, fmt.Errorf("parse log: unexpected record %q", rec)
+		}
+		unix, err := strconv.ParseInt(f[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse log: author time %q: %w", f[4], err)
+		}
+		commits = append(commits, Commit{
+			Hash:      f[0],
+			ShortHash: f[1],
+			Author:    f[2],
+			Email:     f[3],
+			Time:      time.Unix(unix, 0),
+			Parents:   strings.Fields(f[5]),
+			Subject:   f[6],
+		})
+	}
+	return commits, nil
+}
@@ -0,0 +1,241 @@
+package porcelain
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRepo 在临时目录创建仓库, 隔离全局配置并固定作者和时间
+func newRepo(t *testing.T) *Repo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	r := &Repo{Dir: t.TempDir(), Env: []string{
+		"HOME=" + t.TempDir(),
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_GLOBAL=" + os.DevNull,
+		"GIT_AUTHOR_NAME=Fermin", "GIT_AUTHOR_EMAIL=fermin@example.com",
+		"GIT_COMMITTER_NAME=Fermin", "GIT_COMMITTER_EMAIL=fermin@example.com",
+		"GIT_AUTHOR_DATE=2024-09-12T10:00:00Z", "GIT_COMMITTER_DATE=2024-09-12T10:00:00Z",
+	}}
+	git(t, r, "init", "-q", "-b", "main")
+	return r
+}
+
+func git(t *testing.T, r *Repo, args ...string) string {
+	t.Helper()
+	out, err := r.Run(context.Background(), args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func write(t *testing.T, r *Repo, name, content string) {
+	t.Helper()
+	path := filepath.Join(r.Dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func commit(t *testing.T, r *Repo, msg string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		write(t, r, name, content)
+	}
+	git(t, r, "add", "-A")
+	git(t, r, "commit", "-q", "-m", msg)
+}
+
+func TestStatus(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t)
+
+	s, err := r.Status(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Branch.OID != "(initial)" || s.Branch.Head != "main" || !s.Clean() {
+		t.Fatalf("empty repo: %+v", s)
+	}
+
+	commit(t, r, "init", map[string]string{"a.txt": "a\n", "b.txt": "b\nb\nb\n", ".gitignore": "*.log\n"})
+	git(t, r, "branch", "up")
+	git(t, r, "branch", "-u", "up")
+	commit(t, r, "second", map[string]string{"a.txt": "a2\n"})
+
+	write(t, r, "a.txt", "a3\n")
+	write(t, r, "new file.txt", "new\n")
+	git(t, r, "add", "new file.txt")
+	os.Mkdir(filepath.Join(r.Dir, "dir"), 0o755)
+	git(t, r, "mv", "b.txt", "dir/b renamed.txt")
+	write(t, r, "line\nbreak.txt", "x\n")
+	write(t, r, "debug.log", "x\n")
+
+	s, err = r.Status(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBranch := BranchStatus{OID: strings.TrimSpace(git(t, r, "rev-parse", "HEAD")), Head: "main", Upstream: "up", Ahead: 1}
+	if s.Branch != wantBranch {
+		t.Fatalf("branch %+v, want %+v", s.Branch, wantBranch)
+	}
+	want := []StatusEntry{
+		{Kind: Changed, Path: "a.txt", Index: ".", Worktree: "M"},
+		{Kind: Renamed, Path: "dir/b renamed.txt", OrigPath: "b.txt", Index: "R", Worktree: ".", Score: "R100"},
+		{Kind: Changed, Path: "new file.txt", Index: "A", Worktree: "."},
+		{Kind: Untracked, Path: "line\nbreak.txt", Index: "?", Worktree: "?"},
+		{Kind: Ignored, Path: "debug.log", Index: "!", Worktree: "!"},
+	}
+	if !reflect.DeepEqual(s.Entries, want) {
+		t.Fatalf("entries\n got %+v\nwant %+v", s.Entries, want)
+	}
+	if s.Clean() {
+		t.Fatal("dirty repo reported clean")
+	}
+}
+
+func TestLog(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t)
+	commit(t, r, "first", map[string]string{"a.txt": "a\n"})
+	commit(t, r, "第二个提交: with spaces", map[string]string{"b.txt": "b\n"})
+	commit(t, r, "third", map[string]string{"a.txt": "a2\n"})
+
+	commits, err := r.Log(ctx, LogOptions{Max: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 || commits[0].Subject != "third" || commits[1].Subject != "第二个提交: with spaces" {
+		t.Fatalf("got %+v", commits)
+	}
+	c := commits[0]
+	if c.Author != "Fermin" || c.Email != "fermin@example.com" || !c.Time.Equal(time.Date(2024, 9, 12, 10, 0, 0, 0, time.UTC)) ||
+		len(c.Parents) != 1 || c.Parents[0] != commits[1].Hash || !strings.HasPrefix(c.Hash, c.ShortHash) {
+		t.Fatalf("got %+v", c)
+	}
+
+	commits, err = r.Log(ctx, LogOptions{Paths: []string{"a.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 || commits[1].Subject != "first" || len(commits[1].Parents) != 0 {
+		t.Fatalf("path filter: %+v", commits)
+	}
+
+	_, err = r.Log(ctx, LogOptions{Rev: "no-such-branch"})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 128 || !strings.Contains(exitErr.Stderr, "no-such-branch") {
+		t.Fatalf("got %v", err)
+	}
+
+	out := filepath.Join(t.TempDir(), "out")
+	if _, err := r.Log(ctx, LogOptions{Rev: "--output=" + out}); err == nil {
+		t.Fatal("want error for a revision that looks like an option")
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatalf("git wrote %s: %v", out, err)
+	}
+}
+
+func TestBranches(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t)
+	commit(t, r, "init", map[string]string{"a.txt": "a\n"})
+	git(t, r, "branch", "feature")
+	git(t, r, "branch", "temp")
+	git(t, r, "branch", "-u", "feature")
+	git(t, r, "branch", "--set-upstream-to=temp", "feature")
+	git(t, r, "branch", "-D", "temp")
+	commit(t, r, "main only", map[string]string{"a.txt": "a2\n"})
+
+	branches, err := r.Branches(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(branches) != 2 {
+		t.Fatalf("got %+v", branches)
+	}
+	feature, main := branches[0], branches[1]
+	if feature.Name != "feature" || feature.Current || !feature.Gone || feature.Subject != "init" {
+		t.Fatalf("feature %+v", feature)
+	}
+	if main.Name != "main" || !main.Current || main.Upstream != "feature" || main.Ahead != 1 || main.Behind != 0 ||
+		main.Subject != "main only" || main.Time.IsZero() {
+		t.Fatalf("main %+v", main)
+	}
+}
+
+func TestDiffStat(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t)
+	commit(t, r, "init", map[string]string{
+		"a.txt":   "1\n2\n3\n",
+		"old.txt": strings.Repeat("same line\n", 20),
+		"bin.dat": "\x00\x01\x02",
+	})
+	write(t, r, "a.txt", "1\nchanged\n3\n4\n")
+	write(t, r, "bin.dat", "\x00\x03")
+	git(t, r, "mv", "old.txt", "new name.txt")
+
+	stats, err := r.DiffStat(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FileStat{
+		{Path: "a.txt", Added: 2, Deleted: 1},
+		{Path: "bin.dat", Binary: true},
+		{Path: "new name.txt", OldPath: "old.txt"},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Fatalf("got %+v\nwant %+v", stats, want)
+	}
+
+	stats, err = r.DiffStat(ctx, "--cached")
+	if err != nil || len(stats) != 1 || stats[0].Path != "new name.txt" {
+		t.Fatalf("cached: %+v, %v", stats, err)
+	}
+}
+
+func TestNotRepository(t *testing.T) {
+	newRepo(t) // 检查 git 是否安装
+	dir := t.TempDir()
+	_, err := Open(context.Background(), dir)
+	var exitErr *ExitError
+	if !errors.Is(err, ErrNotRepository) || !errors.As(err, &exitErr) || exitErr.Code != 128 {
+		t.Fatalf("got %v", err)
+	}
+	if !strings.Contains(err.Error(), "git rev-parse --git-dir: exit status 128: fatal: not a git repository") {
+		t.Fatalf("message %q", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, records := range [][]string{
+		{"1 M. short"},
+		{"2 R. N... 100644 100644 100644 abc def R100 new"},
+		{"x unknown"},
+		{"# branch.ab +x -1"},
+	} {
+		if _, err := parseStatus(records); err == nil {
+			t.Errorf("parseStatus(%q) want error", records)
+		}
+	}
+	for _, records := range [][]string{{"1\t2"}, {"a\tb\tpath"}, {"1\t1\t", "old"}} {
+		if _, err := parseNumstat(records); err == nil {
+			t.Errorf("parseNumstat(%q) want error", records)
+		}
+	}
+}
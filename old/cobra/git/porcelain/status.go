@@ -0,0 +1,147 @@
+package porcelain
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EntryKind 状态条目类型
+type EntryKind string
+
+const (
+	Changed   EntryKind = "changed"
+	Renamed   EntryKind = "renamed" // 包括复制
+	Unmerged  EntryKind = "unmerged"
+	Untracked EntryKind = "untracked"
+	Ignored   EntryKind = "ignored"
+)
+
+// StatusEntry 一个文件的状态, Index 和 Worktree 是 git status --short 的两列, 未修改为 '.'
+type StatusEntry struct {
+	Kind     EntryKind `json:"kind"`
+	Path     string    `json:"path"`
+	OrigPath string    `json:"orig_path,omitempty"` // 重命名前的路径
+	Index    string    `json:"index"`
+	Worktree string    `json:"worktree"`
+	Score    string    `json:"score,omitempty"` // 重命名相似度, 如 R100
+}
+
+// BranchStatus 当前分支
+type BranchStatus struct {
+	OID      string `json:"oid"`  // 没有提交时为 (initial)
+	Head     string `json:"head"` // 分离 HEAD 时为 (detached)
+	Upstream string `json:"upstream,omitempty"`
+	Ahead    int    `json:"ahead"`
+	Behind   int    `json:"behind"`
+}
+
+// Status git status 的结果
+type Status struct {
+	Branch  BranchStatus  `json:"branch"`
+	Entries []StatusEntry `json:"entries"`
+}
+
+// Clean 没有任何改动和未跟踪文件
+func (s *Status) Clean() bool {
+	for _, e := range s.Entries {
+		if e.Kind != Ignored {
+			return false
+		}
+	}
+	return true
+}
+
+// Status 执行 git status --porcelain=v2 --branch -z
+func (r *Repo) Status(ctx context.Context, ignored bool) (*Status, error) {
+	args := []string{"status", "--porcelain=v2", "--branch", "-z", "--untracked-files=all"}
+	if ignored {
+		args = append(args, "--ignored")
+	}
+	out, err := r.Run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseStatus(splitZ(out))
+}
+
+func parseStatus(records []string) (*Status, error) {
+	s := &Status{Entries: []StatusEntry{}}
+	for i := 0; i < len(records); i++ {
+		rec := records[i]
+		if rec == "" {
+			continue
+		}
+		bad := fmt.Errorf("parse status: unexpected record %q", rec)
+		switch rec[0] {
+		case '#':
+			if err := s.Branch.parseHeader(rec); err != nil {
+				return nil, err
+			}
+		case '1':
+			// 1 XY sub mH mI mW hH hI path
+			f := strings.SplitN(rec, " ", 9)
+			if len(f) != 9 || len(f[1]) != 2 {
+				return nil, bad
+			}
+			s.Entries = append(s.Entries, StatusEntry{Kind: Changed, Path: f[8], Index: f[1][:1], Worktree: f[1][1:]})
+		case '2':
+			// 2 XY sub mH mI mW hH hI Xscore path, -z 时原路径是下一条记录
+			f := strings.SplitN(rec, " ", 10)
+			if len(f) != 10 || len(f[1]) != 2 || i+1 >= len(records) {
+				return nil, bad
+			}
+			i++
+			s.Entries = append(s.Entries, StatusEntry{Kind: Renamed, Path: f[9], OrigPath: records[i], Index: f[1][:1], Worktree: f[1][1:], Score: f[8]})
+		case 'u':
+			// u XY sub m1 m2 m3 mW h1 h2 h3 path
+			f := strings.SplitN(rec, " ", 11)
+			if len(f) != 11 || len(f[1]) != 2 {
+				return nil, bad
+			}
+			s.Entries = append(s.Entries, StatusEntry{Kind: Unmerged, Path: f[10], Index: f[1][:1], Worktree: f[1][1:]})
+		case '?':
+			s.Entries = append(s.Entries, StatusEntry{Kind: Untracked, Path: strings.TrimPrefix(rec, "? "), Index: "?", Worktree: "?"})
+		case '!':
+			s.Entries = append(s.Entries, StatusEntry{Kind: Ignored, Path: strings.TrimPrefix(rec, "! "), Index: "!", Worktree: "!"})
+		default:
+			return nil, bad
+		}
+	}
+	return s, nil
+}
+
+// parseHeader # branch.oid / branch.head / branch.upstream / branch.ab
+func (b *BranchStatus) parseHeader(rec string) error {
+	key, value, _ := strings.Cut(strings.TrimPrefix(rec, "# "), " ")
+	switch key {
+	case "branch.oid":
+		b.OID = value
+	case "branch.head":
+		b.Head = value
+	case "branch.upstream":
+		b.Upstream = value
+	case "branch.ab":
+		var err error
+		if b.Ahead, b.Behind, err = parseAheadBehind(value); err != nil {
+			return fmt.Errorf("parse status: %q: %w", rec, err)
+		}
+	}
+	return nil
+}
+
+// parseAheadBehind +1 -2
+func parseAheadBehind(s string) (ahead, behind int, err error) {
+	a, b, ok := strings.Cut(s, " ")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid ahead/behind %q", s)
+	}
+	if ahead, err = strconv.Atoi(strings.TrimPrefix(a, "+")); err != nil {
+		return 0, 0, err
+	}
+	if behind, err = strconv.Atoi(strings.TrimPrefix(b, "-")); err != nil {
+		return 0, 0, err
+	}
+	return ahead, behind, nil
+}
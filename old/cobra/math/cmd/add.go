@@ -12,7 +12,10 @@ var addCmd = &cobra.Command{
 	Short: "Add subcommand add all passed args.",
 	Run: func(cmd *cobra.Command, args []string) {
 		values := ConvertArgsToFloat64Slice(args, ErrorHandling(parseHandling))
-		result := calc(values, ADD)
+		result, err := calc(values, ADD)
+		if err != nil {
+			Error(cmd, args, err)
+		}
 		fmt.Printf("%s = %.2f\n", strings.Join(args, "+"), result)
 	},
 }
@@ -16,13 +16,16 @@ var divideCmd = &cobra.Command{
 	Short: "Divide subcommand divide all passed args.",
 	Run: func(cmd *cobra.Command, args []string) {
 		values := ConvertArgsToFloat64Slice(args, ErrorHandling(parseHandling))
-		result := calc(values, DIVIDE)
+		result, err := calc(values, DIVIDE)
+		if err != nil {
+			Error(cmd, args, err)
+		}
 		fmt.Printf("%s = %.2f\n", strings.Join(args, "/"), result)
 	},
 }
 
 func init() {
-	divideCmd.Flags().IntVarP(&dividedByZeroHanding, "divide_by_zero", "d", int(PanicOnDividedByZero), "do what when divided by zero")
+	divideCmd.Flags().IntVarP(&dividedByZeroHanding, "divide_by_zero", "d", int(ErrorOnDividedByZero), "do what when divided by zero")
 
 	rootCmd.AddCommand(divideCmd)
 }
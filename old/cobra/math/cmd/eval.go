@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"testGo/cobra/math/expr"
+)
+
+var (
+	evalPrec    uint     // big.Float 精度, 0 表示 float64
+	evalVars    []string // name=表达式
+	evalHistory string   // REPL 历史文件
+)
+
+var evalCmd = &cobra.Command{
+	Use:   "eval [expression]",
+	Short: "Eval subcommand evaluates an expression, or starts a REPL without args.",
+	Example: `  math eval "1 + 2 * (3 - 4) ^ 2"
+  math eval --var r=2 "pi * r ^ 2"
+  math eval --prec 256 "sqrt(2)"
+  math eval -- "-2 ^ 2"
+  math eval`,
+	Run: func(cmd *cobra.Command, args []string) {
+		env := expr.NewEnv(evalPrec)
+		for _, def := range evalVars {
+			stmt, err := expr.Parse(def)
+			if err == nil && stmt.Assign == "" {
+				err = fmt.Errorf("--var %q is not an assignment like name=value", def)
+			}
+			if err == nil {
+				_, err = env.Exec(def)
+			}
+			if err != nil {
+				Error(cmd, args, err)
+			}
+		}
+
+		if len(args) == 0 {
+			repl := &expr.REPL{Env: env, In: os.Stdin, Out: os.Stdout, Prompt: "> ", HistoryFile: evalHistory}
+			if err := repl.Run(); err != nil {
+				Error(cmd, args, err)
+			}
+			return
+		}
+		v, err := env.Exec(strings.Join(args, " "))
+		if err != nil {
+			Error(cmd, args, err)
+		}
+		fmt.Println(v)
+	},
+}
+
+func init() {
+	history := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		history = filepath.Join(home, ".math_history")
+	}
+	evalCmd.Flags().UintVarP(&evalPrec, "prec", "P", 0, "use math/big with this many bits of precision, 0 for float64")
+	evalCmd.Flags().StringArrayVarP(&evalVars, "var", "v", nil, "define a variable, name=expression, repeatable")
+	evalCmd.Flags().StringVar(&evalHistory, "history", history, "REPL history file, empty to disable")
+
+	rootCmd.AddCommand(evalCmd)
+}
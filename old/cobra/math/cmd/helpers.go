@@ -9,10 +9,13 @@ import (
 	"github.com/spf13/cobra"
 )
 
-func calc(values []float64, opType OpType) float64 {
+// ErrDividedByZero 除 0 且处理方式为 ErrorOnDividedByZero
+var ErrDividedByZero = errors.New("divided by 0")
+
+func calc(values []float64, opType OpType) (float64, error) {
 	var result float64
 	if len(values) == 0 {
-		return result
+		return result, nil
 	}
 
 	result = values[0]
@@ -28,20 +31,22 @@ func calc(values []float64, opType OpType) float64 {
 			if values[i] == 0 {
 				switch ErrorHandling(dividedByZeroHanding) {
 				case ReturnOnDividedByZero:
-					return result
+					return result, nil
 				case PanicOnDividedByZero:
-					panic(errors.New("divided by 0"))
+					panic(ErrDividedByZero)
+				case ErrorOnDividedByZero:
+					return result, ErrDividedByZero
 				}
 			}
 			result /= values[i]
 		}
 	}
 
-	return result
+	return result, nil
 }
 
 func Error(cmd *cobra.Command, args []string, err error) {
-	fmt.Fprintf(os.Stderr, "execute %s args:%v error:%v", cmd.Name(), args, err)
+	fmt.Fprintf(os.Stderr, "execute %s args:%v error:%v\n", cmd.Name(), args, err)
 	os.Exit(1)
 }
 
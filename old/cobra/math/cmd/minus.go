@@ -12,7 +12,10 @@ var minusCmd = &cobra.Command{
 	Short: "Minus subcommand minus all passed args.",
 	Run: func(cmd *cobra.Command, args []string) {
 		values := ConvertArgsToFloat64Slice(args, ErrorHandling(parseHandling))
-		result := calc(values, MINUS)
+		result, err := calc(values, MINUS)
+		if err != nil {
+			Error(cmd, args, err)
+		}
 		fmt.Printf("%s = %.2f\n", strings.Join(args, "-"), result)
 	},
 }
@@ -12,7 +12,10 @@ var multiplyCmd = &cobra.Command{
 	Short: "Multiply subcommand multiply all passed args.",
 	Run: func(cmd *cobra.Command, args []string) {
 		values := ConvertArgsToFloat64Slice(args, ErrorHandling(parseHandling))
-		result := calc(values, MULTIPLY)
+		result, err := calc(values, MULTIPLY)
+		if err != nil {
+			Error(cmd, args, err)
+		}
 		fmt.Printf("%s = %.2f\n", strings.Join(args, "*"), result)
 	},
 }
@@ -14,6 +14,7 @@ const (
 	PanicOnParseError     ErrorHandling = 3 // 解析错误 panic
 	ReturnOnDividedByZero ErrorHandling = 4 // 除0返回
 	PanicOnDividedByZero  ErrorHandling = 5 // 除0 painc
+	ErrorOnDividedByZero  ErrorHandling = 6 // 除0返回错误
 )
 
 type OpType int
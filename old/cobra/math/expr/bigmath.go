@@ -0,0 +1,112 @@
+package expr
+
+import (
+	"math/big"
+)
+
+// math/big 没有 log 和 exp, 这里用级数实现; prec 为工作精度, 调用方应比结果精度多留一些位
+
+// bigLog 自然对数, x > 0
+//
+// x = m * 2^k, m ∈ [0.5, 1), ln x = ln m + k*ln2, ln m = 2*atanh((m-1)/(m+1))
+func bigLog(x *big.Float, prec uint) *big.Float {
+	m := new(big.Float).SetPrec(prec)
+	k := x.MantExp(m)
+	one := big.NewFloat(1)
+	z := new(big.Float).SetPrec(prec).Sub(m, one)
+	z.Quo(z, new(big.Float).SetPrec(prec).Add(m, one))
+	result := atanh2(z, prec)
+	if k != 0 {
+		t := new(big.Float).SetPrec(prec).SetInt64(int64(k))
+		result.Add(result, t.Mul(t, bigLn2(prec)))
+	}
+	return result
+}
+
+// bigLn2 ln2 = 2*atanh(1/3)
+func bigLn2(prec uint) *big.Float {
+	third := new(big.Float).SetPrec(prec).Quo(big.NewFloat(1), big.NewFloat(3))
+	return atanh2(third, prec)
+}
+
+// atanh2 2*atanh(z) = 2*(z + z^3/3 + z^5/5 + ...), |z| <= 1/3 时每项至少收敛 3 位
+func atanh2(z *big.Float, prec uint) *big.Float {
+	sum := new(big.Float).SetPrec(prec).Set(z)
+	if z.Sign() == 0 {
+		return sum
+	}
+	z2 := new(big.Float).SetPrec(prec).Mul(z, z)
+	power := new(big.Float).SetPrec(prec).Set(z)
+	term := new(big.Float).SetPrec(prec)
+	for n := int64(3); ; n += 2 {
+		power.Mul(power, z2)
+		term.Quo(power, new(big.Float).SetInt64(n))
+		if term.Sign() == 0 || term.MantExp(nil) < sum.MantExp(nil)-int(prec) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	return sum.Mul(sum, big.NewFloat(2))
+}
+
+// bigExp e^x, 调用方保证 |x| 不会让结果的指数溢出
+//
+// x = k*ln2 + r, 0 <= r < 2*ln2, 再把 r 缩小 2^8 倍求泰勒级数后平方 8 次
+func bigExp(x *big.Float, prec uint) *big.Float {
+	const squarings = 8
+	ln2 := bigLn2(prec)
+	kf := new(big.Float).SetPrec(prec).Quo(x, ln2)
+	k, _ := kf.Int64()
+	if kf.Sign() < 0 {
+		k--
+	}
+	r := new(big.Float).SetPrec(prec).Mul(ln2, new(big.Float).SetInt64(k))
+	r.Sub(x, r)
+	r.SetMantExp(r, -squarings)
+
+	sum := new(big.Float).SetPrec(prec).SetInt64(1)
+	term := new(big.Float).SetPrec(prec).SetInt64(1)
+	for n := int64(1); ; n++ {
+		term.Mul(term, r)
+		term.Quo(term, new(big.Float).SetInt64(n))
+		if term.Sign() == 0 || term.MantExp(nil) < -int(prec) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	for i := 0; i < squarings; i++ {
+		sum.Mul(sum, sum)
+	}
+	return sum.SetMantExp(sum, int(k))
+}
+
+// bigPi Machin 公式 pi = 16*atan(1/5) - 4*atan(1/239)
+func bigPi(prec uint) *big.Float {
+	a := atanInv(5, prec)
+	b := atanInv(239, prec)
+	a.Mul(a, big.NewFloat(16))
+	b.Mul(b, big.NewFloat(4))
+	return a.Sub(a, b)
+}
+
+// atanInv atan(1/n) = 1/n - 1/(3n^3) + 1/(5n^5) - ...
+func atanInv(n int64, prec uint) *big.Float {
+	nf := new(big.Float).SetPrec(prec).SetInt64(n)
+	n2 := new(big.Float).SetPrec(prec).Mul(nf, nf)
+	power := new(big.Float).SetPrec(prec).Quo(big.NewFloat(1), nf)
+	sum := new(big.Float).SetPrec(prec).Set(power)
+	term := new(big.Float).SetPrec(prec)
+	for k := int64(1); ; k++ {
+		power.Quo(power, n2)
+		term.Quo(power, new(big.Float).SetInt64(2*k+1))
+		if term.MantExp(nil) < -int(prec) {
+			break
+		}
+		if k%2 == 1 {
+			sum.Sub(sum, term)
+		} else {
+			sum.Add(sum, term)
+		}
+	}
+	return sum
+}
@@ -0,0 +1,419 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+)
+
+var (
+	ErrDivisionByZero = errors.New("division by zero")
+	ErrDomain         = errors.New("argument out of domain")
+	ErrOverflow       = errors.New("overflow")
+	ErrUndefined      = errors.New("undefined variable")
+	ErrUnknownFunc    = errors.New("unknown function")
+	ErrArgCount       = errors.New("wrong number of arguments")
+)
+
+// EvalError 计算错误, Pos 为出错的运算符或函数在表达式中的位置
+type EvalError struct {
+	Pos int
+	Err error
+}
+
+func (e *EvalError) Error() string {
+	return fmt.Sprintf("at %d: %v", e.Pos, e.Err)
+}
+
+func (e *EvalError) Unwrap() error {
+	return e.Err
+}
+
+// Value 计算结果, 任意精度模式下 Big 不为 nil
+type Value struct {
+	Float float64
+	Big   *big.Float
+}
+
+func (v Value) String() string {
+	if v.Big != nil {
+		// 只输出精度范围内有意义的十进制位数
+		digits := int(float64(v.Big.Prec()) * math.Log10(2))
+		return v.Big.Text('g', max(digits, 1))
+	}
+	return strconv.FormatFloat(v.Float, 'g', -1, 64)
+}
+
+// Float64 转为 float64, 任意精度模式下可能损失精度
+func (v Value) Float64() float64 {
+	if v.Big != nil {
+		f, _ := v.Big.Float64()
+		return f
+	}
+	return v.Float
+}
+
+// Env 变量和计算模式, 不能并发使用
+type Env struct {
+	// Prec 为 0 时用 float64 计算, 否则用精度为 Prec 位的 big.Float
+	Prec uint
+	vars map[string]Value
+}
+
+// NewEnv 内置常量 pi 和 e 不需要定义, 定义同名变量会覆盖它们
+func NewEnv(prec uint) *Env {
+	return &Env{Prec: prec, vars: map[string]Value{}}
+}
+
+// Set 设置变量
+func (e *Env) Set(name string, v Value) {
+	e.vars[name] = v
+}
+
+// Get 读取变量
+func (e *Env) Get(name string) (Value, bool) {
+	v, ok := e.vars[name]
+	return v, ok
+}
+
+// Vars 已定义的变量名, 按字母排序
+func (e *Env) Vars() []string {
+	names := make([]string, 0, len(e.vars))
+	for name := range e.vars {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Exec 解析并执行一条语句, 赋值语句同时设置变量
+func (e *Env) Exec(src string) (Value, error) {
+	stmt, err := Parse(src)
+	if err != nil {
+		return Value{}, err
+	}
+	v, err := e.Eval(stmt.Expr)
+	if err != nil {
+		return Value{}, err
+	}
+	if stmt.Assign != "" {
+		e.Set(stmt.Assign, v)
+	}
+	return v, nil
+}
+
+// Eval 计算表达式
+func (e *Env) Eval(n Node) (Value, error) {
+	if e.Prec == 0 {
+		f, err := eval[float64](floatArith{}, e, n)
+		return Value{Float: f}, err
+	}
+	b, err := eval[*big.Float](bigArith{prec: e.Prec}, e, n)
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{Big: b}, nil
+}
+
+// Eval 用 float64 计算表达式, 没有变量
+func Eval(src string) (float64, error) {
+	v, err := NewEnv(0).Exec(src)
+	return v.Float, err
+}
+
+// arith 一种数值类型的运算, 出错时返回 ErrXxx, 由 eval 加上位置
+type arith[T any] interface {
+	parse(lit string) (T, error)
+	constant(name string) (T, bool)
+	from(v Value) T
+	neg(x T) T
+	binary(op rune, x, y T) (T, error)
+	sqrt(x T) (T, error)
+	log(x T) (T, error)
+}
+
+func eval[T any](a arith[T], env *Env, n Node) (T, error) {
+	var zero T
+	fail := func(err error) (T, error) {
+		return zero, &EvalError{Pos: n.pos(), Err: err}
+	}
+	switch n := n.(type) {
+	case *Number:
+		v, err := a.parse(n.Lit)
+		if err != nil {
+			return fail(err)
+		}
+		return v, nil
+	case *Ident:
+		if v, ok := env.vars[n.Name]; ok {
+			return a.from(v), nil
+		}
+		if v, ok := a.constant(n.Name); ok {
+			return v, nil
+		}
+		return fail(fmt.Errorf("%w %q", ErrUndefined, n.Name))
+	case *Unary:
+		x, err := eval(a, env, n.X)
+		if err != nil || n.Op == '+' {
+			return x, err
+		}
+		return a.neg(x), nil
+	case *Binary:
+		x, err := eval(a, env, n.X)
+		if err != nil {
+			return zero, err
+		}
+		y, err := eval(a, env, n.Y)
+		if err != nil {
+			return zero, err
+		}
+		v, err := a.binary(n.Op, x, y)
+		if err != nil {
+			return fail(err)
+		}
+		return v, nil
+	case *Call:
+		args := make([]T, len(n.Args))
+		for i, arg := range n.Args {
+			v, err := eval(a, env, arg)
+			if err != nil {
+				return zero, err
+			}
+			args[i] = v
+		}
+		v, err := call(a, n.Func, args)
+		if err != nil {
+			return fail(err)
+		}
+		return v, nil
+	}
+	return fail(fmt.Errorf("unknown node %T", n))
+}
+
+// call 内置函数: sqrt(x), pow(x, y), log(x) 自然对数, log(x, base)
+func call[T any](a arith[T], name string, args []T) (T, error) {
+	var zero T
+	want := map[string][]int{"sqrt": {1}, "pow": {2}, "log": {1, 2}}[name]
+	if want == nil {
+		return zero, fmt.Errorf("%w %q", ErrUnknownFunc, name)
+	}
+	if !slices.Contains(want, len(args)) {
+		return zero, fmt.Errorf("%w: %s takes %v, got %d", ErrArgCount, name, want, len(args))
+	}
+	switch name {
+	case "sqrt":
+		return a.sqrt(args[0])
+	case "pow":
+		return a.binary('^', args[0], args[1])
+	}
+	v, err := a.log(args[0])
+	if err != nil || len(args) == 1 {
+		return v, err
+	}
+	base, err := a.log(args[1])
+	if err != nil {
+		return zero, err
+	}
+	return a.binary('/', v, base)
+}
+
+type floatArith struct{}
+
+func (floatArith) parse(lit string) (float64, error) {
+	f, err := strconv.ParseFloat(lit, 64)
+	if err != nil {
+		return 0, ErrOverflow
+	}
+	return f, nil
+}
+
+func (floatArith) constant(name string) (float64, bool) {
+	switch name {
+	case "pi":
+		return math.Pi, true
+	case "e":
+		return math.E, true
+	}
+	return 0, false
+}
+
+func (floatArith) from(v Value) float64  { return v.Float64() }
+func (floatArith) neg(x float64) float64 { return -x }
+
+func (floatArith) binary(op rune, x, y float64) (float64, error) {
+	var v float64
+	switch op {
+	case '+':
+		v = x + y
+	case '-':
+		v = x - y
+	case '*':
+		v = x * y
+	case '/':
+		if y == 0 {
+			return 0, ErrDivisionByZero
+		}
+		v = x / y
+	case '^':
+		if x == 0 && y < 0 {
+			return 0, ErrDivisionByZero
+		}
+		v = math.Pow(x, y)
+	}
+	switch {
+	case math.IsNaN(v):
+		return 0, ErrDomain
+	case math.IsInf(v, 0):
+		return 0, ErrOverflow
+	}
+	return v, nil
+}
+
+func (floatArith) sqrt(x float64) (float64, error) {
+	if x < 0 {
+		return 0, ErrDomain
+	}
+	return math.Sqrt(x), nil
+}
+
+func (floatArith) log(x float64) (float64, error) {
+	if x <= 0 {
+		return 0, ErrDomain
+	}
+	return math.Log(x), nil
+}
+
+// bigArith 每一步都按 prec 舍入, log 和非整数次幂在内部多用 64 位计算后再舍入
+type bigArith struct {
+	prec uint
+}
+
+// maxBigExp 任意精度模式下结果的二进制指数上限 (约 10^315652), 超出时返回 ErrOverflow;
+// big.Float 本身能表示大得多的数, 但转成十进制输出的耗时随指数超线性增长
+const maxBigExp = 1 << 20
+
+// check 检查结果的数量级, 绝对值过大或过小都视为溢出
+func (b bigArith) check(v *big.Float) (*big.Float, error) {
+	if v.IsInf() {
+		return nil, ErrOverflow
+	}
+	if exp := v.MantExp(nil); exp > maxBigExp || exp < -maxBigExp {
+		return nil, ErrOverflow
+	}
+	return v, nil
+}
+
+func (b bigArith) new() *big.Float {
+	return new(big.Float).SetPrec(b.prec)
+}
+
+func (b bigArith) parse(lit string) (*big.Float, error) {
+	f, _, err := b.new().Parse(lit, 10)
+	if err != nil {
+		return nil, ErrOverflow
+	}
+	return b.check(f)
+}
+
+func (b bigArith) constant(name string) (*big.Float, bool) {
+	switch name {
+	case "pi":
+		return b.new().Set(bigPi(b.prec + 64)), true
+	case "e":
+		return b.new().Set(bigExp(big.NewFloat(1).SetPrec(b.prec+64), b.prec+64)), true
+	}
+	return nil, false
+}
+
+func (b bigArith) from(v Value) *big.Float {
+	if v.Big != nil {
+		return b.new().Set(v.Big)
+	}
+	return b.new().SetFloat64(v.Float)
+}
+
+func (b bigArith) neg(x *big.Float) *big.Float { return b.new().Neg(x) }
+
+func (b bigArith) binary(op rune, x, y *big.Float) (*big.Float, error) {
+	v := b.new()
+	switch op {
+	case '+':
+		v.Add(x, y)
+	case '-':
+		v.Sub(x, y)
+	case '*':
+		v.Mul(x, y)
+	case '/':
+		if y.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		v.Quo(x, y)
+	case '^':
+		var err error
+		if v, err = b.pow(x, y); err != nil {
+			return nil, err
+		}
+	}
+	return b.check(v)
+}
+
+// pow 整数次幂用平方求幂, 结果只有每次乘法的舍入误差; 其他情况用 exp(y * ln x)
+func (b bigArith) pow(x, y *big.Float) (*big.Float, error) {
+	if x.Sign() == 0 {
+		switch y.Sign() {
+		case -1:
+			return nil, ErrDivisionByZero
+		case 0:
+			return b.new().SetInt64(1), nil
+		}
+		return b.new(), nil
+	}
+	if y.IsInt() {
+		n, acc := y.Int64()
+		if acc != big.Exact {
+			return nil, ErrOverflow
+		}
+		wprec := b.prec + 64
+		result, base := new(big.Float).SetPrec(wprec).SetInt64(1), new(big.Float).SetPrec(wprec).Set(x)
+		for k := n; k != 0; k /= 2 {
+			if k%2 != 0 {
+				result.Mul(result, base)
+			}
+			base.Mul(base, base)
+		}
+		if n < 0 {
+			result.Quo(new(big.Float).SetInt64(1), result)
+		}
+		return b.new().Set(result), nil
+	}
+	if x.Sign() < 0 {
+		return nil, ErrDomain
+	}
+	wprec := b.prec + 64
+	ln := bigLog(x, wprec)
+	exponent := new(big.Float).SetPrec(wprec).Mul(ln, y)
+	if f, _ := exponent.Float64(); math.Abs(f) > 1<<40 {
+		return nil, ErrOverflow
+	}
+	return b.new().Set(bigExp(exponent, wprec)), nil
+}
+
+func (b bigArith) sqrt(x *big.Float) (*big.Float, error) {
+	if x.Sign() < 0 {
+		return nil, ErrDomain
+	}
+	if x.Sign() == 0 {
+		return b.new(), nil
+	}
+	return b.new().Sqrt(x), nil
+}
+
+func (b bigArith) log(x *big.Float) (*big.Float, error) {
+	if x.Sign() <= 0 {
+		return nil, ErrDomain
+	}
+	return b.new().Set(bigLog(x, b.prec+64)), nil
+}
@@ -0,0 +1,216 @@
+package expr
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvalFloat(t *testing.T) {
+	tests := map[string]float64{
+		"1 + 2 * 3":             7,
+		"(1 + 2) * 3":           9,
+		"10 - 4 - 3":            3,
+		"2 * 3 / 4":             1.5,
+		"-2 ^ 2":                -4,
+		"2 ^ 3 ^ 2":             512,
+		"2 ^ -1":                0.5,
+		"--3":                   3,
+		"+-3 * -(1 + 1)":        6,
+		".5 + 1e2 + 2.5E-1":     100.75,
+		"sqrt(16) + pow(2, 10)": 1028,
+		"log(e)":                1,
+		"log(8, 2)":             3,
+		"pi":                    math.Pi,
+	}
+	for src, want := range tests {
+		got, err := Eval(src)
+		if err != nil || math.Abs(got-want) > 1e-12 {
+			t.Errorf("Eval(%q) = %v, %v, want %v", src, got, err, want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := map[string]struct {
+		err error
+		pos int
+	}{
+		"1 / (2 - 2)":  {ErrDivisionByZero, 2},
+		"0 ^ -1":       {ErrDivisionByZero, 2},
+		"sqrt(-1)":     {ErrDomain, 0},
+		"1 + log(0)":   {ErrDomain, 4},
+		"(-8) ^ 0.5":   {ErrDomain, 5},
+		"10 ^ 400":     {ErrOverflow, 3},
+		"x + 1":        {ErrUndefined, 0},
+		"foo(1)":       {ErrUnknownFunc, 0},
+		"pow(1)":       {ErrArgCount, 0},
+		"log(1, 2, 3)": {ErrArgCount, 0},
+		"log(2, 1)":    {ErrDivisionByZero, 0},
+	}
+	for src, want := range tests {
+		_, err := Eval(src)
+		var evalErr *EvalError
+		if !errors.Is(err, want.err) || !errors.As(err, &evalErr) || evalErr.Pos != want.pos {
+			t.Errorf("Eval(%q) error = %v, want %v at %d", src, err, want.err, want.pos)
+		}
+	}
+
+	syntax := map[string]int{
+		"":         0,
+		"1 +":      3,
+		"(1 + 2":   6,
+		"1 2":      2,
+		"2 $ 3":    2,
+		"sqrt(1,)": 7,
+		"1.2.3":    3,
+		"x = ":     4,
+		"1 = 2":    2,
+	}
+	for src, pos := range syntax {
+		_, err := Eval(src)
+		var se *SyntaxError
+		if !errors.As(err, &se) || se.Pos != pos {
+			t.Errorf("Eval(%q) error = %v, want syntax error at %d", src, err, pos)
+		}
+	}
+}
+
+func TestVariables(t *testing.T) {
+	env := NewEnv(0)
+	for _, src := range []string{"r = 2", "area = pi * r ^ 2", "pi = 3"} {
+		if _, err := env.Exec(src); err != nil {
+			t.Fatal(err)
+		}
+	}
+	area, _ := env.Get("area")
+	if math.Abs(area.Float-4*math.Pi) > 1e-12 {
+		t.Fatalf("area = %v", area)
+	}
+	// 变量覆盖内置常量
+	if v, _ := env.Exec("pi * r"); v.Float != 6 {
+		t.Fatalf("pi * r = %v", v)
+	}
+	if got := strings.Join(env.Vars(), ","); got != "area,pi,r" {
+		t.Fatalf("vars %s", got)
+	}
+	if _, err := ParseExpr("x = 1"); err == nil {
+		t.Fatal("ParseExpr accepts assignment")
+	}
+}
+
+func TestEvalBig(t *testing.T) {
+	env := NewEnv(256)
+	tests := map[string]string{
+		"0.1 + 0.2":         "0.3",
+		"2 ^ 100":           "1267650600228229401496703205376",
+		"(1 / 3) * 3":       "1",
+		"sqrt(2)":           "1.414213562373095048801688724209698078569671875376948073176679737990732",
+		"pi":                "3.141592653589793238462643383279502884197169399375105820974944592307816",
+		"e":                 "2.718281828459045235360287471352662497757247093699959574966967627724076",
+		"log(2)":            "0.6931471805599453094172321214581765680755001343602552541206800094933936",
+		"log(1000, 10)":     "3",
+		"pow(2, 0.5)":       "1.414213562373095048801688724209698078569671875376948073176679737990732",
+		"exp = 2 ^ -3":      "0.125",
+		"10 ^ 400 / 10^399": "10",
+	}
+	for src, want := range tests {
+		v, err := env.Exec(src)
+		if err != nil {
+			t.Errorf("Exec(%q): %v", src, err)
+			continue
+		}
+		// 最后一两位可能因舍入不同, 比较前 70 位有效数字
+		got := v.String()
+		if n := min(len(want), 70); len(got) < n || got[:n] != want[:n] {
+			t.Errorf("Exec(%q) = %s, want %s", src, got, want)
+		}
+	}
+	if _, err := env.Exec("1 / 0"); !errors.Is(err, ErrDivisionByZero) {
+		t.Fatalf("got %v", err)
+	}
+
+	// 数量级过大的结果和字面量直接报溢出, 不能在输出时卡住
+	start := time.Now()
+	for _, src := range []string{"2 ^ 1000000000", "0.5 ^ 1000000000", "1e100000000", "1e-100000000", "(2 ^ 1000000) ^ 2"} {
+		if v, err := env.Exec(src); !errors.Is(err, ErrOverflow) {
+			t.Errorf("Exec(%q) = %v, %v, want ErrOverflow", src, v, err)
+		}
+	}
+	if v, err := env.Exec("2 ^ 1000000"); err != nil || !strings.HasPrefix(v.String(), "9.90065") {
+		t.Errorf("Exec(2 ^ 1000000) = %v, %v", v, err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("huge results took %v", d)
+	}
+
+	// 切换模式时变量按新模式读取
+	env.Set("third", Value{Big: new(big.Float).SetPrec(256).Quo(big.NewFloat(1), big.NewFloat(3))})
+	env.Prec = 0
+	if v, err := env.Exec("third * 3"); err != nil || v.Float != 1 {
+		t.Fatalf("got %v, %v", v, err)
+	}
+}
+
+func TestREPL(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "history")
+	os.WriteFile(history, []byte("1 + 1\n"), 0o600)
+
+	input := strings.Join([]string{
+		"x = 2",
+		"x * 3",
+		"ans + 1",
+		"1 / 0",
+		"!1",
+		"!!",
+		":prec 128",
+		"1 / 3",
+		":vars",
+		":history",
+		":nope",
+		":quit",
+		"never reached",
+	}, "\n")
+	var out strings.Builder
+	r := &REPL{Env: NewEnv(0), In: strings.NewReader(input), Out: &out, HistoryFile: history}
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"2\n6\n7\n",
+		"error: at 2: division by zero\n",
+		"1 + 1\n2\n1 + 1\n2\n",
+		"0.33333333333333333333333333333333333333",
+		"x = 2\n",
+		"   1  1 + 1\n   2  x = 2\n",
+		"error: unknown command :nope",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "never") {
+		t.Fatal(":quit did not stop the REPL")
+	}
+
+	data, _ := os.ReadFile(history)
+	if lines := strings.Count(string(data), "\n"); lines != 8 {
+		t.Fatalf("history file has %d lines:\n%s", lines, data)
+	}
+
+	// 历史文件写不进去时提示一次, 会话继续
+	out.Reset()
+	r = &REPL{Env: NewEnv(0), In: strings.NewReader("1 + 1\n2 * 3\n!1\n"), Out: &out,
+		HistoryFile: filepath.Join(t.TempDir(), "missing", "history")}
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); strings.Count(got, "history not saved") != 1 || !strings.Contains(got, "2\n6\n1 + 1\n2\n") {
+		t.Fatalf("output:\n%s", got)
+	}
+}
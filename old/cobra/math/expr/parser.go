@@ -0,0 +1,323 @@
+// Package expr 四则运算表达式: 优先级、括号、一元负号、乘方、函数(sqrt, pow, log)和变量,
+// 可以用 float64 或 math/big 的任意精度计算
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SyntaxError 语法错误, Pos 为出错位置(字节偏移)
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %d: %s", e.Pos, e.Msg)
+}
+
+// Node 语法树节点
+type Node interface {
+	String() string
+	pos() int
+}
+
+type (
+	// Number 数字字面量, 保留原文以便任意精度模式按精度解析
+	Number struct {
+		Lit string
+		At  int
+	}
+	// Ident 变量
+	Ident struct {
+		Name string
+		At   int
+	}
+	// Unary 一元运算 -x, +x
+	Unary struct {
+		Op rune
+		X  Node
+		At int
+	}
+	// Binary 二元运算 + - * / ^
+	Binary struct {
+		Op   rune
+		X, Y Node
+		At   int
+	}
+	// Call 函数调用
+	Call struct {
+		Func string
+		Args []Node
+		At   int
+	}
+)
+
+func (n *Number) String() string { return n.Lit }
+func (n *Ident) String() string  { return n.Name }
+func (n *Unary) String() string  { return fmt.Sprintf("(%c%s)", n.Op, n.X) }
+func (n *Binary) String() string { return fmt.Sprintf("(%s %c %s)", n.X, n.Op, n.Y) }
+func (n *Call) String() string {
+	args := make([]string, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = arg.String()
+	}
+	return n.Func + "(" + strings.Join(args, ", ") + ")"
+}
+
+func (n *Number) pos() int { return n.At }
+func (n *Ident) pos() int  { return n.At }
+func (n *Unary) pos() int  { return n.At }
+func (n *Binary) pos() int { return n.At }
+func (n *Call) pos() int   { return n.At }
+
+// Stmt 一条语句: 表达式, 或者 name = 表达式 的赋值
+type Stmt struct {
+	Assign string // 赋值的变量名, 不是赋值时为空
+	Expr   Node
+}
+
+// Parse 解析一条语句
+//
+//	stmt    = [ident "="] expr
+//	expr    = term {("+" | "-") term}
+//	term    = unary {("*" | "/") unary}
+//	unary   = ("-" | "+") unary | power
+//	power   = primary ["^" unary]     右结合, -2^2 = -4, 2^-1 = 0.5
+//	primary = number | ident | ident "(" [expr {"," expr}] ")" | "(" expr ")"
+func Parse(src string) (Stmt, error) {
+	p := &parser{lex: lexer{src: src}}
+	p.next()
+	var stmt Stmt
+	if p.tok.kind == tokIdent {
+		// 向前看一个 token 判断是不是赋值
+		save := *p
+		name := p.tok.text
+		p.next()
+		if p.tok.kind == '=' {
+			stmt.Assign = name
+			p.next()
+		} else {
+			*p = save
+		}
+	}
+	stmt.Expr = p.expr()
+	if p.err == nil && p.tok.kind != tokEOF {
+		p.fail(p.tok.pos, "unexpected %s", p.tok)
+	}
+	if p.err != nil {
+		return Stmt{}, p.err
+	}
+	return stmt, nil
+}
+
+// ParseExpr 解析一个表达式, 不允许赋值
+func ParseExpr(src string) (Node, error) {
+	stmt, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if stmt.Assign != "" {
+		return nil, &SyntaxError{Pos: 0, Msg: "assignment is not allowed here"}
+	}
+	return stmt.Expr, nil
+}
+
+const (
+	tokEOF = -(iota + 1)
+	tokNumber
+	tokIdent
+	tokError
+)
+
+type token struct {
+	kind int // 单字符运算符就是字符本身
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type lexer struct {
+	src string
+	off int
+}
+
+func (l *lexer) next() token {
+	for l.off < len(l.src) {
+		r, size := utf8.DecodeRuneInString(l.src[l.off:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		l.off += size
+	}
+	start := l.off
+	if l.off >= len(l.src) {
+		return token{kind: tokEOF, pos: start}
+	}
+	r, size := utf8.DecodeRuneInString(l.src[l.off:])
+	switch {
+	case isDigit(r) || r == '.':
+		l.number()
+		return token{kind: tokNumber, text: l.src[start:l.off], pos: start}
+	case r == '_' || unicode.IsLetter(r):
+		for l.off < len(l.src) {
+			r, size := utf8.DecodeRuneInString(l.src[l.off:])
+			if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				break
+			}
+			l.off += size
+		}
+		return token{kind: tokIdent, text: l.src[start:l.off], pos: start}
+	case strings.ContainsRune("+-*/^(),=", r):
+		l.off += size
+		return token{kind: int(r), text: string(r), pos: start}
+	}
+	l.off += size
+	return token{kind: tokError, text: string(r), pos: start}
+}
+
+// number 数字: 123, 1.5, .5, 1e10, 2.5E-3
+func (l *lexer) number() {
+	digits := func() int {
+		n := 0
+		for l.off < len(l.src) && isDigit(rune(l.src[l.off])) {
+			l.off++
+			n++
+		}
+		return n
+	}
+	digits()
+	if l.off < len(l.src) && l.src[l.off] == '.' {
+		l.off++
+		digits()
+	}
+	if l.off < len(l.src) && (l.src[l.off] == 'e' || l.src[l.off] == 'E') {
+		save := l.off
+		l.off++
+		if l.off < len(l.src) && (l.src[l.off] == '+' || l.src[l.off] == '-') {
+			l.off++
+		}
+		if digits() == 0 {
+			// 不是指数, 比如 2e 后面跟的是变量名, 交给后面报错
+			l.off = save
+		}
+	}
+}
+
+func isDigit(r rune) bool { return '0' <= r && r <= '9' }
+
+type parser struct {
+	lex lexer
+	tok token
+	err error
+}
+
+func (p *parser) next() {
+	p.tok = p.lex.next()
+	if p.tok.kind == tokError {
+		p.fail(p.tok.pos, "unexpected character %q", p.tok.text)
+	}
+}
+
+// fail 只记录第一个错误
+func (p *parser) fail(pos int, format string, args ...any) {
+	if p.err == nil {
+		p.err = &SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+	}
+	p.tok = token{kind: tokEOF, pos: pos}
+}
+
+func (p *parser) expr() Node {
+	x := p.term()
+	for p.tok.kind == '+' || p.tok.kind == '-' {
+		op := p.tok
+		p.next()
+		x = &Binary{Op: rune(op.kind), X: x, Y: p.term(), At: op.pos}
+	}
+	return x
+}
+
+func (p *parser) term() Node {
+	x := p.unary()
+	for p.tok.kind == '*' || p.tok.kind == '/' {
+		op := p.tok
+		p.next()
+		x = &Binary{Op: rune(op.kind), X: x, Y: p.unary(), At: op.pos}
+	}
+	return x
+}
+
+func (p *parser) unary() Node {
+	if p.tok.kind == '-' || p.tok.kind == '+' {
+		op := p.tok
+		p.next()
+		return &Unary{Op: rune(op.kind), X: p.unary(), At: op.pos}
+	}
+	return p.power()
+}
+
+func (p *parser) power() Node {
+	x := p.primary()
+	if p.tok.kind == '^' {
+		op := p.tok
+		p.next()
+		return &Binary{Op: '^', X: x, Y: p.unary(), At: op.pos}
+	}
+	return x
+}
+
+func (p *parser) primary() Node {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber:
+		if tok.text == "." {
+			p.fail(tok.pos, "invalid number %q", tok.text)
+			return nil
+		}
+		p.next()
+		return &Number{Lit: tok.text, At: tok.pos}
+	case tokIdent:
+		p.next()
+		if p.tok.kind != '(' {
+			return &Ident{Name: tok.text, At: tok.pos}
+		}
+		p.next()
+		call := &Call{Func: tok.text, At: tok.pos}
+		if p.tok.kind != ')' {
+			call.Args = append(call.Args, p.expr())
+			for p.tok.kind == ',' {
+				p.next()
+				call.Args = append(call.Args, p.expr())
+			}
+		}
+		p.expect(')')
+		return call
+	case '(':
+		p.next()
+		x := p.expr()
+		p.expect(')')
+		return x
+	case tokEOF:
+		p.fail(tok.pos, "unexpected end of input")
+	default:
+		p.fail(tok.pos, "unexpected %s", tok)
+	}
+	return nil
+}
+
+func (p *parser) expect(kind int) {
+	if p.tok.kind != kind {
+		p.fail(p.tok.pos, "expected %q, got %s", rune(kind), p.tok)
+		return
+	}
+	p.next()
+}
@@ -0,0 +1,159 @@
+package expr
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const replHelp = `expressions: 1 + 2 * (3 - 4) ^ 2, sqrt(2), pow(2, 10), log(e), log(8, 2)
+assignment:  x = 3.5, the last result is stored in ans
+commands:
+  :vars        list variables
+  :prec N      switch to N-bit big.Float, 0 for float64
+  :history     list history
+  !N, !!       run history entry N, or the last one
+  :help        show this help
+  :quit        exit (or Ctrl-D)
+`
+
+// REPL 交互式计算, 每个结果保存到变量 ans
+type REPL struct {
+	Env    *Env
+	In     io.Reader
+	Out    io.Writer
+	Prompt string
+	// HistoryFile 不为空时启动时载入历史, 每条输入追加写入; 写入失败时提示一次, 之后只保存在内存里
+	HistoryFile string
+
+	history     []string
+	historyLost bool // 写历史文件失败过, 不再写入
+}
+
+// Run 读取并执行输入, 直到 EOF 或 :quit; 计算出错只输出错误, 不退出
+func (r *REPL) Run() error {
+	if err := r.loadHistory(); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(r.In)
+	for {
+		fmt.Fprint(r.Out, r.Prompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(r.Out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			quit, err := r.command(line)
+			if err != nil {
+				fmt.Fprintln(r.Out, "error:", err)
+			}
+			if quit {
+				return nil
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "!") {
+			expanded, err := r.expand(line)
+			if err != nil {
+				fmt.Fprintln(r.Out, "error:", err)
+				continue
+			}
+			line = expanded
+			fmt.Fprintln(r.Out, line)
+		}
+		if err := r.addHistory(line); err != nil {
+			fmt.Fprintln(r.Out, "error: history not saved:", err)
+			r.historyLost = true
+		}
+		v, err := r.Env.Exec(line)
+		if err != nil {
+			fmt.Fprintln(r.Out, "error:", err)
+			continue
+		}
+		r.Env.Set("ans", v)
+		fmt.Fprintln(r.Out, v)
+	}
+}
+
+func (r *REPL) command(line string) (quit bool, err error) {
+	name, arg, _ := strings.Cut(line, " ")
+	switch name {
+	case ":quit", ":q", ":exit":
+		return true, nil
+	case ":help":
+		fmt.Fprint(r.Out, replHelp)
+	case ":vars":
+		for _, name := range r.Env.Vars() {
+			v, _ := r.Env.Get(name)
+			fmt.Fprintf(r.Out, "%s = %s\n", name, v)
+		}
+	case ":prec":
+		prec, err := strconv.ParseUint(strings.TrimSpace(arg), 10, 32)
+		if err != nil {
+			return false, fmt.Errorf("invalid precision %q", arg)
+		}
+		r.Env.Prec = uint(prec)
+	case ":history":
+		for i, h := range r.history {
+			fmt.Fprintf(r.Out, "%4d  %s\n", i+1, h)
+		}
+	default:
+		return false, fmt.Errorf("unknown command %s, try :help", name)
+	}
+	return false, nil
+}
+
+// expand !! 为上一条, !N 为第 N 条
+func (r *REPL) expand(line string) (string, error) {
+	if len(r.history) == 0 {
+		return "", errors.New("history is empty")
+	}
+	if line == "!!" {
+		return r.history[len(r.history)-1], nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 || n > len(r.history) {
+		return "", fmt.Errorf("no history entry %s", line[1:])
+	}
+	return r.history[n-1], nil
+}
+
+func (r *REPL) loadHistory() error {
+	if r.HistoryFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(r.HistoryFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			r.history = append(r.history, line)
+		}
+	}
+	return nil
+}
+
+func (r *REPL) addHistory(line string) error {
+	r.history = append(r.history, line)
+	if r.HistoryFile == "" || r.historyLost {
+		return nil
+	}
+	f, err := os.OpenFile(r.HistoryFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(f, line)
+	return errors.Join(err, f.Close())
+}
@@ -1,25 +1,73 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"github.com/robfig/cron"
+	"log"
+	"net/http"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"testGo/cron/scheduler"
+)
+
+var (
+	dataPath = flag.String("data", "jobs.json", "任务定义和执行记录保存的文件")
+	addr     = flag.String("addr", ":8090", "管理接口监听地址")
 )
 
 func main() {
+	flag.Parse()
 
-	i := 0
-	c := cron.New()
-	spec := "*/2 * * * * ?"
-	err := c.AddFunc(spec, func() {
-		i++
-		fmt.Println("cron times : ", i)
-	})
+	s, err := scheduler.New(*dataPath, scheduler.Options{})
 	if err != nil {
-		fmt.Errorf("AddFunc error : %v", err)
-		return
+		log.Fatal(err)
 	}
-	c.Start()
 
-	defer c.Stop()
-	select {}
+	var i atomic.Int64
+	s.RegisterTask("print", func(ctx context.Context, args json.RawMessage) error {
+		var p struct {
+			Message string `json:"message"`
+		}
+		if len(args) > 0 {
+			if err := json.Unmarshal(args, &p); err != nil {
+				return fmt.Errorf("print args: %w", err)
+			}
+		}
+		fmt.Println(p.Message, i.Add(1))
+		return nil
+	})
+
+	// 第一次启动时添加示例任务, 之后以文件中的定义为准
+	if _, err := s.Job("times"); errors.Is(err, scheduler.ErrJobNotFound) {
+		err = s.Put(scheduler.Job{
+			Name: "times",
+			Spec: "*/2 * * * * ?",
+			Task: "print",
+			Args: json.RawMessage(`{"message":"cron times : "}`),
+		})
+		if err != nil {
+			log.Fatalf("add job error : %v", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: *addr, Handler: s.Handler()}
+	go func() {
+		log.Println("scheduler api listen on", *addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	if err := s.Run(ctx); err != nil {
+		log.Println(err)
+	}
+	_ = srv.Shutdown(context.Background())
 }
@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Handler 管理接口
+//
+//	GET    /jobs               任务列表
+//	GET    /jobs/{name}        任务详情和执行记录
+//	PUT    /jobs/{name}        新增或更新任务, body 为 Job 的 json, name 以路径为准
+//	DELETE /jobs/{name}        删除任务
+//	POST   /jobs/{name}/pause  暂停
+//	POST   /jobs/{name}/resume 恢复
+//	POST   /jobs/{name}/trigger 立即执行一次
+func (s *Scheduler) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /jobs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Jobs())
+	})
+	mux.HandleFunc("GET /jobs/{name}", func(w http.ResponseWriter, r *http.Request) {
+		job, err := s.Job(r.PathValue("name"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	})
+	mux.HandleFunc("PUT /jobs/{name}", func(w http.ResponseWriter, r *http.Request) {
+		var job Job
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&job); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		job.Name = r.PathValue("name")
+		if err := s.Put(job); err != nil {
+			writeError(w, err)
+			return
+		}
+		status, _ := s.Job(job.Name)
+		writeJSON(w, http.StatusOK, status)
+	})
+	mux.HandleFunc("DELETE /jobs/{name}", s.action(s.Delete))
+	mux.HandleFunc("POST /jobs/{name}/pause", s.action(s.Pause))
+	mux.HandleFunc("POST /jobs/{name}/resume", s.action(s.Resume))
+	mux.HandleFunc("POST /jobs/{name}/trigger", s.action(s.Trigger))
+	return mux
+}
+
+// action 对单个任务的操作, 成功时返回 204
+func (s *Scheduler) action(fn func(name string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := fn(r.PathValue("name")); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusBadRequest
+	if errors.Is(err, ErrJobNotFound) {
+		code = http.StatusNotFound
+	}
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,145 @@
+// Package scheduler 持久化的定时任务调度, 基于 robfig/cron 的表达式解析
+//
+// 任务定义和运行状态保存在磁盘上, 重启后继续调度, 并按任务的补跑策略执行停机期间错过的调度;
+// 任务逻辑通过 RegisterTask 按类型注册在代码里, 任务定义只引用类型名和参数
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+// Overlap 上一次还没执行完时又到了调度时间的处理方式
+type Overlap string
+
+const (
+	OverlapSkip  Overlap = "skip"  // 跳过本次, 记录为 skipped
+	OverlapQueue Overlap = "queue" // 排队, 上一次结束后马上执行
+	OverlapAllow Overlap = "allow" // 并发执行
+)
+
+// CatchUp 重启后如何处理停机期间错过的调度
+type CatchUp string
+
+const (
+	CatchUpNone CatchUp = "none" // 不补跑
+	CatchUpOnce CatchUp = "once" // 错过多次也只补跑一次
+	CatchUpAll  CatchUp = "all"  // 每次都补跑, 最多 MaxCatchUpRuns 次
+)
+
+// MaxCatchUpRuns CatchUpAll 最多补跑的次数, 防止长时间停机后瞬间涌入大量执行
+const MaxCatchUpRuns = 100
+
+// Job 任务定义
+type Job struct {
+	Name string `json:"name"`
+	// Spec 带秒的 cron 表达式, 例如 "*/2 * * * * ?", 也支持 "@every 1m"、"@daily" 这样的描述符
+	Spec string `json:"spec"`
+	// TimeZone IANA 时区名, 例如 Asia/Shanghai; 为空时使用 UTC
+	TimeZone string          `json:"timeZone,omitempty"`
+	Task     string          `json:"task"`
+	Args     json.RawMessage `json:"args,omitempty"`
+	Overlap  Overlap         `json:"overlap,omitempty"`
+	CatchUp  CatchUp         `json:"catchUp,omitempty"`
+	Timeout  Duration        `json:"timeout,omitempty"`
+	// Jitter 每次执行前随机延迟 [0, Jitter), 避免大量任务在同一时刻执行
+	Jitter Duration `json:"jitter,omitempty"`
+	Paused bool     `json:"paused,omitempty"`
+}
+
+// schedule 解析表达式和时区
+func (j *Job) schedule() (cron.Schedule, *time.Location, error) {
+	loc := time.UTC
+	if j.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(j.TimeZone); err != nil {
+			return nil, nil, fmt.Errorf("job %s: time zone: %w", j.Name, err)
+		}
+	}
+	sched, err := cron.Parse(j.Spec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("job %s: spec %q: %w", j.Name, j.Spec, err)
+	}
+	return sched, loc, nil
+}
+
+// normalize 校验并补全默认值
+func (j *Job) normalize() error {
+	if j.Name == "" {
+		return errors.New("job name is required")
+	}
+	if j.Task == "" {
+		return fmt.Errorf("job %s: task is required", j.Name)
+	}
+	switch j.Overlap {
+	case "":
+		j.Overlap = OverlapSkip
+	case OverlapSkip, OverlapQueue, OverlapAllow:
+	default:
+		return fmt.Errorf("job %s: unknown overlap policy %q", j.Name, j.Overlap)
+	}
+	switch j.CatchUp {
+	case "":
+		j.CatchUp = CatchUpNone
+	case CatchUpNone, CatchUpOnce, CatchUpAll:
+	default:
+		return fmt.Errorf("job %s: unknown catch-up policy %q", j.Name, j.CatchUp)
+	}
+	if j.Timeout < 0 || j.Jitter < 0 {
+		return fmt.Errorf("job %s: timeout and jitter must not be negative", j.Name)
+	}
+	_, _, err := j.schedule()
+	return err
+}
+
+// Duration json 中使用 "1m30s" 这样的文本
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Status 一次执行的结果
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusTimeout   Status = "timeout"
+	StatusSkipped   Status = "skipped" // 上一次还在执行, 按 OverlapSkip 跳过
+)
+
+// Run 一次执行记录
+type Run struct {
+	// Scheduled 调度时间, 手动触发时为触发时间
+	Scheduled time.Time `json:"scheduled"`
+	Started   time.Time `json:"started"`
+	Duration  Duration  `json:"duration"`
+	Status    Status    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Trigger   string    `json:"trigger"` // schedule, manual, catch-up
+}
+
+// 执行的触发来源
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+	TriggerCatchUp  = "catch-up"
+)
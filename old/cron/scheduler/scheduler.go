@@ -0,0 +1,492 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+var (
+	// ErrJobNotFound 任务不存在
+	ErrJobNotFound = errors.New("scheduler: job not found")
+	// ErrUnknownTask 任务类型没有通过 RegisterTask 注册
+	ErrUnknownTask = errors.New("scheduler: unknown task")
+)
+
+// TaskFunc 任务逻辑, args 为任务定义中的 Args; 超时或调度器停止时 ctx 被取消
+type TaskFunc func(ctx context.Context, args json.RawMessage) error
+
+// Options 调度器参数
+type Options struct {
+	// HistoryLimit 每个任务保留的执行记录条数, 默认 50
+	HistoryLimit int
+	Logger       *log.Logger
+}
+
+// Scheduler 调度器, 任务定义和状态保存在 path 指向的 json 文件中
+type Scheduler struct {
+	path  string
+	opts  Options
+	now   func() time.Time
+	tasks map[string]TaskFunc
+
+	mu   sync.Mutex
+	jobs map[string]*entry
+	wake chan struct{}
+	ctx  context.Context // Run 的 ctx, 执行任务时从这里派生
+
+	running sync.WaitGroup
+}
+
+// entry 任务定义加运行时状态, 字段由 Scheduler.mu 保护
+type entry struct {
+	record
+
+	sched   cron.Schedule
+	loc     *time.Location
+	next    time.Time
+	running int
+	queue   []pending // OverlapQueue 排队等待的执行
+}
+
+type pending struct {
+	scheduled time.Time
+	trigger   string
+}
+
+// record 持久化的部分
+type record struct {
+	Job Job `json:"job"`
+	// LastScheduled 已经处理到的调度时间, 重启后从这里计算错过的调度
+	LastScheduled time.Time `json:"lastScheduled"`
+	History       []Run     `json:"history,omitempty"`
+}
+
+// New 从 path 加载任务, 文件不存在时从空开始
+func New(path string, opts Options) (*Scheduler, error) {
+	if opts.HistoryLimit <= 0 {
+		opts.HistoryLimit = 50
+	}
+	if opts.Logger == nil {
+		opts.Logger = log.Default()
+	}
+	s := &Scheduler{
+		path:  path,
+		opts:  opts,
+		now:   time.Now,
+		tasks: make(map[string]TaskFunc),
+		jobs:  make(map[string]*entry),
+		wake:  make(chan struct{}, 1),
+		ctx:   context.Background(),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("load jobs from %s: %w", path, err)
+	}
+	for _, r := range records {
+		e, err := newEntry(r)
+		if err != nil {
+			return nil, fmt.Errorf("load jobs from %s: %w", path, err)
+		}
+		s.jobs[r.Job.Name] = e
+	}
+	return s, nil
+}
+
+func newEntry(r record) (*entry, error) {
+	if err := r.Job.normalize(); err != nil {
+		return nil, err
+	}
+	sched, loc, err := r.Job.schedule()
+	if err != nil {
+		return nil, err
+	}
+	return &entry{record: r, sched: sched, loc: loc}, nil
+}
+
+// RegisterTask 注册任务类型, 需要在 Run 之前调用
+func (s *Scheduler) RegisterTask(name string, fn TaskFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[name] = fn
+}
+
+// Put 新增或更新任务; 更新时保留执行记录, 正在执行的任务按旧定义执行完
+func (s *Scheduler) Put(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tasks[job.Task]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownTask, job.Task)
+	}
+	e, err := newEntry(record{Job: job, LastScheduled: s.now()})
+	if err != nil {
+		return err
+	}
+	if old, ok := s.jobs[job.Name]; ok {
+		// 原地更新, 执行中的 goroutine 持有的是同一个 entry
+		old.Job, old.LastScheduled, old.sched, old.loc = e.Job, e.LastScheduled, e.sched, e.loc
+		e = old
+	}
+	e.next = e.nextAfter(e.LastScheduled)
+	s.jobs[job.Name] = e
+	s.changed()
+	return nil
+}
+
+// Delete 删除任务, 正在执行的不受影响
+func (s *Scheduler) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; !ok {
+		return ErrJobNotFound
+	}
+	delete(s.jobs, name)
+	s.changed()
+	return nil
+}
+
+// Pause 暂停调度, 暂停期间错过的调度在恢复后不补跑; 仍然可以手动触发
+func (s *Scheduler) Pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+// Resume 恢复调度
+func (s *Scheduler) Resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+func (s *Scheduler) setPaused(name string, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.jobs[name]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if e.Job.Paused == paused {
+		return nil
+	}
+	e.Job.Paused = paused
+	if !paused {
+		e.LastScheduled = s.now()
+		e.next = e.nextAfter(e.LastScheduled)
+	}
+	s.changed()
+	return nil
+}
+
+// Trigger 立即执行一次, 同样遵守任务的 Overlap 策略
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.jobs[name]
+	if !ok {
+		return ErrJobNotFound
+	}
+	s.fire(e, s.now(), TriggerManual)
+	s.save()
+	return nil
+}
+
+// changed 任务定义变化后保存并唤醒调度循环重新计算等待时间, 调用方持有 mu
+func (s *Scheduler) changed() {
+	s.save()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (e *entry) nextAfter(t time.Time) time.Time {
+	return e.sched.Next(t.In(e.loc))
+}
+
+// Run 补跑停机期间错过的调度, 然后按时间调度, 直到 ctx 结束; 返回前等待正在执行的任务结束
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.catchUp(s.now())
+	s.mu.Unlock()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		s.tick(s.now())
+
+		timer.Reset(s.untilNext())
+		select {
+		case <-ctx.Done():
+			s.running.Wait()
+			s.mu.Lock()
+			s.save()
+			s.mu.Unlock()
+			return nil
+		case <-s.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// untilNext 距离最近一次调度的时间, 没有任务时等一个小时或被唤醒
+func (s *Scheduler) untilNext() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wait := time.Hour
+	now := s.now()
+	for _, e := range s.jobs {
+		if !e.Job.Paused && e.next.Sub(now) < wait {
+			wait = e.next.Sub(now)
+		}
+	}
+	return max(wait, 0)
+}
+
+// catchUp 按各任务的 CatchUp 策略补跑 LastScheduled 到 now 之间错过的调度
+func (s *Scheduler) catchUp(now time.Time) {
+	for _, e := range s.sortedJobs() {
+		if e.Job.Paused {
+			continue
+		}
+		var missed []time.Time
+		for t := e.nextAfter(e.LastScheduled); !t.After(now) && len(missed) < MaxCatchUpRuns; t = e.nextAfter(t) {
+			missed = append(missed, t)
+		}
+		if len(missed) > 0 {
+			s.opts.Logger.Printf("[scheduler] job %s missed %d runs since %s, catch-up policy %s",
+				e.Job.Name, len(missed), e.LastScheduled.Format(time.RFC3339), e.Job.CatchUp)
+		}
+		switch {
+		case len(missed) == 0 || e.Job.CatchUp == CatchUpNone:
+		case e.Job.CatchUp == CatchUpOnce:
+			s.fire(e, missed[len(missed)-1], TriggerCatchUp)
+		case e.Job.CatchUp == CatchUpAll:
+			for _, t := range missed {
+				s.fire(e, t, TriggerCatchUp)
+			}
+		}
+		e.LastScheduled = now
+		e.next = e.nextAfter(now)
+	}
+	s.save()
+}
+
+// tick 执行所有到期的任务
+func (s *Scheduler) tick(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fired := false
+	for _, e := range s.sortedJobs() {
+		if e.Job.Paused || e.next.IsZero() || e.next.After(now) {
+			continue
+		}
+		s.fire(e, e.next, TriggerSchedule)
+		// 进程卡顿错过多次时只执行一次, 从当前时间重新计算
+		e.LastScheduled = e.next
+		e.next = e.nextAfter(now)
+		fired = true
+	}
+	if fired {
+		s.save()
+	}
+}
+
+func (s *Scheduler) sortedJobs() []*entry {
+	jobs := make([]*entry, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		jobs = append(jobs, e)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Job.Name < jobs[j].Job.Name })
+	return jobs
+}
+
+// fire 按 Overlap 策略执行或跳过, 调用方持有 mu
+func (s *Scheduler) fire(e *entry, scheduled time.Time, trigger string) {
+	if e.running > 0 {
+		switch e.Job.Overlap {
+		case OverlapSkip:
+			s.record(e, Run{Scheduled: scheduled, Started: s.now(), Status: StatusSkipped, Trigger: trigger,
+				Error: "previous run still in progress"})
+			return
+		case OverlapQueue:
+			e.queue = append(e.queue, pending{scheduled: scheduled, trigger: trigger})
+			return
+		}
+	}
+	e.running++
+	s.running.Add(1)
+	go s.execute(s.ctx, e, e.Job, scheduled, trigger)
+}
+
+// execute 执行一次任务并记录结果; job 是触发时的任务定义, 执行期间任务被更新不受影响
+func (s *Scheduler) execute(ctx context.Context, e *entry, job Job, scheduled time.Time, trigger string) {
+	defer s.running.Done()
+
+	if job.Jitter > 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(rand.N(time.Duration(job.Jitter))):
+		}
+	}
+	run := Run{Scheduled: scheduled, Started: s.now(), Trigger: trigger}
+	err := s.invoke(ctx, job)
+	run.Duration = Duration(s.now().Sub(run.Started))
+	switch {
+	case err == nil:
+		run.Status = StatusSucceeded
+	case errors.Is(err, context.DeadlineExceeded):
+		run.Status = StatusTimeout
+		run.Error = err.Error()
+	default:
+		run.Status = StatusFailed
+		run.Error = err.Error()
+	}
+	if err != nil {
+		s.opts.Logger.Printf("[scheduler] job %s %s: %v", job.Name, run.Status, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.running--
+	s.record(e, run)
+	if len(e.queue) > 0 && e.running == 0 {
+		next := e.queue[0]
+		e.queue = e.queue[1:]
+		e.running++
+		s.running.Add(1)
+		go s.execute(s.ctx, e, e.Job, next.scheduled, next.trigger)
+	}
+	s.save()
+}
+
+// invoke 调用任务函数, 处理超时和 panic
+func (s *Scheduler) invoke(ctx context.Context, job Job) (err error) {
+	s.mu.Lock()
+	fn, ok := s.tasks[job.Task]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownTask, job.Task)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(job.Timeout))
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	err = fn(ctx, job.Args)
+	if err != nil && ctx.Err() != nil && !errors.Is(err, ctx.Err()) {
+		// 任务没有包装 ctx 的错误, 按超时/取消处理
+		err = fmt.Errorf("%w: %v", ctx.Err(), err)
+	}
+	return err
+}
+
+func (s *Scheduler) record(e *entry, run Run) {
+	e.History = append(e.History, run)
+	if over := len(e.History) - s.opts.HistoryLimit; over > 0 {
+		e.History = slices.Delete(e.History, 0, over)
+	}
+}
+
+// save 原子地写入任务文件, 调用方持有 mu
+func (s *Scheduler) save() {
+	records := make([]record, 0, len(s.jobs))
+	for _, e := range s.sortedJobs() {
+		records = append(records, e.record)
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(s.path, data)
+	}
+	if err != nil {
+		s.opts.Logger.Printf("[scheduler] save %s: %v", s.path, err)
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// JobStatus 任务定义和当前状态
+type JobStatus struct {
+	Job
+	Next    *time.Time `json:"next,omitempty"` // 暂停时为空
+	Running int        `json:"running"`
+	Queued  int        `json:"queued"`
+	LastRun *Run       `json:"lastRun,omitempty"`
+	History []Run      `json:"history,omitempty"`
+}
+
+func (e *entry) status(withHistory bool) JobStatus {
+	st := JobStatus{Job: e.Job, Running: e.running, Queued: len(e.queue)}
+	if !e.Job.Paused && !e.next.IsZero() {
+		next := e.next
+		st.Next = &next
+	}
+	if n := len(e.History); n > 0 {
+		last := e.History[n-1]
+		st.LastRun = &last
+	}
+	if withHistory {
+		st.History = slices.Clone(e.History)
+	}
+	return st
+}
+
+// Jobs 所有任务, 按名称排序
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]JobStatus, 0, len(s.jobs))
+	for _, e := range s.sortedJobs() {
+		out = append(out, e.status(false))
+	}
+	return out
+}
+
+// Job 单个任务, 包含执行记录
+func (s *Scheduler) Job(name string) (JobStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.jobs[name]
+	if !ok {
+		return JobStatus{}, ErrJobNotFound
+	}
+	return e.status(true), nil
+}
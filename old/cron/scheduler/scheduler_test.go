@@ -0,0 +1,271 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// clock 测试用的可控时钟
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+func newTestScheduler(t *testing.T, path string, c *clock) *Scheduler {
+	t.Helper()
+	s, err := New(path, Options{Logger: log.New(io.Discard, "", 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = c.Now
+	return s
+}
+
+func mustParse(t *testing.T, s string) time.Time {
+	t.Helper()
+	v, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func history(t *testing.T, s *Scheduler, name string) []Run {
+	t.Helper()
+	s.running.Wait()
+	st, err := s.Job(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st.History
+}
+
+func TestTimeZoneAndPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	c := &clock{now: mustParse(t, "2024-05-01T00:00:00Z")} // 上海 08:00
+	s := newTestScheduler(t, path, c)
+	calls := 0
+	s.RegisterTask("count", func(ctx context.Context, args json.RawMessage) error {
+		calls++
+		return nil
+	})
+
+	if err := s.Put(Job{Name: "report", Spec: "0 0 9 * * *", TimeZone: "Asia/Shanghai", Task: "missing"}); !errors.Is(err, ErrUnknownTask) {
+		t.Fatalf("want ErrUnknownTask, got %v", err)
+	}
+	if err := s.Put(Job{Name: "report", Spec: "0 0 9 * * *", TimeZone: "Mars/Olympus", Task: "count"}); err == nil {
+		t.Fatal("want time zone error")
+	}
+	if err := s.Put(Job{Name: "report", Spec: "0 0 9 * * *", TimeZone: "Asia/Shanghai", Task: "count"}); err != nil {
+		t.Fatal(err)
+	}
+	st, _ := s.Job("report")
+	if want := mustParse(t, "2024-05-01T01:00:00Z"); st.Next == nil || !st.Next.Equal(want) {
+		t.Fatalf("next %v, want %v", st.Next, want)
+	}
+
+	s.tick(mustParse(t, "2024-05-01T00:59:59Z"))
+	s.tick(mustParse(t, "2024-05-01T01:00:00Z"))
+	runs := history(t, s, "report")
+	if calls != 1 || len(runs) != 1 || runs[0].Status != StatusSucceeded || runs[0].Trigger != TriggerSchedule {
+		t.Fatalf("calls %d runs %+v", calls, runs)
+	}
+
+	// 重启后任务和执行记录还在
+	s2 := newTestScheduler(t, path, c)
+	st, err := s2.Job("report")
+	if err != nil || st.Overlap != OverlapSkip || len(st.History) != 1 {
+		t.Fatalf("reloaded %+v %v", st, err)
+	}
+}
+
+func TestOverlapPolicies(t *testing.T) {
+	c := &clock{now: mustParse(t, "2024-05-01T00:00:00Z")}
+	for _, tt := range []struct {
+		overlap     Overlap
+		maxParallel int
+		statuses    []Status
+	}{
+		{OverlapSkip, 1, []Status{StatusSkipped, StatusSucceeded}},
+		{OverlapQueue, 1, []Status{StatusSucceeded, StatusSucceeded}},
+		{OverlapAllow, 2, []Status{StatusSucceeded, StatusSucceeded}},
+	} {
+		t.Run(string(tt.overlap), func(t *testing.T) {
+			s := newTestScheduler(t, filepath.Join(t.TempDir(), "jobs.json"), c)
+			release := make(chan struct{})
+			started := make(chan struct{}, 2)
+			var mu sync.Mutex
+			running, maxRunning := 0, 0
+			s.RegisterTask("block", func(ctx context.Context, args json.RawMessage) error {
+				mu.Lock()
+				running++
+				maxRunning = max(maxRunning, running)
+				mu.Unlock()
+				started <- struct{}{}
+				<-release
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return nil
+			})
+			if err := s.Put(Job{Name: "j", Spec: "@every 1m", Task: "block", Overlap: tt.overlap}); err != nil {
+				t.Fatal(err)
+			}
+			s.Trigger("j")
+			<-started
+			s.Trigger("j")
+			if tt.overlap == OverlapAllow {
+				<-started
+			}
+			close(release)
+
+			runs := history(t, s, "j")
+			if maxRunning != tt.maxParallel || len(runs) != len(tt.statuses) {
+				t.Fatalf("max running %d, runs %+v", maxRunning, runs)
+			}
+			for i, status := range tt.statuses {
+				if runs[i].Status != status {
+					t.Fatalf("run %d: %s, want %s", i, runs[i].Status, status)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeoutAndPanic(t *testing.T) {
+	c := &clock{now: time.Now()}
+	s := newTestScheduler(t, filepath.Join(t.TempDir(), "jobs.json"), c)
+	s.RegisterTask("slow", func(ctx context.Context, args json.RawMessage) error {
+		<-ctx.Done()
+		return errors.New("gave up")
+	})
+	s.RegisterTask("panic", func(ctx context.Context, args json.RawMessage) error {
+		panic("boom")
+	})
+	s.Put(Job{Name: "slow", Spec: "@every 1m", Task: "slow", Timeout: Duration(10 * time.Millisecond)})
+	s.Put(Job{Name: "panic", Spec: "@every 1m", Task: "panic"})
+	s.Trigger("slow")
+	s.Trigger("panic")
+
+	if runs := history(t, s, "slow"); runs[0].Status != StatusTimeout || !strings.Contains(runs[0].Error, "gave up") {
+		t.Fatalf("got %+v", runs)
+	}
+	if runs := history(t, s, "panic"); runs[0].Status != StatusFailed || !strings.Contains(runs[0].Error, "boom") {
+		t.Fatalf("got %+v", runs)
+	}
+}
+
+func TestCatchUp(t *testing.T) {
+	for _, tt := range []struct {
+		policy    CatchUp
+		scheduled []string
+	}{
+		{CatchUpNone, nil},
+		{CatchUpOnce, []string{"2024-05-01T13:00:00Z"}},
+		{CatchUpAll, []string{"2024-05-01T11:00:00Z", "2024-05-01T12:00:00Z", "2024-05-01T13:00:00Z"}},
+	} {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "jobs.json")
+			c := &clock{now: mustParse(t, "2024-05-01T10:30:00Z")}
+			s := newTestScheduler(t, path, c)
+			s.RegisterTask("noop", func(context.Context, json.RawMessage) error { return nil })
+			if err := s.Put(Job{Name: "hourly", Spec: "0 0 * * * *", Task: "noop", CatchUp: tt.policy, Overlap: OverlapQueue}); err != nil {
+				t.Fatal(err)
+			}
+
+			// 停机到 13:10 后重启
+			c.Set(mustParse(t, "2024-05-01T13:10:00Z"))
+			s = newTestScheduler(t, path, c)
+			s.RegisterTask("noop", func(context.Context, json.RawMessage) error { return nil })
+			s.mu.Lock()
+			s.catchUp(c.Now())
+			s.mu.Unlock()
+
+			runs := history(t, s, "hourly")
+			if len(runs) != len(tt.scheduled) {
+				t.Fatalf("got %+v", runs)
+			}
+			for i, want := range tt.scheduled {
+				if !runs[i].Scheduled.Equal(mustParse(t, want)) || runs[i].Trigger != TriggerCatchUp {
+					t.Fatalf("run %d: %+v, want scheduled %s", i, runs[i], want)
+				}
+			}
+			st, _ := s.Job("hourly")
+			if !st.Next.Equal(mustParse(t, "2024-05-01T14:00:00Z")) {
+				t.Fatalf("next %v", st.Next)
+			}
+		})
+	}
+}
+
+func TestHTTPAPI(t *testing.T) {
+	c := &clock{now: time.Now()}
+	s := newTestScheduler(t, filepath.Join(t.TempDir(), "jobs.json"), c)
+	s.RegisterTask("echo", func(ctx context.Context, args json.RawMessage) error { return nil })
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	do := func(method, path, body string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	if code, body := do("PUT", "/jobs/hello", `{"spec":"*/2 * * * * ?","task":"echo","timeout":"5s","jitter":"1s"}`); code != http.StatusOK || !strings.Contains(body, `"timeout":"5s"`) {
+		t.Fatalf("put: %d %s", code, body)
+	}
+	if code, body := do("PUT", "/jobs/bad", `{"spec":"every day","task":"echo"}`); code != http.StatusBadRequest {
+		t.Fatalf("put bad spec: %d %s", code, body)
+	}
+	if code, _ := do("POST", "/jobs/hello/pause", ""); code != http.StatusNoContent {
+		t.Fatalf("pause: %d", code)
+	}
+	if code, _ := do("POST", "/jobs/hello/trigger", ""); code != http.StatusNoContent {
+		t.Fatalf("trigger: %d", code)
+	}
+	s.running.Wait()
+
+	code, body := do("GET", "/jobs", "")
+	var list []JobStatus
+	if err := json.Unmarshal([]byte(body), &list); err != nil || code != http.StatusOK || len(list) != 1 {
+		t.Fatalf("list: %d %s", code, body)
+	}
+	if !list[0].Paused || list[0].Next != nil || list[0].LastRun == nil || list[0].LastRun.Trigger != TriggerManual {
+		t.Fatalf("list: %s", body)
+	}
+
+	if code, _ := do("DELETE", "/jobs/hello", ""); code != http.StatusNoContent {
+		t.Fatalf("delete: %d", code)
+	}
+	if code, _ := do("GET", "/jobs/hello", ""); code != http.StatusNotFound {
+		t.Fatalf("get deleted: %d", code)
+	}
+}
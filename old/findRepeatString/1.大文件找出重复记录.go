@@ -2,149 +2,130 @@ package main
 
 import (
 	"bufio"
-	"crypto/sha256"
+	"context"
+	"flag"
 	"fmt"
+	"io"
+	"log"
 	"os"
-	"path/filepath"
-	"sync"
+	"os/signal"
+	"strings"
+
+	"testGo/findRepeatString/lineset"
 )
 
+// 对大文件按行做集合运算, 内存不够时按哈希分片到临时目录再逐片处理
+//
+//	go run . -op intersect file1.txt file2.txt
+//	go run . -op dup -csv-col 0 -counts -o dup.txt big.csv
+//	zcat huge.gz | go run . -op intersect -prefilter -progress small.txt -
 func main() {
-	file1 := "file1.txt"
-	file2 := "file2.txt"
-	outputFile := "duplicates.txt"
-
-	// 创建临时目录
-	tmpDir := "./tmp_chunks"
-	os.RemoveAll(tmpDir)
-	os.MkdirAll(tmpDir, 0755)
-
-	// 阶段1：哈希分片处理两个文件
-	chunkFiles1 := processFile(file1, tmpDir, "file1")
-	chunkFiles2 := processFile(file2, tmpDir, "file2")
-
-	// 阶段2：找出重复记录
-	findDuplicates(chunkFiles1, chunkFiles2, outputFile, tmpDir)
-
-	// 清理临时文件
-	os.RemoveAll(tmpDir)
-
-	fmt.Println("完成！重复记录已保存到", outputFile)
-}
+	var (
+		opName  = flag.String("op", "intersect", "intersect, diff, union 或 dup(单个文件内重复)")
+		output  = flag.String("o", "", "输出文件, 默认标准输出")
+		mem     = flag.String("mem", "512MB", "内存预算, 用于自动计算分片数")
+		shards  = flag.Int("shards", 0, "分片数, 0 表示自动")
+		tmpDir  = flag.String("tmp", "", "临时目录, 默认系统临时目录")
+		workers = flag.Int("workers", 0, "并行度, 默认 CPU 数")
+		csvCol  = flag.Int("csv-col", -1, "取第几列(从 0 开始)作为 key, -1 表示整行")
+		csvSep  = flag.String("csv-sep", ",", "csv 分隔符")
+		pattern = flag.String("regex", "", "用正则取 key, 有分组时取第一个分组")
+		counts  = flag.Bool("counts", false, "输出每个 key 在各文件中出现的次数")
+		prefilt = flag.Bool("prefilter", false, "交集和差集先用布隆过滤器过滤, 只分片可能命中的行")
+		fpRate  = flag.Float64("fp", 0.01, "布隆过滤器误判率")
+		showPro = flag.Bool("progress", false, "在标准错误输出读取进度")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] file1 [file2], 文件名为 - 时读标准输入\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
 
-// 处理单个文件：哈希分片
-func processFile(filename, tmpDir, prefix string) []string {
-	file, err := os.Open(filename)
+	op, err := lineset.ParseOp(*opName)
 	if err != nil {
-		panic(err)
+		log.Fatal(err)
 	}
-	defer file.Close()
-
-	// 创建分片文件
-	chunkFiles := make([]*os.File, 256)
-	chunkWriters := make([]*bufio.Writer, 256)
-	chunkFileNames := make([]string, 256)
-
-	for i := 0; i < 256; i++ {
-		chunkName := filepath.Join(tmpDir, fmt.Sprintf("%s_chunk_%03d.txt", prefix, i))
-		chunkFiles[i], _ = os.Create(chunkName)
-		chunkWriters[i] = bufio.NewWriter(chunkFiles[i])
-		chunkFileNames[i] = chunkName
+	opts := lineset.Options{
+		Shards:            *shards,
+		TempDir:           *tmpDir,
+		Workers:           *workers,
+		Prefilter:         *prefilt,
+		FalsePositiveRate: *fpRate,
 	}
-	defer func() {
-		for i := 0; i < 256; i++ {
-			if chunkWriters[i] != nil {
-				chunkWriters[i].Flush()
-			}
-			if chunkFiles[i] != nil {
-				chunkFiles[i].Close()
+	if *showPro {
+		opts.Progress = func(p lineset.Progress) {
+			fmt.Fprintf(os.Stderr, "\r%s\033[K", p)
+			if p.Done {
+				fmt.Fprintln(os.Stderr)
 			}
 		}
-	}()
-
-	scanner := bufio.NewScanner(file)
-	buffer := make([]byte, 0, 64*1024)
-	scanner.Buffer(buffer, 10*1024*1024) // 支持长行
-
-	var wg sync.WaitGroup
-	ch := make(chan string, 10000)
-
-	// 启动多个worker并行处理
-	for i := 0; i < 16; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for line := range ch {
-				hash := sha256.Sum256([]byte(line))
-				chunkIndex := int(hash[0]) // 用第一个字节作为分片索引
-
-				chunkWriters[chunkIndex].WriteString(line + "\n")
-			}
-		}()
-	}
-
-	// 读取文件并分发
-	for scanner.Scan() {
-		ch <- scanner.Text()
 	}
-	close(ch)
-	wg.Wait()
-
-	// 刷新所有writer
-	for i := 0; i < 256; i++ {
-		chunkWriters[i].Flush()
+	if opts.MemoryBudget, err = lineset.ParseSize(*mem); err != nil {
+		log.Fatal(err)
 	}
-
-	return chunkFileNames
-}
-
-// 找出重复记录
-func findDuplicates(chunks1, chunks2 []string, outputFile, tmpDir string) {
-	outFile, _ := os.Create(outputFile)
-	defer outFile.Close()
-	writer := bufio.NewWriter(outFile)
-	defer writer.Flush()
-
-	var wg sync.WaitGroup
-	results := make(chan string, 1000)
-
-	// 启动结果收集器
-	go func() {
-		for dup := range results {
-			writer.WriteString(dup + "\n")
+	switch {
+	case *csvCol >= 0 && *pattern != "":
+		log.Fatal("-csv-col and -regex are mutually exclusive")
+	case *csvCol >= 0:
+		sep := []rune(*csvSep)
+		if len(sep) != 1 {
+			log.Fatalf("-csv-sep must be a single character, got %q", *csvSep)
 		}
-	}()
-
-	// 并行处理每个分片
-	for i := 0; i < 256; i++ {
-		wg.Add(1)
-		go func(idx int) {
-			defer wg.Done()
-
-			// 读取第一个文件的分片到map
-			set1 := make(map[string]bool)
-			if f1, err := os.Open(chunks1[idx]); err == nil {
-				scanner := bufio.NewScanner(f1)
-				for scanner.Scan() {
-					set1[scanner.Text()] = true
-				}
-				f1.Close()
-			}
+		opts.Key = lineset.CSVColumn(*csvCol, sep[0])
+	case *pattern != "":
+		if opts.Key, err = lineset.Regexp(*pattern); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-			// 检查第二个文件的分片
-			if f2, err := os.Open(chunks2[idx]); err == nil {
-				scanner := bufio.NewScanner(f2)
-				for scanner.Scan() {
-					line := scanner.Text()
-					if set1[line] {
-						results <- line
-					}
-				}
-				f2.Close()
+	var inputs []lineset.Input
+	stdin := 0
+	for _, path := range flag.Args() {
+		if path == "-" {
+			if stdin++; stdin > 1 {
+				log.Fatal("stdin can only be used once")
 			}
-		}(i)
+		}
+		in, f, err := lineset.OpenFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		inputs = append(inputs, in)
 	}
 
-	wg.Wait()
-	close(results)
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	stats, err := lineset.Run(ctx, op, inputs, opts, func(r lineset.Result) error {
+		if !*counts {
+			_, err := fmt.Fprintln(w, r.Key)
+			return err
+		}
+		fields := []string{r.Key, fmt.Sprint(r.Counts[0])}
+		if len(inputs) > 1 {
+			fields = append(fields, fmt.Sprint(r.Counts[1]))
+		}
+		_, err := fmt.Fprintln(w, strings.Join(fields, "\t"))
+		return err
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	n := len(inputs)
+	log.Printf("%s: lines %v, skipped %v, filtered %v, shards %d, filter %d bytes, results %d",
+		op, stats.Lines[:n], stats.Skipped[:n], stats.Filtered[:n], stats.Shards, stats.FilterBytes, stats.Results)
 }
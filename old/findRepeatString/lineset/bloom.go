@@ -0,0 +1,61 @@
+package lineset
+
+import (
+	"hash/maphash"
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+// bloom 并发安全的布隆过滤器, 多个 worker 可以同时 add
+type bloom struct {
+	bits []atomic.Uint64
+	m    uint64 // 位数
+	k    int    // 哈希函数个数
+}
+
+// newBloom 按预计元素个数 n 和误判率 p 计算大小, 位数组不超过 maxBytes
+func newBloom(n int64, p float64, maxBytes int64) *bloom {
+	n = max(n, 1)
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	m = min(max(m, 64), uint64(max(maxBytes, 8))*8)
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	k = min(max(k, 1), 16)
+	words := (m + 63) / 64
+	return &bloom{bits: make([]atomic.Uint64, words), m: words * 64, k: k}
+}
+
+var bloomSeed = maphash.MakeSeed()
+
+// locations 双重哈希 h1 + i*h2 生成 k 个位置, 与分片用的 seed 不同, 避免同一分片的 key 聚集
+func (b *bloom) locations(key string, fn func(pos uint64) bool) {
+	h1 := maphash.String(bloomSeed, key)
+	h2 := bits.RotateLeft64(h1, 32) | 1
+	for i := 0; i < b.k; i++ {
+		if !fn((h1 + uint64(i)*h2) % b.m) {
+			return
+		}
+	}
+}
+
+func (b *bloom) add(key string) {
+	b.locations(key, func(pos uint64) bool {
+		b.bits[pos/64].Or(1 << (pos % 64))
+		return true
+	})
+}
+
+// mayContain 返回 false 时 key 一定不在集合中
+func (b *bloom) mayContain(key string) bool {
+	found := true
+	b.locations(key, func(pos uint64) bool {
+		found = b.bits[pos/64].Load()&(1<<(pos%64)) != 0
+		return found
+	})
+	return found
+}
+
+// size 位数组占用的内存
+func (b *bloom) size() int64 {
+	return int64(len(b.bits)) * 8
+}
@@ -0,0 +1,93 @@
+package lineset
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// KeyFunc 从一行中取出参与比较的 key, ok 为 false 时跳过这一行
+type KeyFunc func(line string) (key string, ok bool)
+
+// WholeLine 整行作为 key
+func WholeLine(line string) (string, bool) {
+	return line, true
+}
+
+// CSVColumn 取第 col 列(从 0 开始)作为 key, 支持双引号包裹的字段; 列数不够的行跳过
+func CSVColumn(col int, sep rune) KeyFunc {
+	return func(line string) (string, bool) {
+		if !strings.ContainsRune(line, '"') {
+			// 没有引号时不需要 csv 解析, 快很多
+			for i := 0; i < col; i++ {
+				idx := strings.IndexRune(line, sep)
+				if idx < 0 {
+					return "", false
+				}
+				line = line[idx+len(string(sep)):]
+			}
+			if idx := strings.IndexRune(line, sep); idx >= 0 {
+				line = line[:idx]
+			}
+			return line, true
+		}
+		r := csv.NewReader(strings.NewReader(line))
+		r.Comma = sep
+		r.FieldsPerRecord = -1
+		r.LazyQuotes = true
+		fields, err := r.Read()
+		if err != nil || col >= len(fields) {
+			return "", false
+		}
+		return fields[col], true
+	}
+}
+
+// Regexp 取第一个分组作为 key, 没有分组时取整个匹配; 不匹配的行跳过
+func Regexp(pattern string) (KeyFunc, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("key regexp: %w", err)
+	}
+	group := 0
+	if re.NumSubexp() > 0 {
+		group = 1
+	}
+	return func(line string) (string, bool) {
+		m := re.FindStringSubmatchIndex(line)
+		if m == nil || m[2*group] < 0 {
+			return "", false
+		}
+		return line[m[2*group]:m[2*group+1]], true
+	}, nil
+}
+
+// ParseSize 解析 512MB, 2G, 1024 这样的大小, 单位按 1024 进制, 不区分大小写
+func ParseSize(s string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	num := strings.TrimSuffix(strings.TrimSuffix(upper, "B"), "I")
+	shift := 0
+	if n := len(num); n > 0 {
+		switch num[n-1] {
+		case 'K':
+			shift = 10
+		case 'M':
+			shift = 20
+		case 'G':
+			shift = 30
+		case 'T':
+			shift = 40
+		}
+		if shift > 0 {
+			num = num[:n-1]
+		}
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(num), 10, 64)
+	if err != nil || v < 0 || v > math.MaxInt64>>shift {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return v << shift, nil
+}
@@ -0,0 +1,566 @@
+// Package lineset 对超出内存的大文件按行做集合运算: 交集、差集、并集和单文件内的重复
+//
+// 两阶段处理: 先按 key 的哈希把每个输入分片写到临时目录, 同一个 key 一定落在同一个分片里;
+// 再逐个分片载入内存精确计数. 分片数由内存预算自动决定, 保证单个分片能放进内存
+//
+// 交集和差集可以开启预过滤: 先完整分片一个输入并用它的 key 建布隆过滤器, 另一个输入只分片
+// 可能命中的行, 最后仍然精确比较, 所以结果不受误判影响
+package lineset
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Op 集合运算
+type Op int
+
+const (
+	Intersect  Op = iota // 两个输入中都出现的 key
+	Difference           // 只在第一个输入中出现的 key
+	Union                // 任一输入中出现的 key
+	Duplicates           // 单个输入中出现不止一次的 key
+)
+
+var opNames = []string{"intersect", "diff", "union", "dup"}
+
+func (op Op) String() string {
+	if op < 0 || int(op) >= len(opNames) {
+		return fmt.Sprintf("Op(%d)", int(op))
+	}
+	return opNames[op]
+}
+
+// ParseOp 解析 intersect, diff, union, dup
+func ParseOp(s string) (Op, error) {
+	for i, name := range opNames {
+		if s == name {
+			return Op(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown op %q, want one of %s", s, strings.Join(opNames, ", "))
+}
+
+func (op Op) inputs() int {
+	if op == Duplicates {
+		return 1
+	}
+	return 2
+}
+
+// Input 一个输入, Size 用于估算分片数, 未知时为 -1
+type Input struct {
+	Name string
+	R    io.Reader
+	Size int64
+}
+
+// OpenFile 打开文件作为输入, 调用方负责关闭返回的文件; path 为 - 时读标准输入
+func OpenFile(path string) (Input, *os.File, error) {
+	if path == "-" {
+		return Input{Name: "stdin", R: os.Stdin, Size: -1}, os.Stdin, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return Input{}, nil, err
+	}
+	size := int64(-1)
+	if st, err := f.Stat(); err == nil && st.Mode().IsRegular() {
+		size = st.Size()
+	}
+	return Input{Name: path, R: f, Size: size}, f, nil
+}
+
+// Result 一个 key 和它在各输入中出现的次数
+type Result struct {
+	Key    string
+	Counts [2]int64
+}
+
+// Stats 处理统计
+type Stats struct {
+	Bytes    [2]int64 // 各输入读到的字节数
+	Lines    [2]int64 // 各输入读到的行数
+	Skipped  [2]int64 // 没有取到 key 被跳过的行数
+	Filtered [2]int64 // 被预过滤掉、没有写入分片的行数
+	Shards   int
+	Results  int64
+	// FilterBytes 预过滤使用的布隆过滤器大小, 没有开启时为 0
+	FilterBytes int64
+}
+
+// Progress 分片阶段的读取进度
+type Progress struct {
+	Input   string
+	Bytes   int64
+	Lines   int64
+	Elapsed time.Duration
+	Done    bool // 这个输入已经读完
+}
+
+func (p Progress) String() string {
+	secs := max(p.Elapsed.Seconds(), 1e-9)
+	return fmt.Sprintf("%s: %s, %d lines in %s (%s/s, %.0f lines/s)",
+		p.Input, formatBytes(p.Bytes), p.Lines, p.Elapsed.Round(time.Millisecond),
+		formatBytes(int64(float64(p.Bytes)/secs)), float64(p.Lines)/secs)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Options 参数
+type Options struct {
+	// Key 取 key 的方式, 默认整行
+	Key KeyFunc
+	// MemoryBudget 处理分片时可以使用的内存, 默认 512MB
+	MemoryBudget int64
+	// Shards 分片数, 0 表示按 MemoryBudget 和输入大小自动计算
+	Shards int
+	// TempDir 临时分片所在目录, 默认系统临时目录
+	TempDir string
+	// Workers 并行度, 默认 CPU 数
+	Workers int
+	// MaxLineSize 单行最大长度, 默认 10MB
+	MaxLineSize int
+
+	// Prefilter 交集和差集时开启布隆过滤器预过滤, 其他运算忽略
+	Prefilter bool
+	// FalsePositiveRate 布隆过滤器的误判率, 默认 0.01; 过滤器大小不超过 MemoryBudget
+	FalsePositiveRate float64
+
+	// Progress 不为 nil 时每隔 ProgressInterval(默认 1 秒)报告一次读取进度, 每个输入读完时再报告一次
+	Progress         func(Progress)
+	ProgressInterval time.Duration
+}
+
+const (
+	defaultShards = 256
+	maxShards     = 4096
+	// memoryFactor 分片内容载入 map 后相对文件大小的膨胀系数
+	memoryFactor = 4
+)
+
+func (o *Options) normalize() {
+	if o.Key == nil {
+		o.Key = WholeLine
+	}
+	if o.MemoryBudget <= 0 {
+		o.MemoryBudget = 512 << 20
+	}
+	if o.Workers <= 0 {
+		o.Workers = runtime.NumCPU()
+	}
+	if o.MaxLineSize <= 0 {
+		o.MaxLineSize = 10 << 20
+	}
+	if o.FalsePositiveRate <= 0 || o.FalsePositiveRate >= 1 {
+		o.FalsePositiveRate = 0.01
+	}
+	if o.ProgressInterval <= 0 {
+		o.ProgressInterval = time.Second
+	}
+}
+
+// ShardCount 按输入大小和内存预算计算分片数: Workers 个分片同时在内存中, 每个分片膨胀 memoryFactor 倍
+// 输入大小未知时使用 256
+func ShardCount(sizes []int64, opts Options) int {
+	opts.normalize()
+	var total int64
+	for _, size := range sizes {
+		if size < 0 {
+			return defaultShards
+		}
+		total += size
+	}
+	perShard := opts.MemoryBudget / int64(opts.Workers) / memoryFactor
+	if perShard <= 0 {
+		return maxShards
+	}
+	n := (total + perShard - 1) / perShard
+	return int(min(max(n, 1), maxShards))
+}
+
+// Run 执行集合运算, 按分片顺序、分片内按 key 排序调用 emit
+func Run(ctx context.Context, op Op, inputs []Input, opts Options, emit func(Result) error) (Stats, error) {
+	var stats Stats
+	if len(inputs) != op.inputs() {
+		return stats, fmt.Errorf("%s needs %d inputs, got %d", op, op.inputs(), len(inputs))
+	}
+	opts.normalize()
+	stats.Shards = opts.Shards
+	if stats.Shards <= 0 {
+		sizes := make([]int64, len(inputs))
+		for i, in := range inputs {
+			sizes[i] = in.Size
+		}
+		stats.Shards = ShardCount(sizes, opts)
+	}
+
+	dir, err := os.MkdirTemp(opts.TempDir, "lineset-*")
+	if err != nil {
+		return stats, err
+	}
+	defer os.RemoveAll(dir)
+
+	// 预过滤时先分片 src, 用它建过滤器, 再分片另一个输入
+	src, order := 0, []int{0, 1}[:len(inputs)]
+	prefilter := opts.Prefilter && (op == Intersect || op == Difference)
+	if prefilter && op == Intersect && smaller(inputs[1].Size, inputs[0].Size) {
+		src, order = 1, []int{1, 0}
+	}
+
+	parts := make([][]string, len(inputs))
+	var filter *bloom
+	for _, i := range order {
+		in := inputs[i]
+		c := counters{filter: filter}
+		parts[i], err = spill(ctx, in, filepath.Join(dir, fmt.Sprintf("in%d", i)), stats.Shards, opts, &c)
+		stats.Bytes[i], stats.Lines[i] = c.bytes.Load(), c.lines.Load()
+		stats.Skipped[i], stats.Filtered[i] = c.skipped.Load(), c.filtered.Load()
+		if err != nil {
+			return stats, fmt.Errorf("partition %s: %w", in.Name, err)
+		}
+		if prefilter && i == src {
+			filter, err = buildFilter(ctx, parts[i], stats.Lines[i]-stats.Skipped[i], opts)
+			if err != nil {
+				return stats, fmt.Errorf("prefilter %s: %w", in.Name, err)
+			}
+			stats.FilterBytes = filter.size()
+		}
+	}
+	err = merge(ctx, op, parts, opts, func(r Result) error {
+		stats.Results++
+		return emit(r)
+	})
+	return stats, err
+}
+
+// smaller 大小未知的输入视为最大
+func smaller(a, b int64) bool {
+	if a < 0 {
+		return false
+	}
+	return b < 0 || a < b
+}
+
+// buildFilter 从已经写好的分片文件建过滤器, n 为 key 的个数上限
+func buildFilter(ctx context.Context, paths []string, n int64, opts Options) (*bloom, error) {
+	filter := newBloom(n, opts.FalsePositiveRate, opts.MemoryBudget)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(opts.Workers)
+	for _, path := range paths {
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return scanFile(path, opts.MaxLineSize, func(key string) { filter.add(key) })
+		})
+	}
+	return filter, g.Wait()
+}
+
+// scanFile 逐行读分片文件, 文件不存在表示分片为空
+func scanFile(path string, maxLine int, fn func(line string)) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, min(64<<10, maxLine)), maxLine)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+var seed = maphash.MakeSeed()
+
+func shardOf(key string, shards int) int {
+	return int(maphash.String(seed, key) % uint64(shards))
+}
+
+// shardWriter 一个分片文件, 多个 worker 共享, 写入时加锁; 第一次写入时才创建文件
+type shardWriter struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	w    *bufio.Writer
+}
+
+func (s *shardWriter) write(keys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		f, err := os.Create(s.path)
+		if err != nil {
+			return err
+		}
+		s.f, s.w = f, bufio.NewWriterSize(f, 32<<10)
+	}
+	for _, key := range keys {
+		s.w.WriteString(key)
+		if err := s.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *shardWriter) close() error {
+	if s.f == nil {
+		return nil
+	}
+	return errors.Join(s.w.Flush(), s.f.Close())
+}
+
+// counters 分片一个输入时的计数, 读取和进度报告并发访问
+type counters struct {
+	bytes, lines, skipped, filtered atomic.Int64
+	// filter 不为 nil 时只分片可能在过滤器中的 key
+	filter *bloom
+}
+
+// countingReader 统计读到的字节数
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// spill 按 key 的哈希把输入分片写入 prefix_NNNN 文件, 返回各分片的路径(没有内容的分片路径不存在)
+func spill(ctx context.Context, in Input, prefix string, shards int, opts Options, c *counters) ([]string, error) {
+	writers := make([]*shardWriter, shards)
+	paths := make([]string, shards)
+	for i := range writers {
+		paths[i] = fmt.Sprintf("%s_%04d", prefix, i)
+		writers[i] = &shardWriter{path: paths[i]}
+	}
+
+	if opts.Progress != nil {
+		start := time.Now()
+		report := func(done bool) {
+			opts.Progress(Progress{Input: in.Name, Bytes: c.bytes.Load(), Lines: c.lines.Load(), Elapsed: time.Since(start), Done: done})
+		}
+		stop := make(chan struct{})
+		finished := make(chan struct{})
+		go func() {
+			defer close(finished)
+			ticker := time.NewTicker(opts.ProgressInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					report(false)
+				case <-stop:
+					return
+				}
+			}
+		}()
+		defer func() {
+			close(stop)
+			<-finished
+			report(true)
+		}()
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	batches := make(chan []string, opts.Workers*2)
+	g.Go(func() error {
+		defer close(batches)
+		return readLines(ctx, countingReader{in.R, &c.bytes}, opts.MaxLineSize, batches, &c.lines)
+	})
+
+	for i := 0; i < opts.Workers; i++ {
+		g.Go(func() error {
+			buckets := make([][]string, shards)
+			for batch := range batches {
+				for _, line := range batch {
+					key, ok := opts.Key(line)
+					if !ok {
+						c.skipped.Add(1)
+						continue
+					}
+					if c.filter != nil && !c.filter.mayContain(key) {
+						c.filtered.Add(1)
+						continue
+					}
+					idx := shardOf(key, shards)
+					buckets[idx] = append(buckets[idx], key)
+				}
+				// 每批按分片聚合后再加锁写入, 减少锁竞争
+				for idx, keys := range buckets {
+					if len(keys) == 0 {
+						continue
+					}
+					if err := writers[idx].write(keys); err != nil {
+						return err
+					}
+					buckets[idx] = keys[:0]
+				}
+			}
+			return nil
+		})
+	}
+	err := g.Wait()
+	for _, w := range writers {
+		err = errors.Join(err, w.close())
+	}
+	return paths, err
+}
+
+// readLines 按批发送行, 去掉行尾的 \r
+func readLines(ctx context.Context, r io.Reader, maxLine int, out chan<- []string, lines *atomic.Int64) error {
+	const batchSize = 1024
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, min(64<<10, maxLine)), maxLine)
+	batch := make([]string, 0, batchSize)
+	for scanner.Scan() {
+		batch = append(batch, strings.TrimSuffix(scanner.Text(), "\r"))
+		if len(batch) == batchSize {
+			select {
+			case out <- batch:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			lines.Add(int64(len(batch)))
+			batch = make([]string, 0, batchSize)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	lines.Add(int64(len(batch)))
+	if len(batch) > 0 {
+		select {
+		case out <- batch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// merge 并行处理分片, 按分片顺序输出; 同时在内存中的分片不超过 Workers 个
+func merge(ctx context.Context, op Op, parts [][]string, opts Options, emit func(Result) error) error {
+	shards := len(parts[0])
+	slots := make([]chan []Result, shards)
+	for i := range slots {
+		slots[i] = make(chan []Result, 1)
+	}
+	sem := make(chan struct{}, opts.Workers)
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		for i := 0; i < shards; i++ {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			g.Go(func() error {
+				results, err := mergeShard(op, parts, i, opts.MaxLineSize)
+				if err != nil {
+					return err
+				}
+				slots[i] <- results
+				return nil
+			})
+		}
+		return nil
+	})
+	g.Go(func() error {
+		for i := 0; i < shards; i++ {
+			var results []Result
+			select {
+			case results = <-slots[i]:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			<-sem
+			for _, r := range results {
+				if err := emit(r); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return g.Wait()
+}
+
+// mergeShard 统计一个分片中各 key 的次数, 按运算筛选后排序
+func mergeShard(op Op, parts [][]string, shard, maxLine int) ([]Result, error) {
+	counts := make(map[string]*[2]int64)
+	for i, paths := range parts {
+		err := scanFile(paths[shard], maxLine, func(key string) {
+			c, ok := counts[key]
+			if !ok {
+				if i > 0 && op != Union {
+					// 第二个输入中独有的 key 在交集和差集中都用不到
+					return
+				}
+				c = new([2]int64)
+				counts[key] = c
+			}
+			c[i]++
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]Result, 0, len(counts))
+	for key, c := range counts {
+		if keep(op, c) {
+			results = append(results, Result{Key: key, Counts: *c})
+		}
+	}
+	slices.SortFunc(results, func(a, b Result) int { return strings.Compare(a.Key, b.Key) })
+	return results, nil
+}
+
+func keep(op Op, c *[2]int64) bool {
+	switch op {
+	case Intersect:
+		return c[0] > 0 && c[1] > 0
+	case Difference:
+		return c[0] > 0 && c[1] == 0
+	case Duplicates:
+		return c[0] > 1
+	default:
+		return true
+	}
+}
@@ -0,0 +1,291 @@
+package lineset
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func run(t *testing.T, op Op, opts Options, texts ...string) []Result {
+	t.Helper()
+	inputs := make([]Input, len(texts))
+	for i, text := range texts {
+		inputs[i] = Input{Name: fmt.Sprint("in", i), R: strings.NewReader(text), Size: int64(len(text))}
+	}
+	var got []Result
+	_, err := Run(context.Background(), op, inputs, opts, func(r Result) error {
+		got = append(got, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 分片之间的顺序取决于哈希, 比较前统一排序
+	slices.SortFunc(got, func(a, b Result) int { return strings.Compare(a.Key, b.Key) })
+	return got
+}
+
+func TestOps(t *testing.T) {
+	a := "apple\nbanana\napple\ncherry\r\n\n"
+	b := "banana\ndate\nbanana\napple\n"
+	opts := Options{Shards: 4, Workers: 3, TempDir: t.TempDir()}
+
+	tests := []struct {
+		op    Op
+		texts []string
+		want  []Result
+	}{
+		{Intersect, []string{a, b}, []Result{{"apple", [2]int64{2, 1}}, {"banana", [2]int64{1, 2}}}},
+		{Difference, []string{a, b}, []Result{{"", [2]int64{1, 0}}, {"cherry", [2]int64{1, 0}}}},
+		{Union, []string{a, b}, []Result{
+			{"", [2]int64{1, 0}}, {"apple", [2]int64{2, 1}}, {"banana", [2]int64{1, 2}},
+			{"cherry", [2]int64{1, 0}}, {"date", [2]int64{0, 1}},
+		}},
+		{Duplicates, []string{a}, []Result{{"apple", [2]int64{2, 0}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.op.String(), func(t *testing.T) {
+			if got := run(t, tt.op, opts, tt.texts...); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	entries, _ := os.ReadDir(opts.TempDir)
+	if len(entries) != 0 {
+		t.Fatalf("temp files left: %v", entries)
+	}
+}
+
+func TestKeyExtraction(t *testing.T) {
+	a := "1,alice,x\n2,\"bob, jr\",y\n3,carol\nbroken\n"
+	b := "9;alice\n8;\"bob, jr\"\n"
+	got := run(t, Intersect, Options{Key: CSVColumn(1, ','), Shards: 2}, a, strings.ReplaceAll(b, ";", ","))
+	want := []Result{{"alice", [2]int64{1, 1}}, {"bob, jr", [2]int64{1, 1}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("csv: got %v, want %v", got, want)
+	}
+
+	key, err := Regexp(`user=(\w+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := "t=1 user=tom ok\nt=2 user=amy ok\nt=3 anonymous\nt=4 user=tom fail\n"
+	got = run(t, Duplicates, Options{Key: key}, logs)
+	if want := []Result{{"tom", [2]int64{2, 0}}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("regexp: got %v, want %v", got, want)
+	}
+}
+
+// TestConcurrentSpill 大量重复行, 多 worker 同时写同一个分片, 计数必须精确, 行不能被写坏
+func TestConcurrentSpill(t *testing.T) {
+	const distinct, repeat = 500, 40
+	var a, b strings.Builder
+	want := map[string][2]int64{}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < distinct*repeat; i++ {
+		key := fmt.Sprintf("key-%04d-%s", rng.Intn(distinct), strings.Repeat("x", rng.Intn(50)))
+		a.WriteString(key + "\n")
+		c := want[key]
+		c[0]++
+		want[key] = c
+		if i%3 == 0 {
+			b.WriteString(key + "\n")
+			c[1]++
+			want[key] = c
+		}
+	}
+
+	got := run(t, Union, Options{Shards: 3, Workers: 16}, a.String(), b.String())
+	if len(got) != len(want) {
+		t.Fatalf("got %d keys, want %d", len(got), len(want))
+	}
+	for _, r := range got {
+		if want[r.Key] != r.Counts {
+			t.Fatalf("%q: got %v, want %v", r.Key, r.Counts, want[r.Key])
+		}
+	}
+}
+
+func TestShardCount(t *testing.T) {
+	opts := Options{MemoryBudget: 64 << 20, Workers: 4}
+	tests := []struct {
+		sizes []int64
+		want  int
+	}{
+		{[]int64{0}, 1},
+		{[]int64{1 << 20, 1 << 20}, 1},
+		{[]int64{1 << 30, 1 << 30}, 512},
+		{[]int64{1 << 40}, maxShards},
+		{[]int64{1 << 20, -1}, defaultShards},
+	}
+	for _, tt := range tests {
+		if got := ShardCount(tt.sizes, opts); got != tt.want {
+			t.Errorf("ShardCount(%v) = %d, want %d", tt.sizes, got, tt.want)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{"1024": 1024, "512MB": 512 << 20, "2g": 2 << 30, "64KiB": 64 << 10} {
+		if got, err := ParseSize(s); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "MB", "-1", "1.5G", "99999999999T"} {
+		if _, err := ParseSize(s); err == nil {
+			t.Errorf("ParseSize(%q) want error", s)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := ParseOp("xor"); err == nil {
+		t.Fatal("want unknown op error")
+	}
+	in := Input{R: strings.NewReader("a\n"), Size: -1}
+	if _, err := Run(context.Background(), Intersect, []Input{in}, Options{}, nil); err == nil {
+		t.Fatal("want input count error")
+	}
+
+	long := strings.Repeat("x", 100) + "\n"
+	_, err := Run(context.Background(), Duplicates, []Input{{R: strings.NewReader(long), Size: -1}}, Options{MaxLineSize: 10}, func(Result) error { return nil })
+	if err == nil {
+		t.Fatal("want line too long error")
+	}
+
+	stop := fmt.Errorf("stop")
+	_, err = Run(context.Background(), Duplicates, []Input{{R: strings.NewReader("a\na\nb\nb\n"), Size: -1}}, Options{Shards: 8}, func(Result) error { return stop })
+	if err != stop {
+		t.Fatalf("got %v, want emit error", err)
+	}
+}
+
+func TestPrefilter(t *testing.T) {
+	small := genLines(1, 2000, 1000)
+	large := genLines(2, 50000, 40000)
+	key := CSVColumn(0, ',')
+	for _, op := range []Op{Intersect, Difference} {
+		for _, texts := range [][]string{{small, large}, {large, small}} {
+			want := run(t, op, Options{Key: key, Shards: 8}, texts...)
+			got := run(t, op, Options{Key: key, Shards: 8, Prefilter: true}, texts...)
+			if len(want) == 0 || !reflect.DeepEqual(got, want) {
+				t.Fatalf("%s: prefilter changed the result: %d vs %d keys", op, len(got), len(want))
+			}
+		}
+	}
+
+	inputs := []Input{
+		{Name: "large", R: strings.NewReader(large), Size: int64(len(large))},
+		{Name: "small", R: strings.NewReader(small), Size: int64(len(small))},
+	}
+	stats, err := Run(context.Background(), Intersect, inputs, Options{Key: key, Prefilter: true}, func(Result) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 过滤器从小文件建立, 大文件里绝大部分行不会写入分片
+	if stats.FilterBytes == 0 || stats.Filtered[1] != 0 || stats.Filtered[0] < stats.Lines[0]*9/10 {
+		t.Fatalf("got %+v", stats)
+	}
+}
+
+func TestBloom(t *testing.T) {
+	const n = 10000
+	b := newBloom(n, 0.01, 1<<20)
+	for i := 0; i < n; i++ {
+		b.add(fmt.Sprint("in-", i))
+	}
+	falsePositive := 0
+	for i := 0; i < n; i++ {
+		if !b.mayContain(fmt.Sprint("in-", i)) {
+			t.Fatalf("false negative for in-%d", i)
+		}
+		if b.mayContain(fmt.Sprint("out-", i)) {
+			falsePositive++
+		}
+	}
+	if rate := float64(falsePositive) / n; rate > 0.02 {
+		t.Fatalf("false positive rate %.4f", rate)
+	}
+	if tiny := newBloom(n, 0.01, 64); tiny.size() != 64 {
+		t.Fatalf("filter ignores memory budget: %d bytes", tiny.size())
+	}
+}
+
+func TestProgress(t *testing.T) {
+	var mu sync.Mutex
+	var reports []Progress
+	text := genLines(3, 5000, 100)
+	opts := Options{
+		ProgressInterval: time.Millisecond,
+		Progress: func(p Progress) {
+			mu.Lock()
+			reports = append(reports, p)
+			mu.Unlock()
+		},
+	}
+	in := Input{Name: "stdin", R: slowReader{strings.NewReader(text)}, Size: -1}
+	if _, err := Run(context.Background(), Duplicates, []Input{in}, opts, func(Result) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	last := reports[len(reports)-1]
+	if len(reports) < 2 || !last.Done || last.Bytes != int64(len(text)) || last.Lines != 5000 {
+		t.Fatalf("got %d reports, last %+v", len(reports), last)
+	}
+	if s := last.String(); !strings.Contains(s, "stdin: ") || !strings.Contains(s, "lines/s") {
+		t.Fatalf("got %q", s)
+	}
+}
+
+// slowReader 每次只读一小段, 让进度报告有机会在读完前触发
+type slowReader struct{ r *strings.Reader }
+
+func (s slowReader) Read(p []byte) (int, error) {
+	time.Sleep(100 * time.Microsecond)
+	return s.r.Read(p[:min(len(p), 512)])
+}
+
+// genLines n 行, 第一列是取自 [0, distinct) 的 key
+func genLines(seed int64, n, distinct int) string {
+	rng := rand.New(rand.NewSource(seed))
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "%08d,payload-%d\n", rng.Intn(distinct), i)
+	}
+	return sb.String()
+}
+
+// BenchmarkIntersect 小文件与大文件求交集: 两阶段全量分片 vs 先用布隆过滤器过滤大文件
+func BenchmarkIntersect(b *testing.B) {
+	small := genLines(1, 20000, 20000)
+	large := genLines(2, 500000, 400000)
+	key := CSVColumn(0, ',')
+	for _, bc := range []struct {
+		name string
+		opts Options
+	}{
+		{"partition", Options{Key: key, Shards: 256}},
+		{"prefilter", Options{Key: key, Shards: 256, Prefilter: true}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			bc.opts.TempDir = b.TempDir()
+			b.SetBytes(int64(len(small) + len(large)))
+			for i := 0; i < b.N; i++ {
+				inputs := []Input{
+					{R: strings.NewReader(large), Size: int64(len(large))},
+					{R: strings.NewReader(small), Size: int64(len(small))},
+				}
+				if _, err := Run(context.Background(), Intersect, inputs, bc.opts, func(Result) error { return nil }); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
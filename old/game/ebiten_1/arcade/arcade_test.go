@@ -0,0 +1,285 @@
+package arcade
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var cfg = Config{Width: 480, Height: 480, Size: 48}
+
+func newGame(t *testing.T, levels ...*Level) *Game {
+	t.Helper()
+	for _, l := range levels {
+		if err := l.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g, err := NewGame(cfg, levels, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func kinds(evs []Event) []EventKind {
+	out := make([]EventKind, len(evs))
+	for i, e := range evs {
+		out[i] = e.Kind
+	}
+	return out
+}
+
+// run 连续 n 帧相同输入, 返回所有事件
+func run(g *Game, in Input, n int) []EventKind {
+	var out []EventKind
+	for range n {
+		out = append(out, kinds(g.Update(in))...)
+	}
+	return out
+}
+
+func TestLoadLevels(t *testing.T) {
+	fsys := fstest.MapFS{
+		"levels/02.json": {Data: []byte(`{"goal": 5, "waves": [
+			{"at": 3, "count": 2, "behavior": "patrol", "pattern": "edges", "speed": 1},
+			{"at": 1, "count": 1, "behavior": "chase", "pattern": "random", "speed": 0.5}]}`)},
+		"levels/01.json": {Data: []byte(`{"name": "第一关", "goal": 3, "items": 2}`)},
+	}
+	levels, err := LoadLevels(fsys, "levels/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(levels) != 2 || levels[0].Name != "第一关" || levels[1].Name != "02.json" {
+		t.Fatalf("levels %+v", levels)
+	}
+	if levels[1].PlayerSpeed != 2 || levels[1].Waves[0].At != 1 {
+		t.Fatalf("defaults or wave order not applied: %+v", levels[1])
+	}
+
+	for name, src := range map[string]string{
+		"goal":     `{"goal": 0}`,
+		"behavior": `{"goal": 1, "waves": [{"count": 1, "behavior": "dance", "pattern": "line", "speed": 1}]}`,
+		"pattern":  `{"goal": 1, "waves": [{"count": 1, "behavior": "flee", "pattern": "spiral", "speed": 1}]}`,
+		"power-up": `{"goal": 1, "power_ups": [{"kind": "freeze", "every": 5}]}`,
+		"unknown":  `{"goal": 1, "enemies": 3}`,
+	} {
+		if _, err := LoadLevel(strings.NewReader(src)); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+	if _, err := LoadLevels(fsys, "missing/*.json"); err == nil {
+		t.Fatal("want error for no levels")
+	}
+}
+
+func TestStateMachine(t *testing.T) {
+	g := newGame(t, &Level{Goal: 1}, &Level{Goal: 1})
+	if g.State != Menu {
+		t.Fatalf("initial %v", g.State)
+	}
+	run(g, Input{Left: true}, 10)
+	if g.State != Menu || g.Tick != 0 {
+		t.Fatal("game advanced in the menu")
+	}
+	if ev := run(g, Input{Confirm: true}, 1); !slices.Equal(ev, []EventKind{EventStart}) || g.State != Playing {
+		t.Fatalf("start %v %v", ev, g.State)
+	}
+
+	g.Update(Input{Pause: true})
+	if g.State != Paused {
+		t.Fatalf("pause %v", g.State)
+	}
+	tick := g.Tick
+	run(g, Input{Right: true}, 10)
+	if g.Tick != tick {
+		t.Fatal("game advanced while paused")
+	}
+	g.Update(Input{Pause: true})
+	if g.State != Playing {
+		t.Fatalf("resume %v", g.State)
+	}
+
+	// 放一个金币在玩家身上过关
+	g.Items = []Vec{g.Player}
+	if ev := run(g, Input{}, 1); !slices.Contains(ev, EventLevelComplete) || g.State != LevelComplete {
+		t.Fatalf("level complete %v %v", ev, g.State)
+	}
+	g.Update(Input{Confirm: true})
+	if g.State != Playing || g.Level != 1 || g.Score != 1 || g.LevelScore != 0 {
+		t.Fatalf("next level: %v level %d score %d/%d", g.State, g.Level, g.Score, g.LevelScore)
+	}
+	g.Items = []Vec{g.Player}
+	if ev := run(g, Input{}, 1); !slices.Contains(ev, EventVictory) || g.State != Victory {
+		t.Fatalf("victory %v %v", ev, g.State)
+	}
+	g.Update(Input{Confirm: true})
+	if g.State != Menu {
+		t.Fatalf("back to menu %v", g.State)
+	}
+	g.Update(Input{Confirm: true})
+	if g.Score != 0 || g.Level != 0 {
+		t.Fatal("new game kept the old score")
+	}
+}
+
+func TestWavesAndBehaviors(t *testing.T) {
+	g := newGame(t, &Level{Goal: 100, Waves: []Wave{
+		{At: 1, Count: 4, Behavior: Patrol, Pattern: Circle, Speed: 1},
+		{At: 2, Count: 3, Behavior: Flee, Pattern: Line, Speed: 1, Score: 5},
+	}})
+	g.Update(Input{Confirm: true})
+
+	if ev := run(g, Input{}, TPS-1); slices.Contains(ev, EventWave) {
+		t.Fatal("wave spawned early")
+	}
+	if ev := run(g, Input{}, 1); !slices.Equal(ev, []EventKind{EventWave}) || len(g.Enemies) != 4 {
+		t.Fatalf("first wave %v, %d enemies", ev, len(g.Enemies))
+	}
+	// 巡逻的敌人沿着路线走一圈回到出生点, 不会追玩家
+	e := g.Enemies[0]
+	want := 1
+	for range 8 * patrolSide {
+		g.Update(Input{})
+		if e.Pos.Equal(e.route[want%len(e.route)]) {
+			want++
+		}
+	}
+	if want <= len(e.route) || g.State != Playing {
+		t.Fatalf("patrol reached %d waypoints of %v", want-1, e.route)
+	}
+
+	run(g, Input{}, 2*TPS)
+	var flee []*Enemy
+	for _, e := range g.Enemies {
+		if e.Behavior == Flee {
+			flee = append(flee, e)
+		}
+	}
+	if len(flee) != 3 {
+		t.Fatalf("%d flee enemies", len(flee))
+	}
+	// 逃跑的敌人在玩家附近时远离玩家
+	f := flee[0]
+	g.Enemies = []*Enemy{f}
+	f.Pos = g.Player.Add(Vec{60, 0})
+	before := f.Pos.Dist(g.Player)
+	g.Update(Input{})
+	if f.Pos.Dist(g.Player) <= before {
+		t.Fatal("flee enemy did not move away")
+	}
+	// 抓到逃跑的敌人加分
+	f.Pos = g.Player
+	if ev := run(g, Input{}, 1); !slices.Contains(ev, EventCatch) || g.Score != 5 || len(g.Enemies) != 0 {
+		t.Fatalf("catch %v score %d", ev, g.Score)
+	}
+}
+
+func TestChaseAndPowerUps(t *testing.T) {
+	g := newGame(t, &Level{Goal: 100, PowerUps: []PowerUpSpawn{
+		{Kind: Shield, Every: 1},
+		{Kind: Freeze, Every: 1, Duration: 2},
+	}})
+	g.Update(Input{Confirm: true})
+	run(g, Input{}, TPS)
+	if len(g.PowerUps) != 2 {
+		t.Fatalf("%d power-ups", len(g.PowerUps))
+	}
+	run(g, Input{}, TPS)
+	if len(g.PowerUps) != 2 {
+		t.Fatal("respawned a power-up that is still on the field")
+	}
+
+	for _, p := range g.PowerUps {
+		p.Pos = g.Player
+	}
+	if ev := run(g, Input{}, 1); !slices.Equal(ev, []EventKind{EventPowerUp, EventPowerUp}) || !g.Shielded || g.Effect(Freeze) != 2 {
+		t.Fatalf("pick up %v shield %v freeze %v", ev, g.Shielded, g.Effect(Freeze))
+	}
+
+	// 冻结时追击的敌人不动
+	chaser := &Enemy{Pos: Vec{0, 0}, Speed: 3, Behavior: Chase}
+	g.Enemies = []*Enemy{chaser}
+	g.Update(Input{})
+	if chaser.Pos != (Vec{}) {
+		t.Fatal("frozen enemy moved")
+	}
+	run(g, Input{}, 2*TPS)
+	if g.Effect(Freeze) != 0 || chaser.Pos == (Vec{}) {
+		t.Fatal("freeze did not wear off")
+	}
+
+	// 护盾挡一次, 第二次游戏结束
+	g.Enemies = []*Enemy{{Pos: g.Player, Speed: 1, Behavior: Chase}}
+	if ev := run(g, Input{}, 1); !slices.Contains(ev, EventShieldBreak) || g.Shielded || g.State != Playing {
+		t.Fatalf("shield %v %v", ev, g.State)
+	}
+	g.PowerUps = nil
+	g.Enemies = []*Enemy{{Pos: g.Player, Speed: 1, Behavior: Chase}}
+	if ev := run(g, Input{}, 1); !slices.Equal(ev, []EventKind{EventGameOver}) || g.State != GameOver {
+		t.Fatalf("game over %v %v", ev, g.State)
+	}
+}
+
+func TestPlayerMovement(t *testing.T) {
+	g := newGame(t, &Level{Goal: 100, PlayerSpeed: 2})
+	g.Update(Input{Confirm: true})
+	start := g.Player
+	g.Update(Input{Right: true, Down: true})
+	if d := g.Player.Dist(start); d < 1.99 || d > 2.01 {
+		t.Fatalf("diagonal moved %v", d)
+	}
+	run(g, Input{Left: true, Up: true}, 1000)
+	if g.Player != (Vec{}) {
+		t.Fatalf("not clamped: %v", g.Player)
+	}
+}
+
+func TestHighScores(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scores", "highscores.json")
+	hs, err := LoadHighScores(path)
+	if err != nil || len(hs) != 0 {
+		t.Fatalf("missing file: %v %v", hs, err)
+	}
+	now := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	for i := range MaxHighScores {
+		hs, _ = hs.Add(HighScore{Name: "a", Score: 10 * (i + 1), Time: now})
+	}
+	if hs.Qualifies(10) || !hs.Qualifies(11) {
+		t.Fatal("Qualifies wrong at the bottom of a full table")
+	}
+	hs, rank := hs.Add(HighScore{Name: "b", Score: 50, Time: now})
+	if rank != 7 || len(hs) != MaxHighScores || hs[6].Name != "b" || hs[len(hs)-1].Score != 20 {
+		t.Fatalf("rank %d table %+v", rank, hs)
+	}
+	if _, rank := hs.Add(HighScore{Score: 5}); rank != 0 {
+		t.Fatalf("low score ranked %d", rank)
+	}
+
+	if err := hs.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadHighScores(path)
+	if err != nil || !slices.Equal(loaded, hs) {
+		t.Fatalf("round trip %v %v", loaded, err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatal("temp file left behind")
+	}
+}
+
+// 仓库里的关卡文件都能加载
+func TestBundledLevels(t *testing.T) {
+	levels, err := LoadLevels(os.DirFS("../assets"), "levels/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(levels) < 3 {
+		t.Fatalf("%d levels", len(levels))
+	}
+}
@@ -0,0 +1,104 @@
+package arcade
+
+import "math"
+
+const (
+	safeDistance = 120 // random 出生的敌人离玩家的最小距离
+	fleeRadius   = 150 // 玩家进入这个距离时 flee 敌人开始逃跑
+	patrolSide   = 120 // patrol 方形路线的边长
+)
+
+// spawnWave 按出生模式放出一波敌人
+func (g *Game) spawnWave(w Wave) {
+	for _, pos := range g.spawnPositions(w.Pattern, w.Count) {
+		e := &Enemy{Pos: pos, Speed: w.Speed, Behavior: w.Behavior, Score: w.Score}
+		if w.Behavior == Patrol {
+			e.route = g.patrolRoute(pos)
+		}
+		g.Enemies = append(g.Enemies, e)
+	}
+}
+
+func (g *Game) spawnPositions(p Pattern, n int) []Vec {
+	maxX, maxY := g.cfg.Width-g.cfg.Size, g.cfg.Height-g.cfg.Size
+	out := make([]Vec, n)
+	for i := range out {
+		switch p {
+		case Random:
+			// 重试几次避开玩家, 实在找不到就用最后一次的位置
+			for try := 0; try < 10; try++ {
+				out[i] = g.randomPos()
+				if out[i].Dist(g.Player) >= safeDistance {
+					break
+				}
+			}
+		case Edges:
+			t := g.rng.Float64()
+			switch g.rng.Intn(4) {
+			case 0:
+				out[i] = Vec{t * maxX, 0}
+			case 1:
+				out[i] = Vec{t * maxX, maxY}
+			case 2:
+				out[i] = Vec{0, t * maxY}
+			default:
+				out[i] = Vec{maxX, t * maxY}
+			}
+		case Circle:
+			center := Vec{maxX / 2, maxY / 2}
+			r := math.Min(maxX, maxY) / 2
+			a := 2 * math.Pi * float64(i) / float64(n)
+			out[i] = center.Add(Vec{math.Cos(a), math.Sin(a)}.Scale(r))
+		case Line:
+			out[i] = Vec{maxX * (float64(i) + 0.5) / float64(n), 0}
+		}
+	}
+	return out
+}
+
+// patrolRoute 以出生点为一个角的方形路线, 超出画面时往里翻
+func (g *Game) patrolRoute(start Vec) []Vec {
+	dx, dy := float64(patrolSide), float64(patrolSide)
+	if start.X+dx > g.cfg.Width-g.cfg.Size {
+		dx = -dx
+	}
+	if start.Y+dy > g.cfg.Height-g.cfg.Size {
+		dy = -dy
+	}
+	return []Vec{
+		start,
+		g.clamp(start.Add(Vec{dx, 0})),
+		g.clamp(start.Add(Vec{dx, dy})),
+		g.clamp(start.Add(Vec{0, dy})),
+	}
+}
+
+func (g *Game) moveEnemy(e *Enemy) {
+	switch e.Behavior {
+	case Chase:
+		e.Pos = e.Pos.Towards(g.Player, e.Speed)
+	case Patrol:
+		if e.Pos.Equal(e.route[e.next]) {
+			e.next = (e.next + 1) % len(e.route)
+		}
+		e.Pos = e.Pos.Towards(e.route[e.next], e.Speed)
+	case Flee:
+		d := e.Pos.Sub(g.Player)
+		dist := d.Len()
+		if dist >= fleeRadius {
+			return
+		}
+		if dist == 0 {
+			d, dist = Vec{1, 0}, 1
+		}
+		next := g.clamp(e.Pos.Add(d.Scale(e.Speed / dist)))
+		if next.Equal(e.Pos) {
+			// 被逼到角落时沿墙滑动
+			next = g.clamp(e.Pos.Add(Vec{0, math.Copysign(e.Speed, d.Y)}))
+			if next.Equal(e.Pos) {
+				next = g.clamp(e.Pos.Add(Vec{math.Copysign(e.Speed, d.X), 0}))
+			}
+		}
+		e.Pos = next
+	}
+}
@@ -0,0 +1,353 @@
+// Package arcade ebiten_1 小游戏的游戏逻辑: 关卡和敌人波次、敌人行为、道具、状态机和最高分,
+// 不依赖 ebiten, 没有窗口也能测试. main 包只负责把键盘转成 Input、按 Game 的状态绘制、按 Event 播放音效
+package arcade
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// TPS 每秒更新次数, 和 ebiten 默认的一致, 关卡文件里的秒数按它换算成帧
+const TPS = 60
+
+// State 游戏状态
+type State int
+
+const (
+	Menu          State = iota // 开始画面
+	Playing                    // 游戏中
+	Paused                     // 暂停
+	LevelComplete              // 本关完成, 等待进入下一关
+	GameOver                   // 被敌人碰到
+	Victory                    // 所有关卡完成
+)
+
+func (s State) String() string {
+	switch s {
+	case Menu:
+		return "menu"
+	case Playing:
+		return "playing"
+	case Paused:
+		return "paused"
+	case LevelComplete:
+		return "level complete"
+	case GameOver:
+		return "game over"
+	case Victory:
+		return "victory"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Input 一帧的输入, 方向键为按住状态, Confirm 和 Pause 为刚按下
+type Input struct {
+	Left, Right, Up, Down bool
+	Confirm               bool // 空格: 开始、下一关、重新开始
+	Pause                 bool // P: 暂停和继续
+}
+
+// EventKind 游戏中发生的事件, main 包据此播放音效和记录最高分
+type EventKind int
+
+const (
+	EventStart       EventKind = iota // 开始游戏
+	EventPickup                       // 捡到金币
+	EventPowerUp                      // 捡到道具
+	EventShieldBreak                  // 护盾抵挡了一次敌人
+	EventCatch                        // 抓到逃跑的敌人
+	EventWave                         // 新一波敌人出现
+	EventLevelComplete
+	EventGameOver
+	EventVictory
+)
+
+// Event 事件, Score 为事件发生后的总分
+type Event struct {
+	Kind  EventKind
+	Score int
+}
+
+// Config 画面和实体大小
+type Config struct {
+	Width, Height float64
+	Size          float64 // 玩家、敌人、金币、道具的边长
+}
+
+// Enemy 场上的敌人, Pos 为左上角
+type Enemy struct {
+	Pos      Vec
+	Speed    float64
+	Behavior Behavior
+	Score    int
+	route    []Vec // patrol 的路线
+	next     int
+}
+
+// PowerUp 场上的道具
+type PowerUp struct {
+	Pos      Vec
+	Kind     PowerUpKind
+	duration int // 帧
+}
+
+// Game 游戏状态, 所有随机数来自 rng, 给定种子和输入序列时结果确定
+type Game struct {
+	cfg    Config
+	levels []*Level
+	rng    *rand.Rand
+
+	State      State
+	Level      int // 当前关卡下标
+	Score      int // 总分
+	LevelScore int // 本关得分
+	Tick       int // 本关进行的帧数
+
+	Player   Vec
+	Shielded bool
+	Enemies  []*Enemy
+	Items    []Vec
+	PowerUps []*PowerUp
+	effects  map[PowerUpKind]int // 剩余帧数
+
+	nextWave    int
+	lastPowerUp []int // 每种道具规则上次刷新的帧
+
+	events []Event
+}
+
+// NewGame levels 至少一个, rng 为 nil 时用固定种子
+func NewGame(cfg Config, levels []*Level, rng *rand.Rand) (*Game, error) {
+	if len(levels) == 0 {
+		return nil, errors.New("arcade: no levels")
+	}
+	if cfg.Width <= cfg.Size || cfg.Height <= cfg.Size || cfg.Size <= 0 {
+		return nil, fmt.Errorf("arcade: invalid config %+v", cfg)
+	}
+	if rng == nil {
+		rng = rand.New(rand.NewSource(1))
+	}
+	g := &Game{cfg: cfg, levels: levels, rng: rng}
+	g.startLevel(0)
+	g.State = Menu
+	return g, nil
+}
+
+// CurrentLevel 当前关卡
+func (g *Game) CurrentLevel() *Level { return g.levels[g.Level] }
+
+// Levels 关卡数
+func (g *Game) Levels() int { return len(g.levels) }
+
+// Effect 道具效果剩余的秒数, 没有效果时为 0
+func (g *Game) Effect(kind PowerUpKind) float64 {
+	return float64(g.effects[kind]) / TPS
+}
+
+// Update 处理一帧, 返回这一帧发生的事件
+func (g *Game) Update(in Input) []Event {
+	g.events = g.events[:0]
+	switch g.State {
+	case Menu:
+		if in.Confirm {
+			g.Score = 0
+			g.startLevel(0)
+			g.State = Playing
+			g.emit(EventStart)
+		}
+	case Playing:
+		if in.Pause {
+			g.State = Paused
+			break
+		}
+		g.step(in)
+	case Paused:
+		if in.Pause || in.Confirm {
+			g.State = Playing
+		}
+	case LevelComplete:
+		if in.Confirm {
+			g.startLevel(g.Level + 1)
+			g.State = Playing
+		}
+	case GameOver, Victory:
+		if in.Confirm {
+			g.startLevel(0)
+			g.State = Menu
+		}
+	}
+	return g.events
+}
+
+// startLevel 初始化第 i 关, 总分保留
+func (g *Game) startLevel(i int) {
+	l := g.levels[i]
+	g.Level, g.LevelScore, g.Tick = i, 0, 0
+	g.Player = Vec{g.cfg.Width/2 - g.cfg.Size/2, g.cfg.Height/2 - g.cfg.Size/2}
+	g.Shielded = false
+	g.Enemies, g.PowerUps = nil, nil
+	g.effects = map[PowerUpKind]int{}
+	g.nextWave = 0
+	g.lastPowerUp = make([]int, len(l.PowerUps))
+	g.Items = make([]Vec, l.Items)
+	for j := range g.Items {
+		g.Items[j] = g.randomPos()
+	}
+}
+
+// step 游戏中的一帧: 出波、刷道具、移动、碰撞、判断过关
+func (g *Game) step(in Input) {
+	l := g.CurrentLevel()
+	g.Tick++
+	for g.nextWave < len(l.Waves) && g.Tick >= seconds(l.Waves[g.nextWave].At) {
+		g.spawnWave(l.Waves[g.nextWave])
+		g.nextWave++
+		g.emit(EventWave)
+	}
+	g.spawnPowerUps(l)
+	for k, left := range g.effects {
+		if left <= 1 {
+			delete(g.effects, k)
+		} else {
+			g.effects[k] = left - 1
+		}
+	}
+
+	g.movePlayer(in, l.PlayerSpeed)
+	if g.effects[Freeze] == 0 {
+		for _, e := range g.Enemies {
+			g.moveEnemy(e)
+		}
+	}
+	if g.collide() {
+		return
+	}
+	if g.LevelScore >= l.Goal {
+		if g.Level == len(g.levels)-1 {
+			g.State = Victory
+			g.emit(EventVictory)
+		} else {
+			g.State = LevelComplete
+			g.emit(EventLevelComplete)
+		}
+	}
+}
+
+func (g *Game) movePlayer(in Input, speed float64) {
+	if g.effects[Speed] > 0 {
+		speed *= 1.5
+	}
+	var d Vec
+	if in.Left {
+		d.X--
+	}
+	if in.Right {
+		d.X++
+	}
+	if in.Up {
+		d.Y--
+	}
+	if in.Down {
+		d.Y++
+	}
+	if d.Len() > 0 {
+		// 斜着走不会更快
+		g.Player = g.clamp(g.Player.Add(d.Scale(speed / d.Len())))
+	}
+}
+
+// collide 处理玩家和敌人、金币、道具的碰撞, 游戏结束时返回 true
+func (g *Game) collide() bool {
+	size := g.cfg.Size
+	dead := false
+	g.Enemies = slices.DeleteFunc(g.Enemies, func(e *Enemy) bool {
+		if dead || !overlap(g.Player, e.Pos, size) {
+			return false
+		}
+		switch {
+		case e.Behavior == Flee:
+			g.addScore(e.Score)
+			g.emit(EventCatch)
+		case g.Shielded:
+			g.Shielded = false
+			g.emit(EventShieldBreak)
+		default:
+			dead = true
+			return false
+		}
+		return true
+	})
+	if dead {
+		g.State = GameOver
+		g.emit(EventGameOver)
+		return true
+	}
+
+	for i, item := range g.Items {
+		if overlap(g.Player, item, size) {
+			g.addScore(1)
+			g.Items[i] = g.randomPos()
+			g.emit(EventPickup)
+		}
+	}
+
+	g.PowerUps = slices.DeleteFunc(g.PowerUps, func(p *PowerUp) bool {
+		if !overlap(g.Player, p.Pos, size) {
+			return false
+		}
+		if p.Kind == Shield {
+			g.Shielded = true
+		} else {
+			g.effects[p.Kind] = p.duration
+		}
+		g.emit(EventPowerUp)
+		return true
+	})
+	return false
+}
+
+func (g *Game) addScore(n int) {
+	g.Score += n
+	g.LevelScore += n
+}
+
+func (g *Game) emit(kind EventKind) {
+	g.events = append(g.events, Event{Kind: kind, Score: g.Score})
+}
+
+func (g *Game) spawnPowerUps(l *Level) {
+	for i, rule := range l.PowerUps {
+		if g.Tick-g.lastPowerUp[i] < seconds(rule.Every) {
+			continue
+		}
+		g.lastPowerUp[i] = g.Tick
+		onField := false
+		for _, p := range g.PowerUps {
+			onField = onField || p.Kind == rule.Kind
+		}
+		if !onField {
+			g.PowerUps = append(g.PowerUps, &PowerUp{Pos: g.randomPos(), Kind: rule.Kind, duration: seconds(rule.Duration)})
+		}
+	}
+}
+
+// randomPos 画面内随机位置
+func (g *Game) randomPos() Vec {
+	return Vec{g.rng.Float64() * (g.cfg.Width - g.cfg.Size), g.rng.Float64() * (g.cfg.Height - g.cfg.Size)}
+}
+
+// clamp 限制在画面内
+func (g *Game) clamp(v Vec) Vec {
+	return Vec{
+		math.Max(0, math.Min(v.X, g.cfg.Width-g.cfg.Size)),
+		math.Max(0, math.Min(v.Y, g.cfg.Height-g.cfg.Size)),
+	}
+}
+
+// seconds 秒数换算成帧
+func seconds(s float64) int {
+	return int(math.Round(s * TPS))
+}
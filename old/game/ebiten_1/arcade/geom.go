@@ -0,0 +1,33 @@
+package arcade
+
+import "math"
+
+// Vec 二维坐标或方向
+type Vec struct {
+	X, Y float64
+}
+
+func (v Vec) Add(o Vec) Vec       { return Vec{v.X + o.X, v.Y + o.Y} }
+func (v Vec) Sub(o Vec) Vec       { return Vec{v.X - o.X, v.Y - o.Y} }
+func (v Vec) Scale(k float64) Vec { return Vec{v.X * k, v.Y * k} }
+func (v Vec) Len() float64        { return math.Hypot(v.X, v.Y) }
+func (v Vec) Dist(o Vec) float64  { return v.Sub(o).Len() }
+func (v Vec) Equal(o Vec) bool    { return v.Dist(o) < 1e-9 }
+
+// Towards 朝 o 移动 step, 不会越过 o
+func (v Vec) Towards(o Vec, step float64) Vec {
+	d := o.Sub(v)
+	dist := d.Len()
+	if dist <= step || dist == 0 {
+		return o
+	}
+	return v.Add(d.Scale(step / dist))
+}
+
+// overlap 左上角为 a、b, 边长 size 的两个方块是否重叠, 即原来的 checkCollision
+func overlap(a, b Vec, size float64) bool {
+	return a.X < b.X+size &&
+		a.X+size > b.X &&
+		a.Y < b.Y+size &&
+		a.Y+size > b.Y
+}
@@ -0,0 +1,86 @@
+package arcade
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// MaxHighScores 最高分表保留的条数
+const MaxHighScores = 10
+
+// HighScore 最高分表中的一条
+type HighScore struct {
+	Name  string    `json:"name"`
+	Score int       `json:"score"`
+	Level int       `json:"level"` // 到达的关卡, 从 1 开始
+	Time  time.Time `json:"time"`
+}
+
+// HighScores 从高到低排列的最高分表
+type HighScores []HighScore
+
+// LoadHighScores 读取最高分文件, 文件不存在时返回空表
+func LoadHighScores(path string) (HighScores, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var hs HighScores
+	if err := json.Unmarshal(data, &hs); err != nil {
+		return nil, err
+	}
+	hs.sort()
+	return hs, nil
+}
+
+// Save 先写临时文件再改名, 写到一半退出也不会损坏原来的表
+func (hs HighScores) Save(path string) error {
+	data, err := json.MarshalIndent(hs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Qualifies score 能否进入最高分表
+func (hs HighScores) Qualifies(score int) bool {
+	return score > 0 && (len(hs) < MaxHighScores || score > hs[len(hs)-1].Score)
+}
+
+// Add 加入一条记录, 返回新表和名次(从 1 开始), 没有进入表时名次为 0
+func (hs HighScores) Add(e HighScore) (HighScores, int) {
+	if !hs.Qualifies(e.Score) {
+		return hs, 0
+	}
+	// 同分时先达到的排在前面
+	i, _ := slices.BinarySearchFunc(hs, e.Score, func(h HighScore, score int) int {
+		if h.Score >= score {
+			return -1
+		}
+		return 1
+	})
+	hs = slices.Insert(hs, i, e)
+	if len(hs) > MaxHighScores {
+		hs = hs[:MaxHighScores]
+	}
+	return hs, i + 1
+}
+
+func (hs HighScores) sort() {
+	slices.SortStableFunc(hs, func(a, b HighScore) int { return b.Score - a.Score })
+}
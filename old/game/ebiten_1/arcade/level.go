@@ -0,0 +1,158 @@
+package arcade
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+)
+
+// Behavior 敌人的行为
+type Behavior string
+
+const (
+	Chase  Behavior = "chase"  // 直线追玩家, 碰到玩家游戏结束
+	Patrol Behavior = "patrol" // 沿出生点附近的方形路线巡逻, 碰到玩家游戏结束
+	Flee   Behavior = "flee"   // 玩家靠近时逃跑, 被玩家抓到加分
+)
+
+// Pattern 一波敌人的出生位置
+type Pattern string
+
+const (
+	Random Pattern = "random" // 随机位置, 离玩家不会太近
+	Edges  Pattern = "edges"  // 画面四条边上
+	Circle Pattern = "circle" // 围绕画面中心一圈
+	Line   Pattern = "line"   // 画面顶部一排
+)
+
+// PowerUpKind 道具类型
+type PowerUpKind string
+
+const (
+	Shield PowerUpKind = "shield" // 抵挡一次敌人
+	Freeze PowerUpKind = "freeze" // 敌人暂停不动
+	Speed  PowerUpKind = "speed"  // 玩家移动加速
+)
+
+// Wave 一波敌人
+type Wave struct {
+	At       float64  `json:"at"` // 关卡开始后第几秒出现
+	Count    int      `json:"count"`
+	Behavior Behavior `json:"behavior"`
+	Pattern  Pattern  `json:"pattern"`
+	Speed    float64  `json:"speed"` // 每帧移动的像素
+	Score    int      `json:"score"` // flee 敌人被抓到时加的分
+}
+
+// PowerUpSpawn 道具的刷新规则
+type PowerUpSpawn struct {
+	Kind     PowerUpKind `json:"kind"`
+	Every    float64     `json:"every"`    // 每隔多少秒刷新一次, 场上已有同类道具时不刷新
+	Duration float64     `json:"duration"` // 效果持续秒数, shield 不需要
+}
+
+// Level 一个关卡, 从 json 文件加载
+type Level struct {
+	Name        string         `json:"name"`
+	Goal        int            `json:"goal"`         // 本关得分达到 Goal 过关
+	Items       int            `json:"items"`        // 场上同时存在的金币数
+	PlayerSpeed float64        `json:"player_speed"` // 每帧移动的像素, 默认 2
+	Waves       []Wave         `json:"waves"`
+	PowerUps    []PowerUpSpawn `json:"power_ups"`
+}
+
+// Validate 检查关卡配置, 并补上默认值
+func (l *Level) Validate() error {
+	var errs []error
+	if l.Goal <= 0 {
+		errs = append(errs, errors.New("goal must be positive"))
+	}
+	if l.Items < 0 {
+		errs = append(errs, errors.New("items must not be negative"))
+	}
+	if l.PlayerSpeed == 0 {
+		l.PlayerSpeed = 2
+	}
+	for i, w := range l.Waves {
+		switch {
+		case w.Count <= 0:
+			errs = append(errs, fmt.Errorf("wave %d: count must be positive", i))
+		case w.At < 0:
+			errs = append(errs, fmt.Errorf("wave %d: negative start time", i))
+		case !slices.Contains([]Behavior{Chase, Patrol, Flee}, w.Behavior):
+			errs = append(errs, fmt.Errorf("wave %d: unknown behavior %q", i, w.Behavior))
+		case !slices.Contains([]Pattern{Random, Edges, Circle, Line}, w.Pattern):
+			errs = append(errs, fmt.Errorf("wave %d: unknown pattern %q", i, w.Pattern))
+		case w.Speed <= 0:
+			errs = append(errs, fmt.Errorf("wave %d: speed must be positive", i))
+		}
+	}
+	for i, p := range l.PowerUps {
+		switch {
+		case !slices.Contains([]PowerUpKind{Shield, Freeze, Speed}, p.Kind):
+			errs = append(errs, fmt.Errorf("power-up %d: unknown kind %q", i, p.Kind))
+		case p.Every <= 0:
+			errs = append(errs, fmt.Errorf("power-up %d: every must be positive", i))
+		case p.Kind != Shield && p.Duration <= 0:
+			errs = append(errs, fmt.Errorf("power-up %d: duration must be positive", i))
+		}
+	}
+	// 按出现时间排序, Game 按顺序出波
+	slices.SortStableFunc(l.Waves, func(a, b Wave) int {
+		switch {
+		case a.At < b.At:
+			return -1
+		case a.At > b.At:
+			return 1
+		}
+		return 0
+	})
+	return errors.Join(errs...)
+}
+
+// LoadLevel 读取一个关卡, 未知字段视为错误, 避免拼错字段名后静默使用默认值
+func LoadLevel(r io.Reader) (*Level, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var l Level
+	if err := dec.Decode(&l); err != nil {
+		return nil, err
+	}
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// LoadLevels 按文件名顺序读取 fsys 中匹配 pattern 的关卡, 如 LoadLevels(os.DirFS("assets"), "levels/*.json")
+func LoadLevels(fsys fs.FS, pattern string) ([]*Level, error) {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("arcade: no level matches %s", pattern)
+	}
+	slices.Sort(names)
+	levels := make([]*Level, 0, len(names))
+	for _, name := range names {
+		f, err := fsys.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		l, err := LoadLevel(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("arcade: level %s: %w", name, err)
+		}
+		if l.Name == "" {
+			l.Name = path.Base(name)
+		}
+		levels = append(levels, l)
+	}
+	return levels, nil
+}
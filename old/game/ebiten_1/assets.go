@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"image/color"
+	"io"
+	"io/fs"
+	"log"
+	"path/filepath"
+
+	"github.com/golang/freetype/truetype"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"golang.org/x/image/font"
+)
+
+// assets 图片和音效按名字加载并缓存; 缺少图片时用纯色方块代替, 缺少音效时静音,
+// 新加的敌人和道具不用等美术资源就能玩
+type assets struct {
+	dir    string
+	audio  *audio.Context
+	images map[string]*ebiten.Image
+	sounds map[string]*audio.Player
+}
+
+func newAssets(dir string, audioContext *audio.Context) *assets {
+	return &assets{
+		dir:    dir,
+		audio:  audioContext,
+		images: map[string]*ebiten.Image{},
+		sounds: map[string]*audio.Player{},
+	}
+}
+
+// image 加载图片, 文件不存在时返回 fallback 颜色的方块, 其他错误直接退出
+func (a *assets) image(name string, fallback color.Color) *ebiten.Image {
+	if img, ok := a.images[name]; ok {
+		return img
+	}
+	img, _, err := ebitenutil.NewImageFromFile(filepath.Join(a.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("image %s not found, using placeholder", name)
+		img = ebiten.NewImage(targetWidth, targetHeight)
+		img.Fill(fallback)
+	} else if err != nil {
+		log.Fatalf("Failed to load image %s: %v", name, err)
+	}
+	a.images[name] = img
+	return img
+}
+
+// sound 加载音效, 文件不存在时返回 nil
+func (a *assets) sound(name string) *audio.Player {
+	if p, ok := a.sounds[name]; ok {
+		return p
+	}
+	var player *audio.Player
+	file, err := ebitenutil.OpenFile(filepath.Join(a.dir, name))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		log.Printf("sound %s not found, muted", name)
+	case err != nil:
+		log.Fatalf("Failed to load sound %s: %v", name, err)
+	default:
+		stream, err := mp3.DecodeWithSampleRate(sampleRate, file)
+		if err != nil {
+			log.Fatalf("Failed to decode sound %s: %v", name, err)
+		}
+		if player, err = a.audio.NewPlayer(stream); err != nil {
+			log.Fatalf("Failed to create sound player for %s: %v", name, err)
+		}
+	}
+	a.sounds[name] = player
+	return player
+}
+
+// play 从头播放音效, 没有这个音效时什么也不做
+func (a *assets) play(name string) {
+	if p := a.sound(name); p != nil {
+		p.Rewind()
+		p.Play()
+	}
+}
+
+// font 加载中文字体, 字体是必需的, 失败直接退出
+func (a *assets) font(name string, size float64) font.Face {
+	fontFile, err := ebitenutil.OpenFile(filepath.Join(a.dir, name))
+	if err != nil {
+		log.Fatalf("Failed to load font: %v", err)
+	}
+	defer fontFile.Close()
+
+	fontData, err := io.ReadAll(fontFile)
+	if err != nil {
+		log.Fatalf("Failed to read font data: %v", err)
+	}
+
+	tt, err := truetype.Parse(fontData)
+	if err != nil {
+		log.Fatalf("Failed to parse font: %v", err)
+	}
+	return truetype.NewFace(tt, &truetype.Options{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+}
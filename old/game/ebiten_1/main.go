@@ -1,22 +1,22 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"image/color"
-	"io"
 	"log"
-	"math"
 	"math/rand"
+	"os"
 	"time"
 
-	"github.com/golang/freetype/truetype"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
-	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 	"golang.org/x/image/font"
+
+	"testGo/game/ebiten_1/arcade"
 )
 
 const (
@@ -25,8 +25,12 @@ const (
 	targetWidth  = 48
 	targetHeight = 48
 	sampleRate   = 44100
-	incScore     = 1 // 每次增加分数
-	maxScore     = 0 // 通关分数，0表示无通关分数
+)
+
+var (
+	assetsDir  = flag.String("assets", "assets", "资源目录, 关卡文件在其中的 levels/*.json")
+	scoresFile = flag.String("scores", "highscores.json", "最高分文件")
+	playerName = flag.String("name", "玩家", "记录最高分时的名字")
 )
 
 var (
@@ -34,254 +38,215 @@ var (
 	chineseFont font.Face
 )
 
-type Game struct {
-	playerX     float64
-	playerY     float64
-	playerImage *ebiten.Image
-	enemies     []Enemy
-	items       []Item
-	score       int
-	gameOver    bool
-	gameStart   bool
-	gameWin     bool
-	background  *ebiten.Image
-	audioPlayer *audio.Player
-	goldSound   *audio.Player
-	winSound    *audio.Player
-}
+// 敌人和道具的图片, 缺图时的占位颜色
+var (
+	enemyImages = map[arcade.Behavior]struct {
+		file  string
+		color color.Color
+	}{
+		arcade.Chase:  {"enemy.png", color.RGBA{0xe0, 0x30, 0x30, 0xff}},
+		arcade.Patrol: {"patrol.png", color.RGBA{0xe0, 0x90, 0x20, 0xff}},
+		arcade.Flee:   {"flee.png", color.RGBA{0x40, 0xc0, 0x40, 0xff}},
+	}
+	powerUpImages = map[arcade.PowerUpKind]struct {
+		file  string
+		color color.Color
+	}{
+		arcade.Shield: {"shield.png", color.RGBA{0x40, 0x80, 0xff, 0xff}},
+		arcade.Freeze: {"freeze.png", color.RGBA{0xa0, 0xe0, 0xff, 0xff}},
+		arcade.Speed:  {"speed.png", color.RGBA{0xff, 0xff, 0x40, 0xff}},
+	}
+)
 
-type Enemy struct {
-	x, y  float64
-	image *ebiten.Image
-	speed float64
+// 事件对应的音效文件
+var eventSounds = map[arcade.EventKind]string{
+	arcade.EventPickup:        "gold.mp3",
+	arcade.EventCatch:         "gold.mp3",
+	arcade.EventPowerUp:       "powerup.mp3",
+	arcade.EventShieldBreak:   "shield.mp3",
+	arcade.EventLevelComplete: "win.mp3",
+	arcade.EventVictory:       "win.mp3",
+	arcade.EventGameOver:      "lose.mp3",
 }
 
-type Item struct {
-	x, y  float64
-	image *ebiten.Image
+// Game 把 arcade.Game 接到 ebiten: 键盘转成 Input, 按状态绘制, 按事件播放音效和记录最高分
+type Game struct {
+	logic      *arcade.Game
+	assets     *assets
+	background *ebiten.Image
+	player     *ebiten.Image
+	gold       *ebiten.Image
+	music      *audio.Player
+	highScores arcade.HighScores
+	lastRank   int // 最近一局的名次, 0 为没有上榜
 }
 
-// loadImage 加载图片资源
-func loadImage(path string) *ebiten.Image {
-	img, _, err := ebitenutil.NewImageFromFile(path)
-	if err != nil {
-		log.Fatalf("Failed to load image %s: %v", path, err)
-	}
-	return img
-}
+// NewGame 初始化游戏
+func NewGame() (*Game, error) {
+	a := newAssets(*assetsDir, audio.NewContext(sampleRate))
 
-// loadSound 加载音效资源
-func loadSound(audioContext *audio.Context, path string) *audio.Player {
-	file, err := ebitenutil.OpenFile(path)
-	if err != nil {
-		log.Fatalf("Failed to load sound %s: %v", path, err)
-	}
-	stream, err := mp3.Decode(audioContext, file)
-	if err != nil {
-		log.Fatalf("Failed to decode sound %s: %v", path, err)
-	}
-	player, err := audioContext.NewPlayer(stream)
-	if err != nil {
-		log.Fatalf("Failed to create sound player for %s: %v", path, err)
-	}
-	return player
-}
+	// 加载中文字体
+	chineseFont = a.font("font.ttf", 24)
 
-// loadFont 加载中文字体
-func loadFont() font.Face {
-	fontFile, err := ebitenutil.OpenFile("assets/font.ttf") // 确保 assets/font.ttf 是中文字体文件
+	levels, err := arcade.LoadLevels(os.DirFS(*assetsDir), "levels/*.json")
 	if err != nil {
-		log.Fatalf("Failed to load font: %v", err)
+		return nil, err
 	}
-	defer fontFile.Close()
-
-	fontData, err := io.ReadAll(fontFile) // Read the file into a []byte
+	logic, err := arcade.NewGame(arcade.Config{Width: screenWidth, Height: screenHeight, Size: targetWidth},
+		levels, rand.New(rand.NewSource(time.Now().UnixNano())))
 	if err != nil {
-		log.Fatalf("Failed to read font data: %v", err)
+		return nil, err
 	}
-
-	tt, err := truetype.Parse(fontData)
+	scores, err := arcade.LoadHighScores(*scoresFile)
 	if err != nil {
-		log.Fatalf("Failed to parse font: %v", err)
+		log.Printf("load high scores: %v", err)
 	}
-	return truetype.NewFace(tt, &truetype.Options{
-		Size:    24,
-		DPI:     72,
-		Hinting: font.HintingFull,
-	})
-}
-
-// NewGame 初始化游戏
-func NewGame() *Game {
-	rand.Seed(time.Now().UnixNano())
-	audioContext := audio.NewContext(sampleRate)
-
-	// 加载中文字体
-	chineseFont = loadFont()
-
 	return &Game{
-		playerX:     screenWidth/2 - targetWidth/2,
-		playerY:     screenHeight/2 - targetHeight/2,
-		playerImage: loadImage("assets/player.png"),
-		enemies:     makeEnemies(1),
-		items:       makeItems(5),
-		background:  loadImage("assets/background.png"),
-		audioPlayer: loadSound(audioContext, "assets/background.mp3"),
-		// goldSound:   loadSound(audioContext, "assets/gold.mp3"),
-		// winSound: loadSound(audioContext, "assets/win.mp3"),
-	}
-}
-
-// makeEnemies 初始化敌人
-func makeEnemies(count int) []Enemy {
-	enemies := make([]Enemy, count)
-	for i := range enemies {
-		enemies[i] = Enemy{
-			x:     rand.Float64() * screenWidth,
-			y:     rand.Float64() * screenHeight,
-			image: loadImage("assets/enemy.png"),
-			speed: 0.5 + rand.Float64()*0.5,
-		}
-	}
-	return enemies
+		logic:      logic,
+		assets:     a,
+		background: a.image("background.png", color.Black),
+		player:     a.image("player.png", color.White),
+		gold:       a.image("gold.png", color.RGBA{0xff, 0xd7, 0x00, 0xff}),
+		music:      a.sound("background.mp3"),
+		highScores: scores,
+	}, nil
 }
 
-// makeItems 初始化道具
-func makeItems(count int) []Item {
-	items := make([]Item, count)
-	for i := range items {
-		items[i] = Item{
-			x:     rand.Float64() * screenWidth,
-			y:     rand.Float64() * screenHeight,
-			image: loadImage("assets/gold.png"),
-		}
+// input 读取键盘
+func input() arcade.Input {
+	return arcade.Input{
+		Left:    ebiten.IsKeyPressed(ebiten.KeyArrowLeft),
+		Right:   ebiten.IsKeyPressed(ebiten.KeyArrowRight),
+		Up:      ebiten.IsKeyPressed(ebiten.KeyArrowUp),
+		Down:    ebiten.IsKeyPressed(ebiten.KeyArrowDown),
+		Confirm: inpututil.IsKeyJustPressed(ebiten.KeySpace),
+		Pause:   inpututil.IsKeyJustPressed(ebiten.KeyP) || inpututil.IsKeyJustPressed(ebiten.KeyEscape),
 	}
-	return items
 }
 
-// checkCollision 检测两个矩形是否碰撞
-func checkCollision(x1, y1, w1, h1, x2, y2, w2, h2 float64) bool {
-	return x1 < x2+w2 &&
-		x1+w1 > x2 &&
-		y1 < y2+h2 &&
-		y1+h1 > y2
-}
-
-// handleInput 处理玩家输入
-func (g *Game) handleInput() {
-	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
-		g.playerX -= 2
-	}
-	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
-		g.playerX += 2
-	}
-	if ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
-		g.playerY -= 2
-	}
-	if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
-		g.playerY += 2
-	}
-}
-
-// updateEnemies 更新敌人位置和状态
-func (g *Game) updateEnemies() {
-	for i := range g.enemies {
-		dx := g.playerX - g.enemies[i].x
-		dy := g.playerY - g.enemies[i].y
-		dist := math.Sqrt(dx*dx + dy*dy)
-		if dist > 0 {
-			g.enemies[i].x += dx / dist * g.enemies[i].speed
-			g.enemies[i].y += dy / dist * g.enemies[i].speed
+// Update 更新游戏逻辑
+func (g *Game) Update() error {
+	before := g.logic.State
+	for _, ev := range g.logic.Update(input()) {
+		if name, ok := eventSounds[ev.Kind]; ok {
+			g.assets.play(name)
 		}
-
-		if checkCollision(g.playerX, g.playerY, targetWidth, targetHeight, g.enemies[i].x, g.enemies[i].y, targetWidth, targetHeight) {
-			g.gameOver = true
+		switch ev.Kind {
+		case arcade.EventStart:
+			g.lastRank = 0
+			if g.music != nil {
+				g.music.Rewind()
+				g.music.Play()
+			}
+		case arcade.EventGameOver, arcade.EventVictory:
+			g.recordScore(ev.Score)
 		}
 	}
-}
 
-// updateItems 更新道具位置和状态
-func (g *Game) updateItems() {
-	for i := range g.items {
-		if checkCollision(g.playerX, g.playerY, targetWidth, targetHeight, g.items[i].x, g.items[i].y, targetWidth, targetHeight) {
-			g.score += incScore
-			g.items[i].x = rand.Float64() * screenWidth
-			g.items[i].y = rand.Float64() * screenHeight
-			// g.goldSound.Rewind()
-			// g.goldSound.Play()
+	// 暂停和结束时停止背景音乐
+	if g.music != nil && before != g.logic.State {
+		if g.logic.State == arcade.Playing {
+			g.music.Play()
+		} else {
+			g.music.Pause()
 		}
 	}
+	return nil
 }
 
-// Update 更新游戏逻辑
-func (g *Game) Update() error {
-	if !g.gameStart {
-		if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-			g.gameStart = true
-			g.audioPlayer.Play()
-		}
-		return nil
-	}
-
-	if g.gameOver || g.gameWin {
-		if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-			g.reset()
+func (g *Game) recordScore(score int) {
+	g.highScores, g.lastRank = g.highScores.Add(arcade.HighScore{
+		Name:  *playerName,
+		Score: score,
+		Level: g.logic.Level + 1,
+		Time:  time.Now(),
+	})
+	if g.lastRank > 0 {
+		if err := g.highScores.Save(*scoresFile); err != nil {
+			log.Printf("save high scores: %v", err)
 		}
-		return nil
 	}
-
-	g.handleInput()
-	g.updateEnemies()
-	g.updateItems()
-
-	if maxScore > 0 && g.score >= maxScore {
-		g.gameWin = true
-		// g.winSound.Play()
-	}
-
-	return nil
 }
 
 // Draw 绘制游戏画面
 func (g *Game) Draw(screen *ebiten.Image) {
 	screen.DrawImage(g.background, nil)
+	l := g.logic
 
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Scale(targetWidth/float64(g.playerImage.Bounds().Dx()), targetHeight/float64(g.playerImage.Bounds().Dy()))
-	op.GeoM.Translate(g.playerX, g.playerY)
-	screen.DrawImage(g.playerImage, op)
-
-	for _, enemy := range g.enemies {
-		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Scale(targetWidth/float64(enemy.image.Bounds().Dx()), targetHeight/float64(enemy.image.Bounds().Dy()))
-		op.GeoM.Translate(enemy.x, enemy.y)
-		screen.DrawImage(enemy.image, op)
+	if l.State == arcade.Menu {
+		g.drawMenu(screen)
+		return
 	}
 
-	for _, item := range g.items {
-		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Scale(targetWidth/float64(item.image.Bounds().Dx()), targetHeight/float64(item.image.Bounds().Dy()))
-		op.GeoM.Translate(item.x, item.y)
-		screen.DrawImage(item.image, op)
+	drawSprite(screen, g.player, l.Player)
+	if l.Shielded {
+		drawFrame(screen, l.Player, powerUpImages[arcade.Shield].color)
+	}
+	for _, e := range l.Enemies {
+		img := enemyImages[e.Behavior]
+		drawSprite(screen, g.assets.image(img.file, img.color), e.Pos)
+	}
+	for _, item := range l.Items {
+		drawSprite(screen, g.gold, item)
+	}
+	for _, p := range l.PowerUps {
+		img := powerUpImages[p.Kind]
+		drawSprite(screen, g.assets.image(img.file, img.color), p.Pos)
 	}
 
-	// 显示得分
-	text.Draw(screen, fmt.Sprintf("得分: %d", g.score), chineseFont, 10, 30, color.White)
+	// 显示得分和关卡
+	level := l.CurrentLevel()
+	text.Draw(screen, fmt.Sprintf("得分: %d  %s %d/%d", l.Score, level.Name, l.LevelScore, level.Goal), chineseFont, 10, 30, color.White)
+	if d := l.Effect(arcade.Freeze); d > 0 {
+		text.Draw(screen, fmt.Sprintf("冻结 %.0f", d), chineseFont, 10, 60, color.White)
+	}
+	if d := l.Effect(arcade.Speed); d > 0 {
+		text.Draw(screen, fmt.Sprintf("加速 %.0f", d), chineseFont, 10, 90, color.White)
+	}
 
-	// 显示游戏开始信息
-	if !g.gameStart {
-		text.Draw(screen, "按空格键开始", chineseFont, screenWidth/2-100, screenHeight/2, color.White)
+	switch l.State {
+	case arcade.Paused:
+		text.Draw(screen, "已暂停, 按 P 继续", chineseFont, screenWidth/2-100, screenHeight/2, color.White)
+	case arcade.LevelComplete:
+		text.Draw(screen, fmt.Sprintf("%s 完成! 按空格键进入下一关", level.Name), chineseFont, screenWidth/2-180, screenHeight/2, color.White)
+	case arcade.GameOver:
+		text.Draw(screen, fmt.Sprintf("游戏结束! 最终得分: %d", l.Score), chineseFont, screenWidth/2-150, screenHeight/2, color.White)
+		g.drawRank(screen)
+	case arcade.Victory:
+		text.Draw(screen, fmt.Sprintf("你赢了! 最终得分: %d", l.Score), chineseFont, screenWidth/2-150, screenHeight/2, color.White)
+		g.drawRank(screen)
 	}
+}
 
-	// 显示游戏结束信息
-	if g.gameOver {
-		text.Draw(screen, fmt.Sprintf("游戏结束! 最终得分: %d. 按空格键重新开始", g.score), chineseFont, screenWidth/2-150, screenHeight/2, color.White)
+func (g *Game) drawMenu(screen *ebiten.Image) {
+	text.Draw(screen, "按空格键开始", chineseFont, screenWidth/2-80, 80, color.White)
+	text.Draw(screen, "最高分", chineseFont, screenWidth/2-40, 140, color.White)
+	for i, h := range g.highScores {
+		line := fmt.Sprintf("%2d. %-8s %5d  第%d关", i+1, h.Name, h.Score, h.Level)
+		text.Draw(screen, line, chineseFont, 80, 180+i*28, color.White)
 	}
+}
 
-	// 显示游戏胜利信息
-	if g.gameWin {
-		text.Draw(screen, fmt.Sprintf("你赢了! 最终得分: %d. 按空格键重新开始", g.score), chineseFont, screenWidth/2-150, screenHeight/2, color.White)
+func (g *Game) drawRank(screen *ebiten.Image) {
+	msg := "按空格键返回"
+	if g.lastRank > 0 {
+		msg = fmt.Sprintf("进入最高分第 %d 名! %s", g.lastRank, msg)
 	}
+	text.Draw(screen, msg, chineseFont, screenWidth/2-150, screenHeight/2+40, color.White)
+}
+
+// drawSprite 把图片缩放到实体大小画在 pos
+func drawSprite(screen, img *ebiten.Image, pos arcade.Vec) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(targetWidth/float64(img.Bounds().Dx()), targetHeight/float64(img.Bounds().Dy()))
+	op.GeoM.Translate(pos.X, pos.Y)
+	screen.DrawImage(img, op)
+}
+
+// drawFrame 在实体外画一圈边框, 表示护盾
+func drawFrame(screen *ebiten.Image, pos arcade.Vec, c color.Color) {
+	x, y, w, h := float32(pos.X-2), float32(pos.Y-2), float32(targetWidth+4), float32(targetHeight+4)
+	vector.StrokeRect(screen, x, y, w, h, 2, c, false)
 }
 
 // Layout 设置游戏窗口大小
@@ -289,23 +254,17 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 	return screenWidth, screenHeight
 }
 
-// reset 重置游戏状态
-func (g *Game) reset() {
-	g.playerX = screenWidth/2 - targetWidth/2
-	g.playerY = screenHeight/2 - targetHeight/2
-	g.score = 0
-	g.gameOver = false
-	g.gameStart = false
-	g.gameWin = false
-	g.audioPlayer.Rewind()
-}
-
 // main 函数，游戏入口
 func main() {
+	flag.Parse()
 	ebiten.SetWindowSize(screenWidth, screenHeight)
 	ebiten.SetWindowTitle("我的 Ebiten 游戏")
+	ebiten.SetTPS(arcade.TPS)
 
-	game := NewGame()
+	game, err := NewGame()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	if err := ebiten.RunGame(game); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,245 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+// AbilityKind 技能种类
+type AbilityKind int
+
+const (
+	AbilityHeal        AbilityKind = iota // 治疗：医疗单位为附近受伤的步兵恢复生命
+	AbilityRepair                         // 维修：工程兵为附近受损的载具恢复生命
+	AbilityBuildBridge                    // 架桥：工程兵把水面变成可通行的浮桥
+	AbilityDemolish                       // 拆除：工程兵把浮桥拆回水面
+	AbilityLayMine                        // 布雷：工程兵在脚下埋设地雷，消耗1发弹药
+	AbilityClearMines                     // 排雷：工程兵清除范围内所有地雷
+)
+
+// AbilitySpec 技能的固定参数
+type AbilitySpec struct {
+	Name     string
+	Auto     bool          // 自动技能：冷却结束后自动对范围内的目标释放，否则需要在面板上点击按钮
+	Targeted bool          // 需要选择目标格子，否则以单位自身位置为中心
+	Range    int           // 作用范围（曼哈顿距离，格子数）
+	Cooldown time.Duration // 冷却时间
+	Amount   int           // 治疗、维修的恢复量或地雷的伤害
+}
+
+// abilitySpecs 所有技能的参数
+var abilitySpecs = map[AbilityKind]AbilitySpec{
+	AbilityHeal:        {Name: "治疗", Auto: true, Range: 2, Cooldown: time.Second, Amount: 1},
+	AbilityRepair:      {Name: "维修", Auto: true, Range: 1, Cooldown: 1500 * time.Millisecond, Amount: 1},
+	AbilityBuildBridge: {Name: "架桥", Targeted: true, Range: 1, Cooldown: 5 * time.Second},
+	AbilityDemolish:    {Name: "拆除", Targeted: true, Range: 1, Cooldown: 3 * time.Second},
+	AbilityLayMine:     {Name: "布雷", Cooldown: 4 * time.Second, Amount: 3},
+	AbilityClearMines:  {Name: "排雷", Range: 2, Cooldown: 3 * time.Second},
+}
+
+// unitAbilities 各单位类型拥有的技能，按面板按钮的顺序排列
+var unitAbilities = map[UnitType][]AbilityKind{
+	Engineer:  {AbilityRepair, AbilityBuildBridge, AbilityDemolish, AbilityLayMine, AbilityClearMines},
+	MedicUnit: {AbilityHeal},
+}
+
+// Spec 技能的参数
+func (k AbilityKind) Spec() AbilitySpec {
+	return abilitySpecs[k]
+}
+
+func (k AbilityKind) String() string {
+	return k.Spec().Name
+}
+
+// Ability 单位身上的一个技能及其冷却状态
+type Ability struct {
+	Kind     AbilityKind
+	AutoOn   bool      // 自动技能是否开启，可以在面板上切换
+	LastUsed time.Time // 上次释放的时间
+}
+
+// newAbilities 按单位类型创建技能，初始时冷却已结束
+func newAbilities(unitType UnitType) []*Ability {
+	var abilities []*Ability
+	for _, kind := range unitAbilities[unitType] {
+		abilities = append(abilities, &Ability{Kind: kind, AutoOn: kind.Spec().Auto})
+	}
+	return abilities
+}
+
+// Remaining 剩余冷却时间，可以释放时为0
+func (a *Ability) Remaining(now time.Time) time.Duration {
+	if a.LastUsed.IsZero() {
+		return 0
+	}
+	if d := a.Kind.Spec().Cooldown - now.Sub(a.LastUsed); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Ready 冷却是否已结束
+func (a *Ability) Ready(now time.Time) bool {
+	return a.Remaining(now) == 0
+}
+
+// Ability 查找单位的技能，没有时返回nil
+func (u *Unit) Ability(kind AbilityKind) *Ability {
+	for _, a := range u.Abilities {
+		if a.Kind == kind {
+			return a
+		}
+	}
+	return nil
+}
+
+// IsInfantry 是否为步兵类单位（步兵、工程兵、医疗单位），医疗单位只治疗步兵类单位，工程兵只维修其他单位
+func (u *Unit) IsInfantry() bool {
+	return u.Type == Infantry || u.Type == Engineer || u.Type == MedicUnit
+}
+
+// Mine 地雷，只有布雷阵营和它的同盟能看到，敌对阵营的地面单位踩上会触发
+type Mine struct {
+	X, Y   int
+	Team   TeamID
+	Damage int
+}
+
+// updateAbilities 释放所有冷却结束且开启的自动技能
+func (g *Game) updateAbilities(now time.Time) {
+	for _, unit := range g.units {
+		if unit.IsPassenger {
+			continue
+		}
+		for _, a := range unit.Abilities {
+			if !a.AutoOn || !a.Ready(now) {
+				continue
+			}
+			target := g.supportTarget(unit, a.Kind)
+			if target == nil {
+				continue
+			}
+			spec := a.Kind.Spec()
+			target.Health = min(target.Health+spec.Amount, target.MaxHealth)
+			a.LastUsed = now
+			fmt.Printf("%s%s%s，生命值：%d/%d\n", unit.Name, spec.Name, target.Name, target.Health, target.MaxHealth)
+		}
+	}
+}
+
+// supportTarget 治疗或维修的目标：范围内同盟阵营中受伤最重的单位，没有时返回nil
+func (g *Game) supportTarget(unit *Unit, kind AbilityKind) *Unit {
+	spec := kind.Spec()
+	var best *Unit
+	for _, other := range g.units {
+		if other == unit || other.IsPassenger || other.Health >= other.MaxHealth || other.Health <= 0 {
+			continue
+		}
+		if !g.teams.IsAllied(unit.Team, other.Team) {
+			continue
+		}
+		if kind == AbilityHeal && !other.IsInfantry() || kind == AbilityRepair && other.IsInfantry() {
+			continue
+		}
+		if abs(unit.X-other.X)+abs(unit.Y-other.Y) > spec.Range {
+			continue
+		}
+		if best == nil || other.MaxHealth-other.Health > best.MaxHealth-best.Health {
+			best = other
+		}
+	}
+	return best
+}
+
+// useAbility 释放主动技能；不需要目标的技能忽略x, y。无法释放时返回原因
+func (g *Game) useAbility(unit *Unit, kind AbilityKind, x, y int, now time.Time) error {
+	a := unit.Ability(kind)
+	if a == nil {
+		return fmt.Errorf("%s没有%s技能", unit.Name, kind)
+	}
+	if unit.IsPassenger {
+		return fmt.Errorf("%s在载具中，无法%s", unit.Name, kind)
+	}
+	if d := a.Remaining(now); d > 0 {
+		return fmt.Errorf("%s的%s冷却中，还需%.1f秒", unit.Name, kind, d.Seconds())
+	}
+	spec := kind.Spec()
+	if spec.Targeted {
+		if x < 0 || x >= g.gameMap.Width || y < 0 || y >= g.gameMap.Height {
+			return fmt.Errorf("目标(%d, %d)在地图外", x, y)
+		}
+		if dist := abs(unit.X-x) + abs(unit.Y-y); dist > spec.Range {
+			return fmt.Errorf("目标(%d, %d)超出%s范围：距离%d，范围%d", x, y, kind, dist, spec.Range)
+		}
+	}
+
+	switch kind {
+	case AbilityBuildBridge:
+		if g.gameMap.Terrain[y][x] != Water {
+			return fmt.Errorf("只能在水面上架桥")
+		}
+		g.gameMap.Terrain[y][x] = Bridge
+		fmt.Printf("%s在(%d, %d)架设了浮桥\n", unit.Name, x, y)
+	case AbilityDemolish:
+		if g.gameMap.Terrain[y][x] != Bridge {
+			return fmt.Errorf("(%d, %d)不是浮桥", x, y)
+		}
+		for _, other := range g.units {
+			if other.X == x && other.Y == y && !other.IsPassenger && !other.IsAirUnit() {
+				return fmt.Errorf("浮桥上有单位%s，无法拆除", other.Name)
+			}
+		}
+		g.gameMap.Terrain[y][x] = Water
+		fmt.Printf("%s拆除了(%d, %d)的浮桥\n", unit.Name, x, y)
+	case AbilityLayMine:
+		if unit.CurrentAmmo <= 0 {
+			return fmt.Errorf("%s弹药耗尽，无法布雷", unit.Name)
+		}
+		pos := [2]int{unit.X, unit.Y}
+		if g.mines[pos] != nil {
+			return fmt.Errorf("(%d, %d)已经有地雷", unit.X, unit.Y)
+		}
+		g.mines[pos] = &Mine{X: unit.X, Y: unit.Y, Team: unit.Team, Damage: spec.Amount}
+		unit.CurrentAmmo--
+		fmt.Printf("%s在(%d, %d)埋设了地雷，剩余弹药：%d\n", unit.Name, unit.X, unit.Y, unit.CurrentAmmo)
+	case AbilityClearMines:
+		cleared := 0
+		for pos := range g.mines {
+			if abs(unit.X-pos[0])+abs(unit.Y-pos[1]) <= spec.Range {
+				delete(g.mines, pos)
+				cleared++
+			}
+		}
+		if cleared == 0 {
+			return fmt.Errorf("%s附近没有地雷", unit.Name)
+		}
+		fmt.Printf("%s清除了%d颗地雷\n", unit.Name, cleared)
+	default:
+		return fmt.Errorf("%s是自动技能", kind)
+	}
+	a.LastUsed = now
+	return nil
+}
+
+// triggerMines 敌对阵营的地面单位踩到地雷时引爆，伤害减去防御力，最少1点
+func (g *Game) triggerMines() {
+	for _, unit := range append([]*Unit(nil), g.units...) {
+		if unit.IsPassenger || unit.IsAirUnit() {
+			continue
+		}
+		pos := [2]int{unit.X, unit.Y}
+		mine := g.mines[pos]
+		if mine == nil || !g.teams.IsHostile(mine.Team, unit.Team) {
+			continue
+		}
+		delete(g.mines, pos)
+		damage := max(mine.Damage-unit.Defense, 1)
+		unit.Health -= damage
+		fmt.Printf("%s触发地雷！造成%d点伤害\n", unit.Name, damage)
+		if unit.Health <= 0 {
+			fmt.Printf("%s被消灭！\n", unit.Name)
+			g.removeUnit(unit)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestGame 5x5 的平原地图，(2, 0)-(2, 4) 一列是水；阵营按 2v2 场景，1 和 2 同盟，3 敌对
+func newTestGame(t *testing.T, units ...*Unit) *Game {
+	t.Helper()
+	s, _ := LookupScenario("2v2")
+	teams, err := s.newTeams(5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &GameMap{Width: 5, Height: 5, Terrain: make([][]TerrainType, 5)}
+	for y := range m.Terrain {
+		m.Terrain[y] = []TerrainType{Plain, Plain, Water, Plain, Plain}
+	}
+	return &Game{gameMap: m, units: units, teams: teams, localTeam: 1, mines: map[[2]int]*Mine{}}
+}
+
+func newTestUnit(x, y int, unitType UnitType, team TeamID, health, maxHealth int) *Unit {
+	return &Unit{X: x, Y: y, Type: unitType, Team: team, Health: health, MaxHealth: maxHealth,
+		Abilities: newAbilities(unitType), CurrentAmmo: 2, Name: "unit"}
+}
+
+func TestAbilityCooldown(t *testing.T) {
+	abilities := newAbilities(Engineer)
+	if len(abilities) != 5 || newAbilities(Infantry) != nil {
+		t.Fatalf("engineer abilities %d", len(abilities))
+	}
+	a := abilities[0]
+	if a.Kind != AbilityRepair || !a.AutoOn || !a.Ready(time.Now()) {
+		t.Fatalf("repair should be automatic and ready: %+v", a)
+	}
+
+	now := time.Now()
+	a.LastUsed = now
+	if a.Ready(now) || a.Remaining(now.Add(time.Second)) != 500*time.Millisecond {
+		t.Fatalf("remaining %v", a.Remaining(now.Add(time.Second)))
+	}
+	if !a.Ready(now.Add(AbilityRepair.Spec().Cooldown)) {
+		t.Fatal("should be ready after cooldown")
+	}
+}
+
+func TestHealAndRepair(t *testing.T) {
+	medic := newTestUnit(0, 0, MedicUnit, 1, 3, 3)
+	engineer := newTestUnit(4, 4, Engineer, 1, 2, 2)
+	wounded := newTestUnit(0, 1, Infantry, 2, 1, 3)   // 同盟步兵，伤得最重
+	scratched := newTestUnit(1, 0, Infantry, 1, 2, 3) // 本方步兵
+	enemy := newTestUnit(1, 1, Infantry, 3, 1, 3)     // 敌方步兵，不治疗
+	tank := newTestUnit(0, 2, Armor, 1, 1, 5)         // 载具，医疗单位不治疗
+	truck := newTestUnit(4, 3, Armor, 1, 3, 5)        // 工程兵旁边的载具
+	g := newTestGame(t, medic, engineer, wounded, scratched, enemy, tank, truck)
+
+	now := time.Now()
+	g.updateAbilities(now)
+	if wounded.Health != 2 || scratched.Health != 2 || enemy.Health != 1 || tank.Health != 1 {
+		t.Fatalf("heal: wounded %d scratched %d enemy %d tank %d", wounded.Health, scratched.Health, enemy.Health, tank.Health)
+	}
+	if truck.Health != 4 {
+		t.Fatalf("repair: truck %d", truck.Health)
+	}
+
+	// 冷却中不再释放
+	g.updateAbilities(now.Add(500 * time.Millisecond))
+	if wounded.Health+scratched.Health != 4 {
+		t.Fatal("healed during cooldown")
+	}
+
+	// 关闭自动技能后不再释放，不超过最大生命值
+	engineer.Ability(AbilityRepair).AutoOn = false
+	for i := 1; i <= 5; i++ {
+		g.updateAbilities(now.Add(time.Duration(i) * 2 * time.Second))
+	}
+	if wounded.Health != 3 || scratched.Health != 3 || truck.Health != 4 {
+		t.Fatalf("wounded %d scratched %d truck %d", wounded.Health, scratched.Health, truck.Health)
+	}
+}
+
+func TestBridge(t *testing.T) {
+	engineer := newTestUnit(1, 2, Engineer, 1, 2, 2)
+	g := newTestGame(t, engineer)
+	now := time.Now()
+
+	if err := g.useAbility(engineer, AbilityBuildBridge, 2, 4, now); err == nil {
+		t.Fatal("built out of range")
+	}
+	if err := g.useAbility(engineer, AbilityBuildBridge, 1, 1, now); err == nil {
+		t.Fatal("built on land")
+	}
+	if err := g.useAbility(engineer, AbilityBuildBridge, 2, 2, now); err != nil {
+		t.Fatal(err)
+	}
+	if g.gameMap.Terrain[2][2] != Bridge {
+		t.Fatalf("terrain %v", g.gameMap.Terrain[2][2])
+	}
+	if err := g.useAbility(engineer, AbilityBuildBridge, 2, 1, now.Add(time.Second)); err == nil {
+		t.Fatal("built during cooldown")
+	}
+
+	// 浮桥上有单位时不能拆除
+	crossing := newTestUnit(2, 2, Infantry, 3, 3, 3)
+	g.units = append(g.units, crossing)
+	if err := g.useAbility(engineer, AbilityDemolish, 2, 2, now); err == nil {
+		t.Fatal("demolished with a unit on the bridge")
+	}
+	crossing.X = 3
+	if err := g.useAbility(engineer, AbilityDemolish, 2, 2, now); err != nil {
+		t.Fatal(err)
+	}
+	if g.gameMap.Terrain[2][2] != Water {
+		t.Fatalf("terrain %v", g.gameMap.Terrain[2][2])
+	}
+}
+
+func TestMines(t *testing.T) {
+	engineer := newTestUnit(0, 0, Engineer, 1, 2, 2)
+	ally := newTestUnit(4, 4, Infantry, 2, 3, 3)
+	enemy := newTestUnit(4, 3, Infantry, 3, 3, 3)
+	enemy.Defense = 1
+	victim := newTestUnit(4, 2, Infantry, 3, 2, 3)
+	g := newTestGame(t, engineer, ally, enemy, victim)
+	now := time.Now()
+
+	if err := g.useAbility(engineer, AbilityLayMine, 0, 0, now); err != nil {
+		t.Fatal(err)
+	}
+	if engineer.CurrentAmmo != 1 || g.mines[[2]int{0, 0}] == nil {
+		t.Fatalf("ammo %d mines %v", engineer.CurrentAmmo, g.mines)
+	}
+	// 本方和同盟不会触发
+	g.triggerMines()
+	ally.X, ally.Y = 0, 0
+	g.triggerMines()
+	if ally.Health != 3 || len(g.mines) != 1 {
+		t.Fatal("allied unit triggered the mine")
+	}
+
+	// 敌方踩雷受到伤害（减去防御力），地雷消失
+	ally.X, ally.Y = 4, 4
+	enemy.X, enemy.Y = 0, 0
+	g.triggerMines()
+	if enemy.Health != 1 || len(g.mines) != 0 {
+		t.Fatalf("enemy health %d mines %d", enemy.Health, len(g.mines))
+	}
+
+	// 伤害足够时消灭单位
+	g.mines[[2]int{4, 2}] = &Mine{X: 4, Y: 2, Team: 1, Damage: 3}
+	g.triggerMines()
+	for _, u := range g.units {
+		if u == victim {
+			t.Fatal("victim should be removed")
+		}
+	}
+
+	// 排雷清除范围内所有地雷，包括敌方的
+	g.mines[[2]int{1, 1}] = &Mine{X: 1, Y: 1, Team: 3, Damage: 3}
+	g.mines[[2]int{4, 4}] = &Mine{X: 4, Y: 4, Team: 3, Damage: 3}
+	if err := g.useAbility(engineer, AbilityClearMines, 0, 0, now); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.mines) != 1 || g.mines[[2]int{4, 4}] == nil {
+		t.Fatalf("mines %v", g.mines)
+	}
+	if err := g.useAbility(engineer, AbilityClearMines, 0, 0, now.Add(time.Minute)); err == nil {
+		t.Fatal("cleared with no mines in range")
+	}
+	if err := g.useAbility(engineer, AbilityHeal, 0, 0, now); err == nil {
+		t.Fatal("engineer has no heal")
+	}
+}
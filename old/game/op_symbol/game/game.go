@@ -6,6 +6,7 @@ import (
 	"math"
 	"math/rand"
 	"sort"
+	"strings"
 	"time"
 
 	"os"
@@ -43,34 +44,57 @@ type Game struct {
 	selectionEndX   int  // 圈选结束X坐标
 	selectionEndY   int  // 圈选结束Y坐标
 
-	// 战争迷雾相关
-	fogOfWar        [][]bool // 战争迷雾状态，true表示有迷雾
-	visibleToPlayer [][]bool // 玩家可见区域，true表示可见
+	// 阵营相关，战争迷雾按本地玩家阵营（及其同盟）的视野绘制
+	teams     *Teams // 所有阵营、外交关系和各阵营的视野
+	localTeam TeamID // 本地玩家操控的阵营
 
 	// 攻击目标相关
 	targetUnit      *Unit     // 当前攻击目标单位
 	targetFlashTime time.Time // 攻击目标闪烁时间
 
 	// 单位信息面板相关
-	uiButtons     []UIButton // 操作按钮列表
-	showUnitPanel bool       // 是否显示单位信息面板
+	uiButtons      []UIButton // 操作按钮列表
+	abilityButtons []UIButton // 技能按钮列表，按选中单位拥有的技能生成，显示在操作按钮上方
+	showUnitPanel  bool       // 是否显示单位信息面板
+
+	// 技能相关
+	mines            map[[2]int]*Mine // 地图上的地雷，按格子坐标索引
+	targetingAbility bool             // 是否正在为技能选择目标格子
+	pendingAbility   AbilityKind      // 等待选择目标的技能
 }
 
 // UIButton 表示界面上的按钮
 type UIButton struct {
 	X, Y, Width, Height int
 	Text                string
-	Action              string // 按钮对应的操作
-	Enabled             bool   // 按钮是否可用
-	Hovered             bool   // 鼠标是否悬停在按钮上
+	Action              string      // 按钮对应的操作
+	Enabled             bool        // 按钮是否可用
+	Hovered             bool        // 鼠标是否悬停在按钮上
+	Ability             AbilityKind // Action为"ability"时对应的技能
 }
 
+// NewGame 使用默认的1v1场景创建游戏
 func NewGame() *Game {
+	s, _ := LookupScenario("1v1")
+	g, err := NewScenarioGame(s)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// NewScenarioGame 按场景创建游戏
+func NewScenarioGame(s Scenario) (*Game, error) {
+	teams, err := s.newTeams(MapWidth, MapHeight)
+	if err != nil {
+		return nil, err
+	}
+
 	rand.Seed(time.Now().UnixNano())
 
 	// 初始化单位类型配置
 	configPath := "./unit_types.json"
-	err := InitUnitTypes(configPath)
+	err = InitUnitTypes(configPath)
 	if err != nil {
 		fmt.Printf("警告：无法加载单位类型配置，将使用默认值: %v\n", err)
 		// 创建默认配置
@@ -126,18 +150,6 @@ func NewGame() *Game {
 		fmt.Println("警告：使用默认字体，可能无法正确显示中文")
 	}
 
-	// 初始化战争迷雾
-	fogOfWar := make([][]bool, MapHeight)
-	visibleToPlayer := make([][]bool, MapHeight)
-	for y := 0; y < MapHeight; y++ {
-		fogOfWar[y] = make([]bool, MapWidth)
-		visibleToPlayer[y] = make([]bool, MapWidth)
-		for x := 0; x < MapWidth; x++ {
-			fogOfWar[y][x] = true         // 初始时所有区域都有迷雾
-			visibleToPlayer[y][x] = false // 初始时所有区域都不可见
-		}
-	}
-
 	// 初始化UI按钮
 	uiButtons := []UIButton{
 		{Text: "攻击", Action: "attack", Enabled: false},
@@ -158,35 +170,24 @@ func NewGame() *Game {
 		cameraX:         0,     // 初始相机位置
 		cameraY:         0,
 		isSelecting:     false,
-		fogOfWar:        fogOfWar,
-		visibleToPlayer: visibleToPlayer,
+		teams:           teams,
+		localTeam:       s.LocalTeam,
 		targetUnit:      nil,
 		targetFlashTime: time.Time{},
 		uiButtons:       uiButtons,
 		showUnitPanel:   showUnitPanel,
+		mines:           make(map[[2]int]*Mine),
+	}
+
+	// 创建场景中的初始单位
+	for _, spawn := range s.Units {
+		g.units = append(g.units, NewUnit(spawn.X, spawn.Y, spawn.Type, spawn.Team))
 	}
 
-	// 创建一些初始单位
-	// 玩家单位 (左侧)
-	g.units = append(g.units, NewUnit(3, 5, Infantry, true))
-	g.units = append(g.units, NewUnit(3, 8, Infantry, true))
-	g.units = append(g.units, NewUnit(3, 11, Infantry, true))
-	g.units = append(g.units, NewUnit(2, 8, Armor, true))
-	g.units = append(g.units, NewUnit(2, 5, Artillery, true))
-	g.units = append(g.units, NewUnit(2, 11, Recon, true))
-
-	// 敌方单位 (右侧)
-	g.units = append(g.units, NewUnit(26, 5, Infantry, false))
-	g.units = append(g.units, NewUnit(26, 8, Infantry, false))
-	g.units = append(g.units, NewUnit(26, 11, Infantry, false))
-	g.units = append(g.units, NewUnit(27, 8, Armor, false))
-	g.units = append(g.units, NewUnit(27, 5, Artillery, false))
-	g.units = append(g.units, NewUnit(27, 11, AntiAir, false))
-
-	// 初始化玩家单位的视野
+	// 初始化各阵营的视野
 	g.updateFogOfWar()
 
-	return g
+	return g, nil
 }
 
 func (g *Game) Update() error {
@@ -236,8 +237,23 @@ func (g *Game) Update() error {
 	gridX := mapMouseX / TileSize
 	gridY := mapMouseY / TileSize
 
+	// 右键或Esc取消技能目标选择
+	cancelTargeting := g.targetingAbility &&
+		(inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) || inpututil.IsKeyJustPressed(ebiten.KeyEscape))
+	if cancelTargeting {
+		g.targetingAbility = false
+		fmt.Println("取消技能目标选择")
+	}
+
 	// 处理鼠标左键点击
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+	leftPressed := inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft)
+	switch {
+	case leftPressed && g.isOverPanelButton(mouseX, mouseY):
+		// 点在面板按钮上，由后面的handleUIButtonClick处理，不开始圈选
+	case leftPressed && g.targetingAbility:
+		// 正在选择技能目标，对点击的格子释放技能
+		g.usePendingAbility(gridX, gridY)
+	case leftPressed:
 		// 开始圈选
 		g.isSelecting = true
 		g.selectionStartX = mapMouseX
@@ -253,7 +269,7 @@ func (g *Game) Update() error {
 	}
 
 	// 处理鼠标左键释放
-	if inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) {
+	if inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) && g.isSelecting {
 		// 结束圈选
 		g.isSelecting = false
 
@@ -276,8 +292,8 @@ func (g *Game) Update() error {
 			clickedUnit := g.getUnitAt(gridX, gridY)
 
 			if clickedUnit != nil {
-				// 如果点击的是玩家单位
-				if clickedUnit.IsPlayerUnit {
+				// 如果点击的是本方单位
+				if g.isOwnUnit(clickedUnit) {
 					// 如果按住Shift键，添加到选择列表
 					if ebiten.IsKeyPressed(ebiten.KeyShift) {
 						// 检查单位是否已经被选中
@@ -308,10 +324,10 @@ func (g *Game) Update() error {
 						// 单个单位不需要排序
 					}
 				} else {
-					// 如果点击的是敌方单位，并且有选中的玩家单位
-					if len(g.selectedUnits) > 0 {
+					// 如果点击的是敌对阵营的单位，并且有选中的玩家单位
+					if len(g.selectedUnits) > 0 && g.teams.IsHostile(g.localTeam, clickedUnit.Team) {
 						// 检查敌方单位是否在可见区域内
-						if g.visibleToPlayer[clickedUnit.Y][clickedUnit.X] {
+						if g.isVisibleToPlayer(clickedUnit.X, clickedUnit.Y) {
 							// 设置攻击目标和闪烁时间
 							g.targetUnit = clickedUnit
 							g.targetFlashTime = time.Now().Add(1 * time.Second) // 闪烁1秒
@@ -371,9 +387,9 @@ func (g *Game) Update() error {
 							}
 						}
 					} else {
-						// 如果没有选中的玩家单位，选择敌方单位以查看信息
-						// 只有当敌方单位在可见区域内才能选择
-						if g.visibleToPlayer[clickedUnit.Y][clickedUnit.X] {
+						// 如果没有选中的玩家单位，或者点击的是同盟/中立单位，选择该单位以查看信息
+						// 只有当单位在可见区域内才能选择
+						if g.isVisibleToPlayer(clickedUnit.X, clickedUnit.Y) {
 							for _, unit := range g.selectedUnits {
 								unit.Selected = false
 							}
@@ -391,7 +407,7 @@ func (g *Game) Update() error {
 					// 检查是否所有选中的单位都是玩家单位
 					allPlayerUnits := true
 					for _, unit := range g.selectedUnits {
-						if !unit.IsPlayerUnit {
+						if !g.isOwnUnit(unit) {
 							allPlayerUnits = false
 							break
 						}
@@ -424,7 +440,7 @@ func (g *Game) Update() error {
 
 			// 选择范围内的所有玩家单位
 			for _, unit := range g.units {
-				if unit.IsPlayerUnit && !unit.IsPassenger {
+				if g.isOwnUnit(unit) && !unit.IsPassenger {
 					// 检查单位是否在选择范围内
 					if unit.X >= minGridX && unit.X <= maxGridX && unit.Y >= minGridY && unit.Y <= maxGridY {
 						g.selectedUnits = append(g.selectedUnits, unit)
@@ -438,14 +454,14 @@ func (g *Game) Update() error {
 		}
 	}
 
-	// 处理鼠标右键点击（命令攻击或移动）
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+	// 处理鼠标右键点击（命令攻击或移动），用于取消技能目标选择时不再下达命令
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) && !cancelTargeting {
 		// 如果有选中的玩家单位
 		if len(g.selectedUnits) > 0 {
 			// 检查是否所有选中的单位都是玩家单位
 			allPlayerUnits := true
 			for _, unit := range g.selectedUnits {
-				if !unit.IsPlayerUnit {
+				if !g.isOwnUnit(unit) {
 					allPlayerUnits = false
 					break
 				}
@@ -456,9 +472,9 @@ func (g *Game) Update() error {
 				// 检查点击位置是否有敌方单位
 				clickedUnit := g.getUnitAt(gridX, gridY)
 
-				if clickedUnit != nil && !clickedUnit.IsPlayerUnit {
+				if clickedUnit != nil && g.teams.IsHostile(g.localTeam, clickedUnit.Team) {
 					// 检查敌方单位是否在可见区域内
-					if g.visibleToPlayer[clickedUnit.Y][clickedUnit.X] {
+					if g.isVisibleToPlayer(clickedUnit.X, clickedUnit.Y) {
 						fmt.Printf("右键命令：攻击敌方单位 %s\n", clickedUnit.Name)
 						// 设置攻击目标和闪烁时间
 						g.targetUnit = clickedUnit
@@ -544,95 +560,49 @@ func (g *Game) Update() error {
 		}
 	}
 
-	// 更新所有单位
+	// 更新所有单位：每个单位都正常移动和攻击，索敌用的是单位所属阵营（及同盟）的视野，
+	// 本地玩家的视野只决定画面上显示哪些单位
 	for _, unit := range g.units {
-		// 如果是玩家单位，或者在可见区域内的敌方单位，正常更新
-		if unit.IsPlayerUnit || g.isVisibleToPlayer(unit.X, unit.Y) {
-			// 保存当前位置
-			oldX, oldY := unit.X, unit.Y
-			oldCurrentX, oldCurrentY := unit.CurrentX, unit.CurrentY
-
-			// 更新单位位置
-			unit.Update()
-
-			// 检查碰撞
-			hasCollision := false
-			var collidedUnit *Unit
-			for _, otherUnit := range g.units {
-				if unit != otherUnit && !otherUnit.IsPassenger && !unit.IsPassenger && g.unitsCollide(unit, otherUnit) {
-					hasCollision = true
-					collidedUnit = otherUnit
-					break
-				}
+		// 保存当前位置
+		oldX, oldY := unit.X, unit.Y
+		oldCurrentX, oldCurrentY := unit.CurrentX, unit.CurrentY
+
+		// 更新单位位置
+		unit.Update()
+
+		// 检查碰撞
+		hasCollision := false
+		var collidedUnit *Unit
+		for _, otherUnit := range g.units {
+			if unit != otherUnit && !otherUnit.IsPassenger && !unit.IsPassenger && g.unitsCollide(unit, otherUnit) {
+				hasCollision = true
+				collidedUnit = otherUnit
+				break
 			}
+		}
 
-			// 如果发生碰撞，恢复原位置
-			if hasCollision {
-				unit.X, unit.Y = oldX, oldY
-				unit.CurrentX, unit.CurrentY = oldCurrentX, oldCurrentY
-
-				// 如果是玩家单位，提供碰撞反馈
-				if unit.IsPlayerUnit {
-					fmt.Printf("单位 %s 与 %s 发生碰撞，无法移动！\n", unit.Name, collidedUnit.Name)
-				}
-
-				// 如果发生碰撞，尝试寻找新路径
-				if unit.HasTarget && len(unit.Path) > 0 {
-					// 寻找新的路径
-					unit.Path = FindPathWithUnits(g.gameMap, g, unit.X, unit.Y, unit.TargetX, unit.TargetY, unit.Type)
-				}
-			}
-		} else {
-			// 如果是不可见区域内的敌方单位，只更新移动，不更新攻击
-			// 这样敌方单位仍然会移动，但不会攻击玩家单位，除非被发现
-			if len(unit.Path) > 0 {
-				// 获取下一个路径点
-				nextPoint := unit.Path[0]
-				targetX := float64(nextPoint[0]*TileSize) + float64(TileSize)/2
-				targetY := float64(nextPoint[1]*TileSize) + float64(TileSize)/2
-
-				// 计算方向向量
-				dx := targetX - unit.CurrentX
-				dy := targetY - unit.CurrentY
-				distance := math.Sqrt(dx*dx + dy*dy)
-
-				// 如果已经非常接近目标点，则认为已到达
-				if distance < unit.MoveSpeed {
-					unit.X = nextPoint[0]
-					unit.Y = nextPoint[1]
-					unit.CurrentX = targetX
-					unit.CurrentY = targetY
-					// 移除已到达的路径点
-					unit.Path = unit.Path[1:]
-					// 如果路径为空且已到达目标，清除目标
-					if len(unit.Path) == 0 && unit.X == unit.TargetX && unit.Y == unit.TargetY {
-						unit.HasTarget = false
-					}
-				} else {
-					// 移动单位（使用平滑的移动）
-					moveSpeed := unit.MoveSpeed
-
-					// 计算单位向量
-					normalizedDx := dx / distance
-					normalizedDy := dy / distance
-
-					// 应用移动
-					unit.CurrentX += normalizedDx * moveSpeed
-					unit.CurrentY += normalizedDy * moveSpeed
+		// 如果发生碰撞，恢复原位置
+		if hasCollision {
+			unit.X, unit.Y = oldX, oldY
+			unit.CurrentX, unit.CurrentY = oldCurrentX, oldCurrentY
 
-					// 更新网格位置
-					unit.X = int(unit.CurrentX) / TileSize
-					unit.Y = int(unit.CurrentY) / TileSize
-				}
+			// 如果是玩家单位，提供碰撞反馈
+			if g.isOwnUnit(unit) {
+				fmt.Printf("单位 %s 与 %s 发生碰撞，无法移动！\n", unit.Name, collidedUnit.Name)
 			}
 
-			// 清除目标单位，如果目标单位是玩家单位
-			if unit.TargetUnit != nil && unit.TargetUnit.IsPlayerUnit {
-				unit.TargetUnit = nil
+			// 如果发生碰撞，尝试寻找新路径
+			if unit.HasTarget && len(unit.Path) > 0 {
+				// 寻找新的路径
+				unit.Path = FindPathWithUnits(g.gameMap, g, unit.X, unit.Y, unit.TargetX, unit.TargetY, unit.Type)
 			}
 		}
 	}
 
+	// 检查地雷，释放自动技能
+	g.triggerMines()
+	g.updateAbilities(time.Now())
+
 	// 更新AI
 	g.updateAI()
 
@@ -678,13 +648,36 @@ func (g *Game) isPositionOccupied(x, y int) bool {
 	return false
 }
 
-// 检查位置是否对玩家可见
+// 检查位置是否对玩家可见（本方或同盟阵营能看到）
 func (g *Game) isVisibleToPlayer(x, y int) bool {
-	// 检查坐标是否在地图范围内
-	if x < 0 || x >= MapWidth || y < 0 || y >= MapHeight {
-		return false
+	return g.teams.VisibleTo(g.localTeam, x, y)
+}
+
+// isOwnUnit 单位是否属于本地玩家操控的阵营
+func (g *Game) isOwnUnit(unit *Unit) bool {
+	return unit.Team == g.localTeam
+}
+
+// isAIUnit 单位所属阵营是否由电脑控制
+func (g *Game) isAIUnit(unit *Unit) bool {
+	team := g.teams.Get(unit.Team)
+	return team != nil && team.Controller == AI
+}
+
+// teamColor 单位所属阵营的颜色
+func (g *Game) teamColor(unit *Unit) color.RGBA {
+	if team := g.teams.Get(unit.Team); team != nil {
+		return team.Color
 	}
-	return g.visibleToPlayer[y][x]
+	return color.RGBA{128, 128, 128, 255}
+}
+
+// teamName 单位所属阵营的名字
+func (g *Game) teamName(unit *Unit) string {
+	if team := g.teams.Get(unit.Team); team != nil {
+		return team.Name
+	}
+	return "无阵营"
 }
 
 // 检查两个单位是否碰撞
@@ -795,10 +788,10 @@ func (g *Game) findNearbyEmptyPositionForTarget(x, y int, unitType UnitType) [2]
 
 // 简单的AI逻辑
 func (g *Game) updateAI() {
-	// 每隔一段时间让AI单位移动
+	// 每隔一段时间让电脑控制的阵营的单位移动
 	if rand.Intn(120) == 0 { // 大约每2秒
 		for _, unit := range g.units {
-			if !unit.IsPlayerUnit && !unit.HasTarget && !unit.IsPassenger {
+			if g.isAIUnit(unit) && !unit.HasTarget && !unit.IsPassenger {
 				// 随机选择一个目标位置
 				targetX := rand.Intn(MapWidth)
 				targetY := rand.Intn(MapHeight)
@@ -846,18 +839,31 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		}
 	}
 
+	// 绘制本方和同盟的地雷，敌方地雷不可见
+	for _, mine := range g.mines {
+		if !g.teams.IsAllied(g.localTeam, mine.Team) {
+			continue
+		}
+		centerX := float32(mine.X*TileSize + TileSize/2)
+		centerY := float32(mine.Y*TileSize + TileSize/2)
+		vector.DrawFilledCircle(canvas, centerX, centerY, TileSize/6, color.RGBA{40, 40, 40, 255}, true)
+		if team := g.teams.Get(mine.Team); team != nil {
+			vector.StrokeCircle(canvas, centerX, centerY, TileSize/6, 2, team.Color, true)
+		}
+	}
+
 	// 绘制单位（只绘制可见区域的单位）
 	for _, unit := range g.units {
-		// 只绘制玩家单位或者在可见区域内的敌方单位
-		if unit.IsPlayerUnit || g.visibleToPlayer[unit.Y][unit.X] {
-			unit.Draw(canvas, g.gameFont)
+		// 只绘制玩家单位或者在可见区域内的其他阵营单位
+		if g.isOwnUnit(unit) || g.isVisibleToPlayer(unit.X, unit.Y) {
+			unit.Draw(canvas, g.gameFont, g.teamColor(unit), g.isOwnUnit(unit))
 		}
 	}
 
 	// 绘制攻击目标指示器
 	if g.targetUnit != nil && time.Now().Before(g.targetFlashTime) {
 		// 只有当目标单位在可见区域内才绘制
-		if g.visibleToPlayer[g.targetUnit.Y][g.targetUnit.X] {
+		if g.isVisibleToPlayer(g.targetUnit.X, g.targetUnit.Y) {
 			// 计算单位中心位置
 			centerX := g.targetUnit.CurrentX
 			centerY := g.targetUnit.CurrentY
@@ -884,13 +890,13 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	// 绘制迷雾战
 	for y := 0; y < MapHeight; y++ {
 		for x := 0; x < MapWidth; x++ {
-			if g.fogOfWar[y][x] {
+			if !g.teams.ExploredBy(g.localTeam, x, y) {
 				// 绘制完全迷雾（黑色）
 				ebitenutil.DrawRect(canvas,
 					float64(x*TileSize), float64(y*TileSize),
 					float64(TileSize), float64(TileSize),
 					color.RGBA{0, 0, 0, 200})
-			} else if !g.visibleToPlayer[y][x] {
+			} else if !g.isVisibleToPlayer(x, y) {
 				// 绘制已探索但当前不可见的区域（灰色）
 				ebitenutil.DrawRect(canvas,
 					float64(x*TileSize), float64(y*TileSize),
@@ -900,6 +906,14 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		}
 	}
 
+	// 选择技能目标时，高亮鼠标所在的格子
+	if g.targetingAbility {
+		mouseX, mouseY := ebiten.CursorPosition()
+		gridX := (mouseX + int(g.cameraX)) / TileSize
+		gridY := (mouseY + int(g.cameraY)) / TileSize
+		vector.StrokeRect(canvas, float32(gridX*TileSize), float32(gridY*TileSize), TileSize, TileSize, 2, color.RGBA{255, 255, 0, 255}, false)
+	}
+
 	// 绘制圈选框
 	if g.isSelecting {
 		minX := float64(min(g.selectionStartX, g.selectionEndX))
@@ -924,6 +938,12 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	if g.showUnitPanel && len(g.selectedUnits) > 0 {
 		g.drawUnitInfoPanel(screen, screenWidth, screenHeight)
 	}
+
+	// 选择技能目标时显示提示
+	if g.targetingAbility {
+		hint := fmt.Sprintf("%s：请点击目标格子，右键或Esc取消", g.pendingAbility)
+		text.Draw(screen, hint, g.gameFont, 20, 30, color.RGBA{255, 255, 0, 255})
+	}
 }
 
 // 绘制游戏基本信息 - 已移除，不再显示左侧提示
@@ -999,13 +1019,10 @@ func (g *Game) drawUnitInfoPanel(screen *ebiten.Image, screenWidth, screenHeight
 		iconX := panelX + 20
 		iconY := panelY + 50
 
-		// 根据单位类型选择不同的颜色
-		iconColor := color.RGBA{100, 100, 255, 255} // 默认蓝色
+		// 使用阵营颜色，空中单位颜色更浅
+		iconColor := lighten(g.teamColor(unit))
 		if unit.IsAirUnit() {
-			iconColor = color.RGBA{100, 200, 255, 255} // 空中单位使用浅蓝色
-		}
-		if !unit.IsPlayerUnit {
-			iconColor = color.RGBA{255, 100, 100, 255} // 敌方单位使用红色
+			iconColor = lighten(iconColor)
 		}
 
 		// 绘制单位图标背景
@@ -1057,20 +1074,18 @@ func (g *Game) drawUnitInfoPanel(screen *ebiten.Image, screenWidth, screenHeight
 		// 单位名称
 		text.Draw(screen, unitTypeName, g.gameFont, infoX, infoY+20, color.RGBA{255, 255, 255, 255})
 
-		// 单位阵营
-		teamText := "阵营: "
-		if unit.IsPlayerUnit {
-			teamText += "友方"
-			text.Draw(screen, teamText, g.gameFont, infoX, infoY+45, color.RGBA{100, 100, 255, 255})
-		} else {
-			teamText += "敌方"
-			text.Draw(screen, teamText, g.gameFont, infoX, infoY+45, color.RGBA{255, 100, 100, 255})
+		// 单位阵营及与本方的关系
+		relation := "本方"
+		if !g.isOwnUnit(unit) {
+			relation = g.teams.Relation(g.localTeam, unit.Team).String()
 		}
+		teamText := fmt.Sprintf("阵营: %s (%s)", g.teamName(unit), relation)
+		text.Draw(screen, teamText, g.gameFont, infoX, infoY+45, lighten(g.teamColor(unit)))
 
 		// 生命值
 		healthBarWidth := 150
 		healthBarHeight := 15
-		healthPercentage := float64(unit.Health) / float64(unitTypeData.Health)
+		healthPercentage := float64(unit.Health) / float64(unit.MaxHealth)
 		if healthPercentage > 1.0 {
 			healthPercentage = 1.0
 		}
@@ -1095,7 +1110,7 @@ func (g *Game) drawUnitInfoPanel(screen *ebiten.Image, screenWidth, screenHeight
 			healthColor)
 
 		// 显示生命值文本
-		healthText := fmt.Sprintf("生命值: %d/%d", unit.Health, unitTypeData.Health)
+		healthText := fmt.Sprintf("生命值: %d/%d", unit.Health, unit.MaxHealth)
 		text.Draw(screen, healthText, g.gameFont, infoX+healthBarWidth+10, infoY+68, color.RGBA{255, 255, 255, 255})
 
 		// 显示攻击力
@@ -1136,6 +1151,21 @@ func (g *Game) drawUnitInfoPanel(screen *ebiten.Image, screenWidth, screenHeight
 		// 显示单位视野
 		visionText := fmt.Sprintf("视野: %d", unit.VisionRange)
 		text.Draw(screen, visionText, g.gameFont, rightInfoX, infoY+95, color.RGBA{200, 200, 200, 255})
+
+		// 显示单位技能，冷却中的显示剩余秒数
+		if len(unit.Abilities) > 0 {
+			now := time.Now()
+			names := make([]string, 0, len(unit.Abilities))
+			for _, a := range unit.Abilities {
+				name := a.Kind.String()
+				if d := a.Remaining(now); d > 0 {
+					name += fmt.Sprintf("(%.0fs)", math.Ceil(d.Seconds()))
+				}
+				names = append(names, name)
+			}
+			abilityText := "技能: " + strings.Join(names, " ")
+			text.Draw(screen, abilityText, g.gameFont, rightInfoX, infoY+120, color.RGBA{150, 255, 150, 255})
+		}
 	} else if len(g.selectedUnits) > 1 {
 		// 如果选中了多个单位，显示单位类型统计
 		unitTypeCounts := make(map[UnitType]int)
@@ -1206,57 +1236,71 @@ func (g *Game) drawUnitInfoPanel(screen *ebiten.Image, screenWidth, screenHeight
 	buttonHeight := 30
 	buttonSpacing := 10
 
-	// 计算按钮区域的起始位置（面板右侧）
-	buttonsAreaWidth := len(g.uiButtons) * (buttonWidth + buttonSpacing)
-	buttonsStartX := panelX + panelWidth - buttonsAreaWidth - 20 // 右侧留20像素边距
-	buttonY := panelY + panelHeight - buttonHeight - 15          // 底部留15像素边距
+	// 按钮靠面板右侧排列，右侧留20像素边距；操作按钮在最下面一行，底部留15像素边距，技能按钮在它上面一行
+	buttonsRight := panelX + panelWidth - 20
+	buttonY := panelY + panelHeight - buttonHeight - 15
+	layoutButtons(g.uiButtons, buttonsRight, buttonY, buttonWidth, buttonHeight, buttonSpacing)
+	layoutButtons(g.abilityButtons, buttonsRight, buttonY-buttonHeight-buttonSpacing, buttonWidth, buttonHeight, buttonSpacing)
 
-	// 更新按钮位置
-	for i := range g.uiButtons {
-		g.uiButtons[i].X = buttonsStartX + (buttonWidth+buttonSpacing)*i
-		g.uiButtons[i].Y = buttonY
-		g.uiButtons[i].Width = buttonWidth
-		g.uiButtons[i].Height = buttonHeight
-
-		// 绘制按钮背景
-		buttonColor := color.RGBA{50, 50, 50, 200}
-		if !g.uiButtons[i].Enabled {
-			buttonColor = color.RGBA{30, 30, 30, 200}
-		} else if g.uiButtons[i].Hovered {
-			buttonColor = color.RGBA{80, 80, 80, 200}
-		}
+	for _, button := range g.uiButtons {
+		g.drawButton(screen, button)
+	}
+	for _, button := range g.abilityButtons {
+		g.drawButton(screen, button)
+	}
+}
 
-		ebitenutil.DrawRect(screen,
-			float64(g.uiButtons[i].X), float64(g.uiButtons[i].Y),
-			float64(g.uiButtons[i].Width), float64(g.uiButtons[i].Height),
-			buttonColor)
-
-		// 绘制按钮边框
-		borderColor := color.RGBA{100, 100, 100, 255}
-		if g.uiButtons[i].Enabled {
-			borderColor = color.RGBA{150, 150, 150, 255}
-		}
-		if g.uiButtons[i].Hovered {
-			borderColor = color.RGBA{200, 200, 200, 255}
-		}
+// layoutButtons 把一行按钮右对齐排列，right为最右侧按钮的右边缘
+func layoutButtons(buttons []UIButton, right, y, width, height, spacing int) {
+	startX := right - len(buttons)*(width+spacing) + spacing
+	for i := range buttons {
+		buttons[i].X = startX + (width+spacing)*i
+		buttons[i].Y = y
+		buttons[i].Width = width
+		buttons[i].Height = height
+	}
+}
 
-		vector.StrokeRect(screen,
-			float32(g.uiButtons[i].X), float32(g.uiButtons[i].Y),
-			float32(g.uiButtons[i].Width), float32(g.uiButtons[i].Height),
-			1, borderColor, false)
-
-		// 绘制按钮文本
-		textColor := color.RGBA{150, 150, 150, 255}
-		if g.uiButtons[i].Enabled {
-			textColor = color.RGBA{255, 255, 255, 255}
-		}
+// drawButton 绘制一个按钮
+func (g *Game) drawButton(screen *ebiten.Image, button UIButton) {
+	// 绘制按钮背景
+	buttonColor := color.RGBA{50, 50, 50, 200}
+	if !button.Enabled {
+		buttonColor = color.RGBA{30, 30, 30, 200}
+	} else if button.Hovered {
+		buttonColor = color.RGBA{80, 80, 80, 200}
+	}
 
-		textWidth := len(g.uiButtons[i].Text) * 7 // 估算文本宽度
-		textX := g.uiButtons[i].X + (g.uiButtons[i].Width-textWidth)/2
-		textY := g.uiButtons[i].Y + g.uiButtons[i].Height/2 + 5
+	ebitenutil.DrawRect(screen,
+		float64(button.X), float64(button.Y),
+		float64(button.Width), float64(button.Height),
+		buttonColor)
+
+	// 绘制按钮边框
+	borderColor := color.RGBA{100, 100, 100, 255}
+	if button.Enabled {
+		borderColor = color.RGBA{150, 150, 150, 255}
+	}
+	if button.Hovered {
+		borderColor = color.RGBA{200, 200, 200, 255}
+	}
 
-		text.Draw(screen, g.uiButtons[i].Text, g.gameFont, textX, textY, textColor)
+	vector.StrokeRect(screen,
+		float32(button.X), float32(button.Y),
+		float32(button.Width), float32(button.Height),
+		1, borderColor, false)
+
+	// 绘制按钮文本
+	textColor := color.RGBA{150, 150, 150, 255}
+	if button.Enabled {
+		textColor = color.RGBA{255, 255, 255, 255}
 	}
+
+	textWidth := len(button.Text) * 7 // 估算文本宽度
+	textX := button.X + (button.Width-textWidth)/2
+	textY := button.Y + button.Height/2 + 5
+
+	text.Draw(screen, button.Text, g.gameFont, textX, textY, textColor)
 }
 
 // 更新UI按钮状态
@@ -1312,10 +1356,152 @@ func (g *Game) updateUIButtons() {
 		g.uiButtons[i].Hovered = mx >= g.uiButtons[i].X && mx < g.uiButtons[i].X+g.uiButtons[i].Width &&
 			my >= g.uiButtons[i].Y && my < g.uiButtons[i].Y+g.uiButtons[i].Height
 	}
+
+	// 更新技能按钮
+	g.updateAbilityButtons(time.Now())
+}
+
+// contains 点(x, y)是否在按钮内
+func (b UIButton) contains(x, y int) bool {
+	return x >= b.X && x < b.X+b.Width && y >= b.Y && y < b.Y+b.Height
+}
+
+// isOverPanelButton 屏幕坐标(x, y)是否在单位信息面板的按钮上
+func (g *Game) isOverPanelButton(x, y int) bool {
+	if !g.showUnitPanel || len(g.selectedUnits) == 0 {
+		return false
+	}
+	for _, button := range g.uiButtons {
+		if button.contains(x, y) {
+			return true
+		}
+	}
+	for _, button := range g.abilityButtons {
+		if button.contains(x, y) {
+			return true
+		}
+	}
+	return false
+}
+
+// abilityUsers 选中的本方单位中拥有该技能的单位
+func (g *Game) abilityUsers(kind AbilityKind) []*Unit {
+	var users []*Unit
+	for _, unit := range g.selectedUnits {
+		if g.isOwnUnit(unit) && !unit.IsPassenger && unit.Ability(kind) != nil {
+			users = append(users, unit)
+		}
+	}
+	return users
+}
+
+// updateAbilityButtons 按选中单位拥有的技能生成技能按钮：
+// 自动技能显示开关状态，主动技能在所有单位都冷却中时禁用并显示剩余秒数
+func (g *Game) updateAbilityButtons(now time.Time) {
+	mx, my := ebiten.CursorPosition()
+	var buttons []UIButton
+	for kind := AbilityHeal; kind <= AbilityClearMines; kind++ {
+		users := g.abilityUsers(kind)
+		if len(users) == 0 {
+			continue
+		}
+		spec := kind.Spec()
+		button := UIButton{Text: spec.Name, Action: "ability", Ability: kind, Enabled: true}
+		switch {
+		case spec.Auto:
+			button.Text += ":关"
+			for _, unit := range users {
+				if unit.Ability(kind).AutoOn {
+					button.Text = spec.Name + ":开"
+					break
+				}
+			}
+		case g.targetingAbility && g.pendingAbility == kind:
+			button.Text = "选择目标"
+		default:
+			// 取冷却最短的单位
+			remaining := spec.Cooldown
+			for _, unit := range users {
+				if d := unit.Ability(kind).Remaining(now); d < remaining {
+					remaining = d
+				}
+			}
+			if remaining > 0 {
+				button.Enabled = false
+				button.Text = fmt.Sprintf("%s %.0fs", spec.Name, math.Ceil(remaining.Seconds()))
+			}
+		}
+
+		// 位置在绘制面板时计算，这里沿用上一帧同一位置的按钮
+		if i := len(buttons); i < len(g.abilityButtons) {
+			old := g.abilityButtons[i]
+			button.X, button.Y, button.Width, button.Height = old.X, old.Y, old.Width, old.Height
+		}
+		button.Hovered = button.contains(mx, my)
+		buttons = append(buttons, button)
+	}
+	g.abilityButtons = buttons
+}
+
+// handleAbilityButton 处理技能按钮点击：切换自动技能，主动技能需要目标时进入目标选择，否则立即释放
+func (g *Game) handleAbilityButton(kind AbilityKind) {
+	spec := kind.Spec()
+	users := g.abilityUsers(kind)
+	switch {
+	case spec.Auto:
+		on := true
+		for _, unit := range users {
+			if unit.Ability(kind).AutoOn {
+				on = false
+				break
+			}
+		}
+		for _, unit := range users {
+			unit.Ability(kind).AutoOn = on
+		}
+		if on {
+			fmt.Printf("自动%s已开启\n", spec.Name)
+		} else {
+			fmt.Printf("自动%s已关闭\n", spec.Name)
+		}
+	case spec.Targeted:
+		g.targetingAbility = true
+		g.pendingAbility = kind
+		fmt.Printf("%s：请点击目标格子，右键或Esc取消\n", spec.Name)
+	default:
+		now := time.Now()
+		for _, unit := range users {
+			if err := g.useAbility(unit, kind, unit.X, unit.Y, now); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+}
+
+// usePendingAbility 对目标格子释放等待中的技能，由选中单位中第一个能释放的单位执行
+func (g *Game) usePendingAbility(x, y int) {
+	g.targetingAbility = false
+	now := time.Now()
+	var err error
+	for _, unit := range g.abilityUsers(g.pendingAbility) {
+		if err = g.useAbility(unit, g.pendingAbility, x, y, now); err == nil {
+			return
+		}
+	}
+	if err != nil {
+		fmt.Println(err)
+	}
 }
 
 // 处理UI按钮点击
 func (g *Game) handleUIButtonClick(x, y int) {
+	for _, button := range g.abilityButtons {
+		if button.Enabled && button.contains(x, y) {
+			g.handleAbilityButton(button.Ability)
+			return
+		}
+	}
+
 	for _, button := range g.uiButtons {
 		if button.Enabled && x >= button.X && x < button.X+button.Width && y >= button.Y && y < button.Y+button.Height {
 			// 执行按钮对应的操作
@@ -1467,36 +1653,18 @@ func max(a, b int) int {
 
 // updateFogOfWar 更新战争迷雾状态
 func (g *Game) updateFogOfWar() {
-	// 重置所有区域为有迷雾和不可见
-	for y := 0; y < MapHeight; y++ {
-		for x := 0; x < MapWidth; x++ {
-			g.fogOfWar[y][x] = true
-			g.visibleToPlayer[y][x] = false
-		}
+	// 清除各阵营当前的可见区域，已探索的区域保留
+	for _, team := range g.teams.All() {
+		team.vision.Clear()
 	}
 
-	// 遍历所有玩家单位，更新可见区域
+	// 遍历所有单位，按单位的视野范围更新所属阵营的可见区域
 	for _, unit := range g.units {
-		if unit.IsPlayerUnit && !unit.IsPassenger {
-			// 使用单位的视野范围
-			visionRange := unit.VisionRange
-
-			// 更新单位周围的可见区域
-			for y := unit.Y - visionRange; y <= unit.Y+visionRange; y++ {
-				for x := unit.X - visionRange; x <= unit.X+visionRange; x++ {
-					// 检查是否在地图范围内
-					if x >= 0 && x < MapWidth && y >= 0 && y < MapHeight {
-						// 计算曼哈顿距离
-						dist := abs(x-unit.X) + abs(y-unit.Y)
-
-						// 如果在视野范围内，移除迷雾并标记为可见
-						if dist <= visionRange {
-							g.fogOfWar[y][x] = false
-							g.visibleToPlayer[y][x] = true
-						}
-					}
-				}
-			}
+		if unit.IsPassenger {
+			continue
+		}
+		if vision := g.teams.Vision(unit.Team); vision != nil {
+			vision.Reveal(unit.X, unit.Y, unit.VisionRange)
 		}
 	}
 }
@@ -1512,10 +1680,10 @@ func (g *Game) updateUnitAttacks() {
 
 		// 如果单位有目标单位，执行持续攻击
 		if unit.TargetUnit != nil {
-			// 检查目标单位是否还存在且在可见区域内
+			// 检查目标单位是否还存在、仍然敌对且在本阵营（及同盟）的可见区域内
 			if unit.TargetUnit.Health > 0 &&
-				((unit.IsPlayerUnit && g.visibleToPlayer[unit.TargetUnit.Y][unit.TargetUnit.X]) ||
-					(!unit.IsPlayerUnit && g.visibleToPlayer[unit.Y][unit.X])) {
+				g.teams.IsHostile(unit.Team, unit.TargetUnit.Team) &&
+				g.teams.VisibleTo(unit.Team, unit.TargetUnit.X, unit.TargetUnit.Y) {
 				// 检查目标是否为空中单位，如果是空中单位，则需要检查当前单位是否能攻击空中单位
 				if unit.TargetUnit.IsAirUnit() && !unit.CanAttackAir {
 					fmt.Printf("%s无法攻击空中单位%s\n", unit.Name, unit.TargetUnit.Name)
@@ -1627,18 +1795,13 @@ func (g *Game) updateUnitAttacks() {
 			minDist := 9999
 
 			for _, potentialTarget := range g.units {
-				// 跳过同阵营单位和乘客单位
-				if potentialTarget.IsPlayerUnit == unit.IsPlayerUnit || potentialTarget.IsPassenger {
+				// 跳过非敌对阵营的单位和乘客单位
+				if potentialTarget.IsPassenger || !g.teams.IsHostile(unit.Team, potentialTarget.Team) {
 					continue
 				}
 
-				// 检查目标是否在可见区域内
-				if unit.IsPlayerUnit && !g.visibleToPlayer[potentialTarget.Y][potentialTarget.X] {
-					continue
-				}
-
-				// 如果是敌方单位，检查玩家单位是否在其可见区域内
-				if !unit.IsPlayerUnit && !g.visibleToPlayer[unit.Y][unit.X] {
+				// 检查目标是否在本阵营（及同盟）的可见区域内
+				if !g.teams.VisibleTo(unit.Team, potentialTarget.X, potentialTarget.Y) {
 					continue
 				}
 
@@ -1663,11 +1826,7 @@ func (g *Game) updateUnitAttacks() {
 			// 如果找到了敌方单位，设置为攻击目标
 			if nearestEnemy != nil {
 				unit.TargetUnit = nearestEnemy
-				if unit.IsPlayerUnit {
-					fmt.Printf("%s自动锁定敌方目标%s！\n", unit.Name, nearestEnemy.Name)
-				} else {
-					fmt.Printf("敌方%s自动锁定玩家单位%s！\n", unit.Name, nearestEnemy.Name)
-				}
+				fmt.Printf("%s的%s自动锁定%s的%s！\n", g.teamName(unit), unit.Name, g.teamName(nearestEnemy), nearestEnemy.Name)
 			}
 		}
 	}
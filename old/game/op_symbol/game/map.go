@@ -17,6 +17,7 @@ const (
 	Plain
 	Forest
 	Mountain
+	Bridge // 浮桥：工程兵在水面上架设，地面单位按平原通行
 )
 
 // 地形颜色
@@ -26,6 +27,7 @@ var terrainColors = map[TerrainType]color.RGBA{
 	Plain:    {120, 200, 80, 255},  // 绿色
 	Forest:   {34, 139, 34, 255},   // 深绿色
 	Mountain: {139, 137, 137, 255}, // 灰色
+	Bridge:   {150, 105, 60, 255},  // 棕色
 }
 
 type GameMap struct {
@@ -0,0 +1,350 @@
+package game
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+)
+
+// TeamID 阵营编号, 从1开始, 0表示无阵营
+type TeamID int
+
+// Controller 阵营由谁控制
+type Controller int
+
+const (
+	Human Controller = iota // 玩家控制
+	AI                      // 电脑控制
+)
+
+// Relation 两个阵营之间的外交关系
+type Relation int
+
+const (
+	Hostile Relation = iota // 敌对：互相攻击，默认关系
+	Neutral                 // 中立：不主动攻击，也不共享视野
+	Allied                  // 同盟：不攻击，共享视野
+)
+
+func (r Relation) String() string {
+	switch r {
+	case Allied:
+		return "同盟"
+	case Neutral:
+		return "中立"
+	default:
+		return "敌对"
+	}
+}
+
+// Team 一个阵营（玩家）
+type Team struct {
+	ID         TeamID
+	Name       string
+	Color      color.RGBA
+	Controller Controller
+
+	vision *Vision // 本阵营单位的视野
+}
+
+// Vision 一个阵营的视野图
+type Vision struct {
+	width, height int
+	visible       [][]bool // 当前可见
+	explored      [][]bool // 曾经看到过
+}
+
+// NewVision 创建全部未探索的视野图
+func NewVision(width, height int) *Vision {
+	v := &Vision{width: width, height: height}
+	v.visible = make([][]bool, height)
+	v.explored = make([][]bool, height)
+	for y := 0; y < height; y++ {
+		v.visible[y] = make([]bool, width)
+		v.explored[y] = make([]bool, width)
+	}
+	return v
+}
+
+// Clear 清除当前可见区域，已探索区域保留
+func (v *Vision) Clear() {
+	for y := range v.visible {
+		clear(v.visible[y])
+	}
+}
+
+// Reveal 以(cx, cy)为中心，曼哈顿距离r以内的格子设为可见
+func (v *Vision) Reveal(cx, cy, r int) {
+	for y := max(cy-r, 0); y <= min(cy+r, v.height-1); y++ {
+		for x := max(cx-r, 0); x <= min(cx+r, v.width-1); x++ {
+			if abs(x-cx)+abs(y-cy) <= r {
+				v.visible[y][x] = true
+				v.explored[y][x] = true
+			}
+		}
+	}
+}
+
+// Visible 格子当前是否可见，地图外为false
+func (v *Vision) Visible(x, y int) bool {
+	return x >= 0 && x < v.width && y >= 0 && y < v.height && v.visible[y][x]
+}
+
+// Explored 格子是否曾经被看到过
+func (v *Vision) Explored(x, y int) bool {
+	return x >= 0 && x < v.width && y >= 0 && y < v.height && v.explored[y][x]
+}
+
+// teamPair 无序的阵营对，小的在前
+type teamPair [2]TeamID
+
+func makeTeamPair(a, b TeamID) teamPair {
+	if a > b {
+		a, b = b, a
+	}
+	return teamPair{a, b}
+}
+
+// Teams 所有阵营和它们之间的外交关系；关系是对称的，没有设置过的两个阵营互相敌对
+type Teams struct {
+	list      []*Team
+	byID      map[TeamID]*Team
+	relations map[teamPair]Relation
+}
+
+// NewTeams 创建阵营表，阵营编号不能重复且不能为0
+func NewTeams(width, height int, teams ...*Team) (*Teams, error) {
+	t := &Teams{byID: map[TeamID]*Team{}, relations: map[teamPair]Relation{}}
+	for _, team := range teams {
+		if team.ID == 0 {
+			return nil, fmt.Errorf("阵营 %q 的编号不能为0", team.Name)
+		}
+		if _, ok := t.byID[team.ID]; ok {
+			return nil, fmt.Errorf("阵营编号 %d 重复", team.ID)
+		}
+		team.vision = NewVision(width, height)
+		t.list = append(t.list, team)
+		t.byID[team.ID] = team
+	}
+	return t, nil
+}
+
+// All 按添加顺序返回所有阵营
+func (t *Teams) All() []*Team {
+	return t.list
+}
+
+// Get 按编号查找阵营，不存在时返回nil
+func (t *Teams) Get(id TeamID) *Team {
+	return t.byID[id]
+}
+
+// SetRelation 设置两个阵营之间的关系（双向）
+func (t *Teams) SetRelation(a, b TeamID, r Relation) {
+	if a == b {
+		return
+	}
+	t.relations[makeTeamPair(a, b)] = r
+}
+
+// Ally 让给出的阵营两两结盟
+func (t *Teams) Ally(ids ...TeamID) {
+	for i, a := range ids {
+		for _, b := range ids[i+1:] {
+			t.SetRelation(a, b, Allied)
+		}
+	}
+}
+
+// Relation 两个阵营之间的关系，同一阵营视为同盟
+func (t *Teams) Relation(a, b TeamID) Relation {
+	if a == b {
+		return Allied
+	}
+	return t.relations[makeTeamPair(a, b)]
+}
+
+// IsHostile 两个阵营是否敌对，敌对的单位会互相自动攻击
+func (t *Teams) IsHostile(a, b TeamID) bool {
+	return t.Relation(a, b) == Hostile
+}
+
+// IsAllied 两个阵营是否同盟（包括同一阵营），同盟之间共享视野
+func (t *Teams) IsAllied(a, b TeamID) bool {
+	return t.Relation(a, b) == Allied
+}
+
+// Vision 阵营自己的视野图，阵营不存在时返回nil
+func (t *Teams) Vision(id TeamID) *Vision {
+	if team := t.byID[id]; team != nil {
+		return team.vision
+	}
+	return nil
+}
+
+// VisibleTo 格子对阵营是否可见：自己或任一同盟阵营能看到即可
+func (t *Teams) VisibleTo(id TeamID, x, y int) bool {
+	for _, team := range t.list {
+		if t.IsAllied(id, team.ID) && team.vision.Visible(x, y) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExploredBy 格子是否被阵营自己或同盟探索过
+func (t *Teams) ExploredBy(id TeamID, x, y int) bool {
+	for _, team := range t.list {
+		if t.IsAllied(id, team.ID) && team.vision.Explored(x, y) {
+			return true
+		}
+	}
+	return false
+}
+
+// 预设的阵营颜色
+var (
+	TeamBlue   = color.RGBA{0, 0, 255, 255}
+	TeamRed    = color.RGBA{255, 0, 0, 255}
+	TeamGreen  = color.RGBA{0, 170, 0, 255}
+	TeamOrange = color.RGBA{255, 140, 0, 255}
+)
+
+// lighten 把颜色向白色混合40%，用于面板图标等需要浅色的地方
+func lighten(c color.RGBA) color.RGBA {
+	mix := func(v uint8) uint8 { return v + uint8(float64(255-v)*0.4) }
+	return color.RGBA{mix(c.R), mix(c.G), mix(c.B), c.A}
+}
+
+// UnitSpawn 场景中的初始单位
+type UnitSpawn struct {
+	X, Y int
+	Type UnitType
+	Team TeamID
+}
+
+// Scenario 对局设定：参战阵营、同盟关系、初始单位和本地玩家操控的阵营
+type Scenario struct {
+	Name      string
+	Teams     []Team
+	Alliances [][]TeamID // 每组内的阵营互相结盟，其余都是敌对
+	Units     []UnitSpawn
+	LocalTeam TeamID // 本地玩家操控的阵营，迷雾和选择都以它为准
+}
+
+// squad 一支小队：步兵三个一列，后面跟一辆装甲车、一门火炮和一个支援单位，最后是工程兵和医疗单位；
+// dir为1时向右展开，为-1时向左展开
+func squad(team TeamID, x, y, dir int, support UnitType) []UnitSpawn {
+	return []UnitSpawn{
+		{x, y, Infantry, team},
+		{x, y + 3, Infantry, team},
+		{x, y + 6, Infantry, team},
+		{x - dir, y + 3, Armor, team},
+		{x - dir, y, Artillery, team},
+		{x - dir, y + 6, support, team},
+		{x - 2*dir, y + 3, Engineer, team},
+		{x - 2*dir, y + 5, MedicUnit, team},
+	}
+}
+
+// scenarios 内置的场景
+var scenarios = map[string]func() Scenario{
+	"1v1": func() Scenario {
+		return Scenario{
+			Name: "1v1",
+			Teams: []Team{
+				{ID: 1, Name: "蓝军", Color: TeamBlue, Controller: Human},
+				{ID: 2, Name: "红军", Color: TeamRed, Controller: AI},
+			},
+			Units:     append(squad(1, 3, 5, 1, Recon), squad(2, 26, 5, -1, AntiAir)...),
+			LocalTeam: 1,
+		}
+	},
+	"2v2": func() Scenario {
+		var units []UnitSpawn
+		units = append(units, squad(1, 3, 2, 1, Recon)...)
+		units = append(units, squad(2, 3, 14, 1, AntiAir)...)
+		units = append(units, squad(3, 26, 2, -1, AntiAir)...)
+		units = append(units, squad(4, 26, 14, -1, Recon)...)
+		return Scenario{
+			Name: "2v2",
+			Teams: []Team{
+				{ID: 1, Name: "蓝军", Color: TeamBlue, Controller: Human},
+				{ID: 2, Name: "绿军", Color: TeamGreen, Controller: AI},
+				{ID: 3, Name: "红军", Color: TeamRed, Controller: AI},
+				{ID: 4, Name: "橙军", Color: TeamOrange, Controller: AI},
+			},
+			Alliances: [][]TeamID{{1, 2}, {3, 4}},
+			Units:     units,
+			LocalTeam: 1,
+		}
+	},
+	"ffa": func() Scenario {
+		var units []UnitSpawn
+		units = append(units, squad(1, 3, 2, 1, Recon)...)
+		units = append(units, squad(2, 26, 2, -1, AntiAir)...)
+		units = append(units, squad(3, 3, 15, 1, AntiAir)...)
+		units = append(units, squad(4, 26, 15, -1, Recon)...)
+		return Scenario{
+			Name: "ffa",
+			Teams: []Team{
+				{ID: 1, Name: "蓝军", Color: TeamBlue, Controller: Human},
+				{ID: 2, Name: "红军", Color: TeamRed, Controller: AI},
+				{ID: 3, Name: "绿军", Color: TeamGreen, Controller: AI},
+				{ID: 4, Name: "橙军", Color: TeamOrange, Controller: AI},
+			},
+			Units:     units,
+			LocalTeam: 1,
+		}
+	},
+}
+
+// ScenarioNames 内置场景的名字
+func ScenarioNames() []string {
+	names := make([]string, 0, len(scenarios))
+	for name := range scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupScenario 按名字查找内置场景
+func LookupScenario(name string) (Scenario, error) {
+	s, ok := scenarios[name]
+	if !ok {
+		return Scenario{}, fmt.Errorf("未知场景 %q，可选: %s", name, strings.Join(ScenarioNames(), ", "))
+	}
+	return s(), nil
+}
+
+// newTeams 按场景创建阵营表和同盟关系
+func (s Scenario) newTeams(width, height int) (*Teams, error) {
+	list := make([]*Team, len(s.Teams))
+	for i := range s.Teams {
+		team := s.Teams[i]
+		list[i] = &team
+	}
+	teams, err := NewTeams(width, height, list...)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range s.Alliances {
+		for _, id := range group {
+			if teams.Get(id) == nil {
+				return nil, fmt.Errorf("场景 %s 的同盟中有不存在的阵营 %d", s.Name, id)
+			}
+		}
+		teams.Ally(group...)
+	}
+	if teams.Get(s.LocalTeam) == nil {
+		return nil, fmt.Errorf("场景 %s 的本地阵营 %d 不存在", s.Name, s.LocalTeam)
+	}
+	for _, u := range s.Units {
+		if teams.Get(u.Team) == nil {
+			return nil, fmt.Errorf("场景 %s 的单位属于不存在的阵营 %d", s.Name, u.Team)
+		}
+	}
+	return teams, nil
+}
@@ -0,0 +1,90 @@
+package game
+
+import "testing"
+
+func TestRelations(t *testing.T) {
+	s, err := LookupScenario("2v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	teams, err := s.newTeams(MapWidth, MapHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		a, b TeamID
+		want Relation
+	}{
+		{1, 1, Allied}, {1, 2, Allied}, {2, 1, Allied}, {3, 4, Allied},
+		{1, 3, Hostile}, {4, 2, Hostile},
+	}
+	for _, tt := range tests {
+		if got := teams.Relation(tt.a, tt.b); got != tt.want {
+			t.Errorf("Relation(%d, %d) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	teams.SetRelation(4, 1, Neutral)
+	if teams.IsHostile(1, 4) || teams.IsAllied(1, 4) || teams.Relation(1, 4) != Neutral {
+		t.Errorf("1-4 should be neutral, got %v", teams.Relation(1, 4))
+	}
+}
+
+func TestSharedVision(t *testing.T) {
+	s, _ := LookupScenario("2v2")
+	teams, err := s.newTeams(10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	teams.Vision(2).Reveal(0, 0, 2)
+
+	// 同盟共享视野，敌对阵营看不到
+	if !teams.VisibleTo(1, 1, 1) || !teams.VisibleTo(2, 2, 0) || teams.VisibleTo(3, 1, 1) {
+		t.Fatal("vision should be shared between allies only")
+	}
+	// 曼哈顿距离之外和地图外都不可见
+	if teams.VisibleTo(1, 2, 1) || teams.VisibleTo(1, -1, 0) {
+		t.Fatal("out of range cell is visible")
+	}
+
+	// 清除后不再可见，但仍然是已探索
+	teams.Vision(2).Clear()
+	if teams.VisibleTo(1, 1, 1) || !teams.ExploredBy(1, 1, 1) || teams.ExploredBy(3, 1, 1) {
+		t.Fatal("explored state should survive Clear")
+	}
+}
+
+func TestScenarios(t *testing.T) {
+	for _, name := range ScenarioNames() {
+		s, err := LookupScenario(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.newTeams(MapWidth, MapHeight); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		seen := map[[2]int]bool{}
+		for _, u := range s.Units {
+			if u.X < 0 || u.X >= MapWidth || u.Y < 0 || u.Y >= MapHeight {
+				t.Errorf("%s: unit out of map at (%d, %d)", name, u.X, u.Y)
+			}
+			if seen[[2]int{u.X, u.Y}] {
+				t.Errorf("%s: two units at (%d, %d)", name, u.X, u.Y)
+			}
+			seen[[2]int{u.X, u.Y}] = true
+		}
+	}
+
+	if _, err := LookupScenario("3v3"); err == nil {
+		t.Fatal("unknown scenario should fail")
+	}
+	bad := Scenario{Name: "bad", Teams: []Team{{ID: 1}, {ID: 1}}, LocalTeam: 1}
+	if _, err := bad.newTeams(1, 1); err == nil {
+		t.Fatal("duplicate team id should fail")
+	}
+	bad = Scenario{Name: "bad", Teams: []Team{{ID: 1}}, Alliances: [][]TeamID{{1, 2}}, LocalTeam: 1}
+	if _, err := bad.newTeams(1, 1); err == nil {
+		t.Fatal("alliance with unknown team should fail")
+	}
+}
@@ -46,11 +46,12 @@ const (
 )
 
 type Unit struct {
-	X, Y         int
-	Type         UnitType
-	Health       int
-	IsPlayerUnit bool
-	EntityID     int // 唯一实体ID，用于排序
+	X, Y      int
+	Type      UnitType
+	Health    int
+	MaxHealth int    // 最大生命值，治疗和维修不会超过它
+	Team      TeamID // 所属阵营
+	EntityID  int    // 唯一实体ID，用于排序
 
 	// 添加实时战略所需的属性
 	TargetX, TargetY   int      // 目标位置
@@ -81,13 +82,15 @@ type Unit struct {
 	CurrentAmmo     int       // 当前弹药量
 	Name            string    // 单位名称
 
+	Abilities []*Ability // 技能，如医疗单位的治疗、工程兵的维修和架桥
+
 	// 攻击动画相关
 	IsAttacking        bool      // 是否正在攻击
 	AttackAnimTime     time.Time // 攻击动画开始时间
 	AttackAnimDuration float64   // 攻击动画持续时间（秒）
 }
 
-func NewUnit(x, y int, unitType UnitType, isPlayerUnit bool) *Unit {
+func NewUnit(x, y int, unitType UnitType, team TeamID) *Unit {
 	// 获取唯一的实体ID并递增计数器
 	entityID := nextEntityID
 	nextEntityID++
@@ -260,7 +263,9 @@ func NewUnit(x, y int, unitType UnitType, isPlayerUnit bool) *Unit {
 			Y:               y,
 			Type:            unitType,
 			Health:          health,
-			IsPlayerUnit:    isPlayerUnit,
+			MaxHealth:       health,
+			Abilities:       newAbilities(unitType),
+			Team:            team,
 			EntityID:        entityID, // 设置唯一实体ID
 			HasTarget:       false,
 			Path:            make([][2]int, 0),
@@ -298,7 +303,9 @@ func NewUnit(x, y int, unitType UnitType, isPlayerUnit bool) *Unit {
 		Y:               y,
 		Type:            unitType,
 		Health:          typeData.Health,
-		IsPlayerUnit:    isPlayerUnit,
+		MaxHealth:       typeData.Health,
+		Abilities:       newAbilities(unitType),
+		Team:            team,
 		EntityID:        entityID, // 设置唯一实体ID
 		HasTarget:       false,
 		Path:            make([][2]int, 0),
@@ -470,7 +477,8 @@ func (u *Unit) Update() {
 	}
 }
 
-func (u *Unit) Draw(screen *ebiten.Image, font font.Face) {
+// Draw 绘制单位，teamColor为所属阵营的颜色，own表示是否为本地玩家的单位
+func (u *Unit) Draw(screen *ebiten.Image, font font.Face, teamColor color.RGBA, own bool) {
 	// 如果是乘客，不绘制
 	if u.IsPassenger {
 		return
@@ -507,13 +515,8 @@ func (u *Unit) Draw(screen *ebiten.Image, font font.Face) {
 	x := centerX - unitSize/2
 	y := centerY - unitSize/2
 
-	// 绘制单位
-	var unitColor color.RGBA
-	if u.IsPlayerUnit {
-		unitColor = color.RGBA{0, 0, 255, 255} // 蓝色表示玩家单位
-	} else {
-		unitColor = color.RGBA{255, 0, 0, 255} // 红色表示敌方单位
-	}
+	// 绘制单位，使用阵营颜色
+	unitColor := teamColor
 
 	// 绘制单位形状
 	switch u.Type {
@@ -643,11 +646,11 @@ func (u *Unit) Draw(screen *ebiten.Image, font font.Face) {
 
 	// 如果单位被选中，绘制选择指示器
 	if u.Selected {
-		if u.IsPlayerUnit {
-			// 友方单位选中指示器 - 绿色圆圈
+		if own {
+			// 本方单位选中指示器 - 绿色圆圈
 			vector.StrokeCircle(screen, float32(centerX), float32(centerY), float32(unitSize/2+2), 2, color.RGBA{0, 255, 0, 255}, true)
 		} else {
-			// 敌方单位选中指示器 - 黄色十字准星
+			// 其他阵营单位选中指示器 - 黄色十字准星
 			crossSize := float32(unitSize / 2)
 			// 水平线
 			vector.StrokeLine(screen,
@@ -721,7 +724,7 @@ func (u *Unit) Draw(screen *ebiten.Image, font font.Face) {
 	}
 
 	// 如果单位有攻击目标，绘制攻击线
-	if u.TargetUnit != nil && u.IsPlayerUnit {
+	if u.TargetUnit != nil && own {
 		// 绘制从单位到目标单位的攻击线
 		ebitenutil.DrawLine(screen,
 			float64(centerX),
@@ -840,6 +843,11 @@ func (u *Unit) CanMoveOnTerrain(terrain TerrainType) bool {
 		return false
 	}
 
+	// 浮桥按平原通行
+	if terrain == Bridge {
+		terrain = Plain
+	}
+
 	// 检查地形是否在允许列表中
 	for _, allowedTerrain := range unitData.AllowedTerrains {
 		if allowedTerrain == terrain {
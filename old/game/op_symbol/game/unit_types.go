@@ -313,6 +313,11 @@ func CanMoveOnTerrain(unitType UnitType, terrain TerrainType) (bool, error) {
 		return true, nil
 	}
 
+	// 浮桥按平原通行
+	if terrain == Bridge {
+		terrain = Plain
+	}
+
 	// 检查地形是否在允许列表中
 	for _, allowedTerrain := range unitData.AllowedTerrains {
 		if allowedTerrain == terrain {
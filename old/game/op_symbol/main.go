@@ -1,15 +1,28 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"strings"
 
 	"testGo/game/op_symbol/game"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
+var scenario = flag.String("scenario", "1v1", "对局场景: "+strings.Join(game.ScenarioNames(), ", "))
+
 func main() {
-	g := game.NewGame()
+	flag.Parse()
+
+	s, err := game.LookupScenario(*scenario)
+	if err != nil {
+		log.Fatal(err)
+	}
+	g, err := game.NewScenarioGame(s)
+	if err != nil {
+		log.Fatal(err)
+	}
 	ebiten.SetWindowSize(1024, 768)
 	ebiten.SetWindowTitle("Tactical Game")
 
@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	"log"
+	"strings"
 	"testGo/grpc/order"
+	"testGo/telemetry"
+	"testGo/zookeeper/coord"
 	"time"
 )
 
@@ -19,13 +23,47 @@ const (
 	exampleServiceName = "lb.example.com"
 )
 
+// 设置后从 zookeeper 发现订单服务实例, 按 round_robin 负载均衡
+var zkServers = flag.String("zk", "", "zookeeper addresses, comma separated; empty dials "+address+" directly")
+
 func main() {
-	conn, err := grpc.Dial(address,
+	flag.Parse()
+
+	// 默认不导出, 通过 OTEL_TRACES_EXPORTER 等环境变量开启
+	cfg, err := telemetry.ConfigFromEnv(telemetry.Config{ServiceName: "order-client", Exporter: telemetry.ExporterNone})
+	if err != nil {
+		log.Println("telemetry config.", err)
+		return
+	}
+	shutdown, err := telemetry.Setup(context.Background(), cfg)
+	if err != nil {
+		log.Println("telemetry setup.", err)
+		return
+	}
+	defer shutdown(context.Background())
+
+	target := address
+	opts := telemetry.GRPCDialOptions()
+	if *zkServers != "" {
+		zkClient, err := coord.Dial(strings.Split(*zkServers, ","), 5*time.Second)
+		if err != nil {
+			log.Println("connect zookeeper.", err)
+			return
+		}
+		defer zkClient.Close()
+		target = coord.Scheme + ":///order"
+		opts = append(opts,
+			grpc.WithResolvers(coord.NewResolverBuilder(coord.NewRegistry(zkClient, "/services"))),
+			grpc.WithDefaultServiceConfig(`{"loadBalancingConfig":[{"round_robin":{}}]}`),
+		)
+	}
+
+	conn, err := grpc.Dial(target, append(opts,
 		grpc.WithInsecure(),
 		// 注册拦截器
-		grpc.WithUnaryInterceptor(UnaryClientOrderInterceptor),
+		grpc.WithChainUnaryInterceptor(UnaryClientOrderInterceptor, RetryUnaryClientInterceptor(DefaultRetryPolicy)),
 		grpc.WithStreamInterceptor(StreamClientOrderInterceptor),
-	)
+	)...)
 
 	if err != nil {
 		log.Println("did not connect.", err)
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/gofrs/uuid"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
@@ -22,10 +23,18 @@ func AddOrder(ctx context.Context, client order.OrderManagementClient) string {
 		Destination: "Shanghai",
 		Items:       []string{"doll", "22", "33", "Apple"},
 	}
+	// 每个订单生成一个幂等键, 超时重试时服务端据此返回同一个订单, 不会重复下单
+	key, err := uuid.NewV4()
+	if err != nil {
+		log.Println("gen idempotency key fail.", err)
+		return ""
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, order.IdempotencyKeyHeader, key.String())
+
 	// 接收从服务端发送过来的metadata信息
-	var header metadata.MD
+	var header, trailer metadata.MD
 
-	val, err := client.AddOrder(ctx, odr, grpc.Header(&header))
+	val, err := client.AddOrder(ctx, odr, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
 		log.Println("add order fail.", err)
 		return ""
@@ -33,6 +42,9 @@ func AddOrder(ctx context.Context, client order.OrderManagementClient) string {
 	log.Println("add order success.id = ", val.String())
 
 	log.Printf("接受服务端元数据 : %+v\n", header)
+	if len(trailer.Get(order.IdempotentReplayedHeader)) > 0 {
+		log.Println("服务端返回的是已创建的订单")
+	}
 
 	fmt.Println("")
 	return val.Value
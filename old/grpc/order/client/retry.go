@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testGo/grpc/order"
+)
+
+// RetryPolicy 客户端重试策略
+type RetryPolicy struct {
+	// MaxAttempts 最多调用次数, 包含第一次
+	MaxAttempts int
+	// InitialBackoff 第一次重试前的等待时间, 之后按 Multiplier 指数增长, 不超过 MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// PerAttemptTimeout 单次调用的超时, 0 表示只受调用方 context 的截止时间约束
+	PerAttemptTimeout time.Duration
+	// RetryableCodes 可以重试的错误码
+	RetryableCodes []codes.Code
+}
+
+// DefaultRetryPolicy 默认重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:       4,
+	InitialBackoff:    100 * time.Millisecond,
+	MaxBackoff:        2 * time.Second,
+	Multiplier:        2,
+	PerAttemptTimeout: time.Second,
+	RetryableCodes:    []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted},
+}
+
+// safeMethods 只读方法, 重复调用不会产生副作用
+var safeMethods = map[string]bool{
+	"/order.OrderManagement/getOrder": true,
+	"/order.GreeterService/sayHello":  true,
+}
+
+// RetryUnaryClientInterceptor 客户端重试拦截器
+// 只重试只读方法和携带幂等键的调用; 每次调用都继承调用方的截止时间,
+// 剩余时间不够等待下一次退避时直接返回最后一次的错误
+func RetryUnaryClientInterceptor(policy RetryPolicy) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if policy.MaxAttempts <= 1 || !isRetryable(ctx, method) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		backoff := policy.InitialBackoff
+		var err error
+		for attempt := 1; ; attempt++ {
+			err = invokeAttempt(ctx, policy.PerAttemptTimeout, method, req, reply, cc, invoker, opts...)
+			if err == nil || attempt >= policy.MaxAttempts || !policy.retryableCode(status.Code(err)) {
+				return err
+			}
+			// 调用方已经取消或超时, 不再重试
+			if ctx.Err() != nil {
+				return err
+			}
+
+			wait := jitter(backoff)
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+				return err
+			}
+			log.Printf("[retry] %s attempt %d failed: %v, retry after %s\n", method, attempt, err, wait)
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+
+			backoff = time.Duration(float64(backoff) * policy.Multiplier)
+			if backoff > policy.MaxBackoff {
+				backoff = policy.MaxBackoff
+			}
+		}
+	}
+}
+
+// invokeAttempt 执行一次调用, 单次超时不会超过调用方的截止时间
+func invokeAttempt(ctx context.Context, timeout time.Duration, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// isRetryable 只读方法或者携带了幂等键的调用才允许重试
+func isRetryable(ctx context.Context, method string) bool {
+	if safeMethods[method] {
+		return true
+	}
+	md, ok := metadata.FromOutgoingContext(ctx)
+	return ok && len(md.Get(order.IdempotencyKeyHeader)) > 0
+}
+
+func (p RetryPolicy) retryableCode(code codes.Code) bool {
+	for _, c := range p.RetryableCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// jitter 在 [d/2, d) 之间随机, 避免大量客户端同时重试
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
@@ -0,0 +1,10 @@
+package order
+
+// 客户端与服务端约定的元数据键
+const (
+	// IdempotencyKeyHeader 幂等键, 客户端重试同一次请求时必须携带相同的值
+	IdempotencyKeyHeader = "idempotency-key"
+
+	// IdempotentReplayedHeader 服务端在命中幂等缓存、直接返回首次结果时设置为 "true"
+	IdempotentReplayedHeader = "idempotent-replayed"
+)
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// ErrIdempotencyKeyReused 同一个幂等键被用于内容不同的请求
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+
+// idempotentResult 幂等键对应的首次处理结果
+type idempotentResult struct {
+	fingerprint [sha256.Size]byte
+	value       string
+}
+
+// idempotentCall 正在处理中的请求, 相同幂等键的并发请求等待它完成
+type idempotentCall struct {
+	done        chan struct{}
+	fingerprint [sha256.Size]byte
+	value       string
+	err         error
+}
+
+// IdempotencyStore 记录 幂等键 -> 处理结果, 窗口期内的重复请求直接返回首次的结果
+type IdempotencyStore struct {
+	results *cache.Cache
+
+	mu       sync.Mutex
+	inflight map[string]*idempotentCall
+}
+
+// NewIdempotencyStore 创建幂等存储, window 为结果保留的时长
+func NewIdempotencyStore(window time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		results:  cache.New(window, window),
+		inflight: make(map[string]*idempotentCall),
+	}
+}
+
+// Do 以幂等键执行 fn
+// 键已有结果时不再执行 fn, 直接返回之前的结果且 replayed 为 true;
+// 相同键的并发请求只会执行一次 fn; fn 返回错误时不记录结果, 客户端可以用同一个键重试
+func (s *IdempotencyStore) Do(key string, payload []byte, fn func() (string, error)) (value string, replayed bool, err error) {
+	fingerprint := sha256.Sum256(payload)
+
+	s.mu.Lock()
+	if v, ok := s.results.Get(key); ok {
+		s.mu.Unlock()
+		res := v.(*idempotentResult)
+		if res.fingerprint != fingerprint {
+			return "", false, ErrIdempotencyKeyReused
+		}
+		return res.value, true, nil
+	}
+	if call, ok := s.inflight[key]; ok {
+		s.mu.Unlock()
+		<-call.done
+		if call.fingerprint != fingerprint {
+			return "", false, ErrIdempotencyKeyReused
+		}
+		return call.value, call.err == nil, call.err
+	}
+	call := &idempotentCall{done: make(chan struct{}), fingerprint: fingerprint}
+	s.inflight[key] = call
+	s.mu.Unlock()
+
+	call.value, call.err = fn()
+
+	s.mu.Lock()
+	if call.err == nil {
+		s.results.SetDefault(key, &idempotentResult{fingerprint: fingerprint, value: call.value})
+	}
+	delete(s.inflight, key)
+	s.mu.Unlock()
+	close(call.done)
+
+	return call.value, false, call.err
+}
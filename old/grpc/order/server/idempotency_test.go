@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyStoreReplay(t *testing.T) {
+	store := NewIdempotencyStore(time.Minute)
+	var calls int32
+	fn := func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return "order-1", nil
+	}
+
+	// 并发的重复请求只执行一次
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, _, err := store.Do("k1", []byte("req"), fn); err != nil || v != "order-1" {
+				t.Errorf("got %q, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("fn called %d times, want 1", calls)
+	}
+
+	v, replayed, err := store.Do("k1", []byte("req"), fn)
+	if err != nil || !replayed || v != "order-1" {
+		t.Fatalf("got %q replayed=%v err=%v", v, replayed, err)
+	}
+
+	if _, _, err := store.Do("k1", []byte("other"), fn); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("want ErrIdempotencyKeyReused, got %v", err)
+	}
+}
+
+func TestIdempotencyStoreFailureNotCached(t *testing.T) {
+	store := NewIdempotencyStore(time.Minute)
+	if _, _, err := store.Do("k1", nil, func() (string, error) { return "", errors.New("boom") }); err == nil {
+		t.Fatal("want error")
+	}
+	v, replayed, err := store.Do("k1", nil, func() (string, error) { return "order-2", nil })
+	if err != nil || replayed || v != "order-2" {
+		t.Fatalf("got %q replayed=%v err=%v", v, replayed, err)
+	}
+}
@@ -2,21 +2,55 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"github.com/golang/protobuf/jsonpb"
 	_ "go.uber.org/automaxprocs"
 	"google.golang.org/grpc"
 	"log"
 	"net"
+	"strings"
 	"sync"
 	"testGo/grpc/order"
+	"testGo/telemetry"
+	"testGo/zookeeper/coord"
+	"time"
 )
 
 // const addr = ":50052"
 var addrList = []string{":50052", ":50053", ":50054"}
 
+// 幂等键结果的保留时长, 超过窗口期后同一个键会被当作新请求
+var idempotencyWindow = flag.Duration("idempotency-window", 10*time.Minute, "how long AddOrder remembers an idempotency key")
+
+// 设置后把每个监听地址注册到 zookeeper 的 /services/order 下, 客户端通过 zk:///order 发现
+var zkServers = flag.String("zk", "", "zookeeper addresses, comma separated; empty disables service registration")
+
+var registry *coord.Registry
+
 func main() {
+	flag.Parse()
 	Test()
+
+	// 默认不导出, 通过 OTEL_TRACES_EXPORTER 等环境变量开启
+	cfg, err := telemetry.ConfigFromEnv(telemetry.Config{ServiceName: "order-server", Exporter: telemetry.ExporterNone})
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdown, err := telemetry.Setup(context.Background(), cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdown(context.Background())
+
+	if *zkServers != "" {
+		client, err := coord.Dial(strings.Split(*zkServers, ","), 5*time.Second)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer client.Close()
+		registry = coord.NewRegistry(client, "/services")
+	}
 	var wg sync.WaitGroup
 	for _, addr := range addrList {
 		wg.Add(1)
@@ -34,8 +68,8 @@ func Test() {
 
 	jsonstr := `{"id":"1234","items":["aaaaa","bbbb"],"description":"描述","price":123.44,"destination":"订单目的地"}`
 	pb := &order.Order{}
-	if err := jsonpb.UnmarshalString(jsonstr, pb); err == nil {
-		fmt.Errorf("err:%s\n", err)
+	if err := jsonpb.UnmarshalString(jsonstr, pb); err != nil {
+		log.Printf("err:%s\n", err)
 	}
 	fmt.Printf("pb.say:%s\n", pb.Id)
 }
@@ -51,19 +85,35 @@ func startServer(addr string) {
 	// s := grpc.NewServer()
 
 	// 注册拦截器
-	s := grpc.NewServer(
+	s := grpc.NewServer(append(telemetry.GRPCServerOptions(),
 		grpc.UnaryInterceptor(orderUnaryServerInterceptor),
 		grpc.StreamInterceptor(orderStreamServerInterceptor),
-	)
+	)...)
 
 	// 注册订单服务
-	orderServer := &OrderServer{orderMap: make(map[string]*order.Order)}
+	orderServer := &OrderServer{
+		orderMap:    make(map[string]*order.Order),
+		idempotency: NewIdempotencyStore(*idempotencyWindow),
+	}
 	InitSampleData(orderServer.orderMap)
 	order.RegisterOrderManagementServer(s, orderServer)
 
 	// 注册问候服务
 	order.RegisterGreeterServiceServer(s, &GreeterServer{})
 
+	if registry != nil {
+		instance := listener.Addr().String()
+		if strings.HasPrefix(addr, ":") {
+			instance = "127.0.0.1" + addr
+		}
+		deregister, err := registry.Register("order", instance)
+		if err != nil {
+			log.Println("register to zookeeper err ", err)
+			return
+		}
+		defer deregister()
+	}
+
 	log.Println("start gRPC listen on port " + addr)
 	if err := s.Serve(listener); err != nil {
 		log.Println("failed to serve...", err)
@@ -2,21 +2,28 @@ package main
 
 import (
 	"context"
+	"errors"
 	"github.com/gofrs/uuid"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
 	"io"
 	"log"
 	"strings"
+	"sync"
 	"testGo/grpc/order"
 	"time"
 )
 
 type OrderServer struct {
+	mu       sync.RWMutex
 	orderMap map[string]*order.Order
+
+	// 幂等键缓存, 为 nil 时忽略客户端传来的幂等键
+	idempotency *IdempotencyStore
 }
 
 // InitSampleData 初始化添加一些订单数据
@@ -39,28 +46,69 @@ func (s *OrderServer) AddOrder(ctx context.Context, req *order.Order) (resp *wra
 	}
 
 	// 获取客户端元数据
+	var idempotencyKey string
 	if md, ok := metadata.FromIncomingContext(ctx); !ok {
 		log.Println("failed to get metadata")
 	} else {
 		log.Printf("来自客户端的元数据 : %+v\n", md)
+		if vals := md.Get(order.IdempotencyKeyHeader); len(vals) > 0 {
+			idempotencyKey = vals[0]
+		}
 	}
 
 	resp = &wrappers.StringValue{}
-	if s.orderMap == nil {
-		s.orderMap = make(map[string]*order.Order)
-	}
 
 	// time.Sleep(3 * time.Second)
 
+	// 没有幂等键的请求每次都生成新订单
+	if idempotencyKey == "" || s.idempotency == nil {
+		resp.Value, err = s.createOrder(req)
+		return
+	}
+
+	// 订单 id 由服务端生成, 不参与请求内容的比对
+	odr := proto.Clone(req).(*order.Order)
+	odr.Id = ""
+	payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(odr)
+	if err != nil {
+		return resp, status.Errorf(codes.InvalidArgument, "marshal order err: %v", err)
+	}
+
+	id, replayed, err := s.idempotency.Do(idempotencyKey, payload, func() (string, error) {
+		return s.createOrder(req)
+	})
+	if errors.Is(err, ErrIdempotencyKeyReused) {
+		return resp, status.Errorf(codes.FailedPrecondition, "idempotency key %q: %v", idempotencyKey, err)
+	}
+	if err != nil {
+		return resp, err
+	}
+	if replayed {
+		log.Printf("[server]幂等键 %s 命中, 返回已创建的订单 %s\n", idempotencyKey, id)
+		if err := grpc.SetTrailer(ctx, metadata.Pairs(order.IdempotentReplayedHeader, "true")); err != nil {
+			log.Println("set trailer err", err)
+		}
+	}
+	resp.Value = id
+	return
+}
+
+// createOrder 生成订单 id 并保存订单
+func (s *OrderServer) createOrder(req *order.Order) (string, error) {
 	v4, err := uuid.NewV4()
 	if err != nil {
-		return resp, status.Errorf(codes.Internal, "gen uuid err", err)
+		return "", status.Errorf(codes.Internal, "gen uuid err: %v", err)
 	}
 	id := v4.String()
 	req.Id = id
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.orderMap == nil {
+		s.orderMap = make(map[string]*order.Order)
+	}
 	s.orderMap[id] = req
-	resp.Value = id
-	return
+	return id, nil
 }
 
 // GetOrder 获取订单
@@ -69,7 +117,10 @@ func (s *OrderServer) GetOrder(ctx context.Context, req *wrappers.StringValue) (
 	resp = &order.Order{}
 	id := req.Value
 	var exist bool
-	if resp, exist = s.orderMap[id]; !exist {
+	s.mu.RLock()
+	resp, exist = s.orderMap[id]
+	s.mu.RUnlock()
+	if !exist {
 		err = status.Error(codes.NotFound, "order not found id = "+id)
 		return
 	}
@@ -78,18 +129,26 @@ func (s *OrderServer) GetOrder(ctx context.Context, req *wrappers.StringValue) (
 
 // SearchOrder 搜索订单
 func (s *OrderServer) SearchOrder(searchKey *wrappers.StringValue, stream order.OrderManagement_SearchOrderServer) (err error) {
+	// 先在读锁内挑出匹配的订单, 发送时不持有锁, 避免慢客户端阻塞其他请求
+	var matched []*order.Order
+	s.mu.RLock()
 	for _, val := range s.orderMap {
 		for _, item := range val.Items {
 			if strings.Contains(item, searchKey.Value) {
-				err = stream.Send(val)
-				if err != nil {
-					log.Println("stream send order err.", err)
-					return
-				}
+				matched = append(matched, val)
 				break
 			}
 		}
 	}
+	s.mu.RUnlock()
+
+	for _, val := range matched {
+		err = stream.Send(val)
+		if err != nil {
+			log.Println("stream send order err.", err)
+			return
+		}
+	}
 	return
 }
 
@@ -102,7 +161,15 @@ func (s *OrderServer) UpdateOrder(stream order.OrderManagement_UpdateOrderServer
 			// 向客户端发送消息
 			return stream.SendAndClose(&wrappers.StringValue{Value: updatedIds})
 		}
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		if s.orderMap == nil {
+			s.orderMap = make(map[string]*order.Order)
+		}
 		s.orderMap[val.Id] = val
+		s.mu.Unlock()
 		log.Println("[server]update the order : ", val.Id)
 		updatedIds += val.Id + ", "
 	}
@@ -131,20 +198,21 @@ func (s *OrderServer) ProcessOrder(stream order.OrderManagement_ProcessOrderServ
 			orderId := val.Value
 			log.Printf("[server]reading order : %+v\n", orderId)
 
-			if _, exist := s.orderMap[orderId]; !exist {
+			s.mu.RLock()
+			ord, exist := s.orderMap[orderId]
+			s.mu.RUnlock()
+			if !exist {
 				log.Printf("[server]订单不存在 : %+v\n", orderId)
 				continue
 			}
 
-			dest := s.orderMap[orderId].Destination
+			dest := ord.Destination
 			shipment, exist := combinedShipmentMap[dest]
 			if exist {
-				ord := s.orderMap[orderId]
 				shipment.OrderList = append(shipment.OrderList, ord)
 				combinedShipmentMap[dest] = shipment
 			} else {
-				comShip := &order.CombinedShipment{Id: "cmb - " + (s.orderMap[orderId].Destination), Status: "Processed!"}
-				ord := s.orderMap[orderId]
+				comShip := &order.CombinedShipment{Id: "cmb - " + dest, Status: "Processed!"}
 				comShip.OrderList = append(comShip.OrderList, ord)
 				combinedShipmentMap[dest] = comShip
 				log.Println(len(comShip.OrderList), comShip.GetId())
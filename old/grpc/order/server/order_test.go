@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
+	"testGo/grpc/order"
+)
+
+// searchStream 收集 SearchOrder 发送的订单
+type searchStream struct {
+	grpc.ServerStream
+	sent []*order.Order
+}
+
+func (s *searchStream) Send(o *order.Order) error {
+	s.sent = append(s.sent, o)
+	return nil
+}
+
+// updateStream 依次返回 orders, 之后返回 io.EOF
+type updateStream struct {
+	grpc.ServerStream
+	orders []*order.Order
+}
+
+func (s *updateStream) Recv() (*order.Order, error) {
+	if len(s.orders) == 0 {
+		return nil, io.EOF
+	}
+	o := s.orders[0]
+	s.orders = s.orders[1:]
+	return o, nil
+}
+
+func (s *updateStream) SendAndClose(*wrappers.StringValue) error { return nil }
+
+// 用 go test -race 运行, 并发的添加、更新、搜索不能有数据竞争
+func TestOrderServerConcurrentAccess(t *testing.T) {
+	s := &OrderServer{orderMap: make(map[string]*order.Order)}
+	InitSampleData(s.orderMap)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := s.AddOrder(context.Background(), &order.Order{Items: []string{"Apple Watch"}}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				stream := &updateStream{orders: []*order.Order{{Id: "102", Items: []string{"Google Pixel 4"}}}}
+				if err := s.UpdateOrder(stream); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if err := s.SearchOrder(&wrappers.StringValue{Value: "Apple"}, &searchStream{}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stream := &searchStream{}
+	if err := s.SearchOrder(&wrappers.StringValue{Value: "Apple Watch"}, stream); err != nil {
+		t.Fatal(err)
+	}
+	// 4 个协程各添加 50 个, 再加上示例数据里的 103
+	if len(stream.sent) != 4*50+1 {
+		t.Fatalf("found %d orders, want %d", len(stream.sent), 4*50+1)
+	}
+	if got, _ := s.GetOrder(context.Background(), &wrappers.StringValue{Value: "102"}); got.Items[0] != "Google Pixel 4" {
+		t.Fatalf("order 102 not updated: %v", got.Items)
+	}
+}
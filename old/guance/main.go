@@ -1,14 +1,23 @@
 package main
 
 import (
-	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
-	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
-	"io/ioutil"
+	"context"
+	"log"
 	"net/http"
 	//_ "net/http/pprof"
 	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"testGo/telemetry"
 )
 
+// 通过 dd-trace-go 发给 DataKit, 业务代码只使用 OpenTelemetry API
+var tracer = otel.Tracer("fermin-service-tracer")
+
 func main() {
 
 	//err := profiler.Start(
@@ -33,32 +42,32 @@ func main() {
 	//	log.Fatal(err)
 	//}
 
-	tracer.Start(
-		tracer.WithEnv("test"),
-		tracer.WithService("fermin-service-tracer"),
-		tracer.WithServiceVersion("1.2.3"),
-		tracer.WithGlobalTag("project", "fermin- add-ddtrace-in-golang-project"),
-		tracer.WithAgentAddr("localhost:9529"), // DataKit url
-	)
-
-	//tick := time.NewTicker(time.Second)
+	// DataKit 地址可以用 OTEL_EXPORTER_OTLP_ENDPOINT 覆盖
+	cfg, err := telemetry.ConfigFromEnv(telemetry.Config{
+		ServiceName:    "fermin-service-tracer",
+		ServiceVersion: "1.2.3",
+		Environment:    "test",
+		Attributes:     map[string]string{"project": "fermin- add-ddtrace-in-golang-project"},
+		Exporter:       telemetry.ExporterDatadog,
+		Endpoint:       "localhost:9529", // DataKit url
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdown, err := telemetry.Setup(context.Background(), cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	defer func() {
 		//profiler.Stop()
-		tracer.Stop()
-		//tick.Stop()
+		shutdown(context.Background())
 	}()
 
 	// your-app-main-entry...
-	//for {
 	runApp()
 	runAppWithError()
 
-	//	select {
-	//	case <-tick.C:
-	//	}
-	//}
-
 	// 性能采集
 	//var rate = 1
 	//runtime.SetMutexProfileFraction(rate)
@@ -68,39 +77,44 @@ func main() {
 
 }
 
+// finish 结束 span, 出错时记录错误
+func finish(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
 func runApp() {
-	var err error
 	// Start a root span.
-	span := tracer.StartSpan("get.data")
-	defer span.Finish(tracer.WithError(err))
+	ctx, span := tracer.Start(context.Background(), "get.data")
 
 	// Create a child of it, computing the time needed to read a file.
-	child := tracer.StartSpan("read.file", tracer.ChildOf(span.Context()))
-	child.SetTag(ext.ResourceName, os.Args[0])
+	_, child := tracer.Start(ctx, "read.file", trace.WithAttributes(attribute.String("resource.name", os.Args[0])))
 
 	// Perform an operation.
-	var bts []byte
-	bts, err = ioutil.ReadFile(os.Args[0])
-	span.SetTag("file_len", len(bts))
-	child.Finish(tracer.WithError(err))
+	bts, err := os.ReadFile(os.Args[0])
+	span.SetAttributes(attribute.Int("file_len", len(bts)))
+	finish(child, err)
+	finish(span, err)
 }
 
 func runAppWithError() {
 	var err error
 	// Start a root span.
-	span := tracer.StartSpan("get.data")
+	ctx, span := tracer.Start(context.Background(), "get.data")
 
 	// Create a child of it, computing the time needed to read a file.
-	child := tracer.StartSpan("read.file", tracer.ChildOf(span.Context()))
-	child.SetTag(ext.ResourceName, "somefile-not-found.go")
+	_, child := tracer.Start(ctx, "read.file", trace.WithAttributes(attribute.String("resource.name", "somefile-not-found.go")))
 
 	defer func() {
-		child.Finish(tracer.WithError(err))
-		span.Finish(tracer.WithError(err))
+		finish(child, err)
+		finish(span, err)
 	}()
 
 	// Perform an error operation.
-	if _, err = ioutil.ReadFile("somefile-not-found.go"); err != nil {
+	if _, err = os.ReadFile("somefile-not-found.go"); err != nil {
 		// error handle
 	}
 }
@@ -6,89 +6,45 @@ import (
 	"go.opentelemetry.io/otel/codes"
 	"log"
 	"net/http"
-	"os"
-	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
-	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
-	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 	"go.opentelemetry.io/otel/trace"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 
 	_ "net/http/pprof"
+
+	"testGo/telemetry"
 )
 
 func main() {
-
-	shutdown := initOpenTelemetry()
-	defer func() {
-		shutdown()
-	}()
-
-	log.Println("connect ...")
-
-	http.HandleFunc("/user", web)
-
-	err := http.ListenAndServe(":14317", nil)
+	// 采集端地址等可以用 OTEL_EXPORTER_OTLP_ENDPOINT 等环境变量覆盖, DataKit 没启动也不影响服务启动
+	cfg, err := telemetry.ConfigFromEnv(telemetry.Config{
+		ServiceName: "fermin-OpenTelemetry",
+		Environment: "test",
+		Exporter:    telemetry.ExporterOTLP,
+		Endpoint:    "127.0.0.1:4317",
+		Insecure:    true,
+	})
 	if err != nil {
-		fmt.Println(fmt.Sprintf("服务启动失败: %v", err))
+		log.Fatal("【dataKit】", err)
 	}
-
-	time.Sleep(time.Minute * 2)
-	os.Exit(0)
-}
-
-// 初始化链路追踪
-func initOpenTelemetry() func() {
-	ctx := context.Background()
-
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceNameKey.String("fermin-OpenTelemetry"),
-			semconv.DeploymentEnvironmentKey.String("test"),
-		),
-	)
-
+	shutdown, err := telemetry.Setup(context.Background(), cfg)
 	if err != nil {
-		log.Fatal("【dataKit】初始化失败")
+		log.Fatal("【dataKit】初始化失败: ", err)
 	}
+	defer func() {
+		if err := shutdown(context.Background()); err != nil {
+			log.Println("【dataKit】failed to shutdown TracerProvider: ", err)
+		}
+	}()
 
-	var bsp sdktrace.SpanProcessor
-
-	conn, err := grpc.DialContext(ctx, "127.0.0.1:4317", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
-
-	if err != nil {
-		log.Fatal("【dataKit】failed to create gRPC connection to collector")
-	}
+	log.Println("connect ...")
 
-	traceExporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithGRPCConn(conn))
+	http.Handle("/user", telemetry.HTTPHandler(http.HandlerFunc(web), "/user"))
 
+	err = http.ListenAndServe(":14317", nil)
 	if err != nil {
-		log.Fatal("【dataKit】failed to create trace exporter")
-	}
-
-	bsp = sdktrace.NewBatchSpanProcessor(traceExporter)
-
-	tracerProvider := sdktrace.NewTracerProvider(
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
-		sdktrace.WithResource(res),
-		sdktrace.WithSpanProcessor(bsp),
-	)
-
-	otel.SetTracerProvider(tracerProvider)
-
-	otel.SetTextMapPropagator(propagation.TraceContext{})
-
-	return func() {
-		err = tracerProvider.Shutdown(ctx)
-		if err != nil {
-			log.Fatal("【dataKit】failed to shutdown TracerProvider")
-		}
+		fmt.Println(fmt.Sprintf("服务启动失败: %v", err))
 	}
 }
 
@@ -96,11 +52,11 @@ var tracer = otel.Tracer("tracer_user_login")
 
 // web handler 处理请求数据
 func web(w http.ResponseWriter, r *http.Request) {
-	_, spanA := InitBuryingPoint("demo-error-first")
+	_, spanA := InitBuryingPoint(r.Context(), "demo-error-first")
 	spanA.SetStatus(codes.Error, "span-first is error")
 	spanA.End()
 
-	ctx, span := InitBuryingPoint("demo-first")
+	ctx, span := InitBuryingPoint(r.Context(), "demo-first")
 	span.SetStatus(codes.Error, "span-first is ok")
 
 	defer span.End()
@@ -162,12 +118,10 @@ func dao(ctx context.Context) {
 	}()
 }
 
-// InitBuryingPoint 开启埋点
-func InitBuryingPoint(spanName string) (context.Context, trace.Span) {
+// InitBuryingPoint 开启埋点, ctx 中有请求的 span 时作为它的子 span
+func InitBuryingPoint(ctx context.Context, spanName string) (context.Context, trace.Span) {
 	commonLabels := []attribute.KeyValue{attribute.String("key1", "自定义键值对-val")}
 
-	ctx := context.Background()
-
 	ctx, span := tracer.Start(
 		ctx,
 		spanName,
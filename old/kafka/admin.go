@@ -0,0 +1,113 @@
+package main
+
+import (
+	"github.com/IBM/sarama"
+	"log"
+	"os"
+	"slices"
+	"testGo/kafka/monitor"
+	"time"
+)
+
+// admin 消费者组管理命令, -format json 时输出 json, 否则输出表格
+//
+//	kafka -s admin -group g lag                          查看主 topic、重试 topic 和死信 topic 每个分区的已提交偏移量、高水位和积压
+//	kafka -s admin -history rebalance.log history        查看 moreConsumer 记录的再均衡历史
+//	kafka -s admin -group g -to 2024-05-01T00:00:00Z reset  按时间重置偏移量, 消费者组必须没有活跃成员
+func admin(action string) {
+	if action == "history" {
+		adminHistory()
+		return
+	}
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_0_0
+	client, err := sarama.NewClient(KafkaAddr, config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	cluster, err := monitor.NewSaramaCluster(client)
+	if err != nil {
+		log.Fatal(err)
+	}
+	inspector := monitor.NewInspector(cluster)
+
+	switch action {
+	case "lag", "":
+		topics, err := lagTopics(client)
+		if err != nil {
+			log.Fatal(err)
+		}
+		report, err := inspector.Lag(groupName, topics...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if outputFmt == "json" {
+			err = monitor.WriteJSON(os.Stdout, report)
+		} else {
+			err = monitor.WriteLagTable(os.Stdout, report)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "reset":
+		t, err := time.Parse(time.RFC3339, resetTo)
+		if err != nil {
+			log.Fatalf("invalid -to %q: %v", resetTo, err)
+		}
+		plan, err := inspector.ResetOffsetsToTime(groupName, TestTopicName, t, dryRun)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if outputFmt == "json" {
+			err = monitor.WriteJSON(os.Stdout, plan)
+		} else {
+			err = monitor.WriteResetTable(os.Stdout, plan)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("unknown admin action %q, want lag|history|reset", action)
+	}
+}
+
+// lagTopics 主 topic, 以及已经存在的重试 topic 和死信 topic; 重试和死信 topic 在第一次转投时才会创建.
+// 死信 topic 没有消费者组订阅, 没有已提交偏移量, 高水位就是进入死信的消息数
+func lagTopics(client sarama.Client) ([]string, error) {
+	existing, err := client.Topics()
+	if err != nil {
+		return nil, err
+	}
+	cfg := retryConfig()
+	topics := []string{cfg.Topic}
+	for _, topic := range append(cfg.Topics()[1:], cfg.DLQTopic()) {
+		if slices.Contains(existing, topic) {
+			topics = append(topics, topic)
+		}
+	}
+	return topics, nil
+}
+
+func adminHistory() {
+	f, err := os.Open(historyPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	events, err := monitor.ReadHistory(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if outputFmt == "json" {
+		err = monitor.WriteJSON(os.Stdout, events)
+	} else {
+		err = monitor.WriteHistoryTable(os.Stdout, events)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
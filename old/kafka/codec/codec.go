@@ -0,0 +1,179 @@
+// Package codec kafka 消息的类型化编解码
+//
+// 生产者用 Codec 把业务类型编码为消息内容, 发布前在 schema 注册中心检查兼容性,
+// 并通过 x-schema-id header 携带 schema id; 消费者根据 header 找到写入时的 schema 再解码
+package codec
+
+import (
+	"errors"
+	"fmt"
+
+	jsoniter "github.com/json-iterator/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// 消息内容的编码格式
+const (
+	FormatJSON     = "json"
+	FormatProtobuf = "protobuf"
+	FormatAvro     = "avro"
+)
+
+var (
+	// ErrIncompatibleSchema 新 schema 不满足主题的兼容性要求
+	ErrIncompatibleSchema = errors.New("incompatible schema")
+	// ErrSchemaNotFound 注册中心中没有对应的 schema
+	ErrSchemaNotFound = errors.New("schema not found")
+)
+
+// Codec 业务类型与消息内容之间的编解码
+type Codec[T any] interface {
+	// Format 编码格式, 注册 schema 时记录, 消费时校验
+	Format() string
+	// Schema 消息的 schema 定义
+	Schema() *Schema
+	Encode(v T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+var jsonAPI = jsoniter.ConfigCompatibleWithStandardLibrary
+
+// JSONCodec 使用 json 编码, schema 只用于注册和兼容性检查
+type JSONCodec[T any] struct {
+	schema *Schema
+}
+
+// NewJSONCodec 创建 json 编解码器
+func NewJSONCodec[T any](schema *Schema) *JSONCodec[T] {
+	return &JSONCodec[T]{schema: schema}
+}
+
+func (c *JSONCodec[T]) Format() string  { return FormatJSON }
+func (c *JSONCodec[T]) Schema() *Schema { return c.schema }
+
+func (c *JSONCodec[T]) Encode(v T) ([]byte, error) {
+	data, err := jsonAPI.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("json encode %T: %w", v, err)
+	}
+	return data, nil
+}
+
+func (c *JSONCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	if err := jsonAPI.Unmarshal(data, &v); err != nil {
+		return v, fmt.Errorf("json decode %T: %w", v, err)
+	}
+	return v, nil
+}
+
+// AvroCodec Avro 风格的编解码: 编码前按 schema 校验, 解码时按 schema 补齐旧数据缺少的字段默认值
+type AvroCodec[T any] struct {
+	schema *Schema
+}
+
+// NewAvroCodec 创建 Avro 风格的编解码器
+func NewAvroCodec[T any](schema *Schema) *AvroCodec[T] {
+	return &AvroCodec[T]{schema: schema}
+}
+
+func (c *AvroCodec[T]) Format() string  { return FormatAvro }
+func (c *AvroCodec[T]) Schema() *Schema { return c.schema }
+
+func (c *AvroCodec[T]) Encode(v T) ([]byte, error) {
+	data, err := jsonAPI.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("avro encode %T: %w", v, err)
+	}
+	if err := c.schema.Validate(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (c *AvroCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	resolved, err := c.schema.Resolve(data)
+	if err != nil {
+		return v, err
+	}
+	if err := jsonAPI.Unmarshal(resolved, &v); err != nil {
+		return v, fmt.Errorf("avro decode %T: %w", v, err)
+	}
+	return v, nil
+}
+
+// ProtoCodec protobuf 二进制编码, schema 由 message 描述自动生成
+type ProtoCodec[T proto.Message] struct {
+	newMessage func() T
+	schema     *Schema
+}
+
+// NewProtoCodec 创建 protobuf 编解码器, newMessage 返回一个空的 message, 例如
+//
+//	codec.NewProtoCodec(func() *order.Order { return &order.Order{} })
+func NewProtoCodec[T proto.Message](newMessage func() T) *ProtoCodec[T] {
+	return &ProtoCodec[T]{
+		newMessage: newMessage,
+		schema:     SchemaFromProto(newMessage().ProtoReflect().Descriptor()),
+	}
+}
+
+func (c *ProtoCodec[T]) Format() string  { return FormatProtobuf }
+func (c *ProtoCodec[T]) Schema() *Schema { return c.schema }
+
+func (c *ProtoCodec[T]) Encode(v T) ([]byte, error) {
+	data, err := proto.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("protobuf encode %s: %w", c.schema.Name, err)
+	}
+	return data, nil
+}
+
+func (c *ProtoCodec[T]) Decode(data []byte) (T, error) {
+	v := c.newMessage()
+	if err := proto.Unmarshal(data, v); err != nil {
+		return v, fmt.Errorf("protobuf decode %s: %w", c.schema.Name, err)
+	}
+	return v, nil
+}
+
+// SchemaFromProto 根据 protobuf message 描述生成 schema
+// proto3 的字段都有零值, 所以每个字段都视为有默认值, 兼容性只检查同名字段的类型变化
+func SchemaFromProto(md protoreflect.MessageDescriptor) *Schema {
+	s := &Schema{Type: "record", Name: string(md.FullName())}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		t := FieldType{Primitive: protoPrimitive(fd.Kind())}
+		if fd.IsList() {
+			elem := t
+			t = FieldType{Items: &elem}
+		}
+		s.Fields = append(s.Fields, Field{Name: string(fd.Name()), Type: t, Default: zeroDefault(t)})
+	}
+	return s
+}
+
+func protoPrimitive(kind protoreflect.Kind) string {
+	switch kind {
+	case protoreflect.BoolKind:
+		return "boolean"
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.EnumKind:
+		return "int"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "long"
+	case protoreflect.FloatKind:
+		return "float"
+	case protoreflect.DoubleKind:
+		return "double"
+	case protoreflect.StringKind:
+		return "string"
+	default:
+		// 嵌套 message 按不透明的字节处理
+		return "bytes"
+	}
+}
@@ -0,0 +1,250 @@
+package codec
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"google.golang.org/protobuf/proto"
+	"testGo/grpc/order"
+)
+
+const orderV1 = `{"type":"record","name":"Order","fields":[
+	{"name":"id","type":"string"},
+	{"name":"price","type":"float"}
+]}`
+
+// v2 增加了有默认值的字段, 向后兼容
+const orderV2 = `{"type":"record","name":"Order","fields":[
+	{"name":"id","type":"string"},
+	{"name":"price","type":"double"},
+	{"name":"currency","type":"string","default":"CNY"},
+	{"name":"note","type":["null","string"]}
+]}`
+
+// v3 增加了没有默认值的字段, 不兼容
+const orderV3 = `{"type":"record","name":"Order","fields":[
+	{"name":"id","type":"string"},
+	{"name":"price","type":"double"},
+	{"name":"buyer","type":"string"}
+]}`
+
+type orderV2Value struct {
+	ID       string  `json:"id"`
+	Price    float64 `json:"price"`
+	Currency string  `json:"currency"`
+	Note     *string `json:"note"`
+}
+
+func mustSchema(t *testing.T, def string) *Schema {
+	t.Helper()
+	s, err := ParseSchema([]byte(def))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	v1, v2, v3 := mustSchema(t, orderV1), mustSchema(t, orderV2), mustSchema(t, orderV3)
+
+	tests := []struct {
+		name      string
+		mode      Compatibility
+		old, next *Schema
+		ok        bool
+	}{
+		{"add field with default", CompatibilityBackward, v1, v2, true},
+		{"add field without default", CompatibilityBackward, v1, v3, false},
+		{"narrow type is not forward compatible", CompatibilityForward, v1, v2, false},
+		{"full requires both", CompatibilityFull, v1, v2, false},
+		{"none accepts anything", CompatibilityNone, v1, v3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckCompatibility(tt.mode, tt.old, tt.next)
+			if tt.ok && err != nil {
+				t.Fatalf("want compatible, got %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrIncompatibleSchema) {
+				t.Fatalf("want ErrIncompatibleSchema, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFileRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schemas.json")
+	r := NewFileRegistry(path)
+
+	id1, err := r.Register("orders-value", FormatAvro, mustSchema(t, orderV1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := r.Register("orders-value", FormatAvro, mustSchema(t, orderV1))
+	if err != nil || again != id1 {
+		t.Fatalf("re-register got %d, %v; want %d", again, err, id1)
+	}
+	id2, err := r.Register("orders-value", FormatAvro, mustSchema(t, orderV2))
+	if err != nil || id2 == id1 {
+		t.Fatalf("register v2 got %d, %v", id2, err)
+	}
+	if _, err := r.Register("orders-value", FormatAvro, mustSchema(t, orderV3)); !errors.Is(err, ErrIncompatibleSchema) {
+		t.Fatalf("want ErrIncompatibleSchema, got %v", err)
+	}
+	if _, err := r.Register("orders-value", FormatJSON, mustSchema(t, orderV2)); !errors.Is(err, ErrIncompatibleSchema) {
+		t.Fatalf("format change: want ErrIncompatibleSchema, got %v", err)
+	}
+
+	// 重新打开文件, 数据仍然在
+	rs, err := NewFileRegistry(path).Lookup(id2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Schema.Canonical() != mustSchema(t, orderV2).Canonical() {
+		t.Fatalf("lookup got %s", rs.Schema.Canonical())
+	}
+}
+
+// 多个 FileRegistry 各自持有一份 mu, 和多个进程一样只能靠文件锁互斥
+func TestFileRegistryConcurrentInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schemas.json")
+	ids := make(chan int, 40)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := NewFileRegistry(path)
+			for j := 0; j < 5; j++ {
+				id, err := r.Register("s-"+strconv.Itoa(i*5+j), FormatAvro, mustSchema(t, orderV1))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				ids <- id
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[int]bool{}
+	r := NewFileRegistry(path)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("id %d handed out twice", id)
+		}
+		seen[id] = true
+		if _, err := r.Lookup(id); err != nil {
+			t.Fatalf("lost write: %v", err)
+		}
+	}
+	if len(seen) != 40 {
+		t.Fatalf("got %d ids", len(seen))
+	}
+}
+
+func TestAvroCodecFillsDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schemas.json")
+	r := NewFileRegistry(path)
+
+	sp := mocks.NewSyncProducer(t, nil)
+	defer sp.Close()
+	var sent *sarama.ProducerMessage
+	sp.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		sent = msg
+		return nil
+	})
+
+	// 旧版本生产者发送 v1 数据
+	type orderV1Value struct {
+		ID    string  `json:"id"`
+		Price float32 `json:"price"`
+	}
+	producer, err := NewProducer[orderV1Value](sp, r, "orders", NewAvroCodec[orderV1Value](mustSchema(t, orderV1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := producer.Send("1", orderV1Value{ID: "1", Price: 9.5}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 新版本消费者用 v2 读取
+	value, _ := sent.Value.Encode()
+	msg := &sarama.ConsumerMessage{Topic: "orders", Value: value}
+	for _, h := range sent.Headers {
+		msg.Headers = append(msg.Headers, &h)
+	}
+	got, err := NewDecoder[orderV2Value](r, NewAvroCodec[orderV2Value](mustSchema(t, orderV2))).Decode(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "1" || got.Price != 9.5 || got.Currency != "CNY" || got.Note != nil {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestDecoderRejectsIncompatibleWriter(t *testing.T) {
+	r := NewFileRegistry(filepath.Join(t.TempDir(), "schemas.json"))
+	if err := r.SetCompatibility("orders-value", CompatibilityNone); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Register("orders-value", FormatAvro, mustSchema(t, orderV1)); err != nil {
+		t.Fatal(err)
+	}
+	id, err := r.Register("orders-value", FormatAvro, mustSchema(t, orderV3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// v1 的读 schema 不能读 v3 把 price 改成 double 之后的数据
+	d := NewDecoder[map[string]interface{}](r, NewAvroCodec[map[string]interface{}](mustSchema(t, orderV1)))
+	msg := &sarama.ConsumerMessage{
+		Value:   []byte(`{"id":"1","price":1.5,"buyer":"b"}`),
+		Headers: []*sarama.RecordHeader{{Key: []byte(HeaderSchemaID), Value: []byte(strconv.Itoa(id))}},
+	}
+	if _, err := d.Decode(msg); !errors.Is(err, ErrIncompatibleSchema) {
+		t.Fatalf("want ErrIncompatibleSchema, got %v", err)
+	}
+
+	msg.Headers = nil
+	if _, err := d.Decode(msg); err == nil {
+		t.Fatal("want error for missing schema id header")
+	}
+}
+
+func TestProtoCodecOrder(t *testing.T) {
+	c := NewProtoCodec(func() *order.Order { return &order.Order{} })
+	if c.Schema().Name != "order.Order" {
+		t.Fatalf("schema name %q", c.Schema().Name)
+	}
+
+	in := &order.Order{Id: "102", Items: []string{"Google Pixel 3A"}, Price: 1800, Destination: "Mountain View, CA"}
+	data, err := c.Encode(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := c.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(in, out) {
+		t.Fatalf("got %v, want %v", out, in)
+	}
+
+	// 由 proto 生成的 schema 可以注册, 重复注册得到同一个 id
+	r := NewFileRegistry(filepath.Join(t.TempDir(), "schemas.json"))
+	id1, err := r.Register("orders-value", c.Format(), c.Schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	id2, err := r.Register("orders-value", c.Format(), SchemaFromProto((&order.Order{}).ProtoReflect().Descriptor()))
+	if err != nil || id1 != id2 {
+		t.Fatalf("got %d, %v; want %d", id2, err, id1)
+	}
+}
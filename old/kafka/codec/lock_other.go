@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly || windows)
+
+package codec
+
+import "errors"
+
+// lockFile 这些平台上没有实现进程间文件锁
+func lockFile(path string) (unlock func(), err error) {
+	return nil, errors.New("schema registry: file lock is not supported on this platform")
+}
@@ -0,0 +1,212 @@
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RegisteredSchema 注册中心中的一个 schema
+type RegisteredSchema struct {
+	ID     int     `json:"id"`
+	Format string  `json:"format"`
+	Schema *Schema `json:"schema"`
+}
+
+// subject 一个主题(通常是 <topic>-value)的 schema 版本
+type subject struct {
+	Compatibility Compatibility `json:"compatibility"`
+	Versions      []int         `json:"versions"` // schema id, 按注册顺序
+}
+
+// registryState 注册中心文件的内容
+type registryState struct {
+	NextID   int                       `json:"nextId"`
+	Schemas  map[int]*RegisteredSchema `json:"schemas"`
+	Subjects map[string]*subject       `json:"subjects"`
+}
+
+// FileRegistry 基于本地文件的 schema 注册中心
+// 每次操作都重新读取文件, 写入时先写临时文件再重命名; 读-改-写期间持有 <path>.lock 上的文件锁,
+// 同一台机器上的多个进程可以共用一个文件, 不会丢失写入或分配出重复的 id
+type FileRegistry struct {
+	path string
+	// DefaultCompatibility 新主题的兼容性要求
+	DefaultCompatibility Compatibility
+
+	mu sync.Mutex
+}
+
+// NewFileRegistry 创建注册中心, 文件不存在时在第一次注册时创建
+func NewFileRegistry(path string) *FileRegistry {
+	return &FileRegistry{path: path, DefaultCompatibility: CompatibilityBackward}
+}
+
+// Register 在主题下注册 schema, 返回 schema id
+// 与最新版本相同时直接返回已有的 id; 否则必须与最新版本格式相同并满足主题的兼容性要求
+func (r *FileRegistry) Register(subjectName, format string, schema *Schema) (int, error) {
+	unlock, err := r.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	state, err := r.load()
+	if err != nil {
+		return 0, err
+	}
+
+	sub := state.Subjects[subjectName]
+	if sub == nil {
+		sub = &subject{Compatibility: r.DefaultCompatibility}
+		state.Subjects[subjectName] = sub
+	}
+
+	canonical := schema.Canonical()
+	for _, id := range sub.Versions {
+		if rs := state.Schemas[id]; rs != nil && rs.Format == format && rs.Schema.Canonical() == canonical {
+			return id, nil
+		}
+	}
+
+	if n := len(sub.Versions); n > 0 {
+		latest := state.Schemas[sub.Versions[n-1]]
+		if latest.Format != format {
+			return 0, fmt.Errorf("%w: subject %s uses format %s, got %s", ErrIncompatibleSchema, subjectName, latest.Format, format)
+		}
+		if err := CheckCompatibility(sub.Compatibility, latest.Schema, schema); err != nil {
+			return 0, fmt.Errorf("subject %s: %w", subjectName, err)
+		}
+	}
+
+	state.NextID++
+	id := state.NextID
+	state.Schemas[id] = &RegisteredSchema{ID: id, Format: format, Schema: schema}
+	sub.Versions = append(sub.Versions, id)
+	if err := r.save(state); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// SetCompatibility 修改主题的兼容性要求
+func (r *FileRegistry) SetCompatibility(subjectName string, mode Compatibility) error {
+	if err := CheckCompatibility(mode, &Schema{}, &Schema{}); err != nil {
+		return err
+	}
+
+	unlock, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := r.load()
+	if err != nil {
+		return err
+	}
+	sub := state.Subjects[subjectName]
+	if sub == nil {
+		sub = &subject{}
+		state.Subjects[subjectName] = sub
+	}
+	sub.Compatibility = mode
+	return r.save(state)
+}
+
+// Lookup 根据 id 查找 schema
+func (r *FileRegistry) Lookup(id int) (*RegisteredSchema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	rs, ok := state.Schemas[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: id %d", ErrSchemaNotFound, id)
+	}
+	return rs, nil
+}
+
+// Latest 主题的最新 schema
+func (r *FileRegistry) Latest(subjectName string) (*RegisteredSchema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	sub := state.Subjects[subjectName]
+	if sub == nil || len(sub.Versions) == 0 {
+		return nil, fmt.Errorf("%w: subject %s", ErrSchemaNotFound, subjectName)
+	}
+	return state.Schemas[sub.Versions[len(sub.Versions)-1]], nil
+}
+
+// lock 修改文件前加锁: 进程内用 mu, 进程间用 <path>.lock 上的文件锁
+func (r *FileRegistry) lock() (unlock func(), err error) {
+	r.mu.Lock()
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		r.mu.Unlock()
+		return nil, err
+	}
+	unlockFile, err := lockFile(r.path + ".lock")
+	if err != nil {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("lock schema registry: %w", err)
+	}
+	return func() {
+		unlockFile()
+		r.mu.Unlock()
+	}, nil
+}
+
+func (r *FileRegistry) load() (*registryState, error) {
+	state := &registryState{Schemas: map[int]*RegisteredSchema{}, Subjects: map[string]*subject{}}
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read schema registry: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("parse schema registry %s: %w", r.path, err)
+	}
+	if state.Schemas == nil {
+		state.Schemas = map[int]*RegisteredSchema{}
+	}
+	if state.Subjects == nil {
+		state.Subjects = map[string]*subject{}
+	}
+	return state, nil
+}
+
+func (r *FileRegistry) save(state *registryState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), r.path)
+}
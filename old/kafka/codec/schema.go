@@ -0,0 +1,331 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Schema Avro 风格的 record 定义
+//
+//	{"type":"record","name":"Order","fields":[
+//	  {"name":"id","type":"string"},
+//	  {"name":"items","type":{"type":"array","items":"string"}},
+//	  {"name":"note","type":["null","string"],"default":null}
+//	]}
+type Schema struct {
+	Type   string  `json:"type"`
+	Name   string  `json:"name"`
+	Fields []Field `json:"fields"`
+}
+
+// Field record 的字段, Default 为 nil 表示没有默认值
+type Field struct {
+	Name    string           `json:"name"`
+	Type    FieldType        `json:"type"`
+	Default *json.RawMessage `json:"default,omitempty"`
+}
+
+// FieldType 字段类型: 基本类型、数组或者 ["null", T] 形式的可空类型
+type FieldType struct {
+	Primitive string     // string|int|long|float|double|boolean|bytes
+	Items     *FieldType // 数组元素类型
+	Nullable  bool
+}
+
+var primitives = map[string]bool{
+	"string": true, "int": true, "long": true, "float": true, "double": true, "boolean": true, "bytes": true,
+}
+
+// UnmarshalJSON 解析 "string" / {"type":"array","items":...} / ["null","string"]
+func (t *FieldType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		if !primitives[name] {
+			return fmt.Errorf("unsupported type %q", name)
+		}
+		t.Primitive = name
+		return nil
+	}
+
+	var union []json.RawMessage
+	if err := json.Unmarshal(data, &union); err == nil {
+		if len(union) != 2 || string(union[0]) != `"null"` {
+			return fmt.Errorf("only [\"null\", T] unions are supported, got %s", data)
+		}
+		if err := t.UnmarshalJSON(union[1]); err != nil {
+			return err
+		}
+		if t.Nullable {
+			return fmt.Errorf("nested nullable type %s", data)
+		}
+		t.Nullable = true
+		return nil
+	}
+
+	var array struct {
+		Type  string     `json:"type"`
+		Items *FieldType `json:"items"`
+	}
+	if err := json.Unmarshal(data, &array); err != nil {
+		return fmt.Errorf("invalid type %s: %w", data, err)
+	}
+	if array.Type != "array" || array.Items == nil {
+		return fmt.Errorf("unsupported complex type %s", data)
+	}
+	t.Items = array.Items
+	return nil
+}
+
+// MarshalJSON 与 UnmarshalJSON 相反
+func (t FieldType) MarshalJSON() ([]byte, error) {
+	var base interface{} = t.Primitive
+	if t.Items != nil {
+		base = struct {
+			Type  string    `json:"type"`
+			Items FieldType `json:"items"`
+		}{"array", *t.Items}
+	}
+	if t.Nullable {
+		return json.Marshal([]interface{}{"null", base})
+	}
+	return json.Marshal(base)
+}
+
+func (t FieldType) String() string {
+	s := t.Primitive
+	if t.Items != nil {
+		s = "array<" + t.Items.String() + ">"
+	}
+	if t.Nullable {
+		s = "null|" + s
+	}
+	return s
+}
+
+// ParseSchema 解析并校验 schema 定义
+func ParseSchema(definition []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(definition, &s); err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	if s.Type != "record" {
+		return nil, fmt.Errorf("schema type must be record, got %q", s.Type)
+	}
+	if s.Name == "" {
+		return nil, fmt.Errorf("schema name is required")
+	}
+	seen := make(map[string]bool, len(s.Fields))
+	for _, f := range s.Fields {
+		if f.Name == "" {
+			return nil, fmt.Errorf("schema %s: field name is required", s.Name)
+		}
+		if seen[f.Name] {
+			return nil, fmt.Errorf("schema %s: duplicate field %q", s.Name, f.Name)
+		}
+		seen[f.Name] = true
+		if f.Default != nil {
+			if err := f.Type.check(*f.Default); err != nil {
+				return nil, fmt.Errorf("schema %s: default of field %q: %w", s.Name, f.Name, err)
+			}
+		}
+	}
+	return &s, nil
+}
+
+// Canonical schema 的规范化 json, 用于判断两个 schema 是否相同
+func (s *Schema) Canonical() string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+func (s *Schema) field(name string) (Field, bool) {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// Validate 检查 json 对象是否符合 schema, 缺少的字段必须有默认值
+func (s *Schema) Validate(data []byte) error {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return fmt.Errorf("schema %s: %w", s.Name, err)
+	}
+	for _, f := range s.Fields {
+		v, ok := obj[f.Name]
+		if !ok {
+			if f.Default == nil && !f.Type.Nullable {
+				return fmt.Errorf("schema %s: missing field %q", s.Name, f.Name)
+			}
+			continue
+		}
+		if err := f.Type.check(v); err != nil {
+			return fmt.Errorf("schema %s: field %q: %w", s.Name, f.Name, err)
+		}
+	}
+	return nil
+}
+
+// Resolve 按 schema 补齐缺失字段的默认值, 相当于 avro 的读 schema 解析
+func (s *Schema) Resolve(data []byte) ([]byte, error) {
+	if err := s.Validate(data); err != nil {
+		return nil, err
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	changed := false
+	for _, f := range s.Fields {
+		if _, ok := obj[f.Name]; ok || f.Default == nil {
+			continue
+		}
+		obj[f.Name] = *f.Default
+		changed = true
+	}
+	if !changed {
+		return data, nil
+	}
+	return json.Marshal(obj)
+}
+
+// check 检查 json 值是否符合字段类型
+func (t FieldType) check(v json.RawMessage) error {
+	if string(v) == "null" {
+		if t.Nullable {
+			return nil
+		}
+		return fmt.Errorf("null is not allowed for %s", t)
+	}
+	if t.Items != nil {
+		var items []json.RawMessage
+		if err := json.Unmarshal(v, &items); err != nil {
+			return fmt.Errorf("want %s: %w", t, err)
+		}
+		for i, item := range items {
+			if err := t.Items.check(item); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		return nil
+	}
+
+	var err error
+	switch t.Primitive {
+	case "string", "bytes":
+		var s string
+		err = json.Unmarshal(v, &s)
+	case "int", "long":
+		var n int64
+		err = json.Unmarshal(v, &n)
+	case "float", "double":
+		var f float64
+		err = json.Unmarshal(v, &f)
+	case "boolean":
+		var b bool
+		err = json.Unmarshal(v, &b)
+	}
+	if err != nil {
+		return fmt.Errorf("want %s, got %s", t, v)
+	}
+	return nil
+}
+
+// Compatibility schema 演进的兼容性要求
+type Compatibility string
+
+const (
+	CompatibilityNone     Compatibility = "NONE"
+	CompatibilityBackward Compatibility = "BACKWARD" // 新 schema 能读旧数据
+	CompatibilityForward  Compatibility = "FORWARD"  // 旧 schema 能读新数据
+	CompatibilityFull     Compatibility = "FULL"     // 双向兼容
+)
+
+// CheckCompatibility 检查从 old 演进到 next 是否满足兼容性要求, 返回全部不兼容的原因
+func CheckCompatibility(mode Compatibility, old, next *Schema) error {
+	var problems []string
+	switch mode {
+	case CompatibilityNone:
+		return nil
+	case CompatibilityBackward:
+		problems = canRead(next, old)
+	case CompatibilityForward:
+		problems = canRead(old, next)
+	case CompatibilityFull:
+		problems = append(canRead(next, old), canRead(old, next)...)
+	default:
+		return fmt.Errorf("unknown compatibility %q", mode)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("%w (%s): %s", ErrIncompatibleSchema, mode, strings.Join(problems, "; "))
+}
+
+// canRead 用 reader schema 读取 writer schema 写的数据时会出现的问题
+func canRead(reader, writer *Schema) []string {
+	var problems []string
+	for _, rf := range reader.Fields {
+		wf, ok := writer.field(rf.Name)
+		if !ok {
+			if rf.Default == nil && !rf.Type.Nullable {
+				problems = append(problems, fmt.Sprintf("field %q added without default", rf.Name))
+			}
+			continue
+		}
+		if !promotable(wf.Type, rf.Type) {
+			problems = append(problems, fmt.Sprintf("field %q changed type from %s to %s", rf.Name, wf.Type, rf.Type))
+		}
+	}
+	return problems
+}
+
+// promotable writer 类型的值能否被 reader 类型读取, 规则与 avro 的类型提升一致
+func promotable(writer, reader FieldType) bool {
+	if writer.Nullable && !reader.Nullable {
+		return false
+	}
+	if (writer.Items == nil) != (reader.Items == nil) {
+		return false
+	}
+	if writer.Items != nil {
+		return promotable(*writer.Items, *reader.Items)
+	}
+	if writer.Primitive == reader.Primitive {
+		return true
+	}
+	switch writer.Primitive {
+	case "int":
+		return reader.Primitive == "long" || reader.Primitive == "float" || reader.Primitive == "double"
+	case "long":
+		return reader.Primitive == "float" || reader.Primitive == "double"
+	case "float":
+		return reader.Primitive == "double"
+	case "string":
+		return reader.Primitive == "bytes"
+	case "bytes":
+		return reader.Primitive == "string"
+	}
+	return false
+}
+
+// zeroDefault 字段类型的零值, 作为 proto3 字段的默认值
+func zeroDefault(t FieldType) *json.RawMessage {
+	var v json.RawMessage
+	switch {
+	case t.Items != nil:
+		v = json.RawMessage("[]")
+	case t.Primitive == "string" || t.Primitive == "bytes":
+		v = json.RawMessage(`""`)
+	case t.Primitive == "boolean":
+		v = json.RawMessage("false")
+	default:
+		v = json.RawMessage("0")
+	}
+	return &v
+}
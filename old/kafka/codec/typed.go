@@ -0,0 +1,138 @@
+package codec
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// HeaderSchemaID 消息内容使用的 schema id
+const HeaderSchemaID = "x-schema-id"
+
+// Registry schema 注册中心, FileRegistry 实现了该接口
+type Registry interface {
+	Register(subject, format string, schema *Schema) (int, error)
+	Lookup(id int) (*RegisteredSchema, error)
+}
+
+// ValueSubject topic 消息内容对应的主题名称
+func ValueSubject(topic string) string {
+	return topic + "-value"
+}
+
+// Producer 类型化的生产者
+type Producer[T any] struct {
+	producer sarama.SyncProducer
+	topic    string
+	codec    Codec[T]
+	schemaID int
+}
+
+// NewProducer 创建类型化的生产者
+// 创建时把 codec 的 schema 注册到 <topic>-value 主题, 与已发布的 schema 不兼容时返回 ErrIncompatibleSchema
+func NewProducer[T any](producer sarama.SyncProducer, registry Registry, topic string, codec Codec[T]) (*Producer[T], error) {
+	id, err := registry.Register(ValueSubject(topic), codec.Format(), codec.Schema())
+	if err != nil {
+		return nil, err
+	}
+	return &Producer[T]{producer: producer, topic: topic, codec: codec, schemaID: id}, nil
+}
+
+// SchemaID 发布消息使用的 schema id
+func (p *Producer[T]) SchemaID() int {
+	return p.schemaID
+}
+
+// Send 编码并同步发送一条消息, key 为空时不设置
+func (p *Producer[T]) Send(key string, v T) (partition int32, offset int64, err error) {
+	value, err := p.codec.Encode(v)
+	if err != nil {
+		return 0, 0, err
+	}
+	msg := &sarama.ProducerMessage{
+		Topic:   p.topic,
+		Value:   sarama.ByteEncoder(value),
+		Headers: []sarama.RecordHeader{{Key: []byte(HeaderSchemaID), Value: []byte(strconv.Itoa(p.schemaID))}},
+	}
+	if key != "" {
+		msg.Key = sarama.StringEncoder(key)
+	}
+	return p.producer.SendMessage(msg)
+}
+
+// Decoder 类型化的消费者解码器
+// 根据消息 header 中的 schema id 查找写入时的 schema, 格式不一致或者与读 schema 不兼容时返回错误
+type Decoder[T any] struct {
+	registry Registry
+	codec    Codec[T]
+
+	mu      sync.Mutex
+	checked map[int]error // 已经检查过的写 schema
+}
+
+// NewDecoder 创建解码器
+func NewDecoder[T any](registry Registry, codec Codec[T]) *Decoder[T] {
+	return &Decoder[T]{registry: registry, codec: codec, checked: make(map[int]error)}
+}
+
+// Decode 解码一条消息
+func (d *Decoder[T]) Decode(msg *sarama.ConsumerMessage) (T, error) {
+	var zero T
+	if err := d.checkWriterSchema(msg); err != nil {
+		return zero, err
+	}
+	return d.codec.Decode(msg.Value)
+}
+
+// Handler 把类型化的处理函数转换为按消息处理的函数, 可以直接用于 consumer.NewGroupHandler
+// 解码失败同样返回错误, 由重试/死信机制处理
+func (d *Decoder[T]) Handler(fn func(ctx context.Context, v T, msg *sarama.ConsumerMessage) error) func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		v, err := d.Decode(msg)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, v, msg)
+	}
+}
+
+func (d *Decoder[T]) checkWriterSchema(msg *sarama.ConsumerMessage) error {
+	var raw string
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == HeaderSchemaID {
+			raw = string(h.Value)
+			break
+		}
+	}
+	if raw == "" {
+		return fmt.Errorf("%s/%d/%d: missing %s header", msg.Topic, msg.Partition, msg.Offset, HeaderSchemaID)
+	}
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		return fmt.Errorf("%s/%d/%d: invalid %s header %q", msg.Topic, msg.Partition, msg.Offset, HeaderSchemaID, raw)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err, ok := d.checked[id]; ok {
+		return err
+	}
+
+	writer, err := d.registry.Lookup(id)
+	if err != nil {
+		// 注册中心暂时不可用时不缓存结果, 下次重新查询
+		return err
+	}
+	switch {
+	case writer.Format != d.codec.Format():
+		err = fmt.Errorf("%w: schema %d is %s, decoder expects %s", ErrIncompatibleSchema, id, writer.Format, d.codec.Format())
+	default:
+		// 读 schema 必须能读写 schema 写入的数据
+		err = CheckCompatibility(CompatibilityBackward, writer.Schema, d.codec.Schema())
+	}
+	d.checked[id] = err
+	return err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"strconv"
+	"time"
+)
+
+// newProducerConfig 根据命令行参数构造生产者配置
+func newProducerConfig() (*sarama.Config, error) {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_0_0
+
+	switch acks {
+	case "none", "0":
+		config.Producer.RequiredAcks = sarama.NoResponse
+	case "leader", "1":
+		config.Producer.RequiredAcks = sarama.WaitForLocal
+	case "all", "-1":
+		config.Producer.RequiredAcks = sarama.WaitForAll
+	default:
+		return nil, fmt.Errorf("unknown acks %q, want none|leader|all", acks)
+	}
+
+	config.Producer.Compression = compression
+
+	// 同步生产者必须回复确认
+	config.Producer.Return.Successes = true
+
+	if idempotent {
+		if config.Producer.RequiredAcks != sarama.WaitForAll {
+			return nil, fmt.Errorf("idempotent producer requires acks=all, got %q", acks)
+		}
+		// 幂等生产者要求每个连接只有一个在途请求, 否则重试会打乱顺序
+		config.Producer.Idempotent = true
+		config.Net.MaxOpenRequests = 1
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// offsetGetter 查询分区偏移量, sarama.Client 实现了它
+type offsetGetter interface {
+	GetOffset(topic string, partitionID int32, time int64) (int64, error)
+}
+
+// resolveStartOffset 把 -from 参数解析为分区的起始偏移量
+// earliest/latest 直接对应 sarama 的特殊偏移量, 数字为具体偏移量(必须在分区现有的范围内), 时间则查询该时间之后的第一条消息
+func resolveStartOffset(client offsetGetter, topic string, partition int32, from string) (int64, error) {
+	switch from {
+	case "earliest", "oldest":
+		return sarama.OffsetOldest, nil
+	case "latest", "newest", "":
+		return sarama.OffsetNewest, nil
+	}
+
+	if offset, err := strconv.ParseInt(from, 10, 64); err == nil {
+		if offset < 0 {
+			return 0, fmt.Errorf("invalid offset %d", offset)
+		}
+		// 超出范围时 ConsumePartition 只会返回 ErrOffsetOutOfRange, 这里给出分区的实际范围
+		oldest, err := client.GetOffset(topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return 0, fmt.Errorf("get oldest offset of partition %d: %w", partition, err)
+		}
+		newest, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return 0, fmt.Errorf("get newest offset of partition %d: %w", partition, err)
+		}
+		if offset < oldest || offset > newest {
+			return 0, fmt.Errorf("offset %d out of range [%d, %d] of partition %d", offset, oldest, newest, partition)
+		}
+		return offset, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		return 0, fmt.Errorf("invalid start position %q, want earliest|latest|<offset>|<RFC3339 time>", from)
+	}
+	offset, err := client.GetOffset(topic, partition, t.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("get offset of partition %d at %s: %w", partition, t.Format(time.RFC3339), err)
+	}
+	// 该时间之后没有消息时 kafka 返回 -1, 从最新位置开始
+	if offset < 0 {
+		return sarama.OffsetNewest, nil
+	}
+	return offset, nil
+}
@@ -1,49 +1,126 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"github.com/IBM/sarama"
+	"io"
 	"log"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 )
 
 // 获取消费者
-func consumer() {
+// 消费 -topic 的全部分区(或 -partition 指定的分区), 从 -from 指定的位置开始, 按 -format 输出到标准输出
+func consumer() error {
 	log.Printf("kafka: 消费者")
 
-	// 获取消费者接口，当为外网主机时修改localhost为主机IP地址
-	consumer, err := sarama.NewConsumer(KafkaAddr, sarama.NewConfig())
+	// 先校验输出格式, 避免连上 kafka 之后才报错
+	if err := printMessage(io.Discard, outputFmt, &sarama.ConsumerMessage{}); err != nil {
+		return err
+	}
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_0_0
+
+	// 获取客户端，当为外网主机时修改localhost为主机IP地址
+	client, err := sarama.NewClient(KafkaAddr, config)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
+	}
+	defer client.Close()
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return err
 	}
 
 	defer func() {
 		// 关闭消费者
-		if err = consumer.Close(); err != nil {
-			fmt.Println(err)
-			return
+		if err := consumer.Close(); err != nil {
+			log.Println("close consumer err:", err)
 		}
 	}()
 
-	// 获取消费者的分片接口，sarama.OffsetNewest 标识获取新的消息
-	partitionConsumer, err := consumer.ConsumePartition(TestTopicName, 0, sarama.OffsetNewest)
+	partitions, err := consumer.Partitions(TestTopicName)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
+	}
+	if partitionID >= 0 {
+		partitions = []int32{int32(partitionID)}
 	}
 
-	defer func() {
-		if err = partitionConsumer.Close(); err != nil {
-			log.Fatal(err)
-			return
+	messages := make(chan *sarama.ConsumerMessage)
+	done := make(chan struct{})
+	defer close(done)
+	var pwg sync.WaitGroup
+	for _, partition := range partitions {
+		offset, err := resolveStartOffset(client, TestTopicName, partition, startFrom)
+		if err != nil {
+			return err
 		}
-	}()
 
-	for msg := range partitionConsumer.Messages() {
-		log.Printf("\n分片:%d \n偏移:%d \nKey:%s \nValue:%s \nTime:%s \nMQData:%s \n", msg.Partition, msg.Offset, msg.Key, msg.Value, msg.Timestamp, string(Marshal(msg)))
+		// 获取消费者的分片接口
+		pc, err := consumer.ConsumePartition(TestTopicName, partition, offset)
+		if err != nil {
+			return fmt.Errorf("consume partition %d: %w", partition, err)
+		}
+		defer pc.AsyncClose()
+
+		pwg.Add(1)
+		go func(pc sarama.PartitionConsumer) {
+			defer pwg.Done()
+			for msg := range pc.Messages() {
+				select {
+				case messages <- msg:
+				case <-done:
+					return
+				}
+			}
+		}(pc)
 	}
+	go func() {
+		pwg.Wait()
+		close(messages)
+	}()
 
+	// 捕获中断信号以进行优雅关闭
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	_, err = printMessages(ctx, out, outputFmt, messages, maxMessages)
+	return err
+}
+
+// printMessages 按 format 把 messages 中的消息写到 out, 每条之后 flush 以便交互使用时及时输出;
+// ctx 结束、messages 关闭或者收到 limit 条(limit 为 0 表示不限制)时返回, 返回输出的条数
+func printMessages(ctx context.Context, out *bufio.Writer, format string, messages <-chan *sarama.ConsumerMessage, limit int) (int, error) {
+	var received int
+	for {
+		select {
+		case <-ctx.Done():
+			return received, nil
+		case msg, ok := <-messages:
+			if !ok {
+				return received, nil
+			}
+			if err := printMessage(out, format, msg); err != nil {
+				return received, fmt.Errorf("print message: %w", err)
+			}
+			if err := out.Flush(); err != nil {
+				return received, err
+			}
+			received++
+			if limit > 0 && received >= limit {
+				return received, nil
+			}
+		}
+	}
 }
 
 var wg sync.WaitGroup
@@ -85,8 +162,13 @@ func consumerNew() {
 		// 异步从每个分区消费信息
 		go func(sarama.PartitionConsumer) {
 			for msg := range pc.Messages() {
+				data, err := Marshal(msg)
+				if err != nil {
+					log.Println(err)
+					continue
+				}
 				log.Printf("\npartition: %d \nOffse: %d \nKey: %v \nValue: %s  \nMQData: %s \n\n",
-					msg.Partition, msg.Offset, msg.Key, msg.Value, string(Marshal(msg)))
+					msg.Partition, msg.Offset, msg.Key, msg.Value, data)
 			}
 		}(pc)
 	}
@@ -0,0 +1,262 @@
+// Package consumer 至少一次(at-least-once)语义的 kafka 消费者组框架
+//
+// 业务代码只需要实现 Handler 并返回错误, 失败的消息会依次投递到延迟递增的重试 topic,
+// 重试次数用完后进入死信 topic; 只有消息处理成功或者已经转投到重试/死信 topic 之后才会提交偏移量
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// 重试消息携带的 header
+const (
+	HeaderAttempt       = "x-retry-attempt"    // 已经失败的次数
+	HeaderOriginalTopic = "x-original-topic"   // 消息最初所在的 topic
+	HeaderNotBefore     = "x-retry-not-before" // 最早可以再次处理的时间, unix 毫秒
+	HeaderError         = "x-error"            // 最近一次处理失败的原因
+)
+
+// Handler 业务处理函数, 返回错误表示处理失败
+type Handler func(ctx context.Context, msg *sarama.ConsumerMessage) error
+
+// ErrSkipRetry 包装后返回可以跳过剩余重试直接进入死信 topic, 用于不可恢复的错误
+var ErrSkipRetry = errors.New("skip retry")
+
+// Config 消费者组处理器配置
+type Config struct {
+	// Topic 主 topic
+	Topic string
+	// RetryDelays 每一级重试的延迟, 第 i 级重试 topic 为 <Topic>.retry.<i+1>
+	RetryDelays []time.Duration
+	// DeadLetterTopic 重试用完之后的死信 topic, 默认 <Topic>.dlq
+	DeadLetterTopic string
+	// HandlerTimeout 单条消息的处理超时, 0 表示不限制
+	// 再均衡或关闭时不会取消正在处理的消息, 超时可以避免卡住的消息拖住关闭
+	HandlerTimeout time.Duration
+}
+
+// RetryTopic 第 level 级重试 topic 的名称, level 从 1 开始
+func (c Config) RetryTopic(level int) string {
+	return fmt.Sprintf("%s.retry.%d", c.Topic, level)
+}
+
+// DLQTopic 死信 topic 的名称
+func (c Config) DLQTopic() string {
+	if c.DeadLetterTopic != "" {
+		return c.DeadLetterTopic
+	}
+	return c.Topic + ".dlq"
+}
+
+// Topics 消费者组需要订阅的全部 topic: 主 topic 和所有重试 topic
+func (c Config) Topics() []string {
+	topics := []string{c.Topic}
+	for i := range c.RetryDelays {
+		topics = append(topics, c.RetryTopic(i+1))
+	}
+	return topics
+}
+
+// level topic 对应的重试级别, 主 topic 为 0, 不认识的 topic 返回 -1
+func (c Config) level(topic string) int {
+	if topic == c.Topic {
+		return 0
+	}
+	for i := range c.RetryDelays {
+		if topic == c.RetryTopic(i+1) {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// GroupHandler 实现了 sarama.ConsumerGroupHandler
+type GroupHandler struct {
+	cfg      Config
+	producer sarama.SyncProducer
+	handler  Handler
+
+	// OnSetup/OnCleanup 再均衡钩子, 可以用来记录分区分配的变化, 例如 monitor.RebalanceRecorder
+	OnSetup   func(sess sarama.ConsumerGroupSession)
+	OnCleanup func(sess sarama.ConsumerGroupSession)
+
+	// now 获取当前时间, 测试时可以替换
+	now func() time.Time
+}
+
+// NewGroupHandler 创建消费者组处理器, producer 用于投递重试和死信消息
+func NewGroupHandler(cfg Config, producer sarama.SyncProducer, handler Handler) *GroupHandler {
+	return &GroupHandler{cfg: cfg, producer: producer, handler: handler, now: time.Now}
+}
+
+// Setup 在分配给成员的分区发生变化时调用
+func (h *GroupHandler) Setup(sess sarama.ConsumerGroupSession) error {
+	log.Printf("[consumer] member %s generation %d claims %v", sess.MemberID(), sess.GenerationID(), sess.Claims())
+	if h.OnSetup != nil {
+		h.OnSetup(sess)
+	}
+	return nil
+}
+
+// Cleanup 在成员停止消费分区前调用, sarama 随后会提交已经标记的偏移量
+func (h *GroupHandler) Cleanup(sess sarama.ConsumerGroupSession) error {
+	log.Printf("[consumer] member %s generation %d cleanup", sess.MemberID(), sess.GenerationID())
+	if h.OnCleanup != nil {
+		h.OnCleanup(sess)
+	}
+	return nil
+}
+
+// ConsumeClaim 逐条处理分区中的消息
+// 会话结束(再均衡或关闭)时不再拉取新消息, 正在处理的消息会处理完再返回
+func (h *GroupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	level := h.cfg.level(claim.Topic())
+	if level < 0 {
+		return fmt.Errorf("unexpected topic %s", claim.Topic())
+	}
+
+	for {
+		select {
+		case <-sess.Context().Done():
+			return nil
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			if level > 0 && !h.waitUntilDue(sess.Context(), msg) {
+				// 等待期间会话结束, 消息没有标记, 下次会重新投递
+				return nil
+			}
+			if err := h.process(sess.Context(), msg, level); err != nil {
+				// 重试消息也没能投递出去, 结束本次会话, 未标记的消息会重新消费
+				return err
+			}
+			sess.MarkMessage(msg, "")
+		}
+	}
+}
+
+// process 处理一条消息, 失败时转投到下一级重试 topic 或死信 topic
+// 只有业务处理成功或转投成功才返回 nil
+func (h *GroupHandler) process(ctx context.Context, msg *sarama.ConsumerMessage, level int) error {
+	err := h.callHandler(ctx, msg)
+	if err == nil {
+		return nil
+	}
+
+	next := level + 1
+	topic := h.cfg.DLQTopic()
+	var notBefore time.Time
+	if next <= len(h.cfg.RetryDelays) && !errors.Is(err, ErrSkipRetry) {
+		topic = h.cfg.RetryTopic(next)
+		notBefore = h.now().Add(h.cfg.RetryDelays[next-1])
+	}
+	log.Printf("[consumer] %s/%d/%d failed: %v, forward to %s", msg.Topic, msg.Partition, msg.Offset, err, topic)
+
+	if _, _, perr := h.producer.SendMessage(h.forwardMessage(msg, topic, level+1, notBefore, err)); perr != nil {
+		return fmt.Errorf("forward %s/%d/%d to %s: %w", msg.Topic, msg.Partition, msg.Offset, topic, perr)
+	}
+	return nil
+}
+
+// callHandler 调用业务处理函数, 业务代码 panic 时当作处理失败
+func (h *GroupHandler) callHandler(ctx context.Context, msg *sarama.ConsumerMessage) (err error) {
+	// 会话结束时让正在处理的消息处理完, 而不是半途取消
+	ctx = context.WithoutCancel(ctx)
+	if h.cfg.HandlerTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.cfg.HandlerTimeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return h.handler(ctx, msg)
+}
+
+// forwardMessage 构造转投到重试/死信 topic 的消息, 保留原消息的 key 和业务 header
+func (h *GroupHandler) forwardMessage(msg *sarama.ConsumerMessage, topic string, attempt int, notBefore time.Time, cause error) *sarama.ProducerMessage {
+	original := msg.Topic
+	var headers []sarama.RecordHeader
+	for _, rh := range msg.Headers {
+		if rh == nil {
+			continue
+		}
+		switch string(rh.Key) {
+		case HeaderOriginalTopic:
+			original = string(rh.Value)
+		case HeaderAttempt, HeaderNotBefore, HeaderError:
+		default:
+			headers = append(headers, *rh)
+		}
+	}
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte(HeaderOriginalTopic), Value: []byte(original)},
+		sarama.RecordHeader{Key: []byte(HeaderAttempt), Value: []byte(strconv.Itoa(attempt))},
+		sarama.RecordHeader{Key: []byte(HeaderError), Value: []byte(cause.Error())},
+	)
+	if !notBefore.IsZero() {
+		headers = append(headers, sarama.RecordHeader{Key: []byte(HeaderNotBefore), Value: []byte(strconv.FormatInt(notBefore.UnixMilli(), 10))})
+	}
+
+	out := &sarama.ProducerMessage{Topic: topic, Headers: headers, Value: sarama.ByteEncoder(msg.Value)}
+	if msg.Key != nil {
+		out.Key = sarama.ByteEncoder(msg.Key)
+	}
+	return out
+}
+
+// waitUntilDue 等到重试消息的处理时间, 会话结束返回 false
+// 同一个重试 topic 的延迟相同, 分区内后面的消息不会比当前消息更早到期, 阻塞等待不会耽误其他消息
+func (h *GroupHandler) waitUntilDue(ctx context.Context, msg *sarama.ConsumerMessage) bool {
+	due, ok := header(msg, HeaderNotBefore)
+	if !ok {
+		return true
+	}
+	ms, err := strconv.ParseInt(due, 10, 64)
+	if err != nil {
+		return true
+	}
+	wait := time.UnixMilli(ms).Sub(h.now())
+	if wait <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// Attempt 消息已经失败的次数, 主 topic 的消息为 0
+func Attempt(msg *sarama.ConsumerMessage) int {
+	v, ok := header(msg, HeaderAttempt)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func header(msg *sarama.ConsumerMessage, key string) (string, bool) {
+	for _, rh := range msg.Headers {
+		if rh != nil && string(rh.Key) == key {
+			return string(rh.Value), true
+		}
+	}
+	return "", false
+}
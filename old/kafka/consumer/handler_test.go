@@ -0,0 +1,184 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+)
+
+// sarama 没有提供消费者组的 mock, 这里实现 session 和 claim 接口来驱动 GroupHandler
+
+type fakeSession struct {
+	ctx context.Context
+
+	mu     sync.Mutex
+	marked []int64
+}
+
+func (s *fakeSession) Claims() map[string][]int32                                        { return nil }
+func (s *fakeSession) MemberID() string                                                  { return "member-1" }
+func (s *fakeSession) GenerationID() int32                                               { return 1 }
+func (s *fakeSession) MarkOffset(topic string, partition int32, offset int64, m string)  {}
+func (s *fakeSession) Commit()                                                           {}
+func (s *fakeSession) ResetOffset(topic string, partition int32, offset int64, m string) {}
+func (s *fakeSession) Context() context.Context                                          { return s.ctx }
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.marked = append(s.marked, msg.Offset)
+}
+
+type fakeClaim struct {
+	topic    string
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) Topic() string                            { return c.topic }
+func (c *fakeClaim) Partition() int32                         { return 0 }
+func (c *fakeClaim) InitialOffset() int64                     { return 0 }
+func (c *fakeClaim) HighWaterMarkOffset() int64               { return int64(len(c.messages)) }
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+func newClaim(topic string, msgs ...*sarama.ConsumerMessage) *fakeClaim {
+	c := &fakeClaim{topic: topic, messages: make(chan *sarama.ConsumerMessage, len(msgs))}
+	for _, m := range msgs {
+		c.messages <- m
+	}
+	close(c.messages)
+	return c
+}
+
+func testConfig() Config {
+	return Config{Topic: "orders", RetryDelays: []time.Duration{time.Second, time.Minute}}
+}
+
+func TestConsumeClaimForwardsFailures(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, nil)
+	var forwarded []*sarama.ProducerMessage
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		forwarded = append(forwarded, msg)
+		return nil
+	})
+	defer producer.Close()
+
+	h := NewGroupHandler(testConfig(), producer, func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		if string(msg.Value) == "bad" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	sess := &fakeSession{ctx: context.Background()}
+	claim := newClaim("orders",
+		&sarama.ConsumerMessage{Topic: "orders", Offset: 0, Value: []byte("ok")},
+		&sarama.ConsumerMessage{Topic: "orders", Offset: 1, Key: []byte("k"), Value: []byte("bad")},
+	)
+	if err := h.ConsumeClaim(sess, claim); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sess.marked) != 2 {
+		t.Fatalf("marked %v, want both offsets", sess.marked)
+	}
+	if len(forwarded) != 1 || forwarded[0].Topic != "orders.retry.1" {
+		t.Fatalf("forwarded %+v, want one message to orders.retry.1", forwarded)
+	}
+	out := forwarded[0]
+	var attempt string
+	for _, rh := range out.Headers {
+		if string(rh.Key) == HeaderAttempt {
+			attempt = string(rh.Value)
+		}
+	}
+	if attempt != "1" {
+		t.Fatalf("attempt header %q, want 1", attempt)
+	}
+}
+
+func TestConsumeClaimLastRetryGoesToDLQ(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, nil)
+	var topic string
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		topic = msg.Topic
+		return nil
+	})
+	defer producer.Close()
+
+	h := NewGroupHandler(testConfig(), producer, func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		panic("still broken")
+	})
+
+	sess := &fakeSession{ctx: context.Background()}
+	msg := &sarama.ConsumerMessage{Topic: "orders.retry.2", Offset: 7, Value: []byte("bad"), Headers: []*sarama.RecordHeader{
+		{Key: []byte(HeaderAttempt), Value: []byte("2")},
+		{Key: []byte(HeaderOriginalTopic), Value: []byte("orders")},
+	}}
+	if err := h.ConsumeClaim(sess, newClaim("orders.retry.2", msg)); err != nil {
+		t.Fatal(err)
+	}
+	if topic != "orders.dlq" {
+		t.Fatalf("forwarded to %q, want orders.dlq", topic)
+	}
+	if len(sess.marked) != 1 {
+		t.Fatalf("marked %v, want offset 7", sess.marked)
+	}
+}
+
+func TestConsumeClaimDoesNotMarkWhenForwardFails(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, nil)
+	producer.ExpectSendMessageAndFail(sarama.ErrOutOfBrokers)
+	defer producer.Close()
+
+	h := NewGroupHandler(testConfig(), producer, func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		return errors.New("boom")
+	})
+
+	sess := &fakeSession{ctx: context.Background()}
+	claim := newClaim("orders", &sarama.ConsumerMessage{Topic: "orders", Offset: 3, Value: []byte("bad")})
+	if err := h.ConsumeClaim(sess, claim); !errors.Is(err, sarama.ErrOutOfBrokers) {
+		t.Fatalf("want ErrOutOfBrokers, got %v", err)
+	}
+	if len(sess.marked) != 0 {
+		t.Fatalf("marked %v, want nothing", sess.marked)
+	}
+}
+
+func TestConsumeClaimStopsWaitingOnShutdown(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, nil)
+	defer producer.Close()
+
+	h := NewGroupHandler(testConfig(), producer, func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		t.Error("handler should not run before the retry is due")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sess := &fakeSession{ctx: ctx}
+	due := strconv.FormatInt(time.Now().Add(time.Hour).UnixMilli(), 10)
+	msg := &sarama.ConsumerMessage{Topic: "orders.retry.1", Offset: 1, Headers: []*sarama.RecordHeader{
+		{Key: []byte(HeaderNotBefore), Value: []byte(due)},
+	}}
+
+	done := make(chan error)
+	go func() { done <- h.ConsumeClaim(sess, newClaim("orders.retry.1", msg)) }()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ConsumeClaim did not return after the session ended")
+	}
+	if len(sess.marked) != 0 {
+		t.Fatalf("marked %v, want nothing", sess.marked)
+	}
+}
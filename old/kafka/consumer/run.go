@@ -0,0 +1,55 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// NewConfig 至少一次语义推荐的 sarama 配置
+// 偏移量仍然自动提交, 但只会提交 ConsumeClaim 标记过的消息, 也就是处理成功或已经转投的消息
+func NewConfig() *sarama.Config {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_0_0
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	config.Consumer.Return.Errors = true
+
+	// 转投重试/死信 topic 的生产者需要同步确认
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Return.Successes = true
+	return config
+}
+
+// Run 持续消费直到 ctx 结束, 再均衡之后自动重新加入消费者组
+// ctx 结束后关闭消费者组, 关闭会等待所有 ConsumeClaim 处理完正在处理的消息并提交偏移量
+func Run(ctx context.Context, group sarama.ConsumerGroup, handler *GroupHandler) error {
+	go func() {
+		for err := range group.Errors() {
+			log.Println("[consumer] group error:", err)
+		}
+	}()
+
+	topics := handler.cfg.Topics()
+	for ctx.Err() == nil {
+		err := group.Consume(ctx, topics, handler)
+		if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+			return nil
+		}
+		if err != nil {
+			log.Println("[consumer] consume err:", err)
+			// 避免 broker 不可用时空转
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}
+
+	if err := group.Close(); err != nil && !errors.Is(err, sarama.ErrClosedConsumerGroup) {
+		return err
+	}
+	return nil
+}
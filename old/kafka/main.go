@@ -2,8 +2,11 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"github.com/IBM/sarama"
 	jsoniter "github.com/json-iterator/go"
 	"log"
+	"strings"
 	"time"
 )
 
@@ -12,22 +15,69 @@ var startType string
 var KafkaAddr = []string{"127.0.0.1:9092"}
 var TestTopicName = "test_topic"
 
+// 命令行参数
+var (
+	brokers      string                  // broker 地址, 逗号分隔
+	groupName    string                  // 消费者组
+	messageKey   string                  // 生产消息的 key
+	acks         string                  // 应答机制 none|leader|all
+	compression  sarama.CompressionCodec // 压缩方式 none|gzip|snappy|lz4|zstd
+	idempotent   bool                    // 幂等生产者
+	inputPath    string                  // 消息来源, - 表示标准输入
+	wholeInput   bool                    // 整个输入作为一条消息, 否则每行一条
+	startFrom    string                  // 消费起点 earliest|latest|<offset>|<RFC3339 时间>
+	partitionID  int                     // 只消费指定分区, -1 表示全部分区
+	maxMessages  int                     // 消费多少条后退出, 0 表示不限制
+	outputFmt    string                  // 输出格式 raw|json|hex
+	registryPath string                  // 本地 schema 注册中心文件
+	historyPath  string                  // 再均衡历史文件
+	resetTo      string                  // 重置偏移量的目标时间, RFC3339
+	dryRun       bool                    // 只打印偏移量重置计划
+)
+
 func main() {
-	flag.StringVar(&startType, "s", "", "启动生产者还是消费者")
+	flag.StringVar(&startType, "s", "", "启动生产者还是消费者: producer|producerNew|consumer|consumerNew|moreConsumer|pipeline|orderProducer|admin")
+	flag.StringVar(&brokers, "brokers", strings.Join(KafkaAddr, ","), "kafka broker 地址, 逗号分隔")
+	flag.StringVar(&TestTopicName, "topic", TestTopicName, "topic 名称")
+	flag.StringVar(&groupName, "group", "your_consumer_group", "消费者组名称")
+	flag.StringVar(&messageKey, "key", "", "生产消息的 key, 为空时不设置")
+	flag.StringVar(&acks, "acks", "all", "应答机制: none|leader|all")
+	flag.TextVar(&compression, "compression", sarama.CompressionNone, "压缩方式: none|gzip|snappy|lz4|zstd")
+	flag.BoolVar(&idempotent, "idempotent", false, "开启幂等生产者, 要求 acks=all")
+	flag.StringVar(&inputPath, "input", "-", "待发送消息的文件, - 表示标准输入")
+	flag.BoolVar(&wholeInput, "whole", false, "整个输入作为一条消息发送, 默认每行一条")
+	flag.StringVar(&startFrom, "from", "latest", "消费起点: earliest|latest|<offset>|<RFC3339 时间>")
+	flag.IntVar(&partitionID, "partition", -1, "只消费指定分区, -1 表示全部分区")
+	flag.IntVar(&maxMessages, "n", 0, "消费多少条消息后退出, 0 表示不限制")
+	flag.StringVar(&outputFmt, "format", "raw", "消息输出格式: raw|json|hex")
+	flag.StringVar(&registryPath, "registry", "schemas.json", "本地 schema 注册中心文件")
+	flag.StringVar(&historyPath, "history", "rebalance.log", "消费者组再均衡历史文件")
+	flag.StringVar(&resetTo, "to", "", "admin reset: 把偏移量重置到该时间(RFC3339)之后的第一条消息")
+	flag.BoolVar(&dryRun, "dry-run", false, "admin reset: 只打印重置计划, 不提交")
 
 	flag.Parse()
 
+	KafkaAddr = splitBrokers(brokers)
+
 	switch startType {
 	case "producer":
 		producer()
 	case "producerNew":
 		producerNew()
 	case "consumer":
-		consumer()
+		if err := consumer(); err != nil {
+			log.Fatal(err)
+		}
 	case "consumerNew":
 		consumerNew()
 	case "moreConsumer":
 		MoreConsumer()
+	case "pipeline":
+		pipeline()
+	case "orderProducer":
+		orderProducer()
+	case "admin":
+		admin(flag.Arg(0))
 	default:
 		log.Fatal("请指定启动生产者还是消费者，startType:", startType)
 	}
@@ -35,6 +85,17 @@ func main() {
 	return
 }
 
+// splitBrokers 解析逗号分隔的 broker 地址
+func splitBrokers(s string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(s, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
 // Stamp2Str 时间戳 -> 字符串  1660188363 -> 2022-08-11 11:26:03
 func Stamp2Str(stamp int32) string {
 	timeLayout := "2006-01-02 15:04:05"
@@ -42,18 +103,19 @@ func Stamp2Str(stamp int32) string {
 	return str
 }
 
-func Marshal(value interface{}) []byte {
+// Marshal 使用 jsoniter 编码, 编码失败时返回错误而不是 nil
+func Marshal(value interface{}) ([]byte, error) {
 	data, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(value)
 	if err != nil {
-		log.Printf("Marshal json:%v", err.Error())
+		return nil, fmt.Errorf("marshal json: %w", err)
 	}
-	return data
+	return data, nil
 }
 
+// Unmarshal 使用 jsoniter 解码
 func Unmarshal(data []byte, value interface{}) error {
-	err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, value)
-	if err != nil {
-		log.Printf("Unmarshal json:%v", err.Error())
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("unmarshal json: %w", err)
 	}
-	return err
+	return nil
 }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// fakeOffsets 分区 0 现有偏移量 [10, 20), 2024-05-01 之后的第一条消息是 15
+type fakeOffsets struct{}
+
+var may1 = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func (fakeOffsets) GetOffset(topic string, partition int32, at int64) (int64, error) {
+	if partition != 0 {
+		return 0, sarama.ErrUnknownTopicOrPartition
+	}
+	switch {
+	case at == sarama.OffsetOldest:
+		return 10, nil
+	case at == sarama.OffsetNewest:
+		return 20, nil
+	case at <= may1.UnixMilli():
+		return 15, nil
+	}
+	return -1, nil // 之后没有消息
+}
+
+func TestResolveStartOffset(t *testing.T) {
+	tests := []struct {
+		from      string
+		partition int32
+		want      int64
+		err       string
+	}{
+		{"earliest", 0, sarama.OffsetOldest, ""},
+		{"oldest", 0, sarama.OffsetOldest, ""},
+		{"latest", 0, sarama.OffsetNewest, ""},
+		{"newest", 0, sarama.OffsetNewest, ""},
+		{"", 0, sarama.OffsetNewest, ""},
+		{"10", 0, 10, ""},
+		{"20", 0, 20, ""}, // 等于高水位, 从下一条新消息开始
+		{"9", 0, 0, "out of range [10, 20]"},
+		{"21", 0, 0, "out of range [10, 20]"},
+		{"-1", 0, 0, "invalid offset"},
+		{"5", 1, 0, "get oldest offset of partition 1"},
+		{"2024-05-01T00:00:00Z", 0, 15, ""},
+		{"2024-04-30T08:00:00+08:00", 0, 15, ""},
+		{"2024-06-01T00:00:00Z", 0, sarama.OffsetNewest, ""},
+		{"2024-05-01T00:00:00Z", 1, 0, "get offset of partition 1"},
+		{"yesterday", 0, 0, "invalid start position"},
+	}
+	for _, tt := range tests {
+		got, err := resolveStartOffset(fakeOffsets{}, "orders", tt.partition, tt.from)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("resolveStartOffset(%d, %q) error = %v, want %q", tt.partition, tt.from, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("resolveStartOffset(%d, %q) = %d, %v, want %d", tt.partition, tt.from, got, err, tt.want)
+		}
+	}
+}
+
+func TestPrintMessage(t *testing.T) {
+	msg := &sarama.ConsumerMessage{
+		Topic: "orders", Partition: 1, Offset: 7, Timestamp: may1,
+		Key: []byte("k1"), Value: []byte("hello"),
+		Headers: []*sarama.RecordHeader{{Key: []byte("tenant"), Value: []byte("t1")}},
+	}
+	binary := &sarama.ConsumerMessage{Topic: "orders", Key: []byte("k"), Value: []byte{0xff, 0x00}, Timestamp: may1}
+	tests := []struct {
+		format string
+		msg    *sarama.ConsumerMessage
+		want   []string
+	}{
+		{"raw", msg, []string{"hello\n"}},
+		{"", msg, []string{"hello\n"}},
+		{"json", msg, []string{`"topic":"orders"`, `"partition":1`, `"offset":7`, `"key":"k1"`, `"value":"hello"`, `"headers":{"tenant":"t1"}`}},
+		{"json", binary, []string{`"key":"6b"`, `"value":"ff00"`, `"encoding":"hex"`}},
+		{"hex", msg, []string{"partition:1 offset:7", `key:"k1"`, "2024-05-01T00:00:00Z", "68 65 6c 6c 6f"}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := printMessage(&buf, tt.format, tt.msg); err != nil {
+			t.Errorf("format %q: %v", tt.format, err)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("format %q: output %q does not contain %q", tt.format, buf.String(), want)
+			}
+		}
+	}
+	if err := printMessage(&bytes.Buffer{}, "xml", msg); err == nil {
+		t.Fatal("unknown format should fail")
+	}
+}
+
+func TestPrintMessages(t *testing.T) {
+	send := func(values ...string) chan *sarama.ConsumerMessage {
+		ch := make(chan *sarama.ConsumerMessage, len(values))
+		for _, v := range values {
+			ch <- &sarama.ConsumerMessage{Value: []byte(v)}
+		}
+		return ch
+	}
+
+	// channel 关闭时输出全部消息
+	var buf bytes.Buffer
+	ch := send("a", "b", "c")
+	close(ch)
+	n, err := printMessages(context.Background(), bufio.NewWriter(&buf), "raw", ch, 0)
+	if err != nil || n != 3 || buf.String() != "a\nb\nc\n" {
+		t.Fatalf("got %d %q %v", n, buf.String(), err)
+	}
+
+	// 达到条数限制后返回, 剩余的不输出
+	buf.Reset()
+	n, err = printMessages(context.Background(), bufio.NewWriter(&buf), "raw", send("a", "b", "c"), 2)
+	if err != nil || n != 2 || buf.String() != "a\nb\n" {
+		t.Fatalf("limit: got %d %q %v", n, buf.String(), err)
+	}
+
+	// ctx 结束时返回
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	n, err = printMessages(ctx, bufio.NewWriter(&buf), "raw", make(chan *sarama.ConsumerMessage), 0)
+	if err != nil || n != 0 {
+		t.Fatalf("canceled: got %d %v", n, err)
+	}
+
+	// 输出失败时返回错误
+	n, err = printMessages(context.Background(), bufio.NewWriterSize(errWriter{}, 16), "raw", send("a"), 0)
+	if !errors.Is(err, errWrite) || n != 0 {
+		t.Fatalf("write error: got %d %v", n, err)
+	}
+}
+
+var errWrite = errors.New("disk full")
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, errWrite }
+
+func TestReadMessages(t *testing.T) {
+	tests := []struct {
+		input string
+		whole bool
+		want  []string
+	}{
+		{"a\nb\n\nc", false, []string{"a", "b", "c"}},
+		{"a\r\nb\r\n", false, []string{"a", "b"}},
+		{"", false, nil},
+		{"a\nb\n", true, []string{"a\nb\n"}},
+		{"", true, []string{""}},
+	}
+	for _, tt := range tests {
+		var got []string
+		err := readMessages(strings.NewReader(tt.input), tt.whole, func(value []byte) error {
+			got = append(got, string(value))
+			return nil
+		})
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("readMessages(%q, %v) = %q, %v, want %q", tt.input, tt.whole, got, err, tt.want)
+		}
+	}
+
+	// 发送失败时停止读取
+	calls := 0
+	err := readMessages(strings.NewReader("a\nb\n"), false, func([]byte) error {
+		calls++
+		return errWrite
+	})
+	if !errors.Is(err, errWrite) || calls != 1 {
+		t.Fatalf("got %v after %d calls", err, calls)
+	}
+
+	// 超过 1MB 的行报错
+	long := strings.Repeat("x", 2*1024*1024)
+	if err := readMessages(strings.NewReader(long), false, func([]byte) error { return nil }); err == nil {
+		t.Fatal("want error for too long line")
+	}
+}
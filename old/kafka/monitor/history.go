@@ -0,0 +1,96 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// 再均衡事件类型
+const (
+	EventSetup   = "setup"   // 成员拿到新的分区分配
+	EventCleanup = "cleanup" // 成员释放分区, 通常意味着即将再均衡
+)
+
+// RebalanceEvent 一次再均衡事件
+type RebalanceEvent struct {
+	Time       time.Time          `json:"time"`
+	Type       string             `json:"type"`
+	MemberID   string             `json:"memberId"`
+	Generation int32              `json:"generation"`
+	Claims     map[string][]int32 `json:"claims"`
+}
+
+// RebalanceRecorder 记录消费者组处理器 Setup/Cleanup 钩子产生的再均衡事件
+// 内存中保留最近 limit 条, 同时可以按行写入 json, 供 admin 命令事后查看
+type RebalanceRecorder struct {
+	mu     sync.Mutex
+	events []RebalanceEvent
+	limit  int
+	w      io.Writer
+	now    func() time.Time
+}
+
+// NewRebalanceRecorder 创建记录器, w 为 nil 时只保存在内存中
+func NewRebalanceRecorder(w io.Writer, limit int) *RebalanceRecorder {
+	return &RebalanceRecorder{limit: limit, w: w, now: time.Now}
+}
+
+// Setup 作为消费者组处理器的 Setup 钩子
+func (r *RebalanceRecorder) Setup(sess sarama.ConsumerGroupSession) {
+	r.record(EventSetup, sess)
+}
+
+// Cleanup 作为消费者组处理器的 Cleanup 钩子
+func (r *RebalanceRecorder) Cleanup(sess sarama.ConsumerGroupSession) {
+	r.record(EventCleanup, sess)
+}
+
+func (r *RebalanceRecorder) record(typ string, sess sarama.ConsumerGroupSession) {
+	event := RebalanceEvent{
+		Time:       r.now(),
+		Type:       typ,
+		MemberID:   sess.MemberID(),
+		Generation: sess.GenerationID(),
+		Claims:     sess.Claims(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	if r.limit > 0 && len(r.events) > r.limit {
+		r.events = r.events[len(r.events)-r.limit:]
+	}
+	if r.w != nil {
+		data, _ := json.Marshal(event)
+		if _, err := r.w.Write(append(data, '\n')); err != nil {
+			log.Println("[monitor] write rebalance history err:", err)
+		}
+	}
+}
+
+// Events 内存中保留的事件, 按发生顺序
+func (r *RebalanceRecorder) Events() []RebalanceEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RebalanceEvent(nil), r.events...)
+}
+
+// ReadHistory 读取 RebalanceRecorder 写入的事件, 忽略无法解析的行
+func ReadHistory(r io.Reader) ([]RebalanceEvent, error) {
+	var events []RebalanceEvent
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var event RebalanceEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
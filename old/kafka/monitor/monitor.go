@@ -0,0 +1,191 @@
+// Package monitor 消费者组的积压(lag)监控、分区分配查看和按时间重置偏移量
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrGroupActive 消费者组还有活跃成员, 不能重置偏移量
+var ErrGroupActive = errors.New("consumer group has active members")
+
+// Member 消费者组成员及其分配到的分区
+type Member struct {
+	MemberID   string             `json:"memberId"`
+	ClientID   string             `json:"clientId"`
+	ClientHost string             `json:"clientHost"`
+	Assignment map[string][]int32 `json:"assignment"`
+}
+
+// Cluster 监控需要的 kafka 查询, NewSaramaCluster 提供基于 sarama 的实现
+type Cluster interface {
+	// Partitions topic 的全部分区
+	Partitions(topic string) ([]int32, error)
+	// HighWatermark 分区下一条消息的偏移量
+	HighWatermark(topic string, partition int32) (int64, error)
+	// OffsetAt 分区中时间戳不早于 t 的第一条消息的偏移量, 没有这样的消息时返回 -1
+	OffsetAt(topic string, partition int32, t time.Time) (int64, error)
+	// CommittedOffsets 消费者组已提交的偏移量, 没有提交过的分区不出现
+	CommittedOffsets(group string) (map[string]map[int32]int64, error)
+	// Members 消费者组的状态和成员
+	Members(group string) (state string, members []Member, err error)
+	// CommitOffsets 以非成员身份为消费者组提交偏移量
+	CommitOffsets(group string, offsets map[string]map[int32]int64) error
+}
+
+// PartitionLag 一个分区的消费进度
+type PartitionLag struct {
+	Topic         string `json:"topic"`
+	Partition     int32  `json:"partition"`
+	Committed     int64  `json:"committed"` // -1 表示没有提交过
+	HighWatermark int64  `json:"highWatermark"`
+	Lag           int64  `json:"lag"` // 没有提交过时为 -1
+	MemberID      string `json:"memberId,omitempty"`
+	ClientID      string `json:"clientId,omitempty"`
+	ClientHost    string `json:"clientHost,omitempty"`
+}
+
+// GroupLag 消费者组的消费进度
+type GroupLag struct {
+	Group      string         `json:"group"`
+	State      string         `json:"state"`
+	Members    []Member       `json:"members"`
+	Partitions []PartitionLag `json:"partitions"`
+	TotalLag   int64          `json:"totalLag"`
+}
+
+// OffsetReset 一个分区的偏移量重置计划
+type OffsetReset struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	From      int64  `json:"from"` // -1 表示之前没有提交过
+	To        int64  `json:"to"`
+}
+
+// Inspector 消费者组查看工具
+type Inspector struct {
+	cluster Cluster
+}
+
+// NewInspector 创建查看工具
+func NewInspector(cluster Cluster) *Inspector {
+	return &Inspector{cluster: cluster}
+}
+
+// Lag 查询消费者组在 topics 上的消费进度, topics 为空时使用组内已提交偏移量和成员分配涉及的全部 topic
+func (i *Inspector) Lag(group string, topics ...string) (*GroupLag, error) {
+	state, members, err := i.cluster.Members(group)
+	if err != nil {
+		return nil, fmt.Errorf("describe group %s: %w", group, err)
+	}
+	committed, err := i.cluster.CommittedOffsets(group)
+	if err != nil {
+		return nil, fmt.Errorf("fetch offsets of group %s: %w", group, err)
+	}
+
+	// 分区 -> 成员
+	owners := make(map[string]map[int32]*Member)
+	for m := range members {
+		for topic, partitions := range members[m].Assignment {
+			if owners[topic] == nil {
+				owners[topic] = make(map[int32]*Member)
+			}
+			for _, p := range partitions {
+				owners[topic][p] = &members[m]
+			}
+		}
+	}
+
+	if len(topics) == 0 {
+		seen := make(map[string]bool)
+		for topic := range committed {
+			seen[topic] = true
+		}
+		for topic := range owners {
+			seen[topic] = true
+		}
+		for topic := range seen {
+			topics = append(topics, topic)
+		}
+	}
+	sort.Strings(topics)
+
+	report := &GroupLag{Group: group, State: state, Members: members}
+	for _, topic := range topics {
+		partitions, err := i.cluster.Partitions(topic)
+		if err != nil {
+			return nil, fmt.Errorf("partitions of %s: %w", topic, err)
+		}
+		sort.Slice(partitions, func(a, b int) bool { return partitions[a] < partitions[b] })
+
+		for _, p := range partitions {
+			hwm, err := i.cluster.HighWatermark(topic, p)
+			if err != nil {
+				return nil, fmt.Errorf("high watermark of %s/%d: %w", topic, p, err)
+			}
+			pl := PartitionLag{Topic: topic, Partition: p, Committed: -1, HighWatermark: hwm, Lag: -1}
+			if offset, ok := committed[topic][p]; ok && offset >= 0 {
+				pl.Committed = offset
+				pl.Lag = max(hwm-offset, 0)
+				report.TotalLag += pl.Lag
+			}
+			if m := owners[topic][p]; m != nil {
+				pl.MemberID, pl.ClientID, pl.ClientHost = m.MemberID, m.ClientID, m.ClientHost
+			}
+			report.Partitions = append(report.Partitions, pl)
+		}
+	}
+	return report, nil
+}
+
+// ResetOffsetsToTime 把消费者组在 topic 上的偏移量重置到时间 t 之后的第一条消息
+// t 之后没有消息的分区重置到最新位置; 只能在消费者组没有活跃成员时执行, dryRun 为 true 时只返回计划
+func (i *Inspector) ResetOffsetsToTime(group, topic string, t time.Time, dryRun bool) ([]OffsetReset, error) {
+	state, members, err := i.cluster.Members(group)
+	if err != nil {
+		return nil, fmt.Errorf("describe group %s: %w", group, err)
+	}
+	if len(members) > 0 {
+		return nil, fmt.Errorf("%w: %s is %s with %d members", ErrGroupActive, group, state, len(members))
+	}
+
+	committed, err := i.cluster.CommittedOffsets(group)
+	if err != nil {
+		return nil, fmt.Errorf("fetch offsets of group %s: %w", group, err)
+	}
+	partitions, err := i.cluster.Partitions(topic)
+	if err != nil {
+		return nil, fmt.Errorf("partitions of %s: %w", topic, err)
+	}
+	sort.Slice(partitions, func(a, b int) bool { return partitions[a] < partitions[b] })
+
+	var plan []OffsetReset
+	offsets := map[string]map[int32]int64{topic: {}}
+	for _, p := range partitions {
+		to, err := i.cluster.OffsetAt(topic, p, t)
+		if err != nil {
+			return nil, fmt.Errorf("offset of %s/%d at %s: %w", topic, p, t.Format(time.RFC3339), err)
+		}
+		if to < 0 {
+			if to, err = i.cluster.HighWatermark(topic, p); err != nil {
+				return nil, fmt.Errorf("high watermark of %s/%d: %w", topic, p, err)
+			}
+		}
+		from, ok := committed[topic][p]
+		if !ok {
+			from = -1
+		}
+		plan = append(plan, OffsetReset{Topic: topic, Partition: p, From: from, To: to})
+		offsets[topic][p] = to
+	}
+
+	if dryRun {
+		return plan, nil
+	}
+	if err := i.cluster.CommitOffsets(group, offsets); err != nil {
+		return nil, fmt.Errorf("commit offsets of group %s: %w", group, err)
+	}
+	return plan, nil
+}
@@ -0,0 +1,172 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// fakeCluster 内存中的集群, 每个分区的消息时间戳为 base+offset 秒
+type fakeCluster struct {
+	base      time.Time
+	hwm       map[string][]int64 // topic -> 每个分区的高水位
+	committed map[string]map[int32]int64
+	members   []Member
+	commits   []map[string]map[int32]int64
+}
+
+func (c *fakeCluster) Partitions(topic string) ([]int32, error) {
+	hwm, ok := c.hwm[topic]
+	if !ok {
+		return nil, sarama.ErrUnknownTopicOrPartition
+	}
+	ps := make([]int32, len(hwm))
+	for i := range hwm {
+		ps[i] = int32(i)
+	}
+	return ps, nil
+}
+
+func (c *fakeCluster) HighWatermark(topic string, partition int32) (int64, error) {
+	return c.hwm[topic][partition], nil
+}
+
+func (c *fakeCluster) OffsetAt(topic string, partition int32, t time.Time) (int64, error) {
+	offset := int64(t.Sub(c.base) / time.Second)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= c.hwm[topic][partition] {
+		return -1, nil
+	}
+	return offset, nil
+}
+
+func (c *fakeCluster) CommittedOffsets(group string) (map[string]map[int32]int64, error) {
+	return c.committed, nil
+}
+
+func (c *fakeCluster) Members(group string) (string, []Member, error) {
+	if len(c.members) == 0 {
+		return "Empty", nil, nil
+	}
+	return "Stable", c.members, nil
+}
+
+func (c *fakeCluster) CommitOffsets(group string, offsets map[string]map[int32]int64) error {
+	c.commits = append(c.commits, offsets)
+	return nil
+}
+
+func newFakeCluster() *fakeCluster {
+	return &fakeCluster{
+		base:      time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		hwm:       map[string][]int64{"orders": {10, 20, 5}},
+		committed: map[string]map[int32]int64{"orders": {0: 4, 1: 20}},
+	}
+}
+
+func TestLag(t *testing.T) {
+	c := newFakeCluster()
+	c.members = []Member{{MemberID: "m-1", ClientID: "c-1", ClientHost: "/10.0.0.1", Assignment: map[string][]int32{"orders": {0, 2}}}}
+
+	report, err := NewInspector(c).Lag("g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.State != "Stable" || report.TotalLag != 6 {
+		t.Fatalf("state %s total lag %d", report.State, report.TotalLag)
+	}
+
+	want := []PartitionLag{
+		{Topic: "orders", Partition: 0, Committed: 4, HighWatermark: 10, Lag: 6, MemberID: "m-1", ClientID: "c-1", ClientHost: "/10.0.0.1"},
+		{Topic: "orders", Partition: 1, Committed: 20, HighWatermark: 20, Lag: 0},
+		{Topic: "orders", Partition: 2, Committed: -1, HighWatermark: 5, Lag: -1, MemberID: "m-1", ClientID: "c-1", ClientHost: "/10.0.0.1"},
+	}
+	if len(report.Partitions) != len(want) {
+		t.Fatalf("got %d partitions", len(report.Partitions))
+	}
+	for i := range want {
+		if report.Partitions[i] != want[i] {
+			t.Errorf("partition %d: got %+v, want %+v", i, report.Partitions[i], want[i])
+		}
+	}
+
+	if _, err := NewInspector(c).Lag("g", "missing"); !errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
+		t.Fatalf("want unknown topic error, got %v", err)
+	}
+}
+
+func TestResetOffsetsToTime(t *testing.T) {
+	c := newFakeCluster()
+	inspector := NewInspector(c)
+	at := c.base.Add(8 * time.Second)
+
+	plan, err := inspector.ResetOffsetsToTime("g", "orders", at, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []OffsetReset{
+		{Topic: "orders", Partition: 0, From: 4, To: 8},
+		{Topic: "orders", Partition: 1, From: 20, To: 8},
+		{Topic: "orders", Partition: 2, From: -1, To: 5}, // t 之后没有消息, 重置到高水位
+	}
+	for i := range want {
+		if plan[i] != want[i] {
+			t.Errorf("partition %d: got %+v, want %+v", i, plan[i], want[i])
+		}
+	}
+	if len(c.commits) != 0 {
+		t.Fatal("dry run must not commit")
+	}
+
+	if _, err := inspector.ResetOffsetsToTime("g", "orders", at, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.commits) != 1 || c.commits[0]["orders"][0] != 8 || c.commits[0]["orders"][2] != 5 {
+		t.Fatalf("commits %v", c.commits)
+	}
+
+	c.members = []Member{{MemberID: "m-1"}}
+	if _, err := inspector.ResetOffsetsToTime("g", "orders", at, false); !errors.Is(err, ErrGroupActive) {
+		t.Fatalf("want ErrGroupActive, got %v", err)
+	}
+}
+
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+	generation int32
+	claims     map[string][]int32
+}
+
+func (s *fakeSession) MemberID() string           { return "member-1" }
+func (s *fakeSession) GenerationID() int32        { return s.generation }
+func (s *fakeSession) Claims() map[string][]int32 { return s.claims }
+func (s *fakeSession) Context() context.Context   { return context.Background() }
+
+func TestRebalanceRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRebalanceRecorder(&buf, 2)
+
+	r.Setup(&fakeSession{generation: 1, claims: map[string][]int32{"orders": {0, 1}}})
+	r.Cleanup(&fakeSession{generation: 1, claims: map[string][]int32{"orders": {0, 1}}})
+	r.Setup(&fakeSession{generation: 2, claims: map[string][]int32{"orders": {1}}})
+
+	events := r.Events()
+	if len(events) != 2 || events[0].Type != EventCleanup || events[1].Generation != 2 {
+		t.Fatalf("events %+v", events)
+	}
+
+	buf.WriteString("not json\n")
+	history, err := ReadHistory(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 || history[0].Type != EventSetup || claimsString(history[2].Claims) != "orders:1" {
+		t.Fatalf("history %+v", history)
+	}
+}
@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// WriteJSON 以缩进的 json 输出任意报告
+func WriteJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// WriteLagTable 以表格输出消费进度
+func WriteLagTable(w io.Writer, report *GroupLag) error {
+	fmt.Fprintf(w, "GROUP %s  STATE %s  MEMBERS %d  TOTAL LAG %d\n\n", report.Group, report.State, len(report.Members), report.TotalLag)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOPIC\tPARTITION\tCOMMITTED\tHIGH-WATERMARK\tLAG\tMEMBER\tHOST")
+	for _, p := range report.Partitions {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%s\t%s\t%s\n",
+			p.Topic, p.Partition, offsetString(p.Committed), p.HighWatermark, offsetString(p.Lag), orDash(p.MemberID), orDash(p.ClientHost))
+	}
+	return tw.Flush()
+}
+
+// WriteResetTable 以表格输出偏移量重置计划
+func WriteResetTable(w io.Writer, plan []OffsetReset) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOPIC\tPARTITION\tFROM\tTO")
+	for _, r := range plan {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\n", r.Topic, r.Partition, offsetString(r.From), r.To)
+	}
+	return tw.Flush()
+}
+
+// WriteHistoryTable 以表格输出再均衡历史
+func WriteHistoryTable(w io.Writer, events []RebalanceEvent) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tEVENT\tGENERATION\tMEMBER\tCLAIMS")
+	for _, e := range events {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", e.Time.Format(time.RFC3339), e.Type, e.Generation, e.MemberID, claimsString(e.Claims))
+	}
+	return tw.Flush()
+}
+
+func offsetString(offset int64) string {
+	if offset < 0 {
+		return "-"
+	}
+	return fmt.Sprint(offset)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// claimsString topic:0,1,2 格式的分区列表
+func claimsString(claims map[string][]int32) string {
+	topics := make([]string, 0, len(claims))
+	for topic := range claims {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	parts := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		ps := append([]int32(nil), claims[topic]...)
+		sort.Slice(ps, func(i, j int) bool { return ps[i] < ps[j] })
+		strs := make([]string, len(ps))
+		for i, p := range ps {
+			strs[i] = fmt.Sprint(p)
+		}
+		parts = append(parts, topic+":"+strings.Join(strs, ","))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, " ")
+}
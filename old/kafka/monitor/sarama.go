@@ -0,0 +1,122 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// saramaCluster 基于 sarama 客户端和集群管理接口实现 Cluster
+type saramaCluster struct {
+	client sarama.Client
+	admin  sarama.ClusterAdmin
+}
+
+// NewSaramaCluster 创建基于 sarama 的 Cluster, client 的 Version 至少为 V2_0_0_0
+// 返回的 Cluster 与 client 共用连接, 不再使用时关闭 client 即可
+func NewSaramaCluster(client sarama.Client) (Cluster, error) {
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		return nil, err
+	}
+	return &saramaCluster{client: client, admin: admin}, nil
+}
+
+func (c *saramaCluster) Partitions(topic string) ([]int32, error) {
+	return c.client.Partitions(topic)
+}
+
+func (c *saramaCluster) HighWatermark(topic string, partition int32) (int64, error) {
+	return c.client.GetOffset(topic, partition, sarama.OffsetNewest)
+}
+
+func (c *saramaCluster) OffsetAt(topic string, partition int32, t time.Time) (int64, error) {
+	return c.client.GetOffset(topic, partition, t.UnixMilli())
+}
+
+func (c *saramaCluster) CommittedOffsets(group string) (map[string]map[int32]int64, error) {
+	resp, err := c.admin.ListConsumerGroupOffsets(group, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Err != sarama.ErrNoError {
+		return nil, resp.Err
+	}
+	offsets := make(map[string]map[int32]int64, len(resp.Blocks))
+	for topic, blocks := range resp.Blocks {
+		for partition, block := range blocks {
+			if block.Err != sarama.ErrNoError {
+				return nil, fmt.Errorf("%s/%d: %w", topic, partition, block.Err)
+			}
+			if block.Offset < 0 {
+				continue
+			}
+			if offsets[topic] == nil {
+				offsets[topic] = make(map[int32]int64)
+			}
+			offsets[topic][partition] = block.Offset
+		}
+	}
+	return offsets, nil
+}
+
+func (c *saramaCluster) Members(group string) (string, []Member, error) {
+	groups, err := c.admin.DescribeConsumerGroups([]string{group})
+	if err != nil {
+		return "", nil, err
+	}
+	if len(groups) == 0 {
+		return "", nil, fmt.Errorf("group %s not found", group)
+	}
+	desc := groups[0]
+	if desc.Err != sarama.ErrNoError {
+		return "", nil, desc.Err
+	}
+
+	var members []Member
+	for id, m := range desc.Members {
+		member := Member{MemberID: id, ClientID: m.ClientId, ClientHost: m.ClientHost}
+		if assignment, err := m.GetMemberAssignment(); err == nil && assignment != nil {
+			member.Assignment = assignment.Topics
+		}
+		members = append(members, member)
+	}
+	return desc.State, members, nil
+}
+
+func (c *saramaCluster) CommitOffsets(group string, offsets map[string]map[int32]int64) error {
+	if err := c.client.RefreshCoordinator(group); err != nil {
+		return err
+	}
+	coordinator, err := c.client.Coordinator(group)
+	if err != nil {
+		return err
+	}
+
+	// 以非成员身份提交: generation 为 -1, 成员 id 为空, 只有消费者组为空时 broker 才会接受
+	req := &sarama.OffsetCommitRequest{
+		Version:                 2,
+		ConsumerGroup:           group,
+		ConsumerGroupGeneration: -1,
+		RetentionTime:           -1,
+	}
+	for topic, partitions := range offsets {
+		for partition, offset := range partitions {
+			req.AddBlock(topic, partition, offset, 0, "")
+		}
+	}
+
+	resp, err := coordinator.CommitOffset(req)
+	if err != nil {
+		return err
+	}
+	for topic, partitions := range resp.Errors {
+		for partition, kerr := range partitions {
+			if kerr != sarama.ErrNoError {
+				return fmt.Errorf("%s/%d: %w", topic, partition, kerr)
+			}
+		}
+	}
+	return nil
+}
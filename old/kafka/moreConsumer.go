@@ -5,33 +5,46 @@ import (
 	"context"
 	"fmt"
 	"github.com/IBM/sarama"
-	"github.com/pkg/errors"
+	"log"
 	"os"
 	"os/signal"
 	"sync"
+	"syscall"
+	groupconsumer "testGo/kafka/consumer"
+	"testGo/kafka/monitor"
 	"time"
 )
 
 func MoreConsumer() {
-	// 创建Kafka配置
-	config := sarama.NewConfig()
+	// 创建Kafka配置, 只提交处理成功或已转投重试/死信 topic 的消息
+	config := groupconsumer.NewConfig()
 	// config.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategySticky
 
-	config.Version = sarama.V2_0_0_0
-	config.Consumer.Offsets.Initial = sarama.OffsetNewest
-	config.Consumer.Return.Errors = false
-
-	// 定义topic
-	topic := []string{TestTopicName}
+	cfg := retryConfig()
 
 	// 定义消费者组的名称
-	consumerGroup := "your_consumer_group"
+	consumerGroup := groupName
 
-	// 创建Kafka消费者组
-	group, err := sarama.NewConsumerGroup(KafkaAddr, consumerGroup, config)
+	// 转投重试/死信消息的生产者
+	producer, err := sarama.NewSyncProducer(KafkaAddr, config)
 	if err != nil {
-		panic(err)
+		log.Println("create producer err:", err)
+		return
 	}
+	defer producer.Close()
+
+	// 再均衡历史追加写入 -history 文件, 可以用 -s admin history 查看
+	historyFile, err := os.OpenFile(historyPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		log.Println("open rebalance history err:", err)
+		return
+	}
+	defer historyFile.Close()
+	recorder := monitor.NewRebalanceRecorder(historyFile, 100)
+
+	// 捕获中断信号以进行优雅关闭
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// 定义消费者组成员的数量
 	numConsumers := 3
@@ -45,57 +58,49 @@ func MoreConsumer() {
 		go func(i int) {
 			fmt.Println("启动多个消费者：", i)
 			defer wg.Done()
-			for {
-				// 每个goroutine都处理分配给它的分区
-				err := group.Consume(context.Background(), topic, &consumerGroupHandler{I: i})
-				if err != nil {
-					panic(errors.Wrap(err, "Error consuming from Kafka"))
-				}
+
+			// 创建Kafka消费者组
+			group, err := sarama.NewConsumerGroup(KafkaAddr, consumerGroup, config)
+			if err != nil {
+				log.Println("create consumer group err:", err)
+				return
+			}
+
+			// 每个成员都处理分配给它的分区, 直到收到中断信号
+			handler := groupconsumer.NewGroupHandler(cfg, producer, func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+				return handleMessage(ctx, i, msg)
+			})
+			handler.OnSetup = recorder.Setup
+			handler.OnCleanup = recorder.Cleanup
+			if err := groupconsumer.Run(ctx, group, handler); err != nil {
+				log.Println("consumer stopped with err:", err)
 			}
 		}(i)
 	}
 
-	// 捕获中断信号以进行优雅关闭
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
-
-	select {
-	case <-interrupt:
-		fmt.Println("Received interrupt, shutting down...")
-		if err := group.Close(); err != nil {
-			panic(err)
-		}
-	}
+	<-ctx.Done()
+	fmt.Println("Received interrupt, shutting down...")
 
-	// 等待所有消费者goroutine完成
+	// 等待所有消费者goroutine处理完正在处理的消息
 	wg.Wait()
 }
 
-// consumerGroupHandler 实现了sarama.ConsumerGroupHandler接口，用于处理从Kafka接收到的消息
-type consumerGroupHandler struct {
-	I int
-}
-
-// Setup 在分配给成员的分区发生变化时调用
-func (h *consumerGroupHandler) Setup(sess sarama.ConsumerGroupSession) error {
-	fmt.Println(fmt.Sprintf("Consumer group member is set up, i: %v, memberID: %v", h.I, sess.MemberID()))
-	return nil
-}
-
-// Cleanup 在成员停止消费分区前调用
-func (h *consumerGroupHandler) Cleanup(sess sarama.ConsumerGroupSession) error {
-	fmt.Println(fmt.Sprintf("Consumer group member is cleaned up, i: %v, memberID: %v", h.I, sess.MemberID()))
-	return nil
+// retryConfig 失败的消息依次进入 <topic>.retry.1 / <topic>.retry.2 / <topic>.retry.3, 最后进入 <topic>.dlq;
+// admin lag 按同样的配置查看重试和死信 topic 的积压
+func retryConfig() groupconsumer.Config {
+	return groupconsumer.Config{
+		Topic:          TestTopicName,
+		RetryDelays:    []time.Duration{5 * time.Second, 30 * time.Second, 5 * time.Minute},
+		HandlerTimeout: 30 * time.Second,
+	}
 }
 
-// ConsumeClaim 当分区被分配给该成员时调用
-func (h *consumerGroupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	for msg := range claim.Messages() {
-		// fmt.Printf("Message claimed: memberID=%s, topic=%s, partition=%d, offset=%d, key=%s, value=%s\n", sess.MemberID(), msg.Topic, msg.Partition, msg.Offset, msg.Key, msg.Value)
-
-		fmt.Printf("memberID=%s, offset=%d, key=%s, value=%s\n", sess.MemberID(), msg.Offset, msg.Key, msg.Value)
-		time.Sleep(2 * time.Second)
-		sess.MarkMessage(msg, "")
+// handleMessage 业务处理, 返回错误的消息会进入重试 topic
+func handleMessage(ctx context.Context, i int, msg *sarama.ConsumerMessage) error {
+	fmt.Printf("consumer=%d, topic=%s, partition=%d, offset=%d, attempt=%d, key=%s, value=%s\n",
+		i, msg.Topic, msg.Partition, msg.Offset, groupconsumer.Attempt(msg), msg.Key, msg.Value)
+	if len(msg.Value) == 0 {
+		return fmt.Errorf("empty message")
 	}
 	return nil
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"github.com/IBM/sarama"
+	"google.golang.org/protobuf/encoding/protojson"
+	"log"
+	"os"
+	"testGo/grpc/order"
+	"testGo/kafka/codec"
+)
+
+// orderProducer 从标准输入读取 json 格式的订单, 每行一个, 以 protobuf 编码发送
+// 发送前把 order.Order 的 schema 注册到 -registry 指定的文件, 与已发布的 schema 不兼容时拒绝发送
+func orderProducer() {
+	log.Printf("kafka: 订单生产者")
+
+	config, err := newProducerConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := sarama.NewSyncProducer(KafkaAddr, config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	registry := codec.NewFileRegistry(registryPath)
+	producer, err := codec.NewProducer[*order.Order](client, registry, TestTopicName, codec.NewProtoCodec(func() *order.Order { return &order.Order{} }))
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("schema id: %d", producer.SchemaID())
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		odr := &order.Order{}
+		if err := protojson.Unmarshal(scanner.Bytes(), odr); err != nil {
+			log.Println("invalid order:", err)
+			continue
+		}
+		partition, offset, err := producer.Send(odr.Id, odr)
+		if err != nil {
+			log.Println("send order err:", err)
+			return
+		}
+		log.Printf("order %s partition:%d offset:%d\n", odr.Id, partition, offset)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Println("read stdin err:", err)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/IBM/sarama"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+// jsonMessage 以 json 格式输出的消息
+type jsonMessage struct {
+	Topic     string            `json:"topic"`
+	Partition int32             `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Timestamp time.Time         `json:"timestamp"`
+	Key       string            `json:"key,omitempty"`
+	Value     string            `json:"value"`
+	Encoding  string            `json:"encoding,omitempty"` // 非 utf8 内容使用十六进制编码, 此时为 hex
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+// printMessage 按 -format 参数输出消息
+func printMessage(w io.Writer, format string, msg *sarama.ConsumerMessage) error {
+	switch format {
+	case "raw", "":
+		_, err := fmt.Fprintf(w, "%s\n", msg.Value)
+		return err
+	case "json":
+		out := jsonMessage{
+			Topic:     msg.Topic,
+			Partition: msg.Partition,
+			Offset:    msg.Offset,
+			Timestamp: msg.Timestamp,
+			Key:       string(msg.Key),
+			Value:     string(msg.Value),
+		}
+		if !utf8.Valid(msg.Key) || !utf8.Valid(msg.Value) {
+			out.Key = hex.EncodeToString(msg.Key)
+			out.Value = hex.EncodeToString(msg.Value)
+			out.Encoding = "hex"
+		}
+		if len(msg.Headers) > 0 {
+			out.Headers = make(map[string]string, len(msg.Headers))
+			for _, h := range msg.Headers {
+				out.Headers[string(h.Key)] = string(h.Value)
+			}
+		}
+		data, err := Marshal(out)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case "hex":
+		_, err := fmt.Fprintf(w, "partition:%d offset:%d key:%q time:%s\n%s\n",
+			msg.Partition, msg.Offset, msg.Key, msg.Timestamp.Format(time.RFC3339), hex.Dump(msg.Value))
+		return err
+	default:
+		return fmt.Errorf("unknown output format %q, want raw|json|hex", format)
+	}
+}
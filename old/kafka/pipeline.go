@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"github.com/IBM/sarama"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"testGo/kafka/txn"
+	"time"
+)
+
+// pipeline 精确一次的 读取-处理-写入 示例: 把 -topic 的消息转成大写写入 <topic>.upper
+// 输出和消费偏移量在同一个事务里提交, 再均衡或者重启都不会产生重复的输出
+func pipeline() {
+	log.Printf("kafka: 事务管道")
+
+	outTopic := TestTopicName + ".upper"
+	group, err := sarama.NewConsumerGroup(KafkaAddr, groupName, txn.NewConsumerConfig())
+	if err != nil {
+		log.Println("create consumer group err:", err)
+		return
+	}
+	defer group.Close()
+
+	go func() {
+		for err := range group.Errors() {
+			log.Println("consumer group err:", err)
+		}
+	}()
+
+	processor := txn.NewProcessor(groupName, txn.NewSaramaProducerFactory(KafkaAddr, groupName), func(ctx context.Context, msg *sarama.ConsumerMessage) ([]*sarama.ProducerMessage, error) {
+		out := &sarama.ProducerMessage{Topic: outTopic, Value: sarama.StringEncoder(strings.ToUpper(string(msg.Value)))}
+		if msg.Key != nil {
+			out.Key = sarama.ByteEncoder(msg.Key)
+		}
+		return []*sarama.ProducerMessage{out}, nil
+	})
+
+	// 捕获中断信号以进行优雅关闭
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for ctx.Err() == nil {
+		// 处理失败时会话结束, 重新加入后从事务提交的偏移量继续
+		if err := group.Consume(ctx, []string{TestTopicName}, processor); err != nil {
+			log.Println("consume err:", err)
+			time.Sleep(time.Second)
+		}
+	}
+}
@@ -1,18 +1,36 @@
 package main
 
 import (
-	"fmt"
+	"bufio"
 	"github.com/IBM/sarama"
+	"io"
 	"log"
+	"os"
 	"time"
 )
 
 // 获取生产者
+// 从 -input 读取消息内容, 默认每行一条, 按 -acks/-compression/-idempotent 配置发送
 func producer() {
 	log.Printf("kafka: 生产者")
 
+	config, err := newProducerConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var input io.Reader = os.Stdin
+	if inputPath != "-" {
+		f, err := os.Open(inputPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		input = f
+	}
+
 	// 获取生产者接口，当为外网主机时修改localhost为主机IP地址
-	producer, err := sarama.NewSyncProducer(KafkaAddr, nil)
+	producer, err := sarama.NewSyncProducer(KafkaAddr, config)
 	if err != nil {
 		log.Fatal(err)
 		return
@@ -21,32 +39,62 @@ func producer() {
 	defer func() {
 		// 关闭生产者
 		if err = producer.Close(); err != nil {
-			log.Fatal(err)
-			return
+			log.Println("close producer err:", err)
 		}
 	}()
 
-	for i := 0; i < 100; i++ {
-
+	var sent int
+	err = readMessages(input, wholeInput, func(value []byte) error {
 		// 定义需要发送的消息
 		msg := &sarama.ProducerMessage{
 			Topic:     TestTopicName,
-			Key:       sarama.StringEncoder("test_kafka_key"),
-			Value:     sarama.StringEncoder(fmt.Sprintf("这里是kafka消息-%v", i)),
+			Value:     sarama.ByteEncoder(value),
 			Timestamp: time.Now(),
 		}
-
-		log.Printf("生产者-待发送的消息：%s", string(Marshal(msg)))
+		if messageKey != "" {
+			msg.Key = sarama.StringEncoder(messageKey)
+		}
 
 		// 发送消息，并获取该消息的分片、偏移量
-		partition, offset, _ := producer.SendMessage(msg)
+		partition, offset, err := producer.SendMessage(msg)
 		if err != nil {
-			log.Fatal(err)
-			return
+			return err
 		}
-
+		sent++
 		log.Printf("partition:%d offset:%d\n", partition, offset)
+		return nil
+	})
+	if err != nil {
+		log.Printf("send msg failed after %d messages, err:%v", sent, err)
+		return
 	}
+	log.Printf("sent %d messages to %s", sent, TestTopicName)
+}
+
+// readMessages 读取待发送的消息, whole 为 true 时整个输入作为一条消息, 否则每个非空行一条
+func readMessages(r io.Reader, whole bool, send func([]byte) error) error {
+	if whole {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return send(data)
+	}
+
+	scanner := bufio.NewScanner(r)
+	// 单条消息最大与 kafka 默认的 message.max.bytes 一致
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		// Scanner 会复用缓冲区, 发送前需要复制一份
+		if err := send(append([]byte(nil), line...)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
 }
 
 func producerNew() {
@@ -72,6 +120,7 @@ func producerNew() {
 	client, err := sarama.NewSyncProducer(KafkaAddr, config)
 	if err != nil {
 		log.Printf("producer closed,err:%v", err)
+		return
 	}
 	defer client.Close()
 
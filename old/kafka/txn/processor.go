@@ -0,0 +1,180 @@
+// Package txn 基于 kafka 事务的 读取-处理-写入 管道, 提供精确一次(exactly-once)语义
+//
+// 输出消息和输入消息的消费偏移量在同一个生产者事务里提交: 事务提交则两者都生效,
+// 事务中止或进程崩溃则两者都不生效, 再均衡之后从上次提交的偏移量重新处理, 下游只会读到一份结果
+package txn
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Producer 事务生产者, sarama.SyncProducer 实现了该接口
+type Producer interface {
+	BeginTxn() error
+	CommitTxn() error
+	AbortTxn() error
+	SendMessages(msgs []*sarama.ProducerMessage) error
+	AddMessageToTxn(msg *sarama.ConsumerMessage, groupId string, metadata *string) error
+	Close() error
+}
+
+// ProducerFactory 为分区创建事务生产者
+// 同一个输入分区必须使用固定的 transactional.id, 分区被重新分配后, 新的生产者会隔离(fence)旧成员的未完成事务
+type ProducerFactory func(topic string, partition int32) (Producer, error)
+
+// Transform 把一条输入消息转换成零到多条输出消息, 返回错误会中止整批消息所在的事务
+type Transform func(ctx context.Context, msg *sarama.ConsumerMessage) ([]*sarama.ProducerMessage, error)
+
+// NewProducerConfig 事务生产者的配置: 幂等、acks=all、每个连接只有一个在途请求
+func NewProducerConfig(transactionalID string) *sarama.Config {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_0_0
+	config.Producer.Idempotent = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Return.Successes = true
+	config.Producer.Retry.Max = 10
+	config.Producer.Transaction.ID = transactionalID
+	config.Net.MaxOpenRequests = 1
+	return config
+}
+
+// NewConsumerConfig 事务管道消费者组的配置
+// 只读取已提交事务的消息, 并关闭自动提交, 偏移量只通过生产者事务提交
+func NewConsumerConfig() *sarama.Config {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_0_0
+	config.Consumer.IsolationLevel = sarama.ReadCommitted
+	config.Consumer.Offsets.AutoCommit.Enable = false
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	config.Consumer.Return.Errors = true
+	return config
+}
+
+// NewSaramaProducerFactory 创建 sarama 事务生产者, transactional.id 为 <prefix>-<topic>-<partition>
+func NewSaramaProducerFactory(addrs []string, prefix string) ProducerFactory {
+	return func(topic string, partition int32) (Producer, error) {
+		return sarama.NewSyncProducer(addrs, NewProducerConfig(fmt.Sprintf("%s-%s-%d", prefix, topic, partition)))
+	}
+}
+
+// Processor 实现了 sarama.ConsumerGroupHandler, 每批输入消息在一个事务里完成 处理-写入-提交偏移量
+type Processor struct {
+	groupID     string
+	newProducer ProducerFactory
+	transform   Transform
+
+	// BatchSize 每个事务最多处理的消息数
+	BatchSize int
+	// FlushInterval 消息不足 BatchSize 时最多等待多久提交事务
+	FlushInterval time.Duration
+}
+
+// NewProcessor 创建事务处理器, groupID 必须与消费者组名称一致, 偏移量会提交到这个组
+func NewProcessor(groupID string, newProducer ProducerFactory, transform Transform) *Processor {
+	return &Processor{
+		groupID:       groupID,
+		newProducer:   newProducer,
+		transform:     transform,
+		BatchSize:     100,
+		FlushInterval: 100 * time.Millisecond,
+	}
+}
+
+// Setup 在分配给成员的分区发生变化时调用
+func (p *Processor) Setup(sess sarama.ConsumerGroupSession) error {
+	log.Printf("[txn] member %s generation %d claims %v", sess.MemberID(), sess.GenerationID(), sess.Claims())
+	return nil
+}
+
+// Cleanup 在成员停止消费分区前调用, 偏移量已经随事务提交, 这里不需要再提交
+func (p *Processor) Cleanup(sess sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim 按批处理分区中的消息
+// 任何一步失败都会中止事务并返回错误, sarama 随之结束本次会话, 重新加入后从事务提交的偏移量继续
+func (p *Processor) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	producer, err := p.newProducer(claim.Topic(), claim.Partition())
+	if err != nil {
+		return fmt.Errorf("create producer for %s/%d: %w", claim.Topic(), claim.Partition(), err)
+	}
+	defer producer.Close()
+
+	batch := make([]*sarama.ConsumerMessage, 0, p.BatchSize)
+	ticker := time.NewTicker(p.FlushInterval)
+	defer ticker.Stop()
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := p.processBatch(sess.Context(), producer, batch)
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		select {
+		case <-sess.Context().Done():
+			// 已经取到但没有提交的消息直接丢弃, 下次会从已提交的偏移量重新消费
+			return nil
+		case <-ticker.C:
+			if err := flush(); err != nil {
+				return err
+			}
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return flush()
+			}
+			batch = append(batch, msg)
+			if len(batch) >= p.BatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// processBatch 在一个事务里写入整批消息的输出并提交最后一条消息的偏移量
+func (p *Processor) processBatch(ctx context.Context, producer Producer, batch []*sarama.ConsumerMessage) (err error) {
+	if err := producer.BeginTxn(); err != nil {
+		return fmt.Errorf("begin txn: %w", err)
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if aerr := producer.AbortTxn(); aerr != nil {
+			log.Printf("[txn] abort txn err: %v", aerr)
+		}
+	}()
+
+	for _, msg := range batch {
+		out, err := p.transform(ctx, msg)
+		if err != nil {
+			return fmt.Errorf("transform %s/%d/%d: %w", msg.Topic, msg.Partition, msg.Offset, err)
+		}
+		if len(out) == 0 {
+			continue
+		}
+		if err := producer.SendMessages(out); err != nil {
+			return fmt.Errorf("send output of %s/%d/%d: %w", msg.Topic, msg.Partition, msg.Offset, err)
+		}
+	}
+
+	// 同一分区内偏移量递增, 提交最后一条消息的偏移量就覆盖了整批
+	last := batch[len(batch)-1]
+	if err := producer.AddMessageToTxn(last, p.groupID, nil); err != nil {
+		return fmt.Errorf("add offset %d to txn: %w", last.Offset, err)
+	}
+	if err := producer.CommitTxn(); err != nil {
+		return fmt.Errorf("commit txn: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package txn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// 测试用的内存 kafka: 一个输入分区、一个输出 topic 和消费者组提交的偏移量
+// 事务提交时输出消息和偏移量一起生效, 中止或者生产者被新实例隔离时都不生效
+type cluster struct {
+	mu        sync.Mutex
+	input     []*sarama.ConsumerMessage
+	output    []string // 已提交事务的输出
+	committed int64    // 消费者组提交的下一个偏移量
+
+	// failures 在指定步骤注入一次失败, 键为步骤名, 值为第几次执行该步骤时失败
+	failures map[string]int
+	calls    map[string]int
+
+	active *fakeProducer
+}
+
+var errInjected = errors.New("injected failure")
+
+func newCluster(n int) *cluster {
+	c := &cluster{failures: map[string]int{}, calls: map[string]int{}}
+	for i := 0; i < n; i++ {
+		c.input = append(c.input, &sarama.ConsumerMessage{Topic: "in", Offset: int64(i), Value: []byte(fmt.Sprintf("m%d", i))})
+	}
+	return c
+}
+
+// step 记录一次步骤执行, 命中注入点时返回错误
+func (c *cluster) step(name string) error {
+	c.calls[name]++
+	if c.failures[name] == c.calls[name] {
+		return fmt.Errorf("%s: %w", name, errInjected)
+	}
+	return nil
+}
+
+func (c *cluster) newProducer(topic string, partition int32) (Producer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 相同 transactional.id 的新生产者会隔离旧生产者, 旧的未完成事务被丢弃
+	if c.active != nil {
+		c.active.fenced = true
+	}
+	c.active = &fakeProducer{cluster: c}
+	return c.active, nil
+}
+
+type fakeProducer struct {
+	cluster *cluster
+	fenced  bool
+	inTxn   bool
+	pending []string
+	offset  int64
+}
+
+func (p *fakeProducer) BeginTxn() error {
+	p.cluster.mu.Lock()
+	defer p.cluster.mu.Unlock()
+	if p.fenced {
+		return sarama.ErrProducerFenced
+	}
+	p.inTxn, p.pending, p.offset = true, nil, -1
+	return p.cluster.step("begin")
+}
+
+func (p *fakeProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	p.cluster.mu.Lock()
+	defer p.cluster.mu.Unlock()
+	if err := p.cluster.step("send"); err != nil {
+		return err
+	}
+	for _, m := range msgs {
+		v, _ := m.Value.Encode()
+		p.pending = append(p.pending, string(v))
+	}
+	return nil
+}
+
+func (p *fakeProducer) AddMessageToTxn(msg *sarama.ConsumerMessage, groupId string, metadata *string) error {
+	p.cluster.mu.Lock()
+	defer p.cluster.mu.Unlock()
+	if err := p.cluster.step("offsets"); err != nil {
+		return err
+	}
+	p.offset = msg.Offset + 1
+	return nil
+}
+
+func (p *fakeProducer) CommitTxn() error {
+	p.cluster.mu.Lock()
+	defer p.cluster.mu.Unlock()
+	if p.fenced {
+		return sarama.ErrProducerFenced
+	}
+	if err := p.cluster.step("commit"); err != nil {
+		return err
+	}
+	p.cluster.output = append(p.cluster.output, p.pending...)
+	if p.offset >= 0 {
+		p.cluster.committed = p.offset
+	}
+	p.inTxn, p.pending = false, nil
+	return nil
+}
+
+func (p *fakeProducer) AbortTxn() error {
+	p.cluster.mu.Lock()
+	defer p.cluster.mu.Unlock()
+	p.inTxn, p.pending = false, nil
+	return nil
+}
+
+func (p *fakeProducer) Close() error { return nil }
+
+type fakeSession struct{ ctx context.Context }
+
+func (s *fakeSession) Claims() map[string][]int32                                        { return nil }
+func (s *fakeSession) MemberID() string                                                  { return "member-1" }
+func (s *fakeSession) GenerationID() int32                                               { return 1 }
+func (s *fakeSession) MarkOffset(topic string, partition int32, offset int64, m string)  {}
+func (s *fakeSession) Commit()                                                           {}
+func (s *fakeSession) ResetOffset(topic string, partition int32, offset int64, m string) {}
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string)          {}
+func (s *fakeSession) Context() context.Context                                          { return s.ctx }
+
+type fakeClaim struct{ messages chan *sarama.ConsumerMessage }
+
+func (c *fakeClaim) Topic() string                            { return "in" }
+func (c *fakeClaim) Partition() int32                         { return 0 }
+func (c *fakeClaim) InitialOffset() int64                     { return 0 }
+func (c *fakeClaim) HighWaterMarkOffset() int64               { return 0 }
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+// run 模拟消费者组不断再均衡: 每次会话从已提交的偏移量开始消费, 直到全部输入处理完
+func (c *cluster) run(t *testing.T, p *Processor) {
+	t.Helper()
+	for session := 0; ; session++ {
+		if session > 20 {
+			t.Fatal("pipeline did not converge")
+		}
+		c.mu.Lock()
+		if c.committed >= int64(len(c.input)) {
+			c.mu.Unlock()
+			return
+		}
+		rest := c.input[c.committed:]
+		c.mu.Unlock()
+
+		claim := &fakeClaim{messages: make(chan *sarama.ConsumerMessage, len(rest))}
+		for _, m := range rest {
+			claim.messages <- m
+		}
+		close(claim.messages)
+
+		if err := p.ConsumeClaim(&fakeSession{ctx: context.Background()}, claim); err != nil && !errors.Is(err, errInjected) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func upper(ctx context.Context, msg *sarama.ConsumerMessage) ([]*sarama.ProducerMessage, error) {
+	return []*sarama.ProducerMessage{{Topic: "out", Value: sarama.StringEncoder("out-" + string(msg.Value))}}, nil
+}
+
+func TestProcessorNoDuplicatesWithInjectedFailures(t *testing.T) {
+	steps := []string{"begin", "send", "offsets", "commit"}
+	for _, step := range steps {
+		for _, nth := range []int{1, 2, 3} {
+			t.Run(fmt.Sprintf("%s#%d", step, nth), func(t *testing.T) {
+				c := newCluster(10)
+				c.failures[step] = nth
+
+				p := NewProcessor("group", c.newProducer, upper)
+				p.BatchSize = 3
+				p.FlushInterval = time.Hour
+				c.run(t, p)
+
+				if len(c.output) != len(c.input) {
+					t.Fatalf("got %d outputs, want %d: %v", len(c.output), len(c.input), c.output)
+				}
+				for i, v := range c.output {
+					if want := fmt.Sprintf("out-m%d", i); v != want {
+						t.Fatalf("output[%d] = %q, want %q", i, v, want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestProcessorTransformErrorAbortsBatch(t *testing.T) {
+	c := newCluster(5)
+	failed := false
+	p := NewProcessor("group", c.newProducer, func(ctx context.Context, msg *sarama.ConsumerMessage) ([]*sarama.ProducerMessage, error) {
+		if msg.Offset == 3 && !failed {
+			failed = true
+			return nil, errInjected
+		}
+		return upper(ctx, msg)
+	})
+	p.BatchSize = 2
+	p.FlushInterval = time.Hour
+	c.run(t, p)
+
+	want := []string{"out-m0", "out-m1", "out-m2", "out-m3", "out-m4"}
+	if fmt.Sprint(c.output) != fmt.Sprint(want) {
+		t.Fatalf("got %v, want %v", c.output, want)
+	}
+}
@@ -33,7 +33,7 @@ type TLSConfig struct {
 	EnableTLS          bool   `yaml:"enableTLS"`
 	CACrt              string `yaml:"caCrt"`
 	ClientCrt          string `yaml:"clientCrt"`
-	ClientKey          string `yaml:"clientKey"`
+	ClientKey          string `yaml:"clientKey" secret:"true"`
 	ClientKeyPwd       string `yaml:"clientKeyPwd"`
 	InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
 }
@@ -0,0 +1,182 @@
+package confwatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// RedactedValue 敏感字段脱敏后的值
+const RedactedValue = "******"
+
+// Change 一个字段的变更, 敏感字段的 Old 和 New 已经脱敏
+type Change struct {
+	Path   string // 例如 Kafka.Brokers[0]
+	Old    string
+	New    string
+	Secret bool
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, c.Old, c.New)
+}
+
+// Diff 逐字段比较两个配置, 按路径排序返回发生变化的叶子字段
+//
+// 字段带有 `secret:"true"` 标签, 或字段名包含 password/pwd/secret/token 时视为敏感字段
+func Diff[T any](old, new *T) []Change {
+	before := flatten(reflect.ValueOf(old))
+	after := flatten(reflect.ValueOf(new))
+
+	var changes []Change
+	for path, a := range after {
+		b, ok := before[path]
+		if ok && b.value == a.value {
+			continue
+		}
+		changes = append(changes, newChange(path, b, a))
+	}
+	for path, b := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, newChange(path, b, leaf{}))
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+func newChange(path string, old, new leaf) Change {
+	c := Change{Path: path, Old: old.value, New: new.value, Secret: old.secret || new.secret}
+	if c.Secret {
+		c.Old, c.New = redact(c.Old), redact(c.New)
+	}
+	return c
+}
+
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return RedactedValue
+}
+
+// Redacted 把配置转换为 json, 敏感字段替换为 ******, 用于打印日志
+func Redacted(v any) string {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || (rv.Kind() == reflect.Pointer && rv.IsNil()) {
+		return "null"
+	}
+	data, err := json.Marshal(redactValue(rv))
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	return string(data)
+}
+
+func redactValue(v reflect.Value) any {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		out := make(map[string]any, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if isSecret(f) {
+				out[f.Name] = redact(fmt.Sprint(v.Field(i).Interface()))
+				continue
+			}
+			out[f.Name] = redactValue(v.Field(i))
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = redactValue(v.Index(i))
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = redactValue(iter.Value())
+		}
+		return out
+	default:
+		return v.Interface()
+	}
+}
+
+var secretWords = []string{"password", "pwd", "secret", "token"}
+
+func isSecret(f reflect.StructField) bool {
+	if tag, ok := f.Tag.Lookup("secret"); ok {
+		return tag == "true"
+	}
+	name := strings.ToLower(f.Name)
+	for _, w := range secretWords {
+		if strings.Contains(name, w) {
+			return true
+		}
+	}
+	return false
+}
+
+type leaf struct {
+	value  string
+	secret bool
+}
+
+// flatten 把配置展开为 路径 -> 叶子值
+func flatten(v reflect.Value) map[string]leaf {
+	out := make(map[string]leaf)
+	var walk func(path string, v reflect.Value, secret bool)
+	walk = func(path string, v reflect.Value, secret bool) {
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				f := v.Type().Field(i)
+				if !f.IsExported() {
+					continue
+				}
+				walk(join(path, f.Name), v.Field(i), secret || isSecret(f))
+			}
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < v.Len(); i++ {
+				walk(fmt.Sprintf("%s[%d]", path, i), v.Index(i), secret)
+			}
+		case reflect.Map:
+			iter := v.MapRange()
+			for iter.Next() {
+				walk(fmt.Sprintf("%s[%v]", path, iter.Key().Interface()), iter.Value(), secret)
+			}
+		default:
+			out[path] = leaf{value: fmt.Sprint(v.Interface()), secret: secret}
+		}
+	}
+	if v.IsValid() {
+		walk("", v, false)
+	}
+	return out
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,63 @@
+package confwatch
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileProvider 从本地目录读取配置, key 为目录下的文件名; 用于本地开发和测试, 不需要启动配置中心
+type FileProvider struct {
+	Dir string
+	// Interval 检查文件变化的间隔, 默认 1 秒
+	Interval time.Duration
+}
+
+// NewFileProvider 创建本地文件配置来源
+func NewFileProvider(dir string) *FileProvider {
+	return &FileProvider{Dir: dir, Interval: time.Second}
+}
+
+func (p *FileProvider) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filepath.Join(p.Dir, key))
+}
+
+// Watch 定期比较文件内容, 文件被删除或暂时无法读取时保持上一次的内容
+func (p *FileProvider) Watch(key string, onChange func(content []byte)) (func(), error) {
+	path := filepath.Join(p.Dir, key)
+	last, _ := os.ReadFile(path)
+	interval := p.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			data, err := os.ReadFile(path)
+			if err != nil || bytes.Equal(data, last) {
+				continue
+			}
+			last = data
+			onChange(data)
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}, nil
+}
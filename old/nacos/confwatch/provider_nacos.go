@@ -0,0 +1,59 @@
+package confwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nacos-group/nacos-sdk-go/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+)
+
+// NacosProvider 从 nacos 配置中心读取配置, key 为 dataId
+type NacosProvider struct {
+	client config_client.IConfigClient
+	group  string
+}
+
+// NewNacosProvider group 为空时使用 DEFAULT_GROUP
+func NewNacosProvider(client config_client.IConfigClient, group string) *NacosProvider {
+	if group == "" {
+		group = "DEFAULT_GROUP"
+	}
+	return &NacosProvider{client: client, group: group}
+}
+
+// Get nacos sdk 的 GetConfig 不支持 ctx, ctx 只在调用前检查一次
+func (p *NacosProvider) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	content, err := p.client.GetConfig(vo.ConfigParam{DataId: key, Group: p.group})
+	if err != nil {
+		return nil, fmt.Errorf("nacos get %s/%s: %w", p.group, key, err)
+	}
+	if content == "" {
+		return nil, errors.New("nacos get " + p.group + "/" + key + ": empty config")
+	}
+	return []byte(content), nil
+}
+
+// Watch 配置在 nacos 上被删除时不通知, 保持上一次的配置
+func (p *NacosProvider) Watch(key string, onChange func(content []byte)) (func(), error) {
+	err := p.client.ListenConfig(vo.ConfigParam{
+		DataId: key,
+		Group:  p.group,
+		OnChange: func(namespace, group, dataId, data string) {
+			if data == "" {
+				return
+			}
+			onChange([]byte(data))
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("nacos listen %s/%s: %w", p.group, key, err)
+	}
+	return func() {
+		_ = p.client.CancelListenConfig(vo.ConfigParam{DataId: key, Group: p.group})
+	}, nil
+}
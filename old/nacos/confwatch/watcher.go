@@ -0,0 +1,246 @@
+// Package confwatch 类型化的配置监听: 配置变化时先解码、校验, 通过后原子替换快照并通知订阅者
+//
+// 读取方通过 Load 拿到不可变的快照, 不会和更新产生竞争; 每次成功加载的原始内容保存到本地缓存文件,
+// 配置中心不可用或内容无法通过校验时使用最近一次成功的缓存启动
+package confwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ErrNoConfig 配置中心和本地缓存都没有可用的配置
+var ErrNoConfig = errors.New("confwatch: no usable config")
+
+// Provider 配置来源, NacosProvider 和 FileProvider 实现了该接口
+type Provider interface {
+	// Get 读取 key 当前的内容
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Watch 监听 key 的变化, 返回停止监听的函数; onChange 可能在任意 goroutine 中调用
+	Watch(key string, onChange func(content []byte)) (stop func(), err error)
+}
+
+// 快照的来源
+const (
+	SourceProvider = "provider"
+	SourceCache    = "cache"
+)
+
+// Snapshot 某一时刻的配置, 不要修改 Value
+type Snapshot[T any] struct {
+	Value    *T
+	Raw      []byte
+	Version  int // 每次成功加载加一
+	Source   string
+	LoadedAt time.Time
+}
+
+// Options 监听参数
+type Options[T any] struct {
+	// Decode 解码配置内容, 默认 yaml
+	Decode func(data []byte, v *T) error
+	// Validate 校验解码后的配置, 返回错误时丢弃本次变更
+	Validate func(v *T) error
+	// CacheFile 最近一次成功加载的内容保存的文件, 为空时不缓存
+	CacheFile string
+	Logger    *log.Logger
+}
+
+// Watcher 监听一个配置 key
+type Watcher[T any] struct {
+	provider Provider
+	key      string
+	opts     Options[T]
+
+	current atomic.Pointer[Snapshot[T]]
+
+	mu      sync.Mutex // 串行化加载, 保证订阅者按版本顺序收到通知
+	nextID  int
+	subs    map[int]func(old, new *Snapshot[T], changes []Change)
+	stop    func()
+	stopped bool
+}
+
+// New 创建监听, 调用 Start 之后才有配置
+func New[T any](provider Provider, key string, opts Options[T]) *Watcher[T] {
+	if opts.Decode == nil {
+		opts.Decode = func(data []byte, v *T) error { return yaml.Unmarshal(data, v) }
+	}
+	if opts.Logger == nil {
+		opts.Logger = log.Default()
+	}
+	return &Watcher[T]{
+		provider: provider,
+		key:      key,
+		opts:     opts,
+		subs:     make(map[int]func(old, new *Snapshot[T], changes []Change)),
+	}
+}
+
+// Start 加载配置并开始监听
+// 配置中心读取失败或内容无效时回退到本地缓存; 两者都不可用时返回 ErrNoConfig
+func (w *Watcher[T]) Start(ctx context.Context) error {
+	data, err := w.provider.Get(ctx, w.key)
+	if err == nil {
+		err = w.apply(data, SourceProvider)
+	}
+	if err != nil {
+		w.opts.Logger.Printf("[confwatch] load %s from provider: %v, fallback to cache", w.key, err)
+		if cerr := w.loadCache(); cerr != nil {
+			return fmt.Errorf("%w: %s: provider: %v; cache: %v", ErrNoConfig, w.key, err, cerr)
+		}
+	}
+
+	stop, err := w.provider.Watch(w.key, func(content []byte) {
+		if err := w.apply(content, SourceProvider); err != nil {
+			w.opts.Logger.Printf("[confwatch] reject %s update: %v", w.key, err)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("watch %s: %w", w.key, err)
+	}
+	w.mu.Lock()
+	w.stop = stop
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *Watcher[T]) loadCache() error {
+	if w.opts.CacheFile == "" {
+		return errors.New("cache disabled")
+	}
+	data, err := os.ReadFile(w.opts.CacheFile)
+	if err != nil {
+		return err
+	}
+	return w.apply(data, SourceCache)
+}
+
+// apply 解码、校验, 通过后替换快照、写缓存并通知订阅者
+func (w *Watcher[T]) apply(data []byte, source string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return nil
+	}
+
+	old := w.current.Load()
+	if old != nil && source == SourceProvider && string(old.Raw) == string(data) && old.Source == source {
+		return nil
+	}
+
+	v := new(T)
+	if err := w.opts.Decode(data, v); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	if w.opts.Validate != nil {
+		if err := w.opts.Validate(v); err != nil {
+			return fmt.Errorf("validate: %w", err)
+		}
+	}
+
+	next := &Snapshot[T]{Value: v, Raw: data, Version: 1, Source: source, LoadedAt: time.Now()}
+	var changes []Change
+	if old != nil {
+		next.Version = old.Version + 1
+		changes = Diff(old.Value, v)
+	} else {
+		changes = Diff(new(T), v)
+	}
+	w.current.Store(next)
+
+	if source == SourceProvider && w.opts.CacheFile != "" {
+		if err := writeFileAtomic(w.opts.CacheFile, data); err != nil {
+			w.opts.Logger.Printf("[confwatch] write cache %s: %v", w.opts.CacheFile, err)
+		}
+	}
+	if old != nil && len(changes) > 0 {
+		w.opts.Logger.Printf("[confwatch] %s updated to version %d: %s", w.key, next.Version, FormatChanges(changes))
+	}
+	for _, fn := range w.subs {
+		fn(old, next, changes)
+	}
+	return nil
+}
+
+// Load 当前配置, Start 成功之前为 nil
+func (w *Watcher[T]) Load() *T {
+	if s := w.current.Load(); s != nil {
+		return s.Value
+	}
+	return nil
+}
+
+// Snapshot 当前快照, Start 成功之前为 nil
+func (w *Watcher[T]) Snapshot() *Snapshot[T] {
+	return w.current.Load()
+}
+
+// Subscribe 订阅配置变更, old 在第一次加载时为 nil
+// fn 在加载配置的 goroutine 中同步调用, 不要在 fn 中调用 Subscribe 或 Close
+func (w *Watcher[T]) Subscribe(fn func(old, new *Snapshot[T], changes []Change)) (unsubscribe func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	id := w.nextID
+	w.nextID++
+	w.subs[id] = fn
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.subs, id)
+	}
+}
+
+// Close 停止监听, 之后配置不再变化
+func (w *Watcher[T]) Close() {
+	w.mu.Lock()
+	stop := w.stop
+	w.stopped = true
+	w.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// String 脱敏后的当前配置, 可以安全地打印到日志
+func (w *Watcher[T]) String() string {
+	return Redacted(w.Load())
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// FormatChanges 把变更格式化为一行, 敏感字段已经脱敏
+func FormatChanges(changes []Change) string {
+	parts := make([]string, len(changes))
+	for i, c := range changes {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,229 @@
+package confwatch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+)
+
+type tlsConfig struct {
+	Enable    bool   `yaml:"enable"`
+	ClientKey string `yaml:"clientKey" secret:"true"`
+}
+
+type appConfig struct {
+	Address  []string  `yaml:"address"`
+	Username string    `yaml:"username"`
+	Password string    `yaml:"password"`
+	Workers  int       `yaml:"workers"`
+	TLS      tlsConfig `yaml:"tls"`
+}
+
+func validate(c *appConfig) error {
+	if len(c.Address) == 0 {
+		return errors.New("address is required")
+	}
+	return nil
+}
+
+var quiet = log.New(io.Discard, "", 0)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitFor 等待 cond 成立, 超时后失败
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFileProviderHotReload(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "app.yml"), "address: [a:9092]\npassword: p1\nworkers: 1\n")
+
+	provider := &FileProvider{Dir: dir, Interval: 10 * time.Millisecond}
+	w := New(provider, "app.yml", Options[appConfig]{Validate: validate, Logger: quiet})
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	first := w.Load()
+	if first.Workers != 1 || first.Address[0] != "a:9092" {
+		t.Fatalf("got %+v", first)
+	}
+
+	var mu sync.Mutex
+	var got [][]Change
+	unsubscribe := w.Subscribe(func(old, new *Snapshot[appConfig], changes []Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, changes)
+	})
+	defer unsubscribe()
+
+	// 无法通过校验的变更被丢弃, 继续使用原来的配置
+	writeFile(t, filepath.Join(dir, "app.yml"), "address: []\nworkers: 9\n")
+	time.Sleep(50 * time.Millisecond)
+	if w.Load() != first || w.Snapshot().Version != 1 {
+		t.Fatalf("invalid config applied: %+v", w.Load())
+	}
+
+	writeFile(t, filepath.Join(dir, "app.yml"), "address: [a:9092, b:9092]\npassword: p2\nworkers: 2\n")
+	waitFor(t, "reload", func() bool { return w.Snapshot().Version == 2 })
+
+	if first.Workers != 1 {
+		t.Fatal("old snapshot was modified")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 1 {
+		t.Fatalf("got %d notifications, want 1", len(got))
+	}
+	want := []Change{
+		{Path: "Address[1]", Old: "", New: "b:9092"},
+		{Path: "Password", Old: RedactedValue, New: RedactedValue, Secret: true},
+		{Path: "Workers", Old: "1", New: "2"},
+	}
+	if len(got[0]) != len(want) {
+		t.Fatalf("got changes %v, want %v", got[0], want)
+	}
+	for i := range want {
+		if got[0][i] != want[i] {
+			t.Fatalf("change %d: got %+v, want %+v", i, got[0][i], want[i])
+		}
+	}
+}
+
+// fakeNacos 只实现 provider 用到的方法
+type fakeNacos struct {
+	config_client.IConfigClient
+	mu       sync.Mutex
+	content  string
+	err      error
+	onChange func(namespace, group, dataId, data string)
+}
+
+func (f *fakeNacos) GetConfig(param vo.ConfigParam) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.content, f.err
+}
+
+func (f *fakeNacos) ListenConfig(param vo.ConfigParam) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onChange = param.OnChange
+	return nil
+}
+
+func (f *fakeNacos) CancelListenConfig(param vo.ConfigParam) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onChange = nil
+	return nil
+}
+
+func (f *fakeNacos) publish(data string) {
+	f.mu.Lock()
+	fn := f.onChange
+	f.mu.Unlock()
+	if fn != nil {
+		fn("", "DEFAULT_GROUP", "app.yml", data)
+	}
+}
+
+func TestNacosFallbackToCache(t *testing.T) {
+	cache := filepath.Join(t.TempDir(), "cache", "app.yml")
+	nacos := &fakeNacos{content: "address: [a:9092]\nworkers: 3\n"}
+	opts := Options[appConfig]{Validate: validate, CacheFile: cache, Logger: quiet}
+
+	w := New[appConfig](NewNacosProvider(nacos, ""), "app.yml", opts)
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	nacos.publish("address: [b:9092]\nworkers: 4\n")
+	if w.Load().Workers != 4 {
+		t.Fatalf("got %+v", w.Load())
+	}
+	w.Close()
+	nacos.publish("address: [c:9092]\nworkers: 5\n")
+	if w.Load().Workers != 4 {
+		t.Fatal("update applied after Close")
+	}
+
+	// 配置中心不可用, 使用最近一次成功的缓存
+	nacos.err = errors.New("connection refused")
+	w = New[appConfig](NewNacosProvider(nacos, ""), "app.yml", opts)
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s := w.Snapshot(); s.Source != SourceCache || s.Value.Workers != 4 {
+		t.Fatalf("got %+v from %s", s.Value, s.Source)
+	}
+	// 配置中心恢复后的推送正常生效
+	nacos.publish("address: [d:9092]\nworkers: 6\n")
+	if s := w.Snapshot(); s.Source != SourceProvider || s.Value.Workers != 6 {
+		t.Fatalf("got %+v from %s", s.Value, s.Source)
+	}
+	w.Close()
+
+	// 配置中心返回无效配置时同样回退到缓存
+	nacos.err = nil
+	nacos.content = "address: []\n"
+	w = New[appConfig](NewNacosProvider(nacos, ""), "app.yml", opts)
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if w.Load().Workers != 6 {
+		t.Fatalf("got %+v", w.Load())
+	}
+	w.Close()
+
+	os.Remove(cache)
+	w = New[appConfig](NewNacosProvider(nacos, ""), "app.yml", opts)
+	if err := w.Start(context.Background()); !errors.Is(err, ErrNoConfig) {
+		t.Fatalf("want ErrNoConfig, got %v", err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	c := &appConfig{Address: []string{"a"}, Username: "u", Password: "hunter2", TLS: tlsConfig{ClientKey: "-----BEGIN"}}
+	s := Redacted(c)
+	if strings.Contains(s, "hunter2") || strings.Contains(s, "BEGIN") {
+		t.Fatalf("secret leaked: %s", s)
+	}
+	if !strings.Contains(s, `"Username":"u"`) || !strings.Contains(s, `"Password":"******"`) {
+		t.Fatalf("got %s", s)
+	}
+	if Redacted((*appConfig)(nil)) != "null" {
+		t.Fatal("nil config")
+	}
+
+	changes := Diff(&appConfig{}, c)
+	for _, ch := range changes {
+		if strings.Contains(ch.String(), "hunter2") || strings.Contains(ch.String(), "BEGIN") {
+			t.Fatalf("secret leaked in change %s", ch)
+		}
+	}
+}
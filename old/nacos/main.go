@@ -1,48 +1,73 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
-	"time"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"testGo/nacos/confwatch"
+)
+
+var (
+	addr      = flag.String("addr", "127.0.0.1", "nacos 地址")
+	port      = flag.Uint64("port", 8848, "nacos 端口")
+	namespace = flag.String("namespace", "", "命名空间ID")
+	group     = flag.String("group", "DEFAULT_GROUP", "配置分组")
+	cacheDir  = flag.String("cache", "./tmp/cache", "缓存目录, nacos 不可用时从这里读取最近一次成功的配置")
 )
 
 func main() {
-	// NaCosConfig = NaCosConfigStruct{
-	// 	IpAddr:      "127.0.0.1",
-	// 	Port:        8848,
-	// 	NamespaceId: "9db1dfc0-4ed2-4aa5-a5b5-67af919b971a",
-	// 	TimeoutMs:   5000,
-	// 	LogDir:      "./tmp/log",
-	// 	CacheDir:    "./tmp/cache",
-	// 	LogLevel:    "debug",
-	// }
+	flag.Parse()
 	NaCosConfig = NaCosConfigStruct{
-		IpAddr:      "http://hw-test-nacos.aidyd.com",
-		Port:        8848,
-		NamespaceId: "a3f10455-9f7a-4e6f-bd14-95bc1aa23587",
+		IpAddr:      *addr,
+		Port:        *port,
+		Username:    os.Getenv("NACOS_USERNAME"),
+		Password:    os.Getenv("NACOS_PASSWORD"),
+		NamespaceId: *namespace,
 		TimeoutMs:   5000,
 		LogDir:      "./tmp/log",
-		CacheDir:    "./tmp/cache",
+		CacheDir:    *cacheDir,
 		LogLevel:    "debug",
 	}
-	InitNaCosClient()
-
-	kafkaConfig := &Kafka{}
-	GetConfig("kafka.yml", "DEFAULT_GROUP", kafkaConfig)
+	if err := InitNaCosClient(); err != nil {
+		log.Fatal(err)
+	}
 
-	redisConfig := &Redis{}
-	GetConfig("redis.yml", "DEFAULT_GROUP", redisConfig)
+	kafkaConfig, err := WatchConfig[Kafka]("kafka.yml", *group, func(k *Kafka) error {
+		if len(k.Address) == 0 {
+			return errors.New("kafka address is required")
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer kafkaConfig.Close()
 
-	for {
-		fmt.Println("kafkaConfig Test的值为：", kafkaConfig.Test)
-		time.Sleep(5 * time.Second)
+	redisConfig, err := WatchConfig[Redis]("redis.yml", *group, func(r *Redis) error {
+		if len(r.Address) == 0 {
+			return errors.New("redis address is required")
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
+	defer redisConfig.Close()
 
-	ch := make(chan int)
-	ch <- 1
-}
+	kafkaConfig.Subscribe(func(old, new *confwatch.Snapshot[Kafka], changes []confwatch.Change) {
+		for _, c := range changes {
+			if c.Path == "Test" {
+				log.Println("kafkaConfig Test的值为：", new.Value.Test)
+			}
+		}
+	})
+	log.Println("kafkaConfig Test的值为：", kafkaConfig.Load().Test)
 
-func StructToJsonString(data any) string {
-	jsonData, _ := json.Marshal(data)
-	return string(jsonData)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	<-sig
 }
@@ -1,18 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"path/filepath"
+
+	"testGo/nacos/confwatch"
+
 	naCosClient "github.com/nacos-group/nacos-sdk-go/clients"
 	"github.com/nacos-group/nacos-sdk-go/clients/config_client"
 	naCosConstant "github.com/nacos-group/nacos-sdk-go/common/constant"
-	"github.com/nacos-group/nacos-sdk-go/vo"
-	"gopkg.in/yaml.v2"
 )
 
 var naCosConfigClient config_client.IConfigClient
 var NaCosConfig NaCosConfigStruct
 
-func InitNaCosClient() {
+func InitNaCosClient() error {
 	var err error
 	naCosConfigClient, err = naCosClient.CreateConfigClient(map[string]interface{}{
 		"serverConfigs": []naCosConstant.ServerConfig{{
@@ -23,43 +27,31 @@ func InitNaCosClient() {
 			NamespaceId:         NaCosConfig.NamespaceId,
 			TimeoutMs:           NaCosConfig.TimeoutMs,
 			NotLoadCacheAtStart: true,
+			Username:            NaCosConfig.Username,
+			Password:            NaCosConfig.Password,
 			LogDir:              NaCosConfig.LogDir,
 			CacheDir:            NaCosConfig.CacheDir,
 			LogLevel:            NaCosConfig.LogLevel,
 		},
 	})
-
 	if err != nil {
-		fmt.Println(err.Error())
+		return fmt.Errorf("create nacos config client: %w", err)
 	}
+	return nil
 }
 
-func GetConfig(dataId string, group string, config any) {
-	configContent, err := naCosConfigClient.GetConfig(vo.ConfigParam{
-		DataId: dataId,
-		Group:  group,
+// WatchConfig 加载并监听 dataId, 最近一次成功的配置缓存在 CacheDir/last-good 下, nacos 不可用时从缓存启动
+func WatchConfig[T any](dataId, group string, validate func(*T) error) (*confwatch.Watcher[T], error) {
+	w := confwatch.New(confwatch.NewNacosProvider(naCosConfigClient, group), dataId, confwatch.Options[T]{
+		Validate:  validate,
+		CacheFile: filepath.Join(NaCosConfig.CacheDir, "last-good", dataId),
 	})
-
-	UnmarshalConfig(dataId, configContent, config)
-
-	if err != nil {
-		fmt.Println(err.Error())
+	if err := w.Start(context.Background()); err != nil {
+		return nil, err
 	}
-
-	err = naCosConfigClient.ListenConfig(vo.ConfigParam{
-		DataId: dataId,
-		Group:  group,
-		OnChange: func(namespace, group, dataId, configContent string) {
-			UnmarshalConfig(dataId, configContent, config)
-		},
+	w.Subscribe(func(old, new *confwatch.Snapshot[T], changes []confwatch.Change) {
+		log.Printf("load %s config version %d from %s: %s", dataId, new.Version, new.Source, confwatch.Redacted(new.Value))
 	})
-}
-
-func UnmarshalConfig(dataId, in string, out any) {
-	err := yaml.Unmarshal([]byte(in), out)
-	if err != nil {
-		fmt.Println("\nerr:", err)
-	}
-
-	fmt.Printf("\nload %s config:\n%+v\n", dataId, StructToJsonString(out))
+	log.Printf("load %s config from %s: %s", dataId, w.Snapshot().Source, w)
+	return w, nil
 }
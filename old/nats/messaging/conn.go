@@ -0,0 +1,200 @@
+// Package messaging 基于 nats.go 的消息客户端: 重连退避与回调、SIGTERM 时优雅 drain、
+// 带截止时间的类型化请求-响应、队列组 worker 以及 JetStream 持久消费者
+package messaging
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+// ErrDrainTimeout drain 超过 DrainTimeout 仍未完成
+var ErrDrainTimeout = errors.New("nats: drain timeout")
+
+// Options 连接参数, 零值字段使用 DefaultOptions 中的默认值
+type Options struct {
+	URL              string
+	Name             string        // 连接名称, 在服务端监控中可见
+	MaxReconnects    int           // 最大重连次数, -1 表示一直重连
+	ReconnectWait    time.Duration // 第一次重连前的等待时间, 之后每次翻倍
+	MaxReconnectWait time.Duration // 重连等待时间上限
+	ConnectTimeout   time.Duration
+	RequestTimeout   time.Duration // 请求没有设置截止时间时使用
+	DrainTimeout     time.Duration
+	// RetryOnFailedConnect 第一次连接失败时也按重连策略在后台重试, Connect 不返回错误
+	RetryOnFailedConnect bool
+
+	OnDisconnect func(err error)                 // 连接断开, err 可能为 nil
+	OnReconnect  func(url string)                // 重连成功
+	OnClosed     func()                          // 连接关闭, 不会再重连
+	OnError      func(subject string, err error) // 异步错误, 例如订阅处理太慢
+}
+
+// DefaultOptions 默认连接参数
+func DefaultOptions(url string) Options {
+	return Options{
+		URL:              url,
+		MaxReconnects:    -1,
+		ReconnectWait:    100 * time.Millisecond,
+		MaxReconnectWait: 5 * time.Second,
+		ConnectTimeout:   2 * time.Second,
+		RequestTimeout:   3 * time.Second,
+		DrainTimeout:     30 * time.Second,
+	}
+}
+
+func (o Options) withDefaults() Options {
+	def := DefaultOptions(o.URL)
+	if o.URL == "" {
+		o.URL = nats.DefaultURL
+	}
+	if o.MaxReconnects == 0 {
+		o.MaxReconnects = def.MaxReconnects
+	}
+	if o.ReconnectWait <= 0 {
+		o.ReconnectWait = def.ReconnectWait
+	}
+	if o.MaxReconnectWait <= 0 {
+		o.MaxReconnectWait = def.MaxReconnectWait
+	}
+	if o.ConnectTimeout <= 0 {
+		o.ConnectTimeout = def.ConnectTimeout
+	}
+	if o.RequestTimeout <= 0 {
+		o.RequestTimeout = def.RequestTimeout
+	}
+	if o.DrainTimeout <= 0 {
+		o.DrainTimeout = def.DrainTimeout
+	}
+	return o
+}
+
+// ReconnectDelay 第 attempts 次重连前的等待时间: 指数退避, 加上最多 20% 的随机抖动
+func (o Options) ReconnectDelay(attempts int) time.Duration {
+	delay := o.ReconnectWait
+	for i := 1; i < attempts && delay < o.MaxReconnectWait; i++ {
+		delay *= 2
+	}
+	delay = min(delay, o.MaxReconnectWait)
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+// Conn nats 连接, 可以直接使用 *nats.Conn 的全部方法
+type Conn struct {
+	*nats.Conn
+	opts   Options
+	closed chan struct{}
+}
+
+// Connect 连接 nats 服务器
+func Connect(opts Options) (*Conn, error) {
+	opts = opts.withDefaults()
+	c := &Conn{opts: opts, closed: make(chan struct{})}
+
+	nc, err := nats.Connect(opts.URL,
+		nats.Name(opts.Name),
+		nats.MaxReconnects(opts.MaxReconnects),
+		nats.CustomReconnectDelay(opts.ReconnectDelay),
+		nats.Timeout(opts.ConnectTimeout),
+		nats.DrainTimeout(opts.DrainTimeout),
+		nats.RetryOnFailedConnect(opts.RetryOnFailedConnect),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if opts.OnDisconnect != nil {
+				opts.OnDisconnect(err)
+			} else {
+				log.Println("[nats] disconnected:", err)
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			if opts.OnReconnect != nil {
+				opts.OnReconnect(nc.ConnectedUrl())
+			} else {
+				log.Println("[nats] reconnected to", nc.ConnectedUrl())
+			}
+		}),
+		nats.ClosedHandler(func(*nats.Conn) {
+			close(c.closed)
+			if opts.OnClosed != nil {
+				opts.OnClosed()
+			}
+		}),
+		nats.ErrorHandler(func(_ *nats.Conn, sub *nats.Subscription, err error) {
+			subject := ""
+			if sub != nil {
+				subject = sub.Subject
+			}
+			if opts.OnError != nil {
+				opts.OnError(subject, err)
+			} else {
+				log.Printf("[nats] async error on %q: %v", subject, err)
+			}
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	c.Conn = nc
+	return c, nil
+}
+
+// Closed 连接关闭后关闭的 channel
+func (c *Conn) Closed() <-chan struct{} {
+	return c.closed
+}
+
+// Drain 停止接收新消息, 处理完已收到的消息并刷新待发送的消息后关闭连接, 阻塞直到连接关闭
+func (c *Conn) Drain() error {
+	if c.IsClosed() {
+		return nil
+	}
+	if err := c.Conn.Drain(); err != nil {
+		return err
+	}
+	select {
+	case <-c.closed:
+		return nil
+	case <-time.After(c.opts.DrainTimeout + time.Second):
+		return ErrDrainTimeout
+	}
+}
+
+// DrainOnSignal 阻塞直到收到信号(默认 SIGINT/SIGTERM)或 ctx 结束, 然后 drain 连接
+// 连接因为重连次数用完等原因自己关闭时直接返回 nats.ErrConnectionClosed
+func (c *Conn) DrainOnSignal(ctx context.Context, sigs ...os.Signal) error {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ctx, stop := signal.NotifyContext(ctx, sigs...)
+	defer stop()
+
+	select {
+	case <-ctx.Done():
+		return c.Drain()
+	case <-c.closed:
+		return nats.ErrConnectionClosed
+	}
+}
+
+// QueueWorkers 在队列组 queue 中启动 n 个订阅, 同一条消息只会交给组内的一个 worker
+// 每个订阅的回调串行执行, n 个订阅之间并发
+func (c *Conn) QueueWorkers(subject, queue string, n int, handler nats.MsgHandler) ([]*nats.Subscription, error) {
+	subs := make([]*nats.Subscription, 0, n)
+	for i := 0; i < n; i++ {
+		sub, err := c.QueueSubscribe(subject, queue, handler)
+		if err != nil {
+			for _, s := range subs {
+				_ = s.Unsubscribe()
+			}
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
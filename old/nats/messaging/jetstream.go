@@ -0,0 +1,156 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// ErrTerm 处理函数返回包装了 ErrTerm 的错误时消息被 term, 不再重新投递
+var ErrTerm = errors.New("terminate message")
+
+// Term 把 err 标记为不可重试
+func Term(err error) error {
+	return fmt.Errorf("%w: %v", ErrTerm, err)
+}
+
+// JSHandler 处理 JetStream 消息
+// 返回 nil 时 ack; 返回 Term(err) 时 term; 其他错误按 Backoff 延迟后 nak, 由服务端重新投递
+type JSHandler func(ctx context.Context, msg jetstream.Msg) error
+
+// DurableConfig JetStream 持久消费者配置
+type DurableConfig struct {
+	Stream        string
+	Subjects      []string // 流不存在时用这些 subject 创建
+	Durable       string   // 持久消费者名称, 重启后从上次 ack 的位置继续; 为空时使用临时消费者, 返回时删除
+	FilterSubject string
+	AckWait       time.Duration   // 超过这个时间没有 ack 服务端会重新投递, 处理中会定期发送 in-progress 续期
+	MaxDeliver    int             // 最多投递次数, 0 表示不限制
+	Backoff       []time.Duration // 第 n 次投递失败后 nak 的延迟, 超出长度时使用最后一个
+	MaxAckPending int             // 同时在处理中的消息数上限
+}
+
+// ephemeralInactive 临时消费者没人拉取多久后由服务端删除
+const ephemeralInactive = time.Minute
+
+// NakDelay 第 delivered 次投递失败后 nak 的延迟
+func (cfg DurableConfig) NakDelay(delivered uint64) time.Duration {
+	if len(cfg.Backoff) == 0 || delivered == 0 {
+		return 0
+	}
+	i := min(int(delivered)-1, len(cfg.Backoff)-1)
+	return cfg.Backoff[i]
+}
+
+// EnsureStream 创建或更新流
+func (c *Conn) EnsureStream(ctx context.Context, name string, subjects ...string) (jetstream.JetStream, error) {
+	js, err := jetstream.New(c.Conn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{Name: name, Subjects: subjects}); err != nil {
+		return nil, fmt.Errorf("create stream %s: %w", name, err)
+	}
+	return js, nil
+}
+
+// ConsumeDurable 以持久消费者消费流中的消息, 阻塞直到 ctx 结束; cfg.Durable 为空时用临时消费者, 返回前删除
+func (c *Conn) ConsumeDurable(ctx context.Context, cfg DurableConfig, handler JSHandler) error {
+	if cfg.AckWait <= 0 {
+		cfg.AckWait = 30 * time.Second
+	}
+	js, err := c.EnsureStream(ctx, cfg.Stream, cfg.Subjects...)
+	if err != nil {
+		return err
+	}
+	ccfg := jetstream.ConsumerConfig{
+		Durable:       cfg.Durable,
+		FilterSubject: cfg.FilterSubject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		DeliverPolicy: jetstream.DeliverAllPolicy,
+		AckWait:       cfg.AckWait,
+		MaxDeliver:    cfg.MaxDeliver,
+		MaxAckPending: cfg.MaxAckPending,
+	}
+	if cfg.Durable == "" {
+		// 进程崩溃来不及删除时, 服务端在没人拉取一段时间后自动清理
+		ccfg.InactiveThreshold = ephemeralInactive
+	}
+	cons, err := js.CreateOrUpdateConsumer(ctx, cfg.Stream, ccfg)
+	if err != nil {
+		return fmt.Errorf("create consumer %s: %w", cfg.Durable, err)
+	}
+	if cfg.Durable == "" {
+		cfg.Durable = cons.CachedInfo().Name
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := js.DeleteConsumer(ctx, cfg.Stream, cfg.Durable); err != nil && !errors.Is(err, jetstream.ErrConsumerNotFound) {
+				log.Printf("[nats] delete consumer %s err: %v", cfg.Durable, err)
+			}
+		}()
+	}
+
+	cc, err := cons.Consume(func(msg jetstream.Msg) {
+		if err := settle(msg, cfg, callJSHandler(ctx, cfg.AckWait, handler, msg)); err != nil {
+			log.Printf("[nats] %s: settle err: %v", msg.Subject(), err)
+		}
+	}, jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+		log.Printf("[nats] consumer %s err: %v", cfg.Durable, err)
+	}))
+	if err != nil {
+		return fmt.Errorf("consume %s: %w", cfg.Durable, err)
+	}
+
+	<-ctx.Done()
+	cc.Drain()
+	return nil
+}
+
+// callJSHandler 调用处理函数, 处理期间每半个 AckWait 发送一次 in-progress, panic 视为普通错误
+func callJSHandler(ctx context.Context, ackWait time.Duration, handler JSHandler, msg jetstream.Msg) (err error) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(ackWait / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				_ = msg.InProgress()
+			}
+		}
+	}()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return handler(context.WithoutCancel(ctx), msg)
+}
+
+func settle(msg jetstream.Msg, cfg DurableConfig, err error) error {
+	switch {
+	case err == nil:
+		return msg.Ack()
+	case errors.Is(err, ErrTerm):
+		return msg.TermWithReason(err.Error())
+	}
+
+	var delivered uint64
+	if md, mdErr := msg.Metadata(); mdErr == nil {
+		delivered = md.NumDelivered
+	}
+	log.Printf("[nats] %s: delivery %d failed: %v", msg.Subject(), delivered, err)
+	if delay := cfg.NakDelay(delivered); delay > 0 {
+		return msg.NakWithDelay(delay)
+	}
+	return msg.Nak()
+}
@@ -0,0 +1,278 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	nats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// runServer 启动内嵌的 nats-server, port 为 -1 时随机端口
+func runServer(t *testing.T, port int, storeDir string) *server.Server {
+	t.Helper()
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      port,
+		NoLog:     true,
+		NoSigs:    true,
+		JetStream: true,
+		StoreDir:  storeDir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats-server not ready")
+	}
+	return ns
+}
+
+func connect(t *testing.T, ns *server.Server, opts Options) *Conn {
+	t.Helper()
+	opts.URL = ns.ClientURL()
+	c, err := Connect(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestReconnectDelay(t *testing.T) {
+	o := Options{ReconnectWait: 100 * time.Millisecond, MaxReconnectWait: time.Second}
+	for attempts, want := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		got := o.ReconnectDelay(attempts)
+		if got < want || got > want+want/5 {
+			t.Errorf("attempts %d: got %v, want %v plus at most 20%%", attempts, got, want)
+		}
+	}
+}
+
+func TestReconnectCallbacks(t *testing.T) {
+	ns := runServer(t, -1, t.TempDir())
+	port := ns.Addr().(*net.TCPAddr).Port
+
+	disconnected := make(chan struct{}, 1)
+	reconnected := make(chan struct{}, 1)
+	c := connect(t, ns, Options{
+		ReconnectWait: 20 * time.Millisecond,
+		OnDisconnect:  func(error) { disconnected <- struct{}{} },
+		OnReconnect:   func(string) { reconnected <- struct{}{} },
+	})
+
+	ns.Shutdown()
+	ns.WaitForShutdown()
+	select {
+	case <-disconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no disconnect callback")
+	}
+
+	restarted := runServer(t, port, t.TempDir())
+	defer restarted.Shutdown()
+	select {
+	case <-reconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reconnect callback")
+	}
+	if !c.IsConnected() {
+		t.Fatal("want connected after reconnect")
+	}
+}
+
+type sumRequest struct {
+	A, B int
+}
+
+type sumResponse struct {
+	Sum int
+}
+
+func TestRequestReply(t *testing.T) {
+	ns := runServer(t, -1, t.TempDir())
+	defer ns.Shutdown()
+	c := connect(t, ns, Options{})
+
+	_, err := Handle(c, "math.sum", "math", func(ctx context.Context, req sumRequest) (sumResponse, error) {
+		if req.A < 0 {
+			return sumResponse{}, errors.New("negative operand")
+		}
+		if req.A == 0 {
+			<-ctx.Done() // 一直等到请求方超时
+		}
+		return sumResponse{Sum: req.A + req.B}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := Request[sumRequest, sumResponse](context.Background(), c, "math.sum", sumRequest{A: 1, B: 2})
+	if err != nil || resp.Sum != 3 {
+		t.Fatalf("got %+v, %v", resp, err)
+	}
+
+	var remote *RemoteError
+	if _, err := Request[sumRequest, sumResponse](context.Background(), c, "math.sum", sumRequest{A: -1}); !errors.As(err, &remote) {
+		t.Fatalf("want RemoteError, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := Request[sumRequest, sumResponse](ctx, c, "math.sum", sumRequest{A: 0}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want deadline exceeded, got %v", err)
+	}
+
+	if _, err := Request[sumRequest, sumResponse](context.Background(), c, "math.nobody", sumRequest{}); !errors.Is(err, nats.ErrNoResponders) {
+		t.Fatalf("want no responders, got %v", err)
+	}
+}
+
+func TestQueueWorkersAndDrain(t *testing.T) {
+	ns := runServer(t, -1, t.TempDir())
+	defer ns.Shutdown()
+	sub := connect(t, ns, Options{})
+	pub := connect(t, ns, Options{})
+
+	var total atomic.Int32
+	var mu sync.Mutex
+	perSub := make(map[*nats.Subscription]int)
+	subs, err := sub.QueueWorkers("jobs", "workers", 3, func(msg *nats.Msg) {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		perSub[msg.Sub]++
+		mu.Unlock()
+		total.Add(1)
+	})
+	if err != nil || len(subs) != 3 {
+		t.Fatalf("got %d subs, %v", len(subs), err)
+	}
+	if err := sub.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 60
+	for i := 0; i < n; i++ {
+		if err := pub.Publish("jobs", []byte("job")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pub.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// drain 之后每条消息都恰好被处理一次
+	time.Sleep(20 * time.Millisecond)
+	if err := sub.Drain(); err != nil {
+		t.Fatal(err)
+	}
+	if got := total.Load(); got != n {
+		t.Fatalf("processed %d, want %d", got, n)
+	}
+	if len(perSub) < 2 {
+		t.Fatalf("messages not spread across workers: %v", perSub)
+	}
+	select {
+	case <-sub.Closed():
+	default:
+		t.Fatal("want closed after drain")
+	}
+}
+
+func TestConsumeDurable(t *testing.T) {
+	ns := runServer(t, -1, t.TempDir())
+	defer ns.Shutdown()
+	c := connect(t, ns, Options{})
+
+	js, err := c.EnsureStream(context.Background(), "ORDERS", "orders.>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, subject := range []string{"orders.ok", "orders.retry", "orders.bad"} {
+		if _, err := js.Publish(context.Background(), subject, []byte(subject)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	deliveries := make(map[string]int)
+	acked := make(chan string, 10)
+	handler := func(ctx context.Context, msg jetstream.Msg) error {
+		mu.Lock()
+		deliveries[msg.Subject()]++
+		attempt := deliveries[msg.Subject()]
+		mu.Unlock()
+
+		switch msg.Subject() {
+		case "orders.bad":
+			acked <- msg.Subject()
+			return Term(errors.New("malformed"))
+		case "orders.retry":
+			if attempt < 3 {
+				return errors.New("temporary")
+			}
+		}
+		acked <- msg.Subject()
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- c.ConsumeDurable(ctx, DurableConfig{
+			Stream:     "ORDERS",
+			Durable:    "billing",
+			AckWait:    time.Second,
+			MaxDeliver: 5,
+			Backoff:    []time.Duration{10 * time.Millisecond, 20 * time.Millisecond},
+		}, handler)
+	}()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-acked:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d messages settled", i)
+		}
+	}
+	// 等待 ack 到达服务端
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if deliveries["orders.ok"] != 1 || deliveries["orders.retry"] != 3 || deliveries["orders.bad"] != 1 {
+		t.Fatalf("deliveries %v", deliveries)
+	}
+
+	cons, err := js.Consumer(context.Background(), "ORDERS", "billing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := cons.Info(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.NumAckPending != 0 || info.NumPending != 0 {
+		t.Fatalf("ack pending %d, pending %d", info.NumAckPending, info.NumPending)
+	}
+}
+
+func TestNakDelay(t *testing.T) {
+	cfg := DurableConfig{Backoff: []time.Duration{time.Second, 5 * time.Second}}
+	for delivered, want := range map[uint64]time.Duration{0: 0, 1: time.Second, 2: 5 * time.Second, 9: 5 * time.Second} {
+		if got := cfg.NakDelay(delivered); got != want {
+			t.Errorf("delivered %d: got %v, want %v", delivered, got, want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+// 请求-响应使用的消息头
+const (
+	HeaderDeadline = "X-Deadline" // 请求方的截止时间, unix 纳秒
+	HeaderError    = "X-Error"    // 处理方返回的错误
+)
+
+// RemoteError 处理方返回的错误
+type RemoteError struct {
+	Subject string
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("nats: %s: %s", e.Subject, e.Message)
+}
+
+// Request 以 json 发送请求并等待响应
+// ctx 没有截止时间时使用 Options.RequestTimeout, 截止时间随请求发送给处理方
+func Request[Req, Resp any](ctx context.Context, c *Conn, subject string, req Req) (Resp, error) {
+	var resp Resp
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.RequestTimeout)
+		defer cancel()
+	}
+	deadline, _ := ctx.Deadline()
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return resp, fmt.Errorf("marshal request: %w", err)
+	}
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	msg.Header.Set(HeaderDeadline, strconv.FormatInt(deadline.UnixNano(), 10))
+
+	reply, err := c.RequestMsgWithContext(ctx, msg)
+	if err != nil {
+		return resp, fmt.Errorf("request %s: %w", subject, err)
+	}
+	if e := reply.Header.Get(HeaderError); e != "" {
+		return resp, &RemoteError{Subject: subject, Message: e}
+	}
+	if err := json.Unmarshal(reply.Data, &resp); err != nil {
+		return resp, fmt.Errorf("unmarshal response: %w", err)
+	}
+	return resp, nil
+}
+
+// Handle 处理 subject 上的 json 请求, queue 不为空时加入队列组
+// fn 的 ctx 带有请求方的截止时间, 请求方已经超时的请求不再处理也不再响应
+func Handle[Req, Resp any](c *Conn, subject, queue string, fn func(ctx context.Context, req Req) (Resp, error)) (*nats.Subscription, error) {
+	return c.QueueSubscribe(subject, queue, func(msg *nats.Msg) {
+		ctx, cancel := requestContext(msg, c.opts.RequestTimeout)
+		defer cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		var req Req
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			respondError(msg, fmt.Errorf("unmarshal request: %w", err))
+			return
+		}
+		resp, err := fn(ctx, req)
+		if ctx.Err() != nil {
+			log.Printf("[nats] %s: deadline exceeded, drop response", subject)
+			return
+		}
+		if err != nil {
+			respondError(msg, err)
+			return
+		}
+		data, err := json.Marshal(resp)
+		if err != nil {
+			respondError(msg, fmt.Errorf("marshal response: %w", err))
+			return
+		}
+		if err := msg.Respond(data); err != nil {
+			log.Printf("[nats] %s: respond err: %v", subject, err)
+		}
+	})
+}
+
+// requestContext 按请求头中的截止时间创建 context, 没有时使用 timeout
+func requestContext(msg *nats.Msg, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if v := msg.Header.Get(HeaderDeadline); v != "" {
+		if nanos, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return context.WithDeadline(context.Background(), time.Unix(0, nanos))
+		}
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+func respondError(msg *nats.Msg, err error) {
+	reply := nats.NewMsg(msg.Reply)
+	reply.Header.Set(HeaderError, err.Error())
+	if err := msg.RespondMsg(reply); err != nil {
+		log.Printf("[nats] %s: respond err: %v", msg.Subject, err)
+	}
+}
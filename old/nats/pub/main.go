@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"testGo/nats/messaging"
+	"time"
+)
+
+// helpRequest 请求-响应模式的请求, 与 sub 中的定义一致
+type helpRequest struct {
+	Question string `json:"question"`
+}
+
+type helpReply struct {
+	Answer string `json:"answer"`
+}
+
+func main() {
+	url := flag.String("url", "nats://127.0.0.1:4222", "nats 服务器地址")
+	flag.Parse()
+
+	pub(*url)
+}
+
+func pub(url string) {
+	// 连接Nats服务器
+	opts := messaging.DefaultOptions(url)
+	opts.Name = "pub"
+	nc, err := messaging.Connect(opts)
+	if err != nil {
+		log.Fatal("connect nats err:", err)
+	}
+	// 退出前把缓冲中的消息发送出去
+	defer func() {
+		if err := nc.Drain(); err != nil {
+			log.Println("drain err:", err)
+		}
+	}()
+
+	// 发布-订阅 模式，向 test1 发布一个 `Hello World` 数据
+	if err := nc.Publish("test1", []byte("Hello World")); err != nil {
+		log.Println("publish test1 err:", err)
+	}
+
+	// 队列 模式，发布是一样的，只是订阅不同，向 test2 发布数据
+	for i := 0; i < 5; i++ {
+		if err := nc.Publish("test2", []byte(fmt.Sprintf("Hello test2 #%d", i))); err != nil {
+			log.Println("publish test2 err:", err)
+		}
+	}
+
+	// 请求-响应， 向 test3 发布一个请求，设置超时间3秒，如果有多个响应，只接收第一个收到的消息
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	reply, err := messaging.Request[helpRequest, helpReply](ctx, nc, "test3", helpRequest{Question: "help test3"})
+	if err != nil {
+		fmt.Println(err)
+	} else {
+		fmt.Printf("help test3 success : %s\n", reply.Answer)
+	}
+
+	// JetStream, 消息持久化到流 ORDERS 中, sub 不在线时也不会丢
+	js, err := nc.EnsureStream(ctx, "ORDERS", "orders.>")
+	if err != nil {
+		log.Println("ensure stream err:", err)
+		return
+	}
+	ack, err := js.Publish(ctx, "orders.created", []byte(`{"id":"102"}`))
+	if err != nil {
+		log.Println("publish orders.created err:", err)
+		return
+	}
+	fmt.Printf("orders.created stored in %s, seq %d\n", ack.Stream, ack.Sequence)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"testGo/nats/messaging"
+
+	nats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// helpRequest 请求-响应模式的请求, 与 pub 中的定义一致
+type helpRequest struct {
+	Question string `json:"question"`
+}
+
+type helpReply struct {
+	Answer string `json:"answer"`
+}
+
+func main() {
+	url := flag.String("url", "nats://127.0.0.1:4222", "nats 服务器地址")
+	workers := flag.Int("workers", 3, "test2 队列组中的 worker 数量")
+	flag.Parse()
+
+	if err := sub(*url, *workers); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func sub(url string, workers int) error {
+	// 连接Nats服务器, 断线后一直重连
+	opts := messaging.DefaultOptions(url)
+	opts.Name = "sub"
+	nc, err := messaging.Connect(opts)
+	if err != nil {
+		return fmt.Errorf("connect nats: %w", err)
+	}
+
+	// 发布-订阅 模式，异步订阅 test1
+	if _, err := nc.Subscribe("test1", func(m *nats.Msg) {
+		fmt.Printf("test1 Received a message: %s\n", string(m.Data))
+	}); err != nil {
+		return err
+	}
+
+	// 队列 模式，订阅 test2， 队列为queue, 同一队列只有一个 worker 能收到消息
+	if _, err := nc.QueueWorkers("test2", "queue", workers, func(msg *nats.Msg) {
+		fmt.Printf("test2 Queue a message: %s\n", string(msg.Data))
+	}); err != nil {
+		return err
+	}
+
+	// 请求-响应， 响应 test3 消息, 请求方超时之后不再响应
+	if _, err := messaging.Handle(nc, "test3", "", func(ctx context.Context, req helpRequest) (helpReply, error) {
+		fmt.Printf("test3 Reply a message: %s\n", req.Question)
+		return helpReply{Answer: "I can help for test3!!"}, nil
+	}); err != nil {
+		return err
+	}
+
+	// 收到 SIGINT/SIGTERM 后先停止 JetStream 消费, 再 drain 连接处理完已收到的消息
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// JetStream 持久消费者, 重启后从上次 ack 的位置继续
+	consumed := make(chan error, 1)
+	go func() {
+		consumed <- nc.ConsumeDurable(ctx, messaging.DurableConfig{
+			Stream:   "ORDERS",
+			Subjects: []string{"orders.>"},
+			Durable:  "sub",
+		}, func(ctx context.Context, msg jetstream.Msg) error {
+			fmt.Printf("%s JetStream message: %s\n", msg.Subject(), string(msg.Data()))
+			return nil
+		})
+	}()
+
+	// JetStream 不可用时只记录错误, 其他订阅继续工作
+	select {
+	case err := <-consumed:
+		if err != nil {
+			log.Println("jetstream consumer err:", err)
+		}
+		<-ctx.Done()
+	case <-ctx.Done():
+		<-consumed
+	}
+	return nc.Drain()
+}
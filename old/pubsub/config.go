@@ -0,0 +1,56 @@
+package pubsub
+
+import (
+	"fmt"
+
+	"github.com/IBM/sarama"
+	"testGo/nats/messaging"
+)
+
+// 后端类型
+const (
+	BackendMemory = "memory"
+	BackendKafka  = "kafka"
+	BackendNats   = "nats"
+)
+
+// Config 按配置选择后端, 服务代码只依赖 Publisher/Subscriber
+type Config struct {
+	Backend string   `yaml:"backend" json:"backend"` // memory|kafka|nats
+	Brokers []string `yaml:"brokers" json:"brokers"` // kafka broker 地址
+	URL     string   `yaml:"url" json:"url"`         // nats 服务器地址
+	Stream  string   `yaml:"stream" json:"stream"`   // nats JetStream 流名称
+	// Subjects nats 流包含的 subject, 默认 <Stream>.>
+	Subjects []string `yaml:"subjects" json:"subjects"`
+}
+
+// Open 按配置创建 Publisher 和 Subscriber, 两者可能是同一个对象, 调用方都需要 Close
+func Open(cfg Config) (Publisher, Subscriber, error) {
+	switch cfg.Backend {
+	case BackendMemory, "":
+		bus := NewMemoryBus()
+		return bus, bus, nil
+	case BackendKafka:
+		config := sarama.NewConfig()
+		config.Producer.RequiredAcks = sarama.WaitForAll
+		config.Producer.Return.Successes = true
+		producer, err := sarama.NewSyncProducer(cfg.Brokers, config)
+		if err != nil {
+			return nil, nil, fmt.Errorf("kafka producer: %w", err)
+		}
+		return NewKafkaPublisher(producer), NewKafkaSubscriber(cfg.Brokers, nil), nil
+	case BackendNats:
+		conn, err := messaging.Connect(messaging.DefaultOptions(cfg.URL))
+		if err != nil {
+			return nil, nil, fmt.Errorf("nats connect: %w", err)
+		}
+		subjects := cfg.Subjects
+		if len(subjects) == 0 {
+			subjects = []string{cfg.Stream + ".>"}
+		}
+		ps := NewNatsPubSub(conn, cfg.Stream, subjects...)
+		return ps, ps, nil
+	default:
+		return nil, nil, fmt.Errorf("pubsub: unknown backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,200 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gofrs/uuid"
+	groupconsumer "testGo/kafka/consumer"
+)
+
+// HeaderMessageID kafka 中保存消息 id 的 header
+const HeaderMessageID = "x-message-id"
+
+// KafkaPublisher 基于 sarama 同步生产者的 Publisher
+type KafkaPublisher struct {
+	producer sarama.SyncProducer
+}
+
+// NewKafkaPublisher 创建 Publisher, Close 时关闭 producer
+func NewKafkaPublisher(producer sarama.SyncProducer) *KafkaPublisher {
+	return &KafkaPublisher{producer: producer}
+}
+
+// Publish 批量发送, 元数据写入 header
+func (p *KafkaPublisher) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	batch := make([]*sarama.ProducerMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		pm := &sarama.ProducerMessage{Topic: topic, Value: sarama.ByteEncoder(msg.Payload)}
+		if msg.Key != "" {
+			pm.Key = sarama.StringEncoder(msg.Key)
+		}
+		if msg.ID != "" {
+			pm.Headers = append(pm.Headers, sarama.RecordHeader{Key: []byte(HeaderMessageID), Value: []byte(msg.ID)})
+		}
+		for k, v := range msg.Metadata {
+			pm.Headers = append(pm.Headers, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+		}
+		batch = append(batch, pm)
+	}
+	return p.producer.SendMessages(batch)
+}
+
+// Close 关闭生产者
+func (p *KafkaPublisher) Close() error {
+	return p.producer.Close()
+}
+
+// KafkaSubscriber 基于 sarama 消费者组的 Subscriber
+type KafkaSubscriber struct {
+	addrs  []string
+	config *sarama.Config
+	// RejoinDelay nack 之后重新加入消费者组前的等待, 避免同一条消息空转
+	RejoinDelay time.Duration
+
+	mu       sync.Mutex
+	attempts map[string]int // topic/partition/offset -> 已投递次数, 用于填充 Message.Attempt
+	closed   chan struct{}
+	once     sync.Once
+}
+
+// NewKafkaSubscriber 创建 Subscriber, config 为 nil 时使用 kafka/consumer.NewConfig
+func NewKafkaSubscriber(addrs []string, config *sarama.Config) *KafkaSubscriber {
+	if config == nil {
+		config = groupconsumer.NewConfig()
+	}
+	return &KafkaSubscriber{
+		addrs:       addrs,
+		config:      config,
+		RejoinDelay: time.Second,
+		attempts:    make(map[string]int),
+		closed:      make(chan struct{}),
+	}
+}
+
+// Subscribe 以消费者组 group 消费 topic, group 为空时使用一次性的组并从最新位置开始
+func (s *KafkaSubscriber) Subscribe(ctx context.Context, topic, group string, handler Handler) error {
+	config := s.config
+	if group == "" {
+		group = "pubsub-" + uuid.Must(uuid.NewV4()).String()
+		copied := *s.config
+		copied.Consumer.Offsets.Initial = sarama.OffsetNewest
+		config = &copied
+	}
+	cg, err := sarama.NewConsumerGroup(s.addrs, group, config)
+	if err != nil {
+		return err
+	}
+	defer cg.Close()
+	if config.Consumer.Return.Errors {
+		go func() {
+			for err := range cg.Errors() {
+				log.Println("[pubsub] kafka group error:", err)
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	h := &kafkaGroupHandler{sub: s, handler: handler}
+	for ctx.Err() == nil {
+		err := cg.Consume(ctx, []string{topic}, h)
+		if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+			break
+		}
+		if err != nil {
+			log.Println("[pubsub] kafka consume err:", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(s.RejoinDelay):
+		}
+	}
+
+	select {
+	case <-s.closed:
+		return ErrClosed
+	default:
+		return nil
+	}
+}
+
+// Close 结束所有订阅
+func (s *KafkaSubscriber) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
+
+func (s *KafkaSubscriber) attempt(msg *sarama.ConsumerMessage) int {
+	key := fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts[key]++
+	return s.attempts[key]
+}
+
+func (s *KafkaSubscriber) done(msg *sarama.ConsumerMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset))
+}
+
+type kafkaGroupHandler struct {
+	sub     *KafkaSubscriber
+	handler Handler
+}
+
+func (h *kafkaGroupHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (h *kafkaGroupHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+// ConsumeClaim 处理成功才标记消息; 失败时结束本轮会话, 重新加入后从已提交的位置再次投递
+func (h *kafkaGroupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case <-sess.Context().Done():
+			return nil
+		case cm, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			msg := fromKafka(cm, h.sub.attempt(cm))
+			if err := h.handler(sess.Context(), msg); err != nil {
+				return fmt.Errorf("%s/%d offset %d: %w", cm.Topic, cm.Partition, cm.Offset, err)
+			}
+			h.sub.done(cm)
+			sess.MarkMessage(cm, "")
+		}
+	}
+}
+
+func fromKafka(cm *sarama.ConsumerMessage, attempt int) *Message {
+	msg := &Message{
+		Key:      string(cm.Key),
+		Payload:  cm.Value,
+		Metadata: make(map[string]string, len(cm.Headers)),
+		Topic:    cm.Topic,
+		Attempt:  attempt,
+		Time:     cm.Timestamp,
+	}
+	for _, h := range cm.Headers {
+		if string(h.Key) == HeaderMessageID {
+			msg.ID = string(h.Value)
+			continue
+		}
+		msg.Metadata[string(h.Key)] = string(h.Value)
+	}
+	return msg
+}
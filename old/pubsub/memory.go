@@ -0,0 +1,173 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryBus 进程内的消息总线, 同时实现 Publisher 和 Subscriber, 适合单元测试
+//
+// 每个 topic 是一个只追加的日志, 和 kafka 一样每个 group 有自己的消费位置:
+// 命名 group 第一次订阅时从头开始消费, 匿名 group("") 只收到订阅之后发布的消息
+// nack 的消息在 RedeliveryDelay 之后重新投递给同一 group
+type MemoryBus struct {
+	RedeliveryDelay time.Duration
+
+	mu     sync.Mutex
+	topics map[string]*memoryTopic
+	closed chan struct{}
+	once   sync.Once
+}
+
+type memoryTopic struct {
+	log    []*Message
+	groups map[string]*memoryGroup
+	notify chan struct{} // 有新消息时关闭并替换, 唤醒等待中的订阅者
+}
+
+type memoryGroup struct {
+	next    int
+	retries []memoryDelivery
+}
+
+type memoryDelivery struct {
+	offset    int
+	attempt   int
+	notBefore time.Time
+}
+
+// NewMemoryBus 创建内存总线
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		RedeliveryDelay: 10 * time.Millisecond,
+		topics:          make(map[string]*memoryTopic),
+		closed:          make(chan struct{}),
+	}
+}
+
+func (b *MemoryBus) topic(name string) *memoryTopic {
+	t := b.topics[name]
+	if t == nil {
+		t = &memoryTopic{groups: make(map[string]*memoryGroup), notify: make(chan struct{})}
+		b.topics[name] = t
+	}
+	return t
+}
+
+func (t *memoryTopic) broadcast() {
+	close(t.notify)
+	t.notify = make(chan struct{})
+}
+
+// Publish 追加消息到 topic
+func (b *MemoryBus) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	select {
+	case <-b.closed:
+		return ErrClosed
+	default:
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.topic(topic)
+	now := time.Now()
+	for _, msg := range msgs {
+		stored := *msg
+		stored.Topic = topic
+		stored.Time = now
+		t.log = append(t.log, &stored)
+	}
+	t.broadcast()
+	return nil
+}
+
+// Subscribe 消费 topic, 阻塞直到 ctx 结束或总线关闭
+func (b *MemoryBus) Subscribe(ctx context.Context, topic, group string, handler Handler) error {
+	b.mu.Lock()
+	t := b.topic(topic)
+	g := t.groups[group]
+	if group == "" {
+		g = &memoryGroup{next: len(t.log)}
+	} else if g == nil {
+		g = &memoryGroup{}
+		t.groups[group] = g
+	}
+	b.mu.Unlock()
+
+	for {
+		d, msg, wait, notify := b.poll(t, g)
+		if msg == nil {
+			if err := b.wait(ctx, wait, notify); err != nil || ctx.Err() != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := handler(ctx, msg); err != nil {
+			b.mu.Lock()
+			g.retries = append(g.retries, memoryDelivery{offset: d.offset, attempt: d.attempt + 1, notBefore: time.Now().Add(b.RedeliveryDelay)})
+			b.mu.Unlock()
+		}
+	}
+}
+
+// poll 取下一条待投递的消息: 先取到期的重新投递, 再取日志中的新消息
+// 没有消息时返回需要等待的时间(0 表示等到有新消息)和唤醒用的 channel
+func (b *MemoryBus) poll(t *memoryTopic, g *memoryGroup) (memoryDelivery, *Message, time.Duration, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	for i, d := range g.retries {
+		if !d.notBefore.After(now) {
+			g.retries = append(g.retries[:i], g.retries[i+1:]...)
+			return d, t.deliver(d), 0, nil
+		}
+		if until := d.notBefore.Sub(now); wait == 0 || until < wait {
+			wait = until
+		}
+	}
+	if g.next < len(t.log) {
+		d := memoryDelivery{offset: g.next, attempt: 1}
+		g.next++
+		return d, t.deliver(d), 0, nil
+	}
+	return memoryDelivery{}, nil, wait, t.notify
+}
+
+// wait 等待新消息、重新投递到期、ctx 结束或总线关闭
+func (b *MemoryBus) wait(ctx context.Context, d time.Duration, notify <-chan struct{}) error {
+	var timer <-chan time.Time
+	if d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timer = t.C
+	}
+	select {
+	case <-ctx.Done():
+	case <-b.closed:
+		return ErrClosed
+	case <-notify:
+	case <-timer:
+	}
+	return nil
+}
+
+// deliver 复制一份消息交给处理函数, 避免处理函数修改日志中的消息
+func (t *memoryTopic) deliver(d memoryDelivery) *Message {
+	msg := *t.log[d.offset]
+	msg.Attempt = d.attempt
+	msg.Metadata = make(map[string]string, len(t.log[d.offset].Metadata))
+	for k, v := range t.log[d.offset].Metadata {
+		msg.Metadata[k] = v
+	}
+	return &msg
+}
+
+// Close 关闭总线, 所有订阅返回 ErrClosed
+func (b *MemoryBus) Close() error {
+	b.once.Do(func() { close(b.closed) })
+	return nil
+}
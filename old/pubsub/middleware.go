@@ -0,0 +1,136 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrPermanent 包装了 ErrPermanent 的错误不再重试
+var ErrPermanent = errors.New("pubsub: permanent error")
+
+// Permanent 把 err 标记为不可重试
+func Permanent(err error) error {
+	return fmt.Errorf("%w: %v", ErrPermanent, err)
+}
+
+// Retry 在本地重试处理函数, 第 n 次失败后等待 delays[n-1], 超出长度时不再重试
+// 重试用尽或遇到 Permanent 错误时把错误交给后端 nack
+func Retry(delays ...time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			err := next(ctx, msg)
+			for _, delay := range delays {
+				if err == nil || errors.Is(err, ErrPermanent) {
+					return err
+				}
+				select {
+				case <-ctx.Done():
+					return err
+				case <-time.After(delay):
+				}
+				err = next(ctx, msg)
+			}
+			return err
+		}
+	}
+}
+
+// Recoverer 把处理函数的 panic 转成错误
+func Recoverer() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("pubsub: handler panic: %v", r)
+				}
+			}()
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Logging 记录每条消息的处理结果和耗时, logger 为 nil 时使用标准库默认 logger
+func Logging(logger *log.Logger) Middleware {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			start := time.Now()
+			err := next(ctx, msg)
+			if err != nil {
+				logger.Printf("[pubsub] %s id=%s attempt=%d cost=%v err=%v", msg.Topic, msg.ID, msg.Attempt, time.Since(start), err)
+			} else {
+				logger.Printf("[pubsub] %s id=%s attempt=%d cost=%v ok", msg.Topic, msg.ID, msg.Attempt, time.Since(start))
+			}
+			return err
+		}
+	}
+}
+
+const tracerName = "testGo/pubsub"
+
+// Tracing 从消息元数据中恢复发布端的链路信息, 为每次处理创建 consumer span
+func Tracing() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(msg.Metadata))
+			ctx, span := otel.Tracer(tracerName).Start(ctx, msg.Topic+" process",
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(
+					attribute.String("messaging.destination.name", msg.Topic),
+					attribute.String("messaging.message.id", msg.ID),
+					attribute.Int("messaging.delivery.attempt", msg.Attempt),
+				))
+			defer span.End()
+
+			err := next(ctx, msg)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return err
+		}
+	}
+}
+
+// WithTracing 为每次发布创建 producer span, 并把链路信息写入消息元数据
+func WithTracing(p Publisher) Publisher {
+	return &tracingPublisher{Publisher: p}
+}
+
+type tracingPublisher struct {
+	Publisher
+}
+
+func (p *tracingPublisher) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", topic),
+			attribute.Int("messaging.batch.message_count", len(msgs)),
+		))
+	defer span.End()
+
+	for _, msg := range msgs {
+		if msg.Metadata == nil {
+			msg.Metadata = map[string]string{}
+		}
+		otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(msg.Metadata))
+	}
+	err := p.Publisher.Publish(ctx, topic, msgs...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,161 @@
+package pubsub
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"testGo/nats/messaging"
+)
+
+// NatsPubSub 基于 JetStream 的 Publisher 和 Subscriber, topic 即 subject
+// 所有 topic 保存在同一个流中, 流的 subject 为 Subjects
+type NatsPubSub struct {
+	conn     *messaging.Conn
+	stream   string
+	subjects []string
+	// AckWait、MaxDeliver、Backoff 传给每个持久消费者
+	AckWait    time.Duration
+	MaxDeliver int
+	Backoff    []time.Duration
+
+	initMu sync.Mutex
+	js     jetstream.JetStream // 创建流成功后才设置, 失败时下次调用重试
+	closed chan struct{}
+	once   sync.Once
+}
+
+// NewNatsPubSub 创建基于 JetStream 流 stream 的 Publisher/Subscriber, Close 时 drain 连接
+func NewNatsPubSub(conn *messaging.Conn, stream string, subjects ...string) *NatsPubSub {
+	return &NatsPubSub{
+		conn:     conn,
+		stream:   stream,
+		subjects: subjects,
+		Backoff:  []time.Duration{100 * time.Millisecond, time.Second, 5 * time.Second},
+		closed:   make(chan struct{}),
+	}
+}
+
+// jetStream 第一次成功调用时创建流; ctx 被取消或者服务端暂时不可用导致的失败不会被记住, 下次调用重新创建
+func (n *NatsPubSub) jetStream(ctx context.Context) (jetstream.JetStream, error) {
+	n.initMu.Lock()
+	defer n.initMu.Unlock()
+	if n.js != nil {
+		return n.js, nil
+	}
+	js, err := n.conn.EnsureStream(ctx, n.stream, n.subjects...)
+	if err != nil {
+		return nil, err
+	}
+	n.js = js
+	return js, nil
+}
+
+// Publish 发布到 JetStream, 消息 id 作为 Nats-Msg-Id, 服务端在去重窗口内丢弃重复的消息
+func (n *NatsPubSub) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	js, err := n.jetStream(ctx)
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		nm := nats.NewMsg(topic)
+		nm.Data = msg.Payload
+		for k, v := range msg.Metadata {
+			nm.Header.Set(k, v)
+		}
+		if msg.Key != "" {
+			nm.Header.Set(headerKey, msg.Key)
+		}
+		var opts []jetstream.PublishOpt
+		if msg.ID != "" {
+			opts = append(opts, jetstream.WithMsgID(msg.ID))
+		}
+		if _, err := js.PublishMsg(ctx, nm, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const headerKey = "x-message-key"
+
+// Subscribe 以持久消费者 group 消费 topic, group 为空时使用临时消费者, 返回时删除
+func (n *NatsPubSub) Subscribe(ctx context.Context, topic, group string, handler Handler) error {
+	if _, err := n.jetStream(ctx); err != nil {
+		return err
+	}
+	durable := ""
+	if group != "" {
+		durable = durableName(group, topic)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-n.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := n.conn.ConsumeDurable(ctx, messaging.DurableConfig{
+		Stream:        n.stream,
+		Subjects:      n.subjects,
+		Durable:       durable,
+		FilterSubject: topic,
+		AckWait:       n.AckWait,
+		MaxDeliver:    n.MaxDeliver,
+		Backoff:       n.Backoff,
+	}, func(ctx context.Context, m jetstream.Msg) error {
+		return handler(ctx, fromNats(m))
+	})
+	if err != nil {
+		return err
+	}
+	select {
+	case <-n.closed:
+		return ErrClosed
+	default:
+		return nil
+	}
+}
+
+// Close 结束所有订阅并 drain 连接
+func (n *NatsPubSub) Close() error {
+	n.once.Do(func() { close(n.closed) })
+	return n.conn.Drain()
+}
+
+// durableName 持久消费者名称不能包含 . * > 等字符
+func durableName(group, topic string) string {
+	return strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_").Replace(group + "-" + topic)
+}
+
+func fromNats(m jetstream.Msg) *Message {
+	msg := &Message{
+		Payload:  m.Data(),
+		Metadata: make(map[string]string, len(m.Headers())),
+		Topic:    m.Subject(),
+		Attempt:  1,
+	}
+	for k := range m.Headers() {
+		v := m.Headers().Get(k)
+		switch k {
+		case jetstream.MsgIDHeader:
+			msg.ID = v
+		case headerKey:
+			msg.Key = v
+		default:
+			msg.Metadata[k] = v
+		}
+	}
+	if md, err := m.Metadata(); err == nil {
+		msg.Attempt = int(md.NumDelivered)
+		msg.Time = md.Timestamp
+	}
+	return msg
+}
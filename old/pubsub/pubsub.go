@@ -0,0 +1,64 @@
+// Package pubsub 与消息中间件无关的发布/订阅接口, 提供 kafka、nats(JetStream) 和内存三种实现
+//
+// 订阅处理函数返回 nil 表示 ack, 返回错误表示 nack, 消息由后端重新投递:
+//   - memory: 放回队列末尾重新投递
+//   - nats:   按 NakDelay nak, 由 JetStream 重新投递
+//   - kafka:  不提交偏移量并结束本轮会话, 重新加入消费者组后从已提交的位置继续, 代价较大, 建议配合 Retry 中间件
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// ErrClosed 发布者或订阅者已经关闭
+var ErrClosed = errors.New("pubsub: closed")
+
+// Message 一条消息
+type Message struct {
+	ID       string // 消息 id, NewMessage 生成 uuid
+	Key      string // 分区键, kafka 中相同 key 的消息进入同一分区
+	Payload  []byte
+	Metadata map[string]string // 随消息传递的元数据, 例如链路追踪信息
+
+	// 以下字段由订阅端填充
+	Topic   string
+	Attempt int       // 第几次投递, 从 1 开始; 后端无法提供时为 1
+	Time    time.Time // 消息写入时间, 后端无法提供时为零值
+}
+
+// NewMessage 创建带 uuid 的消息
+func NewMessage(payload []byte) *Message {
+	return &Message{ID: uuid.Must(uuid.NewV4()).String(), Payload: payload, Metadata: map[string]string{}}
+}
+
+// Handler 处理消息, 返回 nil 时 ack, 返回错误时 nack
+type Handler func(ctx context.Context, msg *Message) error
+
+// Publisher 发布消息
+type Publisher interface {
+	Publish(ctx context.Context, topic string, msgs ...*Message) error
+	Close() error
+}
+
+// Subscriber 订阅消息
+type Subscriber interface {
+	// Subscribe 阻塞消费 topic 直到 ctx 结束或订阅者关闭
+	// 相同 group 的订阅竞争消费, 每条消息只交给其中一个; 不同 group 各自收到全部消息
+	Subscribe(ctx context.Context, topic, group string, handler Handler) error
+	Close() error
+}
+
+// Middleware 包装订阅处理函数
+type Middleware func(Handler) Handler
+
+// Chain 依次应用中间件, 第一个中间件在最外层
+func Chain(handler Handler, mws ...Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+	return handler
+}
@@ -0,0 +1,326 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testGo/nats/messaging"
+)
+
+// collect 启动订阅, 返回收到的消息
+func collect(t *testing.T, ctx context.Context, sub Subscriber, topic, group string, handler Handler) <-chan *Message {
+	t.Helper()
+	out := make(chan *Message, 100)
+	go func() {
+		err := sub.Subscribe(ctx, topic, group, func(ctx context.Context, msg *Message) error {
+			if handler != nil {
+				if err := handler(ctx, msg); err != nil {
+					return err
+				}
+			}
+			out <- msg
+			return nil
+		})
+		if err != nil && !errors.Is(err, ErrClosed) {
+			t.Error(err)
+		}
+	}()
+	return out
+}
+
+func receive(t *testing.T, ch <-chan *Message) *Message {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+		return nil
+	}
+}
+
+func TestMemoryBusGroups(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for i := 0; i < 4; i++ {
+		msg := NewMessage([]byte{byte('a' + i)})
+		if err := bus.Publish(ctx, "orders", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 同一 group 的两个订阅者竞争消费, 另一个 group 收到全部消息
+	billing1 := collect(t, ctx, bus, "orders", "billing", nil)
+	billing2 := collect(t, ctx, bus, "orders", "billing", nil)
+	audit := collect(t, ctx, bus, "orders", "audit", nil)
+
+	seen := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		select {
+		case msg := <-billing1:
+			seen[string(msg.Payload)]++
+		case msg := <-billing2:
+			seen[string(msg.Payload)]++
+		case <-time.After(5 * time.Second):
+			t.Fatal("billing did not receive all messages")
+		}
+	}
+	for i := 0; i < 4; i++ {
+		if msg := receive(t, audit); msg.Topic != "orders" || msg.Time.IsZero() {
+			t.Fatalf("got %+v", msg)
+		}
+	}
+	if len(seen) != 4 {
+		t.Fatalf("billing got %v", seen)
+	}
+	for payload, n := range seen {
+		if n != 1 {
+			t.Fatalf("%s delivered %d times", payload, n)
+		}
+	}
+}
+
+func TestMemoryBusRedelivery(t *testing.T) {
+	bus := NewMemoryBus()
+	bus.RedeliveryDelay = time.Millisecond
+	defer bus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgs := collect(t, ctx, bus, "orders", "billing", func(ctx context.Context, msg *Message) error {
+		if msg.Attempt < 3 {
+			return errors.New("temporary")
+		}
+		return nil
+	})
+	if err := bus.Publish(ctx, "orders", NewMessage([]byte("x"))); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, msgs); msg.Attempt != 3 {
+		t.Fatalf("attempt %d", msg.Attempt)
+	}
+
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Publish(ctx, "orders", NewMessage(nil)); !errors.Is(err, ErrClosed) {
+		t.Fatalf("want ErrClosed, got %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var order []string
+	var calls int
+	tag := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, msg *Message) error {
+				order = append(order, name)
+				return next(ctx, msg)
+			}
+		}
+	}
+	handler := Chain(func(ctx context.Context, msg *Message) error {
+		calls++
+		switch {
+		case msg.Key == "panic":
+			panic("boom")
+		case msg.Key == "permanent":
+			return Permanent(errors.New("bad payload"))
+		case calls < 3:
+			return errors.New("temporary")
+		}
+		return nil
+	}, tag("outer"), Recoverer(), Retry(time.Millisecond, time.Millisecond, time.Millisecond), tag("inner"))
+
+	if err := handler(context.Background(), &Message{}); err != nil || calls != 3 {
+		t.Fatalf("got %v after %d calls", err, calls)
+	}
+	if order[0] != "outer" || len(order) != 4 {
+		t.Fatalf("order %v", order)
+	}
+
+	calls = 0
+	if err := handler(context.Background(), &Message{Key: "permanent"}); !errors.Is(err, ErrPermanent) || calls != 1 {
+		t.Fatalf("got %v after %d calls", err, calls)
+	}
+	if err := handler(context.Background(), &Message{Key: "panic"}); err == nil {
+		t.Fatal("want error from panic")
+	}
+}
+
+func TestTracingPropagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tp.Shutdown(context.Background())
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	bus := NewMemoryBus()
+	defer bus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgs := collect(t, ctx, bus, "orders", "billing", Chain(func(ctx context.Context, msg *Message) error { return nil }, Tracing()))
+	if err := WithTracing(bus).Publish(ctx, "orders", NewMessage([]byte("x"))); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, msgs)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+	publish, process := spans[0], spans[1]
+	if process.Parent.SpanID() != publish.SpanContext.SpanID() {
+		t.Fatalf("process span parent %s, want %s", process.Parent.SpanID(), publish.SpanContext.SpanID())
+	}
+}
+
+func TestKafkaPublisher(t *testing.T) {
+	sp := mocks.NewSyncProducer(t, nil)
+	sp.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(pm *sarama.ProducerMessage) error {
+		key, _ := pm.Key.Encode()
+		if string(key) != "k1" || pm.Topic != "orders" {
+			return errors.New("wrong key or topic")
+		}
+		cm := &sarama.ConsumerMessage{Topic: pm.Topic, Key: key}
+		for i := range pm.Headers {
+			cm.Headers = append(cm.Headers, &pm.Headers[i])
+		}
+		if msg := fromKafka(cm, 1); msg.ID != "id-1" || msg.Metadata["tenant"] != "t1" {
+			return errors.New("headers not round-tripped")
+		}
+		return nil
+	})
+	p := NewKafkaPublisher(sp)
+	defer p.Close()
+
+	msg := &Message{ID: "id-1", Key: "k1", Payload: []byte("x"), Metadata: map[string]string{"tenant": "t1"}}
+	if err := p.Publish(context.Background(), "orders", msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNatsPubSub(t *testing.T) {
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true, JetStream: true, StoreDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go ns.Start()
+	defer ns.Shutdown()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats-server not ready")
+	}
+	conn, err := messaging.Connect(messaging.DefaultOptions(ns.ClientURL()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := NewNatsPubSub(conn, "ORDERS", "orders.>")
+	ps.Backoff = []time.Duration{time.Millisecond}
+	defer ps.Close()
+
+	// 第一次创建流失败不影响之后的调用
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	if err := ps.Publish(canceled, "orders.created", NewMessage([]byte("x"))); err == nil {
+		t.Fatal("publish with canceled ctx should fail")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msg := NewMessage([]byte("x"))
+	msg.Key = "k1"
+	msg.Metadata["tenant"] = "t1"
+	// 相同 id 的重复发布被服务端去重
+	if err := ps.Publish(ctx, "orders.created", msg, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	attempts := 0
+	msgs := collect(t, ctx, ps, "orders.created", "billing", func(ctx context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if msg.Attempt < 2 {
+			return errors.New("temporary")
+		}
+		return nil
+	})
+	got := receive(t, msgs)
+	if got.ID != msg.ID || got.Key != "k1" || got.Metadata["tenant"] != "t1" || got.Attempt != 2 || got.Topic != "orders.created" {
+		t.Fatalf("got %+v", got)
+	}
+	select {
+	case dup := <-msgs:
+		t.Fatalf("duplicate delivered: %+v", dup)
+	case <-time.After(100 * time.Millisecond):
+	}
+	mu.Lock()
+	if attempts != 2 {
+		t.Fatalf("handler called %d times", attempts)
+	}
+	mu.Unlock()
+
+	// 不指定 group 时是临时消费者, Subscribe 返回后不会留在服务端
+	anonCtx, anonCancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	got2 := make(chan *Message, 1)
+	go func() {
+		done <- ps.Subscribe(anonCtx, "orders.created", "", func(ctx context.Context, msg *Message) error {
+			got2 <- msg
+			return nil
+		})
+	}()
+	if m := receive(t, got2); m.ID != msg.ID {
+		t.Fatalf("anonymous subscriber got %+v", m)
+	}
+	anonCancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	js, err := jetstream.New(conn.Conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := js.Stream(ctx, "ORDERS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := stream.ConsumerNames(ctx)
+	var consumers []string
+	for name := range names.Name() {
+		consumers = append(consumers, name)
+	}
+	if names.Err() != nil || len(consumers) != 1 {
+		t.Fatalf("consumers %v %v", consumers, names.Err())
+	}
+}
+
+func TestOpen(t *testing.T) {
+	pub, sub, err := Open(Config{Backend: BackendMemory})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub != sub.(Publisher) {
+		t.Fatal("memory backend should share the bus")
+	}
+	if _, _, err := Open(Config{Backend: "rabbitmq"}); err == nil {
+		t.Fatal("want error for unknown backend")
+	}
+}
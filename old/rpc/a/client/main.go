@@ -1,20 +1,33 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
-	"net/rpc"
+	"testGo/rpc/rpcx"
+	"time"
 )
 
 func main() {
+	codecName := flag.String("codec", "gob", "编码, 与服务端一致: gob|json|msgpack")
+	flag.Parse()
 
-	client, err := rpc.Dial("tcp", "localhost:9999")
+	codec, err := rpcx.LookupCodec(*codecName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := rpcx.Dial("tcp", "localhost:9999", codec)
 	if err != nil {
 		log.Fatal("dialing:", err)
 	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
 	var reply string
-	err = client.Call("HelloService.Hello", "哈哈哈哈", &reply)
+	err = client.Call(ctx, "HelloService.Hello", "哈哈哈哈", &reply)
 	if err != nil {
 		log.Fatal(err)
 	}
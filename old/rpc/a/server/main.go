@@ -1,22 +1,35 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"net"
-	"net/rpc"
+	"testGo/rpc/rpcx"
 )
 
 func main() {
-	port := "9999"
-	rpc.RegisterName("HelloService", new(HelloService))
-	listener, err := net.Listen("tcp", ":"+port)
+	port := flag.String("port", "9999", "监听端口")
+	codecName := flag.String("codec", "gob", "编码: gob|json|msgpack, json 时可以用 nc 直接发送 JSON-RPC 2.0 请求")
+	flag.Parse()
 
+	codec, err := rpcx.LookupCodec(*codecName)
 	if err != nil {
-		log.Fatal("ListenTCP error:", err)
+		log.Fatal(err)
 	}
 
-	rpc.Accept(listener)
+	server := rpcx.NewServer()
+	if err := server.RegisterName("HelloService", new(HelloService)); err != nil {
+		log.Fatal("register error:", err)
+	}
+	listener, err := net.Listen("tcp", ":"+*port)
 
+	if err != nil {
+		log.Fatal("ListenTCP error:", err)
+	}
+
+	if err := server.Serve(listener, codec); err != nil {
+		log.Fatal("serve error:", err)
+	}
 }
 
 type HelloService struct{}
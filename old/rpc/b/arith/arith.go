@@ -0,0 +1,43 @@
+// Package arith 示例服务 Arith, 服务端和客户端共用参数与错误定义
+package arith
+
+import (
+	"context"
+	"log"
+
+	"testGo/rpc/rpcx"
+)
+
+// CodeDivideByZero 除数为 0 的业务错误码
+const CodeDivideByZero = 1001
+
+// ErrDivideByZero 除数为 0, 客户端可以用 errors.Is(err, arith.ErrDivideByZero) 判断
+var ErrDivideByZero = &rpcx.Error{Code: CodeDivideByZero, Message: "divide by 0"}
+
+type Args struct {
+	A, B int
+}
+
+type Quotient struct {
+	Quo, Rem int
+}
+
+type Arith int
+
+func (t *Arith) Multiply(args *Args, reply *int) error {
+	log.Println("Arith => Multiply", args)
+	*reply = args.A * args.B
+	return nil
+}
+
+func (t *Arith) Divide(ctx context.Context, args *Args, quo *Quotient) error {
+	log.Println("Arith => Divide", args)
+
+	if args.B == 0 {
+		return ErrDivideByZero
+	}
+
+	quo.Quo = args.A / args.B
+	quo.Rem = args.A % args.B
+	return nil
+}
@@ -1,55 +1,69 @@
-// 异步请求
+// 批量请求, 多个调用在一个请求中发送, 服务端并发处理
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
-	"net/rpc"
+	"testGo/rpc/b/arith"
+	"testGo/rpc/rpcx"
 	"time"
 )
 
-type Args struct {
-	A, B int
-}
-
-type Quotient struct {
-	Quo, Rem int
-}
-
 func main() {
-	client, err := rpc.DialHTTP("tcp", ":1234")
+	addr := flag.String("addr", ":1234", "服务端地址")
+	codecName := flag.String("codec", "gob", "编码, 与服务端一致: gob|json|msgpack")
+	flag.Parse()
+
+	codec, err := rpcx.LookupCodec(*codecName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := rpcx.Dial("tcp", *addr, codec)
 	if err != nil {
 		log.Fatal("dialing:", err)
 	}
+	defer client.Close()
 
-	args1 := &Args{7, 8}
+	args1 := &arith.Args{A: 7, B: 8}
 	var reply int
-	multiplyReply := client.Go("Arith.Multiply", args1, &reply, nil)
+	args2 := &arith.Args{A: 15, B: 6}
+	var quo arith.Quotient
+	args3 := &arith.Args{A: 15, B: 0}
+	var bad arith.Quotient
+
+	calls := []*rpcx.Call{
+		{Method: "Arith.Multiply", Args: args1, Reply: &reply},
+		{Method: "Arith.Divide", Args: args2, Reply: &quo},
+		{Method: "Arith.Divide", Args: args3, Reply: &bad},
+	}
 
-	args2 := &Args{15, 6}
-	var quo Quotient
-	divideReply := client.Go("Arith.Divide", args2, &quo, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- client.Batch(ctx, calls...) }()
 
 	ticker := time.NewTicker(time.Millisecond)
 	defer ticker.Stop()
-
-	var multiplyReplied, divideReplied bool
-	for !multiplyReplied || !divideReplied {
+	for {
 		select {
-		case replyCall := <-multiplyReply.Done:
-			if err := replyCall.Error; err != nil {
+		case err := <-done:
+			if err != nil {
+				log.Fatal("batch error:", err)
+			}
+			if err := calls[0].Error; err != nil {
 				fmt.Println("Multiply error:", err)
 			} else {
 				fmt.Printf("Multiply: %d*%d=%d\n", args1.A, args1.B, reply)
 			}
-			multiplyReplied = true
-		case replyCall := <-divideReply.Done:
-			if err := replyCall.Error; err != nil {
+			if err := calls[1].Error; err != nil {
 				fmt.Println("Divide error:", err)
 			} else {
 				fmt.Printf("Divide: %d/%d=%d...%d\n", args2.A, args2.B, quo.Quo, quo.Rem)
 			}
-			divideReplied = true
+			fmt.Println("Divide by zero error:", calls[2].Error)
+			return
 		case <-ticker.C:
 			fmt.Println("tick")
 		}
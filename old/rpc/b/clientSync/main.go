@@ -2,38 +2,60 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"net/rpc"
+	"testGo/rpc/b/arith"
+	"testGo/rpc/rpcx"
+	"time"
 )
 
-type Args struct {
-	A, B int
-}
-
-type Quotient struct {
-	Quo, Rem int
-}
-
 func main() {
-	client, err := rpc.DialHTTP("tcp", ":1234")
+	addr := flag.String("addr", ":1234", "服务端地址")
+	codecName := flag.String("codec", "gob", "编码, 与服务端一致: gob|json|msgpack")
+	token := flag.String("token", "", "Bearer token")
+	flag.Parse()
+
+	codec, err := rpcx.LookupCodec(*codecName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := rpcx.Dial("tcp", *addr, codec)
 	if err != nil {
 		log.Fatal("dialing:", err)
 	}
+	defer client.Close()
+
+	// 截止时间随请求发送给服务端
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if *token != "" {
+		ctx = rpcx.WithMetadata(ctx, "authorization", "Bearer "+*token)
+	}
 
-	args := &Args{7, 8}
+	args := &arith.Args{A: 7, B: 8}
 	var reply int
-	err = client.Call("Arith.Multiply", args, &reply)
+	err = client.Call(ctx, "Arith.Multiply", args, &reply)
 	if err != nil {
 		log.Fatal("Multiply error:", err)
 	}
 	fmt.Printf("Multiply: %d*%d=%d\n", args.A, args.B, reply)
 
-	args = &Args{15, 6}
-	var quo Quotient
-	err = client.Call("Arith.Divide", args, &quo)
+	args = &arith.Args{A: 15, B: 6}
+	var quo arith.Quotient
+	err = client.Call(ctx, "Arith.Divide", args, &quo)
 	if err != nil {
 		log.Fatal("Divide error:", err)
 	}
 	fmt.Printf("Divide: %d/%d=%d...%d\n", args.A, args.B, quo.Quo, quo.Rem)
+
+	args = &arith.Args{A: 15, B: 0}
+	err = client.Call(ctx, "Arith.Divide", args, &quo)
+	if errors.Is(err, arith.ErrDivideByZero) {
+		fmt.Printf("Divide: %d/%d: %v\n", args.A, args.B, err)
+	} else if err != nil {
+		log.Fatal("Divide error:", err)
+	}
 }
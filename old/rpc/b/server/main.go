@@ -1,46 +1,51 @@
 package main
 
 import (
-	"errors"
+	"flag"
 	"log"
+	"net"
 	"net/http"
-	"net/rpc"
+	"testGo/rpc/b/arith"
+	"testGo/rpc/rpcx"
 )
 
-type Args struct {
-	A, B int
-}
-
-type Quotient struct {
-	Quo, Rem int
-}
-
-type Arith int
-
-func (t *Arith) Multiply(args *Args, reply *int) error {
-	log.Println("Arith => Multiply", args)
-	*reply = args.A * args.B
-	return nil
-}
-
-func (t *Arith) Divide(args *Args, quo *Quotient) error {
-	log.Println("Arith => Divide", args)
+func main() {
+	addr := flag.String("addr", ":1234", "tcp 监听地址")
+	httpAddr := flag.String("http", ":1235", "JSON-RPC over HTTP 监听地址, 为空时不启动")
+	codecName := flag.String("codec", "gob", "tcp 编码: gob|json|msgpack")
+	token := flag.String("token", "", "不为空时要求调用方携带 Bearer token")
+	flag.Parse()
+
+	codec, err := rpcx.LookupCodec(*codecName)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	if args.B == 0 {
-		return errors.New("divide by 0")
+	log.Println("开始启动rpc 服务端")
+	server := rpcx.NewServer()
+	server.Use(rpcx.Logging(nil))
+	if *token != "" {
+		server.Use(rpcx.TokenAuth(*token))
+	}
+	if err := server.Register(new(arith.Arith)); err != nil {
+		log.Fatal("register error:", err)
 	}
 
-	quo.Quo = args.A / args.B
-	quo.Rem = args.A % args.B
-	return nil
-}
+	if *httpAddr != "" {
+		// 其他语言可以直接 POST JSON-RPC 2.0 请求, 例如
+		// curl -d '{"jsonrpc":"2.0","id":1,"method":"Arith.Multiply","params":{"A":7,"B":8}}' localhost:1235
+		go func() {
+			if err := http.ListenAndServe(*httpAddr, server); err != nil {
+				log.Fatal("serve http error:", err)
+			}
+		}()
+	}
 
-func main() {
-	log.Println("开始启动rpc 服务端")
-	arith := new(Arith)
-	rpc.Register(arith)
-	rpc.HandleHTTP()
-	if err := http.ListenAndServe(":1234", nil); err != nil {
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal("listen error:", err)
+	}
+	if err := server.Serve(lis, codec); err != nil {
 		log.Fatal("serve error:", err)
 	}
 }
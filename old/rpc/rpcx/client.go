@@ -0,0 +1,208 @@
+package rpcx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// ErrShutdown 连接已经关闭
+var ErrShutdown = errors.New("rpcx: connection is shut down")
+
+// Call 批量请求中的一次调用
+type Call struct {
+	Method string
+	Args   interface{}
+	Reply  interface{} // 结果解码的目标, 必须是指针, 为 nil 时丢弃结果
+	Error  error       // 调用完成后的错误, 服务端返回的错误为 *Error
+
+	seq  uint64
+	done chan struct{}
+}
+
+// Client rpc 客户端, 可以被多个 goroutine 同时使用
+type Client struct {
+	codec ClientCodec
+
+	sending sync.Mutex
+
+	mu       sync.Mutex
+	seq      uint64
+	pending  map[uint64]*Call
+	closing  bool // 用户调用了 Close
+	shutdown bool // 连接已经断开
+}
+
+// Dial 连接服务端
+func Dial(network, address string, codec Codec) (*Client, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn, codec), nil
+}
+
+// NewClient 在已有连接上创建客户端
+func NewClient(conn io.ReadWriteCloser, codec Codec) *Client {
+	return NewClientWithCodec(codec.NewClientCodec(conn))
+}
+
+// NewClientWithCodec 使用指定的编解码器创建客户端
+func NewClientWithCodec(codec ClientCodec) *Client {
+	c := &Client{codec: codec, pending: make(map[uint64]*Call)}
+	go c.input()
+	return c
+}
+
+// Call 调用 method 并等待结果
+// ctx 的截止时间会发送给服务端; ctx 结束时立即返回 ctx.Err(), 同时通知服务端取消
+func (c *Client) Call(ctx context.Context, method string, args, reply interface{}) error {
+	call := &Call{Method: method, Args: args, Reply: reply}
+	if err := c.do(ctx, []*Call{call}, false); err != nil {
+		return err
+	}
+	return call.Error
+}
+
+// Batch 以一个批量请求发送多次调用, 每次调用的错误在 Call.Error 中
+// 只有发送失败或 ctx 结束时才返回错误
+func (c *Client) Batch(ctx context.Context, calls ...*Call) error {
+	if len(calls) == 0 {
+		return nil
+	}
+	return c.do(ctx, calls, true)
+}
+
+// Notify 发送通知, 服务端不会响应
+func (c *Client) Notify(ctx context.Context, method string, args interface{}) error {
+	deadline, _ := ctx.Deadline()
+	req := &ClientRequest{Method: method, Deadline: deadline, Metadata: outgoingMetadata(ctx), Notify: true, Params: args}
+	return c.write([]*ClientRequest{req}, false)
+}
+
+func (c *Client) do(ctx context.Context, calls []*Call, isBatch bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	md := outgoingMetadata(ctx)
+
+	c.mu.Lock()
+	if c.closing || c.shutdown {
+		c.mu.Unlock()
+		return ErrShutdown
+	}
+	reqs := make([]*ClientRequest, 0, len(calls))
+	for _, call := range calls {
+		c.seq++
+		call.seq = c.seq
+		call.done = make(chan struct{})
+		c.pending[call.seq] = call
+		reqs = append(reqs, &ClientRequest{Seq: call.seq, Method: call.Method, Deadline: deadline, Metadata: md, Params: call.Args})
+	}
+	c.mu.Unlock()
+
+	if err := c.write(reqs, isBatch); err != nil {
+		for _, call := range calls {
+			c.removePending(call.seq)
+		}
+		return err
+	}
+
+	for _, call := range calls {
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			for _, call := range calls {
+				if c.removePending(call.seq) {
+					call.Error = ctx.Err()
+					c.cancelRemote(call.seq)
+				}
+			}
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (c *Client) write(reqs []*ClientRequest, isBatch bool) error {
+	c.sending.Lock()
+	defer c.sending.Unlock()
+	return c.codec.WriteBatch(reqs, isBatch)
+}
+
+// cancelRemote 通知服务端取消请求, 失败时忽略, 服务端最终会因为截止时间或连接断开结束处理
+func (c *Client) cancelRemote(seq uint64) {
+	req := &ClientRequest{Method: MethodCancel, Notify: true, Params: &CancelParams{ID: strconv.FormatUint(seq, 10)}}
+	if err := c.write([]*ClientRequest{req}, false); err != nil {
+		log.Println("rpcx: send cancel:", err)
+	}
+}
+
+// removePending 从等待列表中移除, 返回调用是否还在等待
+func (c *Client) removePending(seq uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.pending[seq]
+	delete(c.pending, seq)
+	return ok
+}
+
+func (c *Client) input() {
+	var err error
+	for err == nil {
+		var resps []*ClientResponse
+		resps, err = c.codec.ReadResponses()
+		for _, resp := range resps {
+			c.mu.Lock()
+			call := c.pending[resp.Seq]
+			delete(c.pending, resp.Seq)
+			c.mu.Unlock()
+			if call == nil {
+				// 已经取消或超时的调用
+				continue
+			}
+			if resp.Error != nil {
+				call.Error = resp.Error
+			} else if call.Reply != nil {
+				if derr := resp.Decode(call.Reply); derr != nil {
+					call.Error = fmt.Errorf("rpcx: decode reply: %w", derr)
+				}
+			}
+			close(call.done)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shutdown = true
+	if errors.Is(err, io.EOF) {
+		if c.closing {
+			err = ErrShutdown
+		} else {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	for seq, call := range c.pending {
+		call.Error = err
+		close(call.done)
+		delete(c.pending, seq)
+	}
+}
+
+// Close 关闭连接, 还在等待的调用返回错误
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closing {
+		c.mu.Unlock()
+		return ErrShutdown
+	}
+	c.closing = true
+	c.mu.Unlock()
+	return c.codec.Close()
+}
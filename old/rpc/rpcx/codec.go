@@ -0,0 +1,117 @@
+package rpcx
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ServerRequest 服务端收到的一个请求
+type ServerRequest struct {
+	ID       uint64            // 编解码器分配的编号, 响应时原样带回
+	Key      string            // 调用方看到的请求 id 的文本形式, 用于取消请求
+	Method   string            // Service.Method
+	Deadline time.Time         // 调用方的截止时间, 零值表示没有
+	Metadata map[string]string // 调用方附带的元数据, 例如认证信息
+	Notify   bool              // 通知, 不需要响应
+	// Err 不为 nil 时请求本身无效, 直接以该错误响应
+	Err *Error
+	// Decode 把参数解码到 v
+	Decode func(v interface{}) error
+}
+
+// ServerResponse 服务端的一个响应
+type ServerResponse struct {
+	ID     uint64
+	Result interface{}
+	Error  *Error
+}
+
+// ServerBatch 一次读到的请求, 非批量请求只有一个元素
+type ServerBatch struct {
+	Requests []*ServerRequest
+	IsBatch  bool
+}
+
+// ServerCodec 服务端编解码器
+// ReadBatch 只在一个 goroutine 中调用, WriteBatch 由服务端加锁串行调用
+type ServerCodec interface {
+	ReadBatch() (*ServerBatch, error)
+	// WriteBatch 写出 batch 对应的响应, 通知没有响应
+	WriteBatch(batch *ServerBatch, resps []*ServerResponse) error
+	Close() error
+}
+
+// ClientRequest 客户端发出的一个请求
+type ClientRequest struct {
+	Seq      uint64
+	Method   string
+	Deadline time.Time
+	Metadata map[string]string
+	Notify   bool
+	Params   interface{}
+}
+
+// ClientResponse 客户端收到的一个响应
+type ClientResponse struct {
+	Seq   uint64
+	Error *Error
+	// Decode 把结果解码到 v, 有 Error 时不要调用
+	Decode func(v interface{}) error
+}
+
+// ClientCodec 客户端编解码器
+// WriteBatch 由客户端加锁串行调用, ReadResponses 只在一个 goroutine 中调用
+type ClientCodec interface {
+	WriteBatch(reqs []*ClientRequest, isBatch bool) error
+	// ReadResponses 读取下一批响应, 批量请求的响应一次返回
+	ReadResponses() ([]*ClientResponse, error)
+	Close() error
+}
+
+// Codec 一种编码方式, 同时提供服务端和客户端实现
+type Codec struct {
+	Name           string
+	NewServerCodec func(conn io.ReadWriteCloser) ServerCodec
+	NewClientCodec func(conn io.ReadWriteCloser) ClientCodec
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = make(map[string]Codec)
+)
+
+// RegisterCodec 注册编码方式, 同名覆盖
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c.Name] = c
+}
+
+// LookupCodec 按名称查找编码方式
+func LookupCodec(name string) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[name]
+	if !ok {
+		return Codec{}, fmt.Errorf("rpcx: unknown codec %q, want one of %v", name, codecNames())
+	}
+	return c, nil
+}
+
+func codecNames() []string {
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterCodec(GobCodec)
+	RegisterCodec(JSONCodec)
+	RegisterCodec(MsgpackCodec)
+}
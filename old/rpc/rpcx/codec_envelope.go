@@ -0,0 +1,246 @@
+package rpcx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	ugorji "github.com/ugorji/go/codec"
+)
+
+// gob 和 msgpack 共用的帧格式: 每次读写一个 wireBatch/wireResponses,
+// 参数和结果单独编码成字节, 等找到方法、知道类型之后再解码
+type wireRequest struct {
+	Seq      uint64
+	Method   string
+	Deadline int64 // unix 纳秒, 0 表示没有截止时间
+	Metadata map[string]string
+	Notify   bool
+	Params   []byte
+}
+
+type wireBatch struct {
+	IsBatch  bool
+	Requests []wireRequest
+}
+
+type wireError struct {
+	Code    int
+	Message string
+	Data    []byte // json
+}
+
+type wireResponse struct {
+	Seq    uint64
+	Result []byte
+	Error  *wireError
+}
+
+type wireResponses struct {
+	Responses []wireResponse
+}
+
+// encoding 一种二进制编码
+type encoding struct {
+	newEncoder func(w io.Writer) func(v interface{}) error
+	newDecoder func(r io.Reader) func(v interface{}) error
+	marshal    func(v interface{}) ([]byte, error)
+	unmarshal  func(data []byte, v interface{}) error
+}
+
+var gobEncoding = &encoding{
+	newEncoder: func(w io.Writer) func(v interface{}) error { return gob.NewEncoder(w).Encode },
+	newDecoder: func(r io.Reader) func(v interface{}) error { return gob.NewDecoder(r).Decode },
+	marshal: func(v interface{}) ([]byte, error) {
+		var buf bytes.Buffer
+		err := gob.NewEncoder(&buf).Encode(v)
+		return buf.Bytes(), err
+	},
+	unmarshal: func(data []byte, v interface{}) error {
+		return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+	},
+}
+
+var msgpackHandle = &ugorji.MsgpackHandle{WriteExt: true}
+
+var msgpackEncoding = &encoding{
+	newEncoder: func(w io.Writer) func(v interface{}) error { return ugorji.NewEncoder(w, msgpackHandle).Encode },
+	newDecoder: func(r io.Reader) func(v interface{}) error { return ugorji.NewDecoder(r, msgpackHandle).Decode },
+	marshal: func(v interface{}) ([]byte, error) {
+		var data []byte
+		err := ugorji.NewEncoderBytes(&data, msgpackHandle).Encode(v)
+		return data, err
+	},
+	unmarshal: func(data []byte, v interface{}) error {
+		return ugorji.NewDecoderBytes(data, msgpackHandle).Decode(v)
+	},
+}
+
+// GobCodec encoding/gob 编码, 与 net/rpc 默认编码一样只适合 Go 之间调用
+var GobCodec = Codec{
+	Name:           "gob",
+	NewServerCodec: func(conn io.ReadWriteCloser) ServerCodec { return newEnvelopeServerCodec(conn, gobEncoding) },
+	NewClientCodec: func(conn io.ReadWriteCloser) ClientCodec { return newEnvelopeClientCodec(conn, gobEncoding) },
+}
+
+// MsgpackCodec msgpack 编码
+var MsgpackCodec = Codec{
+	Name:           "msgpack",
+	NewServerCodec: func(conn io.ReadWriteCloser) ServerCodec { return newEnvelopeServerCodec(conn, msgpackEncoding) },
+	NewClientCodec: func(conn io.ReadWriteCloser) ClientCodec { return newEnvelopeClientCodec(conn, msgpackEncoding) },
+}
+
+// encodeValue 编码参数或结果, nil 编码为空
+func (e *encoding) encodeValue(v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return e.marshal(v)
+}
+
+// decodeValue 解码参数或结果, 空数据保持零值
+func (e *encoding) decodeValue(data []byte) func(v interface{}) error {
+	return func(v interface{}) error {
+		if len(data) == 0 {
+			return nil
+		}
+		return e.unmarshal(data, v)
+	}
+}
+
+type envelopeServerCodec struct {
+	rwc    io.ReadWriteCloser
+	buf    *bufio.Writer
+	enc    *encoding
+	encode func(v interface{}) error
+	decode func(v interface{}) error
+}
+
+func newEnvelopeServerCodec(conn io.ReadWriteCloser, enc *encoding) *envelopeServerCodec {
+	buf := bufio.NewWriter(conn)
+	return &envelopeServerCodec{
+		rwc:    conn,
+		buf:    buf,
+		enc:    enc,
+		encode: enc.newEncoder(buf),
+		decode: enc.newDecoder(bufio.NewReader(conn)),
+	}
+}
+
+func (c *envelopeServerCodec) ReadBatch() (*ServerBatch, error) {
+	var wb wireBatch
+	if err := c.decode(&wb); err != nil {
+		return nil, err
+	}
+	batch := &ServerBatch{IsBatch: wb.IsBatch}
+	for _, wr := range wb.Requests {
+		req := &ServerRequest{
+			ID:       wr.Seq,
+			Key:      strconv.FormatUint(wr.Seq, 10),
+			Method:   wr.Method,
+			Metadata: wr.Metadata,
+			Notify:   wr.Notify,
+			Decode:   c.enc.decodeValue(wr.Params),
+		}
+		if wr.Deadline != 0 {
+			req.Deadline = time.Unix(0, wr.Deadline)
+		}
+		batch.Requests = append(batch.Requests, req)
+	}
+	return batch, nil
+}
+
+func (c *envelopeServerCodec) WriteBatch(batch *ServerBatch, resps []*ServerResponse) error {
+	var out wireResponses
+	for _, resp := range resps {
+		wr := wireResponse{Seq: resp.ID}
+		if resp.Error == nil {
+			result, err := c.enc.encodeValue(resp.Result)
+			if err != nil {
+				resp.Error = NewError(CodeInternalError, "encode result: %v", err)
+			}
+			wr.Result = result
+		}
+		if resp.Error != nil {
+			wr.Result = nil
+			wr.Error = &wireError{Code: resp.Error.Code, Message: resp.Error.Message}
+			if resp.Error.Data != nil {
+				wr.Error.Data, _ = json.Marshal(resp.Error.Data)
+			}
+		}
+		out.Responses = append(out.Responses, wr)
+	}
+	if err := c.encode(&out); err != nil {
+		return err
+	}
+	return c.buf.Flush()
+}
+
+func (c *envelopeServerCodec) Close() error {
+	return c.rwc.Close()
+}
+
+type envelopeClientCodec struct {
+	rwc    io.ReadWriteCloser
+	buf    *bufio.Writer
+	enc    *encoding
+	encode func(v interface{}) error
+	decode func(v interface{}) error
+}
+
+func newEnvelopeClientCodec(conn io.ReadWriteCloser, enc *encoding) *envelopeClientCodec {
+	buf := bufio.NewWriter(conn)
+	return &envelopeClientCodec{
+		rwc:    conn,
+		buf:    buf,
+		enc:    enc,
+		encode: enc.newEncoder(buf),
+		decode: enc.newDecoder(bufio.NewReader(conn)),
+	}
+}
+
+func (c *envelopeClientCodec) WriteBatch(reqs []*ClientRequest, isBatch bool) error {
+	wb := wireBatch{IsBatch: isBatch}
+	for _, req := range reqs {
+		params, err := c.enc.encodeValue(req.Params)
+		if err != nil {
+			return err
+		}
+		wr := wireRequest{Seq: req.Seq, Method: req.Method, Metadata: req.Metadata, Notify: req.Notify, Params: params}
+		if !req.Deadline.IsZero() {
+			wr.Deadline = req.Deadline.UnixNano()
+		}
+		wb.Requests = append(wb.Requests, wr)
+	}
+	if err := c.encode(&wb); err != nil {
+		return err
+	}
+	return c.buf.Flush()
+}
+
+func (c *envelopeClientCodec) ReadResponses() ([]*ClientResponse, error) {
+	var in wireResponses
+	if err := c.decode(&in); err != nil {
+		return nil, err
+	}
+	resps := make([]*ClientResponse, 0, len(in.Responses))
+	for _, wr := range in.Responses {
+		resp := &ClientResponse{Seq: wr.Seq, Decode: c.enc.decodeValue(wr.Result)}
+		if wr.Error != nil {
+			resp.Error = &Error{Code: wr.Error.Code, Message: wr.Error.Message}
+			if len(wr.Error.Data) > 0 {
+				_ = json.Unmarshal(wr.Error.Data, &resp.Error.Data)
+			}
+		}
+		resps = append(resps, resp)
+	}
+	return resps, nil
+}
+
+func (c *envelopeClientCodec) Close() error {
+	return c.rwc.Close()
+}
@@ -0,0 +1,299 @@
+package rpcx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// JSONCodec JSON-RPC 2.0 编码, 请求和响应是连续的 json 值, 可以被其他语言直接调用
+//
+// 在标准字段之外支持两个扩展字段:
+//
+//	{"jsonrpc":"2.0","id":1,"method":"Arith.Divide","params":[{"A":7,"B":2}],
+//	 "deadline":"2024-05-01T08:00:00.5Z","meta":{"authorization":"Bearer xxx"}}
+//
+// params 为只有一个元素的数组时取第一个元素作为参数(与 net/rpc/jsonrpc 相同), 否则整个 params 作为参数
+// 方法 rpc.cancel 取消同一连接上还没有完成的请求, params 为 {"id":"<请求 id 的文本>"}
+var JSONCodec = Codec{
+	Name:           "json",
+	NewServerCodec: func(conn io.ReadWriteCloser) ServerCodec { return newJSONServerCodec(conn) },
+	NewClientCodec: func(conn io.ReadWriteCloser) ClientCodec { return newJSONClientCodec(conn) },
+}
+
+const jsonrpcVersion = "2.0"
+
+type jsonRequest struct {
+	Version  string            `json:"jsonrpc"`
+	ID       *json.RawMessage  `json:"id,omitempty"`
+	Method   string            `json:"method"`
+	Params   json.RawMessage   `json:"params,omitempty"`
+	Deadline string            `json:"deadline,omitempty"`
+	Meta     map[string]string `json:"meta,omitempty"`
+}
+
+type jsonResponse struct {
+	Version string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *Error           `json:"error,omitempty"`
+}
+
+var jsonNull = json.RawMessage("null")
+
+type jsonServerCodec struct {
+	rwc io.ReadWriteCloser
+	dec *json.Decoder
+	enc *json.Encoder
+
+	mu     sync.Mutex
+	seq    uint64
+	ids    map[uint64]*json.RawMessage // 内部编号 -> 调用方的 id
+	broken error                       // 读到无法解析的数据后, 下一次读取返回该错误
+}
+
+func newJSONServerCodec(conn io.ReadWriteCloser) *jsonServerCodec {
+	return &jsonServerCodec{
+		rwc: conn,
+		dec: json.NewDecoder(conn),
+		enc: json.NewEncoder(conn),
+		ids: make(map[uint64]*json.RawMessage),
+	}
+}
+
+func (c *jsonServerCodec) ReadBatch() (*ServerBatch, error) {
+	if c.broken != nil {
+		return nil, c.broken
+	}
+	var raw json.RawMessage
+	if err := c.dec.Decode(&raw); err != nil {
+		var syntax *json.SyntaxError
+		if !errors.As(err, &syntax) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, err
+		}
+		// 流已经无法继续解析, 先响应 parse error 再断开
+		c.broken = err
+		return &ServerBatch{Requests: []*ServerRequest{c.invalid(nil, NewError(CodeParseError, "parse error: %v", err))}}, nil
+	}
+
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] != '[' {
+		return &ServerBatch{Requests: []*ServerRequest{c.parseRequest(raw)}}, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil || len(items) == 0 {
+		return &ServerBatch{Requests: []*ServerRequest{c.invalid(nil, NewError(CodeInvalidRequest, "invalid batch"))}}, nil
+	}
+	batch := &ServerBatch{IsBatch: true}
+	for _, item := range items {
+		batch.Requests = append(batch.Requests, c.parseRequest(item))
+	}
+	return batch, nil
+}
+
+func (c *jsonServerCodec) register(id *json.RawMessage) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	c.ids[c.seq] = id
+	return c.seq
+}
+
+func (c *jsonServerCodec) invalid(id *json.RawMessage, err *Error) *ServerRequest {
+	return &ServerRequest{ID: c.register(id), Err: err}
+}
+
+func (c *jsonServerCodec) parseRequest(raw json.RawMessage) *ServerRequest {
+	var jr jsonRequest
+	if err := json.Unmarshal(raw, &jr); err != nil {
+		return c.invalid(nil, NewError(CodeInvalidRequest, "invalid request: %v", err))
+	}
+	if jr.Version != jsonrpcVersion || jr.Method == "" {
+		return c.invalid(jr.ID, NewError(CodeInvalidRequest, "invalid request: want jsonrpc 2.0 with method"))
+	}
+
+	req := &ServerRequest{
+		ID:       c.register(jr.ID),
+		Method:   jr.Method,
+		Metadata: jr.Meta,
+		Notify:   jr.ID == nil,
+		Decode:   decodeJSONParams(jr.Params),
+	}
+	if jr.ID != nil {
+		req.Key = idKey(*jr.ID)
+	}
+	if jr.Deadline != "" {
+		deadline, err := time.Parse(time.RFC3339Nano, jr.Deadline)
+		if err != nil {
+			req.Err = NewError(CodeInvalidRequest, "invalid deadline: %v", err)
+		}
+		req.Deadline = deadline
+	}
+	return req
+}
+
+// idKey 请求 id 的文本形式, 字符串去掉引号
+func idKey(id json.RawMessage) string {
+	var s string
+	if json.Unmarshal(id, &s) == nil {
+		return s
+	}
+	return string(id)
+}
+
+// decodeJSONParams 按 params 的形状解码参数
+func decodeJSONParams(params json.RawMessage) func(v interface{}) error {
+	return func(v interface{}) error {
+		params := bytes.TrimSpace(params)
+		if len(params) == 0 || bytes.Equal(params, jsonNull) {
+			return nil
+		}
+		if params[0] == '[' && !isSliceTarget(v) {
+			var items []json.RawMessage
+			if err := json.Unmarshal(params, &items); err != nil {
+				return err
+			}
+			if len(items) != 1 {
+				return errors.New("positional params must have exactly one element")
+			}
+			params = items[0]
+		}
+		return json.Unmarshal(params, v)
+	}
+}
+
+func isSliceTarget(v interface{}) bool {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array)
+}
+
+func (c *jsonServerCodec) WriteBatch(batch *ServerBatch, resps []*ServerResponse) error {
+	out := make([]*jsonResponse, 0, len(resps))
+	c.mu.Lock()
+	for _, resp := range resps {
+		jr := &jsonResponse{Version: jsonrpcVersion, ID: c.ids[resp.ID], Error: resp.Error}
+		if jr.ID == nil {
+			jr.ID = &jsonNull
+		}
+		if resp.Error == nil {
+			result, err := json.Marshal(resp.Result)
+			if err != nil {
+				jr.Error = NewError(CodeInternalError, "encode result: %v", err)
+			} else {
+				jr.Result = result
+			}
+		}
+		out = append(out, jr)
+	}
+	for _, req := range batch.Requests {
+		delete(c.ids, req.ID)
+	}
+	c.mu.Unlock()
+
+	switch {
+	case len(out) == 0:
+		return nil
+	case batch.IsBatch:
+		return c.enc.Encode(out)
+	default:
+		return c.enc.Encode(out[0])
+	}
+}
+
+func (c *jsonServerCodec) Close() error {
+	return c.rwc.Close()
+}
+
+type jsonClientCodec struct {
+	rwc io.ReadWriteCloser
+	dec *json.Decoder
+	enc *json.Encoder
+}
+
+func newJSONClientCodec(conn io.ReadWriteCloser) *jsonClientCodec {
+	return &jsonClientCodec{rwc: conn, dec: json.NewDecoder(conn), enc: json.NewEncoder(conn)}
+}
+
+func (c *jsonClientCodec) WriteBatch(reqs []*ClientRequest, isBatch bool) error {
+	out := make([]*jsonRequest, 0, len(reqs))
+	for _, req := range reqs {
+		jr := &jsonRequest{Version: jsonrpcVersion, Method: req.Method, Meta: req.Metadata}
+		if req.Params != nil {
+			params, err := json.Marshal([]interface{}{req.Params})
+			if err != nil {
+				return err
+			}
+			jr.Params = params
+		}
+		if !req.Notify {
+			id := json.RawMessage(strconv.FormatUint(req.Seq, 10))
+			jr.ID = &id
+		}
+		if !req.Deadline.IsZero() {
+			jr.Deadline = req.Deadline.UTC().Format(time.RFC3339Nano)
+		}
+		out = append(out, jr)
+	}
+	if isBatch {
+		return c.enc.Encode(out)
+	}
+	return c.enc.Encode(out[0])
+}
+
+func (c *jsonClientCodec) ReadResponses() ([]*ClientResponse, error) {
+	var raw json.RawMessage
+	if err := c.dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	var items []json.RawMessage
+	if raw = bytes.TrimSpace(raw); len(raw) > 0 && raw[0] == '[' {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+	} else {
+		items = []json.RawMessage{raw}
+	}
+
+	resps := make([]*ClientResponse, 0, len(items))
+	for _, item := range items {
+		var jr jsonResponse
+		if err := json.Unmarshal(item, &jr); err != nil {
+			return nil, err
+		}
+		var seq uint64
+		if jr.ID != nil && json.Unmarshal(*jr.ID, &seq) != nil {
+			log.Printf("rpcx: response with unexpected id %s", *jr.ID)
+			continue
+		}
+		if seq == 0 && jr.Error != nil {
+			// 服务端无法解析请求时 id 为 null, 无法对应到具体调用
+			log.Printf("rpcx: server error without id: %v", jr.Error)
+			continue
+		}
+		result := jr.Result
+		resps = append(resps, &ClientResponse{
+			Seq:   seq,
+			Error: jr.Error,
+			Decode: func(v interface{}) error {
+				if len(result) == 0 {
+					return nil
+				}
+				return json.Unmarshal(result, v)
+			},
+		})
+	}
+	return resps, nil
+}
+
+func (c *jsonClientCodec) Close() error {
+	return c.rwc.Close()
+}
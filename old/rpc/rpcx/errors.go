@@ -0,0 +1,65 @@
+package rpcx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// 错误码, -32768 ~ -32000 沿用 JSON-RPC 2.0 的定义, 业务错误码请使用正数
+const (
+	CodeParseError     = -32700 // 请求无法解析
+	CodeInvalidRequest = -32600 // 请求格式不对
+	CodeMethodNotFound = -32601 // 方法不存在
+	CodeInvalidParams  = -32602 // 参数无法解码
+	CodeInternalError  = -32603 // 服务端内部错误, 例如处理函数 panic
+
+	CodeServerError      = -32000 // 处理函数返回的普通 error
+	CodeDeadlineExceeded = -32001 // 超过调用方设置的截止时间
+	CodeCanceled         = -32002 // 调用方取消了请求
+	CodeUnauthenticated  = -32003 // 认证失败
+)
+
+// Error 结构化的 rpc 错误, 处理函数返回 *Error 时原样传给调用方
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// Is 错误码相同即认为是同一个错误, 方便 errors.Is(err, ErrDivideByZero) 这样的判断
+// 超时和取消的错误码同时匹配 context.DeadlineExceeded 和 context.Canceled
+func (e *Error) Is(target error) bool {
+	switch target {
+	case context.DeadlineExceeded:
+		return e.Code == CodeDeadlineExceeded
+	case context.Canceled:
+		return e.Code == CodeCanceled
+	}
+	var t *Error
+	return errors.As(target, &t) && t.Code == e.Code
+}
+
+// NewError 创建结构化错误
+func NewError(code int, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// toError 把处理函数返回的错误转换为 *Error
+func toError(err error) *Error {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: CodeDeadlineExceeded, Message: err.Error()}
+	case errors.Is(err, context.Canceled):
+		return &Error{Code: CodeCanceled, Message: err.Error()}
+	default:
+		return &Error{Code: CodeServerError, Message: err.Error()}
+	}
+}
@@ -0,0 +1,47 @@
+package rpcx
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"time"
+)
+
+// Logging 记录每次调用的方法、耗时和错误, logger 为 nil 时使用标准库默认 logger
+func Logging(logger *log.Logger) Middleware {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, inv *Invocation) error {
+			start := time.Now()
+			err := next(ctx, inv)
+			if err != nil {
+				logger.Printf("rpcx: %s cost=%v err=%v", inv.Method, time.Since(start), err)
+			} else {
+				logger.Printf("rpcx: %s cost=%v ok", inv.Method, time.Since(start))
+			}
+			return err
+		}
+	}
+}
+
+// TokenAuth 要求元数据 authorization 为 "Bearer <token>", skip 中的方法不需要认证
+func TokenAuth(token string, skip ...string) Middleware {
+	want := []byte("Bearer " + token)
+	skipped := make(map[string]bool, len(skip))
+	for _, m := range skip {
+		skipped[m] = true
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, inv *Invocation) error {
+			if !skipped[inv.Method] {
+				got := []byte(inv.Metadata[MetadataAuthorization])
+				if subtle.ConstantTimeCompare(got, want) != 1 {
+					return NewError(CodeUnauthenticated, "unauthenticated")
+				}
+			}
+			return next(ctx, inv)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package rpcx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type Args struct {
+	A, B int
+}
+
+type Quotient struct {
+	Quo, Rem int
+}
+
+var errDivideByZero = &Error{Code: 1001, Message: "divide by 0"}
+
+type Arith struct {
+	canceled atomic.Int32
+}
+
+func (t *Arith) Multiply(args *Args, reply *int) error {
+	*reply = args.A * args.B
+	return nil
+}
+
+func (t *Arith) Divide(ctx context.Context, args Args, quo *Quotient) error {
+	if args.B == 0 {
+		return errDivideByZero
+	}
+	quo.Quo = args.A / args.B
+	quo.Rem = args.A % args.B
+	return nil
+}
+
+// Sleep 等待 ms 毫秒或被取消
+func (t *Arith) Sleep(ctx context.Context, ms int, reply *bool) error {
+	select {
+	case <-time.After(time.Duration(ms) * time.Millisecond):
+		*reply = true
+		return nil
+	case <-ctx.Done():
+		t.canceled.Add(1)
+		return ctx.Err()
+	}
+}
+
+func (t *Arith) Whoami(ctx context.Context, _ struct{}, reply *string) error {
+	*reply = MetadataFromContext(ctx)["user"]
+	return nil
+}
+
+func newTestServer(t *testing.T) (*Server, *Arith) {
+	t.Helper()
+	s := NewServer()
+	arith := &Arith{}
+	if err := s.Register(arith); err != nil {
+		t.Fatal(err)
+	}
+	return s, arith
+}
+
+// pipeClient 用 net.Pipe 连接服务端和客户端
+func pipeClient(t *testing.T, s *Server, codec Codec) *Client {
+	t.Helper()
+	srv, cli := net.Pipe()
+	go s.ServeConn(srv, codec)
+	c := NewClient(cli, codec)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestCodecs(t *testing.T) {
+	for _, codec := range []Codec{GobCodec, JSONCodec, MsgpackCodec} {
+		t.Run(codec.Name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			c := pipeClient(t, s, codec)
+			ctx := context.Background()
+
+			var product int
+			if err := c.Call(ctx, "Arith.Multiply", &Args{7, 8}, &product); err != nil || product != 56 {
+				t.Fatalf("Multiply got %d, %v", product, err)
+			}
+
+			var quo Quotient
+			if err := c.Call(ctx, "Arith.Divide", &Args{15, 6}, &quo); err != nil || quo != (Quotient{2, 3}) {
+				t.Fatalf("Divide got %+v, %v", quo, err)
+			}
+
+			err := c.Call(ctx, "Arith.Divide", &Args{1, 0}, &quo)
+			var rpcErr *Error
+			if !errors.As(err, &rpcErr) || rpcErr.Code != 1001 || !errors.Is(err, errDivideByZero) {
+				t.Fatalf("want divide by zero error, got %v", err)
+			}
+
+			if err := c.Call(ctx, "Arith.Pow", &Args{}, nil); !errors.Is(err, &Error{Code: CodeMethodNotFound}) {
+				t.Fatalf("want method not found, got %v", err)
+			}
+
+			var who string
+			if err := c.Call(WithMetadata(ctx, "User", "alice"), "Arith.Whoami", struct{}{}, &who); err != nil || who != "alice" {
+				t.Fatalf("Whoami got %q, %v", who, err)
+			}
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	for _, codec := range []Codec{GobCodec, JSONCodec, MsgpackCodec} {
+		t.Run(codec.Name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			c := pipeClient(t, s, codec)
+
+			var product int
+			var quo, bad Quotient
+			calls := []*Call{
+				{Method: "Arith.Multiply", Args: &Args{3, 4}, Reply: &product},
+				{Method: "Arith.Divide", Args: &Args{9, 2}, Reply: &quo},
+				{Method: "Arith.Divide", Args: &Args{9, 0}, Reply: &bad},
+			}
+			if err := c.Batch(context.Background(), calls...); err != nil {
+				t.Fatal(err)
+			}
+			if calls[0].Error != nil || product != 12 {
+				t.Fatalf("Multiply got %d, %v", product, calls[0].Error)
+			}
+			if calls[1].Error != nil || quo != (Quotient{4, 1}) {
+				t.Fatalf("Divide got %+v, %v", quo, calls[1].Error)
+			}
+			if !errors.Is(calls[2].Error, errDivideByZero) {
+				t.Fatalf("want divide by zero, got %v", calls[2].Error)
+			}
+		})
+	}
+}
+
+func TestDeadlineAndCancel(t *testing.T) {
+	s, arith := newTestServer(t)
+	c := pipeClient(t, s, JSONCodec)
+
+	// 截止时间随请求发送, 服务端处理函数的 ctx 同时超时
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	var ok bool
+	if err := c.Call(ctx, "Arith.Sleep", 5000, &ok); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want deadline exceeded, got %v", err)
+	}
+
+	// 没有截止时间的请求被取消后, 服务端收到 rpc.cancel
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(30*time.Millisecond, cancel)
+	if err := c.Call(ctx, "Arith.Sleep", 5000, &ok); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want canceled, got %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for arith.canceled.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("server saw %d cancellations, want 2", arith.canceled.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 连接仍然可用
+	if err := c.Call(context.Background(), "Arith.Sleep", 1, &ok); err != nil || !ok {
+		t.Fatalf("got %v, %v", ok, err)
+	}
+}
+
+func TestTokenAuth(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.Use(TokenAuth("secret", "Arith.Multiply"))
+	c := pipeClient(t, s, GobCodec)
+
+	var quo Quotient
+	if err := c.Call(context.Background(), "Arith.Divide", &Args{4, 2}, &quo); !errors.Is(err, &Error{Code: CodeUnauthenticated}) {
+		t.Fatalf("want unauthenticated, got %v", err)
+	}
+	ctx := WithMetadata(context.Background(), "authorization", "Bearer secret")
+	if err := c.Call(ctx, "Arith.Divide", &Args{4, 2}, &quo); err != nil || quo.Quo != 2 {
+		t.Fatalf("got %+v, %v", quo, err)
+	}
+	var product int
+	if err := c.Call(context.Background(), "Arith.Multiply", &Args{2, 2}, &product); err != nil {
+		t.Fatalf("skipped method: %v", err)
+	}
+}
+
+// 中间件替换的 Args 和 Reply 就是方法收到的参数和返回给调用方的结果
+func TestMiddlewareReplacesInvocation(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.Use(func(next Handler) Handler {
+		return func(ctx context.Context, inv *Invocation) error {
+			switch args := inv.Args.(type) {
+			case *Args:
+				inv.Args = &Args{args.A * 10, args.B}
+			case *struct{}:
+				inv.Args = "wrong type"
+			}
+			if inv.Method == "Arith.Multiply" {
+				inv.Reply = new(int)
+			}
+			return next(ctx, inv)
+		}
+	})
+	c := pipeClient(t, s, GobCodec)
+
+	var product int
+	if err := c.Call(context.Background(), "Arith.Multiply", &Args{2, 3}, &product); err != nil || product != 60 {
+		t.Fatalf("got %d, %v", product, err)
+	}
+	var quo Quotient
+	if err := c.Call(context.Background(), "Arith.Divide", &Args{7, 2}, &quo); err != nil || quo.Quo != 35 {
+		t.Fatalf("got %+v, %v", quo, err)
+	}
+	var name string
+	if err := c.Call(context.Background(), "Arith.Whoami", struct{}{}, &name); !errors.Is(err, &Error{Code: CodeInternalError}) {
+		t.Fatalf("want internal error, got %v", err)
+	}
+}
+
+func TestJSONRPCOverHTTP(t *testing.T) {
+	s, _ := newTestServer(t)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	post := func(body string) (int, string) {
+		t.Helper()
+		resp, err := http.Post(srv.URL, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(data))
+	}
+
+	tests := []struct {
+		name, body, want string
+	}{
+		{"named params", `{"jsonrpc":"2.0","id":1,"method":"Arith.Multiply","params":{"A":6,"B":7}}`,
+			`{"jsonrpc":"2.0","id":1,"result":42}`},
+		{"structured error", `{"jsonrpc":"2.0","id":"a","method":"Arith.Divide","params":[{"A":1,"B":0}]}`,
+			`{"jsonrpc":"2.0","id":"a","error":{"code":1001,"message":"divide by 0"}}`},
+		{"batch with notification and invalid request", `[{"jsonrpc":"2.0","id":1,"method":"Arith.Divide","params":[{"A":7,"B":2}]},{"jsonrpc":"2.0","method":"Arith.Multiply","params":[{"A":1,"B":1}]},{"foo":1}]`,
+			`[{"jsonrpc":"2.0","id":1,"result":{"Quo":3,"Rem":1}},{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"invalid request: want jsonrpc 2.0 with method"}}]`},
+		{"parse error", `{"jsonrpc":`,
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error: unexpected EOF"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, got := post(tt.body)
+			if code != http.StatusOK || got != tt.want {
+				t.Fatalf("got %d %s\nwant %s", code, got, tt.want)
+			}
+		})
+	}
+
+	if code, _ := post(`{"jsonrpc":"2.0","method":"Arith.Multiply","params":[{"A":1,"B":1}]}`); code != http.StatusNoContent {
+		t.Fatalf("notification got %d", code)
+	}
+}
+
+func TestRegisterRejectsBadService(t *testing.T) {
+	type hidden struct{}
+	if err := NewServer().Register(&hidden{}); err == nil {
+		t.Fatal("want error for unexported type")
+	}
+	s, _ := newTestServer(t)
+	if err := s.Register(&Arith{}); err == nil {
+		t.Fatal("want error for duplicate service")
+	}
+}
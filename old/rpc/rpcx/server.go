@@ -0,0 +1,296 @@
+// Package rpcx 沿用 net/rpc 方法约定的小型 rpc 框架
+//
+// 在 net/rpc 的基础上增加了可替换的编码(gob、JSON-RPC 2.0、msgpack)、每次调用的截止时间和取消、
+// 中间件以及批量请求; 处理函数返回 *Error 时调用方收到结构化的错误码
+package rpcx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// MethodCancel 取消同一连接上还没有完成的请求, 参数为 CancelParams
+const MethodCancel = "rpc.cancel"
+
+// CancelParams rpc.cancel 的参数
+type CancelParams struct {
+	ID string `json:"id"` // 要取消的请求 id 的文本形式
+}
+
+// Invocation 一次调用, 中间件可以读取和修改;
+// 替换 Args 或 Reply 时类型必须和方法的参数、结果一致, 方法收到的和返回给调用方的都是替换后的值
+type Invocation struct {
+	Method   string
+	Metadata map[string]string
+	Args     interface{} // 解码后的参数, 总是指针
+	Reply    interface{} // 结果, 总是指针
+}
+
+// Handler 处理一次调用
+type Handler func(ctx context.Context, inv *Invocation) error
+
+// Middleware 包装 Handler, 先注册的在外层
+type Middleware func(next Handler) Handler
+
+// Server rpc 服务端
+type Server struct {
+	services    sync.Map // name -> *service
+	middlewares []Middleware
+}
+
+// NewServer 创建服务端
+func NewServer() *Server {
+	return &Server{}
+}
+
+// Register 以类型名注册服务
+func (s *Server) Register(rcvr interface{}) error {
+	return s.RegisterName("", rcvr)
+}
+
+// RegisterName 以指定名称注册服务
+func (s *Server) RegisterName(name string, rcvr interface{}) error {
+	svc, err := newService(name, rcvr)
+	if err != nil {
+		return err
+	}
+	if _, dup := s.services.LoadOrStore(svc.name, svc); dup {
+		return errors.New("rpcx: service already defined: " + svc.name)
+	}
+	return nil
+}
+
+// Use 添加中间件, 需要在开始服务之前调用
+func (s *Server) Use(mws ...Middleware) {
+	s.middlewares = append(s.middlewares, mws...)
+}
+
+// Serve 接受连接并以 codec 编码提供服务, 直到 listener 关闭
+func (s *Server) Serve(lis net.Listener, codec Codec) error {
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.ServeConn(conn, codec)
+	}
+}
+
+// ServeConn 在一个连接上提供服务, 阻塞直到连接断开
+func (s *Server) ServeConn(conn io.ReadWriteCloser, codec Codec) {
+	s.ServeCodec(codec.NewServerCodec(conn))
+}
+
+// ServeCodec 在一个编解码器上提供服务, 阻塞直到读取出错
+// 每一批请求在单独的 goroutine 中处理, 连接断开时取消所有还在处理的请求
+func (s *Server) ServeCodec(codec ServerCodec) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var (
+		writeMu  sync.Mutex
+		wg       sync.WaitGroup
+		inflight sync.Map // 请求 id -> context.CancelFunc
+	)
+	for {
+		batch, err := codec.ReadBatch()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Println("rpcx: read request:", err)
+			}
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resps := s.handleBatch(ctx, batch, &inflight)
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			if err := codec.WriteBatch(batch, resps); err != nil {
+				log.Println("rpcx: write response:", err)
+			}
+		}()
+	}
+	cancel()
+	wg.Wait()
+	codec.Close()
+}
+
+// ServeHTTP 以 JSON-RPC 2.0 over HTTP 提供服务, 请求体可以是单个请求或批量请求
+// Authorization 请求头在没有 meta.authorization 时作为元数据传给处理函数
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "rpcx: POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	codec := newJSONServerCodec(httpConn{Reader: r.Body, Writer: w})
+	batch, err := codec.ReadBatch()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		for _, req := range batch.Requests {
+			if _, ok := req.Metadata[MetadataAuthorization]; !ok {
+				if req.Metadata == nil {
+					req.Metadata = make(map[string]string)
+				}
+				req.Metadata[MetadataAuthorization] = auth
+			}
+		}
+	}
+
+	resps := s.handleBatch(r.Context(), batch, &sync.Map{})
+	if len(resps) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := codec.WriteBatch(batch, resps); err != nil {
+		log.Println("rpcx: write http response:", err)
+	}
+}
+
+type httpConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (httpConn) Close() error { return nil }
+
+// handleBatch 并发处理一批请求, 按请求顺序返回响应, 通知没有响应
+func (s *Server) handleBatch(ctx context.Context, batch *ServerBatch, inflight *sync.Map) []*ServerResponse {
+	resps := make([]*ServerResponse, len(batch.Requests))
+	var wg sync.WaitGroup
+	for i, req := range batch.Requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resps[i] = s.handleRequest(ctx, req, inflight)
+		}()
+	}
+	wg.Wait()
+
+	out := resps[:0]
+	for _, resp := range resps {
+		if resp != nil {
+			out = append(out, resp)
+		}
+	}
+	return out
+}
+
+func (s *Server) handleRequest(ctx context.Context, req *ServerRequest, inflight *sync.Map) *ServerResponse {
+	if req.Err != nil {
+		return &ServerResponse{ID: req.ID, Error: req.Err}
+	}
+	reply := func(result interface{}, err error) *ServerResponse {
+		if req.Notify {
+			return nil
+		}
+		if err != nil {
+			return &ServerResponse{ID: req.ID, Error: toError(err)}
+		}
+		return &ServerResponse{ID: req.ID, Result: result}
+	}
+
+	if req.Method == MethodCancel {
+		var p CancelParams
+		if err := req.Decode(&p); err != nil {
+			return reply(nil, NewError(CodeInvalidParams, "invalid params: %v", err))
+		}
+		cancel, ok := inflight.Load(p.ID)
+		if ok {
+			cancel.(context.CancelFunc)()
+		}
+		return reply(ok, nil)
+	}
+
+	svc, mt, e := s.lookup(req.Method)
+	if e != nil {
+		return reply(nil, e)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if !req.Deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(ctx, req.Deadline)
+		defer cancel()
+	}
+	if req.Key != "" && !req.Notify {
+		inflight.Store(req.Key, cancel)
+		defer inflight.Delete(req.Key)
+	}
+	if err := ctx.Err(); err != nil {
+		return reply(nil, err)
+	}
+	ctx = withIncomingMetadata(ctx, req.Metadata)
+
+	args := mt.newArgs()
+	if err := req.Decode(args); err != nil {
+		return reply(nil, NewError(CodeInvalidParams, "invalid params: %v", err))
+	}
+	inv := &Invocation{Method: req.Method, Metadata: req.Metadata, Args: args, Reply: reflect.New(mt.replyType.Elem()).Interface()}
+
+	// 最内层按中间件修改后的 Args 和 Reply 调用方法
+	var h Handler = func(ctx context.Context, inv *Invocation) error {
+		arg, replyv, err := mt.callValues(inv)
+		if err != nil {
+			return err
+		}
+		return svc.call(ctx, mt, arg, replyv)
+	}
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		h = s.middlewares[i](h)
+	}
+	err := h(ctx, inv)
+	// 调用方已经超时或取消, 即使处理成功也不再返回结果
+	if err == nil {
+		err = ctx.Err()
+	}
+	return reply(inv.Reply, err)
+}
+
+// MetadataAuthorization 认证信息在元数据中的 key
+const MetadataAuthorization = "authorization"
+
+type incomingMetadataKey struct{}
+
+type outgoingMetadataKey struct{}
+
+func withIncomingMetadata(ctx context.Context, md map[string]string) context.Context {
+	return context.WithValue(ctx, incomingMetadataKey{}, md)
+}
+
+// MetadataFromContext 服务端处理函数中读取调用方附带的元数据
+func MetadataFromContext(ctx context.Context) map[string]string {
+	md, _ := ctx.Value(incomingMetadataKey{}).(map[string]string)
+	return md
+}
+
+// WithMetadata 客户端为调用附带元数据, kv 为 key, value 交替, key 统一转为小写
+func WithMetadata(ctx context.Context, kv ...string) context.Context {
+	old, _ := ctx.Value(outgoingMetadataKey{}).(map[string]string)
+	md := make(map[string]string, len(old)+len(kv)/2)
+	for k, v := range old {
+		md[k] = v
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		md[strings.ToLower(kv[i])] = kv[i+1]
+	}
+	return context.WithValue(ctx, outgoingMetadataKey{}, md)
+}
+
+func outgoingMetadata(ctx context.Context) map[string]string {
+	md, _ := ctx.Value(outgoingMetadataKey{}).(map[string]string)
+	return md
+}
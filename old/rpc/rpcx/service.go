@@ -0,0 +1,171 @@
+package rpcx
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"log"
+	"reflect"
+	"strings"
+)
+
+var (
+	typeOfError   = reflect.TypeOf((*error)(nil)).Elem()
+	typeOfContext = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// methodType 一个可以调用的方法, 与 net/rpc 的约定相同:
+//
+//	func (t *T) Method(args T1, reply *T2) error
+//
+// 另外也可以接收 context:
+//
+//	func (t *T) Method(ctx context.Context, args T1, reply *T2) error
+type methodType struct {
+	method    reflect.Method
+	withCtx   bool
+	argType   reflect.Type
+	replyType reflect.Type
+}
+
+type service struct {
+	name    string
+	rcvr    reflect.Value
+	methods map[string]*methodType
+}
+
+func newService(name string, rcvr interface{}) (*service, error) {
+	s := &service{rcvr: reflect.ValueOf(rcvr)}
+	if name == "" {
+		name = reflect.Indirect(s.rcvr).Type().Name()
+	}
+	if !token.IsExported(name) {
+		return nil, fmt.Errorf("rpcx: type %s is not exported", name)
+	}
+	s.name = name
+
+	typ := reflect.TypeOf(rcvr)
+	s.methods = make(map[string]*methodType)
+	for i := 0; i < typ.NumMethod(); i++ {
+		m := typ.Method(i)
+		if mt := suitableMethod(m); mt != nil {
+			s.methods[m.Name] = mt
+		}
+	}
+	if len(s.methods) == 0 {
+		return nil, fmt.Errorf("rpcx: type %s has no suitable methods", name)
+	}
+	return s, nil
+}
+
+// suitableMethod 检查方法签名, 不符合约定的方法忽略并打印原因
+func suitableMethod(m reflect.Method) *methodType {
+	if !m.IsExported() {
+		return nil
+	}
+	mtype := m.Type
+	mt := &methodType{method: m}
+	in := 1
+	switch mtype.NumIn() {
+	case 3:
+	case 4:
+		if mtype.In(1) != typeOfContext {
+			log.Printf("rpcx: method %s: first argument must be context.Context", m.Name)
+			return nil
+		}
+		mt.withCtx = true
+		in = 2
+	default:
+		return nil
+	}
+
+	mt.argType = mtype.In(in)
+	mt.replyType = mtype.In(in + 1)
+	if !isExportedOrBuiltin(mt.argType) {
+		log.Printf("rpcx: method %s: argument type %s not exported", m.Name, mt.argType)
+		return nil
+	}
+	if mt.replyType.Kind() != reflect.Pointer || !isExportedOrBuiltin(mt.replyType) {
+		log.Printf("rpcx: method %s: reply type %s must be an exported pointer", m.Name, mt.replyType)
+		return nil
+	}
+	if mtype.NumOut() != 1 || mtype.Out(0) != typeOfError {
+		log.Printf("rpcx: method %s must return error", m.Name)
+		return nil
+	}
+	return mt
+}
+
+func isExportedOrBuiltin(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return token.IsExported(t.Name()) || t.PkgPath() == ""
+}
+
+// lookup 按 Service.Method 查找方法
+func (s *Server) lookup(serviceMethod string) (*service, *methodType, *Error) {
+	dot := strings.LastIndex(serviceMethod, ".")
+	if dot < 0 {
+		return nil, nil, NewError(CodeMethodNotFound, "method %q ill-formed", serviceMethod)
+	}
+	svci, ok := s.services.Load(serviceMethod[:dot])
+	if !ok {
+		return nil, nil, NewError(CodeMethodNotFound, "service %q not found", serviceMethod[:dot])
+	}
+	svc := svci.(*service)
+	mt := svc.methods[serviceMethod[dot+1:]]
+	if mt == nil {
+		return nil, nil, NewError(CodeMethodNotFound, "method %q not found", serviceMethod)
+	}
+	return svc, mt, nil
+}
+
+// newArgs 创建用于解码的参数, 总是指针
+func (mt *methodType) newArgs() interface{} {
+	if mt.argType.Kind() == reflect.Pointer {
+		return reflect.New(mt.argType.Elem()).Interface()
+	}
+	return reflect.New(mt.argType).Interface()
+}
+
+// callValues 把 Invocation 中的参数和结果转换为调用时传入的值, 中间件替换成了错误的类型时返回错误
+func (mt *methodType) callValues(inv *Invocation) (arg, reply reflect.Value, err error) {
+	arg = reflect.ValueOf(inv.Args)
+	wantArg := mt.argType
+	if wantArg.Kind() != reflect.Pointer {
+		wantArg = reflect.PointerTo(wantArg)
+	}
+	if !arg.IsValid() || arg.Type() != wantArg || arg.IsNil() {
+		return arg, reply, NewError(CodeInternalError, "%s: args is %T, want %s", inv.Method, inv.Args, wantArg)
+	}
+	if mt.argType.Kind() != reflect.Pointer {
+		arg = arg.Elem()
+	}
+	reply = reflect.ValueOf(inv.Reply)
+	if !reply.IsValid() || reply.Type() != mt.replyType || reply.IsNil() {
+		return arg, reply, NewError(CodeInternalError, "%s: reply is %T, want %s", inv.Method, inv.Reply, mt.replyType)
+	}
+	return arg, reply, nil
+}
+
+// call 调用方法, panic 转换为 CodeInternalError
+func (svc *service) call(ctx context.Context, mt *methodType, arg, reply reflect.Value) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("rpcx: %s.%s panic: %v", svc.name, mt.method.Name, r)
+			err = NewError(CodeInternalError, "internal error")
+		}
+	}()
+
+	in := []reflect.Value{svc.rcvr}
+	if mt.withCtx {
+		in = append(in, reflect.ValueOf(ctx))
+	}
+	in = append(in, arg, reply)
+	out := mt.method.Func.Call(in)
+	if e := out[0].Interface(); e != nil {
+		return e.(error)
+	}
+	return nil
+}
@@ -1,24 +1,10 @@
 package splitString
 
-import "strings"
-
-// Newsplit 切割字符串
+// Newsplit 切割字符串, 丢弃空字段
 // example:
 // abc,b=>[ac]
-func Newsplit(str, sep string) (des []string) {
-	index := strings.Index(str, sep)
-	for index > -1 {
-		sectionBefor := str[:index]
-		if len(sectionBefor) >= 1 {
-			des = append(des, sectionBefor)
-		}
-		str = str[index+len(sep):]
-		index = strings.Index(str, sep)
-	}
-	//最后1
-	if len(str) >= 1 {
-		des = append(des, str)
-	}
-
-	return
+//
+// 需要保留空字段、多个分隔符或处理引号时使用 Split
+func Newsplit(str, sep string) []string {
+	return Split(str, Seps(sep), DropEmpty())
 }
@@ -4,7 +4,9 @@ package splitString
 
 import (
 	"reflect"
+	"strings"
 	"testing"
+	"unicode"
 )
 
 // 测试用例1：以字符分割
@@ -149,3 +151,137 @@ func TestSplitTwo(t *testing.T) {
 		})
 	}
 }
+
+// Newsplit 以前遇到空分隔符会死循环
+func TestNewsplitEmpty(t *testing.T) {
+	if got := Newsplit("abc", ""); !reflect.DeepEqual(got, []string{"abc"}) {
+		t.Fatalf("got %#v", got)
+	}
+	if got := Newsplit(",,a,,", ","); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("got %#v", got)
+	}
+}
+
+var testGroupOptions = map[string]struct {
+	str  string
+	opts []Option
+	want []string
+}{
+	"keepEmpty":     {"a,,b,", []Option{Seps(",")}, []string{"a", "", "b", ""}},
+	"dropEmpty":     {",a,,b,", []Option{Seps(","), DropEmpty()}, []string{"a", "b"}},
+	"emptyString":   {"", []Option{Seps(",")}, []string{""}},
+	"noSeparator":   {"a,b", nil, []string{"a,b"}},
+	"limit":         {"a,b,c,d", []Option{Seps(","), Limit(2)}, []string{"a", "b,c,d"}},
+	"limitDrop":     {",,a,,b,,c", []Option{Seps(","), DropEmpty(), Limit(2)}, []string{"a", "b,,c"}},
+	"multipleSeps":  {"a,b;c|d", []Option{Seps(",", ";", "|")}, []string{"a", "b", "c", "d"}},
+	"longestSep":    {"a::b:c", []Option{Seps(":", "::")}, []string{"a", "b", "c"}},
+	"sepFunc":       {"a1b22c", []Option{SepFunc(unicode.IsDigit)}, []string{"a", "b", "", "c"}},
+	"sepsAndFunc":   {"a b,c", []Option{Seps(","), SepFunc(unicode.IsSpace)}, []string{"a", "b", "c"}},
+	"quoted":        {`a,"b,c",d`, []Option{Seps(","), Quotes(`"`)}, []string{"a", `"b,c"`, "d"}},
+	"unquote":       {`a,"b,""c""",'d,e'`, []Option{Seps(","), Quotes(`"'`), Unquote()}, []string{"a", `b,"c"`, "d,e"}},
+	"quotedEmpty":   {`a,"",b`, []Option{Seps(","), Quotes(`"`), Unquote(), DropEmpty()}, []string{"a", "", "b"}},
+	"unclosedQuote": {`a,"b,c`, []Option{Seps(","), Quotes(`"`)}, []string{"a", `"b,c`}},
+	"escape":        {`a\,b,c\\,d`, []Option{Seps(","), Escape('\\')}, []string{`a\,b`, `c\\`, "d"}},
+	"escapeUnquote": {`a\,b,\"c\"`, []Option{Seps(","), Quotes(`"`), Escape('\\'), Unquote()}, []string{"a,b", `"c"`}},
+	"chinese":       {"京北，北京;之北", []Option{Seps("，", ";")}, []string{"京北", "北京", "之北"}},
+	"logLine":       {`2024-01-02 INFO  "GET /a b" 200`, []Option{SepFunc(unicode.IsSpace), DropEmpty(), Quotes(`"`), Unquote()}, []string{"2024-01-02", "INFO", "GET /a b", "200"}},
+}
+
+func TestSplitOptions(t *testing.T) {
+	for name, test := range testGroupOptions {
+		t.Run(name, func(t *testing.T) {
+			if got := Split(test.str, test.opts...); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("失败！got:%#v want:%#v\n", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAllStopsEarly(t *testing.T) {
+	var got []string
+	for field := range New(Seps(",")).All("a,b,c,d") {
+		got = append(got, field)
+		if len(got) == 2 {
+			break
+		}
+	}
+	if !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("got %#v", got)
+	}
+}
+
+// 迭代器返回的是原字符串的子串, 不分配内存
+func TestAllZeroAlloc(t *testing.T) {
+	line := `2024-01-02 12:00:00,INFO,"GET /a,b",200,,3ms`
+	for name, s := range map[string]*Splitter{
+		"single":  New(Seps(",")),
+		"multi":   New(Seps(",", " ")),
+		"quoted":  New(Seps(","), Quotes(`"`), Escape('\\')),
+		"fields":  New(SepFunc(unicode.IsSpace), DropEmpty()),
+		"limited": New(Seps(","), Limit(3)),
+	} {
+		allocs := testing.AllocsPerRun(100, func() {
+			for field := range s.All(line) {
+				_ = field
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("%s: %v allocs per run", name, allocs)
+		}
+	}
+}
+
+// 与 strings.Split / strings.SplitN 比较
+func FuzzSplit(f *testing.F) {
+	f.Add("a,b,,c", ",", 0)
+	f.Add("", ",", 2)
+	f.Add("京北北京之北", "北京", -1)
+	f.Add("\xff\xfe,", "\xfe", 1)
+	f.Fuzz(func(t *testing.T, str, sep string, n int) {
+		if sep == "" {
+			t.Skip()
+		}
+		if got, want := Split(str, Seps(sep)), strings.Split(str, sep); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Split(%q, %q) = %q, want %q", str, sep, got, want)
+		}
+		// 设置一个不会出现的转义字符, 走逐字节扫描的分支
+		if !strings.ContainsRune(str, unicode.MaxRune) {
+			if got, want := Split(str, Seps(sep), Escape(unicode.MaxRune)), strings.Split(str, sep); !reflect.DeepEqual(got, want) {
+				t.Fatalf("scan Split(%q, %q) = %q, want %q", str, sep, got, want)
+			}
+		}
+		if n > 0 {
+			if got, want := Split(str, Seps(sep), Limit(n)), strings.SplitN(str, sep, n); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Split(%q, %q, Limit(%d)) = %q, want %q", str, sep, n, got, want)
+			}
+		}
+		// 没有引号和转义字符时, 开启它们不改变结果
+		if !strings.ContainsAny(str, `"\`) && !strings.ContainsAny(sep, `"\`) {
+			got := Split(str, Seps(sep), Quotes(`"`), Escape('\\'), Unquote())
+			if want := strings.Split(str, sep); !reflect.DeepEqual(got, want) {
+				t.Fatalf("quoted Split(%q, %q) = %q, want %q", str, sep, got, want)
+			}
+		}
+	})
+}
+
+// 与 strings.FieldsFunc 比较
+func FuzzFieldsFunc(f *testing.F) {
+	f.Add("  a b\tc\n")
+	f.Add("a1b22c")
+	f.Add("\xff 中 文　")
+	f.Fuzz(func(t *testing.T, str string) {
+		for _, fn := range []func(rune) bool{unicode.IsSpace, unicode.IsDigit, unicode.IsPunct} {
+			got, want := Split(str, SepFunc(fn), DropEmpty()), strings.FieldsFunc(str, fn)
+			if len(got) != len(want) || (len(want) > 0 && !reflect.DeepEqual(got, want)) {
+				t.Fatalf("Split(%q) = %q, want %q", str, got, want)
+			}
+			// 与等价的分隔符列表组合时走逐字节扫描的分支
+			got = Split(str, SepFunc(fn), Seps("\x00"), DropEmpty())
+			want = strings.FieldsFunc(str, func(r rune) bool { return r == 0 || fn(r) })
+			if len(got) != len(want) || (len(want) > 0 && !reflect.DeepEqual(got, want)) {
+				t.Fatalf("Split(%q) with \\x00 = %q, want %q", str, got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,239 @@
+package splitString
+
+import (
+	"iter"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Splitter 可配置的字符串切割, 创建后只读, 可以在多个 goroutine 中共用
+//
+// 默认行为与 strings.Split 相同: 保留空字段, 空字符串切割结果为 [""]
+type Splitter struct {
+	seps      []string // 按长度从长到短, 同一位置优先匹配最长的分隔符
+	sepFunc   func(rune) bool
+	first     [utf8.RuneSelf]bool // 单字节分隔符首字节, 快速跳过
+	dropEmpty bool
+	limit     int
+	quotes    string
+	escape    rune
+	unquote   bool
+}
+
+// Option 切割选项
+type Option func(*Splitter)
+
+// Seps 分隔符, 可以有多个, 空串被忽略; 没有任何分隔符时整个字符串作为一个字段
+func Seps(seps ...string) Option {
+	return func(s *Splitter) {
+		for _, sep := range seps {
+			if sep != "" && !slices.Contains(s.seps, sep) {
+				s.seps = append(s.seps, sep)
+			}
+		}
+	}
+}
+
+// SepFunc f 返回 true 的字符作为分隔符, 与 Seps 同时使用时任一匹配即可
+func SepFunc(f func(rune) bool) Option {
+	return func(s *Splitter) { s.sepFunc = f }
+}
+
+// DropEmpty 丢弃空字段, 与 strings.FieldsFunc 相同; 引号包裹的空字段 "" 不算空
+func DropEmpty() Option {
+	return func(s *Splitter) { s.dropEmpty = true }
+}
+
+// Limit 最多 n 个字段, 最后一个字段是剩下未切割的部分, 与 strings.SplitN 相同; n <= 0 表示不限制
+//
+// 与 DropEmpty 同时使用时, 最后一个字段开头的分隔符会被去掉
+func Limit(n int) Option {
+	return func(s *Splitter) { s.limit = n }
+}
+
+// Quotes 引号字符, 引号内的分隔符不切割; 引号没有闭合时一直到字符串末尾
+func Quotes(chars string) Option {
+	return func(s *Splitter) { s.quotes = chars }
+}
+
+// Escape 转义字符, 它后面的一个字符按普通字符处理, 不作为分隔符和引号
+func Escape(r rune) Option {
+	return func(s *Splitter) { s.escape = r }
+}
+
+// Unquote 去掉字段中的引号和转义字符, 引号内连续两个同样的引号表示一个引号(csv 风格)
+// 只有含引号或转义字符的字段才会分配内存
+func Unquote() Option {
+	return func(s *Splitter) { s.unquote = true }
+}
+
+// New 创建 Splitter
+func New(opts ...Option) *Splitter {
+	s := &Splitter{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	slices.SortStableFunc(s.seps, func(a, b string) int { return len(b) - len(a) })
+	for _, sep := range s.seps {
+		if sep[0] < utf8.RuneSelf {
+			s.first[sep[0]] = true
+		}
+	}
+	return s
+}
+
+// Split 按选项切割 str
+func Split(str string, opts ...Option) []string {
+	return New(opts...).Split(str)
+}
+
+// Split 切割 str, 返回所有字段
+func (s *Splitter) Split(str string) []string {
+	return slices.Collect(s.All(str))
+}
+
+// All 逐个返回字段, 字段是 str 的子串, 除 Unquote 需要改写的字段外不分配内存
+func (s *Splitter) All(str string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for n := 1; ; n++ {
+			if s.dropEmpty {
+				str = s.trimSeps(str)
+				if str == "" {
+					return
+				}
+			}
+			field, last := str, true
+			if s.limit <= 0 || n < s.limit {
+				if i, w := s.index(str); i >= 0 {
+					field, str, last = str[:i], str[i+w:], false
+				}
+			}
+			if s.dropEmpty && field == "" {
+				n--
+				continue
+			}
+			if s.unquote {
+				field = s.clean(field)
+			}
+			if !yield(field) || last {
+				return
+			}
+		}
+	}
+}
+
+// index 第一个不在引号内、没有被转义的分隔符的位置和长度, 没有时返回 -1
+func (s *Splitter) index(str string) (int, int) {
+	if s.quotes == "" && s.escape == 0 {
+		switch {
+		case s.sepFunc == nil && len(s.seps) == 1:
+			return strings.Index(str, s.seps[0]), len(s.seps[0])
+		case s.sepFunc != nil && len(s.seps) == 0:
+			i := strings.IndexFunc(str, s.sepFunc)
+			if i < 0 {
+				return -1, 0
+			}
+			_, w := utf8.DecodeRuneInString(str[i:])
+			return i, w
+		}
+	}
+
+	var quote rune
+	for i := 0; i < len(str); {
+		r, size := rune(str[i]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRuneInString(str[i:])
+		}
+		switch {
+		case s.escape != 0 && r == s.escape:
+			// 跳过转义字符和它后面的一个字符
+			i += size
+			if i < len(str) {
+				_, next := utf8.DecodeRuneInString(str[i:])
+				i += next
+			}
+			continue
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case s.quotes != "" && strings.ContainsRune(s.quotes, r):
+			quote = r
+		default:
+			if w := s.matchSep(str[i:], r, size); w > 0 {
+				return i, w
+			}
+		}
+		if s.sepFunc != nil {
+			i += size
+		} else {
+			// 按字节前进, 与 strings.Index 一样可以匹配非法 UTF-8 中间的分隔符
+			i++
+		}
+	}
+	return -1, 0
+}
+
+// matchSep str 开头的分隔符长度, r 和 size 是 str 开头的字符, 不是分隔符时返回 0
+func (s *Splitter) matchSep(str string, r rune, size int) int {
+	// 首字节是 ASCII 且不是任何分隔符的首字节时不用逐个比较
+	if str[0] >= utf8.RuneSelf || s.first[str[0]] {
+		for _, sep := range s.seps {
+			if strings.HasPrefix(str, sep) {
+				return len(sep)
+			}
+		}
+	}
+	if s.sepFunc != nil && s.sepFunc(r) {
+		return size
+	}
+	return 0
+}
+
+// trimSeps 去掉开头连续的分隔符
+func (s *Splitter) trimSeps(str string) string {
+	for str != "" {
+		r, size := utf8.DecodeRuneInString(str)
+		w := s.matchSep(str, r, size)
+		if w == 0 {
+			break
+		}
+		str = str[w:]
+	}
+	return str
+}
+
+// clean 去掉引号和转义字符
+func (s *Splitter) clean(field string) string {
+	if !strings.ContainsAny(field, s.quotes) && (s.escape == 0 || !strings.ContainsRune(field, s.escape)) {
+		return field
+	}
+	var b strings.Builder
+	b.Grow(len(field))
+	var quote rune
+	for i := 0; i < len(field); {
+		r, size := utf8.DecodeRuneInString(field[i:])
+		i += size
+		switch {
+		case s.escape != 0 && r == s.escape:
+			if i < len(field) {
+				_, n := utf8.DecodeRuneInString(field[i:])
+				b.WriteString(field[i : i+n])
+				i += n
+			}
+		case quote != 0 && r == quote:
+			if strings.HasPrefix(field[i:], string(quote)) {
+				b.WriteRune(quote)
+				i += size
+			} else {
+				quote = 0
+			}
+		case quote == 0 && s.quotes != "" && strings.ContainsRune(s.quotes, r):
+			quote = r
+		default:
+			b.WriteString(field[i-size : i])
+		}
+	}
+	return b.String()
+}
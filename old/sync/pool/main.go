@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"sync"
+
+	"testGo/sync/pool/objpool"
 )
 
 var pool *sync.Pool
@@ -34,4 +36,24 @@ func main() {
 
 	fmt.Println("Pool 里已有一个对象：&{first}，调用 Get: ", pool.Get().(*Person))
 	fmt.Println("Pool 没有对象了，调用 Get: ", pool.Get().(*Person))
+
+	typedPool()
+}
+
+// typedPool 带类型的池: 不用类型断言, Put 时自动重置, 可以看到命中率
+func typedPool() {
+	people := objpool.New(objpool.Config[*Person]{
+		New: func() *Person {
+			fmt.Println("Creating a new Person")
+			return new(Person)
+		},
+		Reset:    func(p *Person) { *p = Person{} },
+		Capacity: 4, // 固定容量, GC 不会清空
+	})
+
+	p := people.Get()
+	p.Name = "first"
+	people.Put(p)
+	fmt.Println("重置后再 Get: ", people.Get())
+	fmt.Println("统计: ", people.Stats())
 }
@@ -0,0 +1,151 @@
+package objpool
+
+import (
+	"fmt"
+	"math/bits"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// BytePool 按 2 的幂分级的 []byte 池, Get(n) 从能装下 n 的最小一级里取
+//
+// 每一级的池里只保存底层数组的首地址, Get 时按这一级的大小还原成 slice,
+// 存取都不会像把 []byte 直接放进 sync.Pool 那样产生一次装箱分配
+type BytePool struct {
+	minShift, maxShift int
+	classes            []*Pool[*byte]
+	issued             []*issued // NewCheckedBytePool 创建时才有
+	oversize           atomic.Uint64
+}
+
+// issued 一级里已经借出、还没有放回的底层数组首地址.
+// Put 只收这里登记过的地址, 否则 buf[n:] 这种还属于别人的后半段也会被当成一整块放进池里;
+// 存 uintptr 不会让数组一直存活, 数组被回收时由 finalizer 删掉登记, 地址被复用也不会误收.
+// 每次 Get/Put 都要加锁、改 map, 所以只在 NewCheckedBytePool 里启用
+type issued struct {
+	mu   sync.Mutex
+	ptrs map[uintptr]struct{}
+}
+
+func (s *issued) add(ptr *byte) {
+	s.mu.Lock()
+	s.ptrs[uintptr(unsafe.Pointer(ptr))] = struct{}{}
+	s.mu.Unlock()
+}
+
+// take 删除登记, 返回 ptr 是否是借出的数组首地址
+func (s *issued) take(ptr *byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := uintptr(unsafe.Pointer(ptr))
+	if _, ok := s.ptrs[key]; !ok {
+		return false
+	}
+	delete(s.ptrs, key)
+	return true
+}
+
+// NewBytePool 最小一级 minSize, 最大一级 maxSize, 都会向上取整到 2 的幂;
+// 超过 maxSize 的请求直接分配, 不进池. capacity 大于 0 时每一级都是固定容量的池.
+//
+// Put 不检查 buffer 是不是借出的整块, 调用方要保证只放回 Get 得到的 buffer 且只放回一次
+func NewBytePool(minSize, maxSize, capacity int) *BytePool {
+	return newBytePool(minSize, maxSize, capacity, false)
+}
+
+// NewCheckedBytePool 同 NewBytePool, 但 Put 只收借出且还没放回的整块 buffer,
+// 用来排查误放回的问题; 每次 Get/Put 多一次加锁和 map 操作, 新建 buffer 时多一个 finalizer
+func NewCheckedBytePool(minSize, maxSize, capacity int) *BytePool {
+	return newBytePool(minSize, maxSize, capacity, true)
+}
+
+func newBytePool(minSize, maxSize, capacity int, checked bool) *BytePool {
+	if minSize <= 0 || maxSize < minSize {
+		panic(fmt.Sprintf("objpool: invalid byte pool sizes %d..%d", minSize, maxSize))
+	}
+	b := &BytePool{minShift: ceilLog2(minSize), maxShift: ceilLog2(maxSize)}
+	for shift := b.minShift; shift <= b.maxShift; shift++ {
+		size := 1 << shift
+		newBuf := func() *byte { return unsafe.SliceData(make([]byte, size)) }
+		if checked {
+			s := &issued{ptrs: map[uintptr]struct{}{}}
+			b.issued = append(b.issued, s)
+			newBuf = func() *byte {
+				// 至少 16 字节, 不走 tiny 分配器, 否则 finalizer 可能永远不执行
+				ptr := unsafe.SliceData(make([]byte, max(size, 16)))
+				runtime.SetFinalizer(ptr, func(ptr *byte) { s.take(ptr) })
+				return ptr
+			}
+		}
+		b.classes = append(b.classes, New(Config[*byte]{New: newBuf, Capacity: capacity}))
+	}
+	return b
+}
+
+// Get 长度为 n 的 buffer, 容量是所在级的大小; 内容不保证为 0
+func (b *BytePool) Get(n int) []byte {
+	i := b.class(n)
+	if i < 0 {
+		b.oversize.Add(1)
+		return make([]byte, n)
+	}
+	ptr := b.classes[i].Get()
+	if b.issued != nil {
+		b.issued[i].add(ptr)
+	}
+	return unsafe.Slice(ptr, 1<<(b.minShift+i))[:n]
+}
+
+// Put 放回 Get 得到的 buffer, 可以改变长度; 容量不是某一级大小的(比如 append 扩容过、切掉了开头)直接丢弃.
+// NewCheckedBytePool 创建的池还会丢弃重复放回的、不是这个池借出的整块 buffer
+func (b *BytePool) Put(buf []byte) {
+	c := cap(buf)
+	if c == 0 || c&(c-1) != 0 {
+		return
+	}
+	i := bits.Len(uint(c)) - 1 - b.minShift
+	if i < 0 || i >= len(b.classes) {
+		return
+	}
+	ptr := unsafe.SliceData(buf[:1])
+	if b.issued != nil && !b.issued[i].take(ptr) {
+		return
+	}
+	b.classes[i].Put(ptr)
+}
+
+// Stats 所有级别合计的统计, oversize 为超过最大一级而直接分配的次数
+func (b *BytePool) Stats() (total Stats, oversize uint64) {
+	for _, p := range b.classes {
+		total = total.add(p.Stats())
+	}
+	return total, b.oversize.Load()
+}
+
+// ClassStats 每一级的大小和统计
+func (b *BytePool) ClassStats() map[int]Stats {
+	m := make(map[int]Stats, len(b.classes))
+	for i, p := range b.classes {
+		m[1<<(b.minShift+i)] = p.Stats()
+	}
+	return m
+}
+
+// class 能装下 n 的最小一级的下标, 超过最大一级时为 -1
+func (b *BytePool) class(n int) int {
+	shift := max(ceilLog2(n), b.minShift)
+	if shift > b.maxShift {
+		return -1
+	}
+	return shift - b.minShift
+}
+
+// ceilLog2 不小于 n 的最小 2 的幂的指数, n <= 1 时为 0
+func ceilLog2(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	return bits.Len(uint(n - 1))
+}
@@ -0,0 +1,322 @@
+package objpool
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// Message test/heapAndStack/4 里的消息
+type Message struct {
+	ID      int
+	Content string
+	Headers map[string]string
+	Data    []byte
+}
+
+// Person sync/pool 里的 Person
+type Person struct {
+	Name string
+	Age  int32
+}
+
+func newMessagePool(capacity int) *Pool[*Message] {
+	return New(Config[*Message]{
+		New: func() *Message {
+			return &Message{Headers: make(map[string]string), Data: make([]byte, 0, 1024)}
+		},
+		Reset: func(m *Message) {
+			m.ID, m.Content = 0, ""
+			clear(m.Headers)
+			m.Data = m.Data[:0]
+		},
+		Keep:     func(m *Message) bool { return cap(m.Data) <= 4096 },
+		Capacity: capacity,
+	})
+}
+
+func TestPool(t *testing.T) {
+	for _, capacity := range []int{0, 2} {
+		p := newMessagePool(capacity)
+		m := p.Get()
+		m.ID, m.Headers["k"] = 1, "v"
+		m.Data = append(m.Data, "payload"...)
+		p.Put(m)
+
+		m2 := p.Get()
+		if m2.ID != 0 || len(m2.Headers) != 0 || len(m2.Data) != 0 {
+			t.Fatalf("capacity %d: not reset: %+v", capacity, m2)
+		}
+		// 太大的对象不放回
+		m2.Data = make([]byte, 8192)
+		p.Put(m2)
+
+		s := p.Stats()
+		if s.Gets != 2 || s.Puts != 2 || s.Drops != 1 || s.Hits+s.Misses != s.Gets {
+			t.Fatalf("capacity %d: stats %v", capacity, s)
+		}
+		if capacity > 0 && (s.Hits != 1 || s.Misses != 1 || s.HitRate() != 0.5) {
+			t.Fatalf("bounded stats %v", s)
+		}
+	}
+}
+
+func TestBoundedSurvivesGC(t *testing.T) {
+	p := newMessagePool(3)
+	ms := []*Message{p.Get(), p.Get(), p.Get(), p.Get()}
+	for _, m := range ms {
+		p.Put(m)
+	}
+	if p.Len() != 3 || p.Stats().Drops != 1 {
+		t.Fatalf("len %d stats %v", p.Len(), p.Stats())
+	}
+	runtime.GC()
+	runtime.GC()
+	for range 3 {
+		p.Get()
+	}
+	if s := p.Stats(); s.Hits != 3 || s.Misses != 4 {
+		t.Fatalf("objects lost after GC: %v", s)
+	}
+	if newMessagePool(0).Len() != -1 {
+		t.Fatal("sync.Pool based Len should be -1")
+	}
+}
+
+func TestPoolConcurrent(t *testing.T) {
+	for _, capacity := range []int{0, 8} {
+		p := newMessagePool(capacity)
+		var wg sync.WaitGroup
+		for g := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range 1000 {
+					m := p.Get()
+					if m.ID != 0 || len(m.Headers) != 0 {
+						t.Errorf("dirty message %+v", m)
+						return
+					}
+					m.ID, m.Headers["g"] = g*1000+i, "x"
+					p.Put(m)
+				}
+			}()
+		}
+		wg.Wait()
+		if s := p.Stats(); s.Gets != 8000 || s.Puts != 8000 {
+			t.Fatalf("stats %v", s)
+		}
+	}
+}
+
+func TestBytePool(t *testing.T) {
+	b := NewBytePool(100, 5000, 0) // 128 .. 8192
+	tests := []struct{ n, cap int }{
+		{0, 128}, {1, 128}, {128, 128}, {129, 256}, {4096, 4096}, {5000, 8192}, {8192, 8192},
+	}
+	for _, tt := range tests {
+		buf := b.Get(tt.n)
+		if len(buf) != tt.n || cap(buf) != tt.cap {
+			t.Errorf("Get(%d) len %d cap %d, want cap %d", tt.n, len(buf), cap(buf), tt.cap)
+		}
+		b.Put(buf)
+	}
+	if buf := b.Get(10000); cap(buf) != 10000 {
+		t.Fatalf("oversize cap %d", cap(buf))
+	}
+	// 切掉开头或者扩容过的不收
+	b.Put(b.Get(256)[1:])
+	b.Put(append(b.Get(128), make([]byte, 200)...))
+	b.Put(make([]byte, 100))
+
+	total, oversize := b.Stats()
+	if oversize != 1 || total.Gets != 9 || total.Puts != 7 {
+		t.Fatalf("stats %v oversize %d", total, oversize)
+	}
+	if s := b.ClassStats()[256]; s.Gets != 2 {
+		t.Fatalf("256 class %v", s)
+	}
+}
+
+// 复用只在固定容量的池上断言: sync.Pool 在 -race 下会随机丢弃放回的对象
+func TestBytePoolReuse(t *testing.T) {
+	b := NewBytePool(100, 5000, 4)
+	buf := b.Get(200)
+	buf[0] = 42
+	b.Put(buf[:0])
+	if again := b.Get(256); &again[0] != &buf[0] || again[0] != 42 {
+		t.Fatal("buffer was not reused")
+	}
+}
+
+func TestCheckedBytePool(t *testing.T) {
+	b := NewCheckedBytePool(100, 5000, 4)
+	b.Put(make([]byte, 128))
+	if buf := b.Get(128); cap(buf) != 128 {
+		t.Fatalf("cap %d", cap(buf))
+	}
+
+	// 后半段的容量正好是下一级的大小, 但内存还属于借出去的整块, 不能收
+	whole := b.Get(4096)
+	b.Put(whole[2048:])
+	if half := b.Get(2048); &half[0] == &whole[2048] {
+		t.Fatal("tail of a live buffer was pooled")
+	}
+	// 重复放回只收一次
+	b.Put(whole)
+	b.Put(whole)
+	if a, c := b.Get(4096), b.Get(4096); &a[0] == &c[0] {
+		t.Fatal("buffer handed out twice")
+	}
+
+	total, _ := b.Stats()
+	if total.Gets != 5 || total.Puts != 1 {
+		t.Fatalf("stats %v", total)
+	}
+}
+
+func TestBytePoolNoAlloc(t *testing.T) {
+	b := NewBytePool(64, 1<<16, 16)
+	b.Put(b.Get(1000))
+	if n := testing.AllocsPerRun(100, func() { b.Put(b.Get(1000)) }); n != 0 {
+		t.Fatalf("%v allocs per Get/Put", n)
+	}
+}
+
+// sink 让基准测试里的对象逃逸到堆上, 和实际使用一致
+var sink any
+
+func BenchmarkMessage(b *testing.B) {
+	raw := sync.Pool{New: func() any {
+		return &Message{Headers: make(map[string]string), Data: make([]byte, 0, 1024)}
+	}}
+	use := func(m *Message, i int) {
+		m.ID, m.Content = i, "Hello World"
+		m.Headers["trace"] = "x"
+		m.Data = append(m.Data, "payload"...)
+		sink = m
+	}
+
+	b.Run("alloc", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			use(&Message{Headers: make(map[string]string), Data: make([]byte, 0, 1024)}, i)
+		}
+	})
+	b.Run("sync.Pool", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m := raw.Get().(*Message)
+			use(m, i)
+			clear(m.Headers)
+			m.Data = m.Data[:0]
+			raw.Put(m)
+		}
+	})
+	for _, tc := range []struct {
+		name     string
+		capacity int
+	}{{"Pool", 0}, {"Pool-bounded", 64}} {
+		b.Run(tc.name, func(b *testing.B) {
+			p := newMessagePool(tc.capacity)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				m := p.Get()
+				use(m, i)
+				p.Put(m)
+			}
+		})
+	}
+}
+
+func BenchmarkMessageParallel(b *testing.B) {
+	b.Run("alloc", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				m := &Message{Headers: make(map[string]string), Data: make([]byte, 0, 1024)}
+				m.Headers["trace"] = "x"
+				sink = m.Data[:1]
+			}
+		})
+	})
+	for _, tc := range []struct {
+		name     string
+		capacity int
+	}{{"Pool", 0}, {"Pool-bounded", 1024}} {
+		b.Run(tc.name, func(b *testing.B) {
+			p := newMessagePool(tc.capacity)
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					m := p.Get()
+					m.Headers["trace"] = "x"
+					p.Put(m)
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkPerson(b *testing.B) {
+	raw := sync.Pool{New: func() any { return new(Person) }}
+	typed := New(Config[*Person]{New: func() *Person { return new(Person) }, Reset: func(p *Person) { *p = Person{} }})
+
+	b.Run("alloc", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sink = &Person{Name: "first", Age: int32(i)}
+		}
+	})
+	b.Run("sync.Pool", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p := raw.Get().(*Person)
+			p.Name, p.Age = "first", int32(i)
+			*p = Person{}
+			raw.Put(p)
+		}
+	})
+	b.Run("Pool", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p := typed.Get()
+			p.Name, p.Age = "first", int32(i)
+			typed.Put(p)
+		}
+	})
+}
+
+func BenchmarkBytes(b *testing.B) {
+	bp := NewBytePool(64, 1<<16, 0)
+	raw := sync.Pool{New: func() any { return make([]byte, 0, 1<<16) }}
+	for _, size := range []int{512, 16 << 10} {
+		b.Run("alloc/"+sizeName(size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				sink = make([]byte, size)
+			}
+		})
+		b.Run("sync.Pool/"+sizeName(size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buf := raw.Get().([]byte)[:size]
+				raw.Put(buf[:0]) // 每次 Put 都要把 slice 装箱, 这就是 BytePool 省掉的那次分配
+			}
+		})
+		b.Run("BytePool/"+sizeName(size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bp.Put(bp.Get(size))
+			}
+		})
+	}
+}
+
+func sizeName(n int) string {
+	if n >= 1<<10 {
+		return strconv.Itoa(n>>10) + "KiB"
+	}
+	return strconv.Itoa(n) + "B"
+}
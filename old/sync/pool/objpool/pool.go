@@ -0,0 +1,140 @@
+// Package objpool 带类型的对象池: 不需要类型断言, Put 时统一重置, 统计命中率;
+// 可以基于 sync.Pool(GC 时会被清空), 也可以是固定容量的池(GC 不清空, 适合创建成本高的对象)
+//
+//	msgs := objpool.New(objpool.Config[*Message]{
+//		New:   func() *Message { return &Message{Headers: map[string]string{}} },
+//		Reset: func(m *Message) { clear(m.Headers) },
+//	})
+//	m := msgs.Get()
+//	defer msgs.Put(m)
+package objpool
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Config 池的配置, New 必填
+type Config[T any] struct {
+	New   func() T     // 池里没有对象时创建
+	Reset func(T)      // Put 时重置对象, 下一个 Get 拿到的是干净的
+	Keep  func(T) bool // Put 时判断是否放回, 返回 false 的对象直接丢弃, 如容量涨得太大的 buffer
+
+	// Capacity 大于 0 时为固定容量的池: 最多保存 Capacity 个对象, GC 时不会被清空, 满了之后 Put 的对象丢弃;
+	// 为 0 时基于 sync.Pool
+	Capacity int
+}
+
+// Stats 池的统计, Hits+Misses == Gets
+type Stats struct {
+	Gets   uint64 // Get 次数
+	Hits   uint64 // 从池里拿到对象的次数
+	Misses uint64 // 调用 New 创建的次数
+	Puts   uint64 // Put 次数
+	Drops  uint64 // Put 时被 Keep 拒绝或者池已满而丢弃的次数
+}
+
+// HitRate 命中率, 没有 Get 时为 0
+func (s Stats) HitRate() float64 {
+	if s.Gets == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Gets)
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("gets=%d hits=%d misses=%d puts=%d drops=%d hit-rate=%.1f%%",
+		s.Gets, s.Hits, s.Misses, s.Puts, s.Drops, s.HitRate()*100)
+}
+
+func (s Stats) add(o Stats) Stats {
+	return Stats{
+		Gets:   s.Gets + o.Gets,
+		Hits:   s.Hits + o.Hits,
+		Misses: s.Misses + o.Misses,
+		Puts:   s.Puts + o.Puts,
+		Drops:  s.Drops + o.Drops,
+	}
+}
+
+// Pool 带类型的对象池, 可以并发使用; T 一般是指针类型, 值类型放进 sync.Pool 时会有一次装箱分配.
+// 统计用原子计数, 每次 Get/Put 比直接用 sync.Pool 多一次原子加法
+type Pool[T any] struct {
+	cfg Config[T]
+
+	pool  sync.Pool // Capacity 为 0 时使用
+	fixed chan T    // Capacity 大于 0 时使用
+
+	hits, misses, puts, drops atomic.Uint64
+}
+
+// New 创建对象池, cfg.New 为 nil 时 panic
+func New[T any](cfg Config[T]) *Pool[T] {
+	if cfg.New == nil {
+		panic("objpool: Config.New is nil")
+	}
+	p := &Pool[T]{cfg: cfg}
+	if cfg.Capacity > 0 {
+		p.fixed = make(chan T, cfg.Capacity)
+	}
+	return p
+}
+
+// Get 从池里取一个对象, 池空时调用 New
+func (p *Pool[T]) Get() T {
+	if p.fixed != nil {
+		select {
+		case x := <-p.fixed:
+			p.hits.Add(1)
+			return x
+		default:
+		}
+	} else if x, ok := p.pool.Get().(T); ok {
+		p.hits.Add(1)
+		return x
+	}
+	p.misses.Add(1)
+	return p.cfg.New()
+}
+
+// Put 重置后放回池里; 放回后调用方不能再使用 x
+func (p *Pool[T]) Put(x T) {
+	p.puts.Add(1)
+	if p.cfg.Keep != nil && !p.cfg.Keep(x) {
+		p.drops.Add(1)
+		return
+	}
+	if p.cfg.Reset != nil {
+		p.cfg.Reset(x)
+	}
+	if p.fixed == nil {
+		p.pool.Put(x)
+		return
+	}
+	select {
+	case p.fixed <- x:
+	default:
+		p.drops.Add(1)
+	}
+}
+
+// Len 固定容量的池中现有的对象数, 基于 sync.Pool 时无法得知, 返回 -1
+func (p *Pool[T]) Len() int {
+	if p.fixed == nil {
+		return -1
+	}
+	return len(p.fixed)
+}
+
+// Stats 统计快照, 并发使用时各项之间不保证完全一致
+func (p *Pool[T]) Stats() Stats {
+	s := Stats{
+		Hits:   p.hits.Load(),
+		Misses: p.misses.Load(),
+		Puts:   p.puts.Load(),
+		Drops:  p.drops.Load(),
+	}
+	s.Gets = s.Hits + s.Misses
+	return s
+}
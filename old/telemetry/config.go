@@ -0,0 +1,173 @@
+package telemetry
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 导出方式
+const (
+	ExporterOTLP    = "otlp"
+	ExporterStdout  = "stdout"
+	ExporterFile    = "file"
+	ExporterDatadog = "datadog" // 通过 dd-trace-go 发给 DataKit / datadog agent
+	ExporterNone    = "none"
+)
+
+// OTLP 协议
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http/protobuf"
+)
+
+// 采样方式, 与 OTEL_TRACES_SAMPLER 的取值相同
+const (
+	SamplerAlwaysOn                = "always_on"
+	SamplerAlwaysOff               = "always_off"
+	SamplerTraceIDRatio            = "traceidratio"
+	SamplerParentBasedAlwaysOn     = "parentbased_always_on"
+	SamplerParentBasedAlwaysOff    = "parentbased_always_off"
+	SamplerParentBasedTraceIDRatio = "parentbased_traceidratio"
+)
+
+// Config 链路追踪配置
+type Config struct {
+	ServiceName    string
+	ServiceVersion string
+	Environment    string
+	// Attributes 额外的资源属性
+	Attributes map[string]string
+
+	// Exporter otlp, stdout, file, datadog 或 none
+	Exporter string
+	// Protocol otlp 协议, grpc 或 http/protobuf
+	Protocol string
+	// Endpoint otlp 为 host:port 或 http://host:port, datadog 为 agent 地址; 为空时使用各 sdk 的默认值
+	Endpoint string
+	Insecure bool
+	// FilePath file 导出时写入的文件, 每行一个 span
+	FilePath string
+
+	Sampler string
+	// SamplerArg ratio 采样的比例, 0 ~ 1; 为 nil 时按 1 处理, 为 0 时不采样
+	SamplerArg *float64
+}
+
+// 环境变量, 除 TELEMETRY_FILE_PATH 外与 OpenTelemetry 规范同名
+const (
+	EnvServiceName        = "OTEL_SERVICE_NAME"
+	EnvResourceAttributes = "OTEL_RESOURCE_ATTRIBUTES"
+	EnvExporter           = "OTEL_TRACES_EXPORTER"
+	EnvProtocol           = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	EnvEndpoint           = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	EnvInsecure           = "OTEL_EXPORTER_OTLP_INSECURE"
+	EnvSampler            = "OTEL_TRACES_SAMPLER"
+	EnvSamplerArg         = "OTEL_TRACES_SAMPLER_ARG"
+	EnvFilePath           = "TELEMETRY_FILE_PATH"
+)
+
+// ConfigFromEnv 以 defaults 为基础, 用环境变量覆盖
+//
+// OTEL_RESOURCE_ATTRIBUTES 为 k1=v1,k2=v2 格式, 其中 service.version 和 deployment.environment
+// 分别覆盖 ServiceVersion 和 Environment
+func ConfigFromEnv(defaults Config) (Config, error) {
+	cfg := defaults
+	if cfg.Attributes == nil {
+		cfg.Attributes = map[string]string{}
+	}
+	if v := os.Getenv(EnvServiceName); v != "" {
+		cfg.ServiceName = v
+	}
+	if v := os.Getenv(EnvResourceAttributes); v != "" {
+		for _, pair := range strings.Split(v, ",") {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || key == "" {
+				return cfg, fmt.Errorf("%s: invalid pair %q", EnvResourceAttributes, pair)
+			}
+			switch key {
+			case "service.name":
+				if os.Getenv(EnvServiceName) == "" {
+					cfg.ServiceName = value
+				}
+			case "service.version":
+				cfg.ServiceVersion = value
+			case "deployment.environment":
+				cfg.Environment = value
+			default:
+				cfg.Attributes[key] = value
+			}
+		}
+	}
+	if v := os.Getenv(EnvExporter); v != "" {
+		cfg.Exporter = v
+	}
+	if v := os.Getenv(EnvProtocol); v != "" {
+		cfg.Protocol = v
+	}
+	if v := os.Getenv(EnvEndpoint); v != "" {
+		cfg.Endpoint = v
+	}
+	if v := os.Getenv(EnvInsecure); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("%s: %w", EnvInsecure, err)
+		}
+		cfg.Insecure = b
+	}
+	if v := os.Getenv(EnvFilePath); v != "" {
+		cfg.FilePath = v
+	}
+	if v := os.Getenv(EnvSampler); v != "" {
+		cfg.Sampler = v
+	}
+	if v := os.Getenv(EnvSamplerArg); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("%s: %w", EnvSamplerArg, err)
+		}
+		cfg.SamplerArg = &f
+	}
+	return cfg, cfg.normalize()
+}
+
+// normalize 补全默认值并校验
+func (c *Config) normalize() error {
+	if c.ServiceName == "" {
+		c.ServiceName = "unknown_service"
+	}
+	if c.Exporter == "" {
+		c.Exporter = ExporterOTLP
+	}
+	if c.Protocol == "" {
+		c.Protocol = ProtocolGRPC
+	}
+	if c.Sampler == "" {
+		c.Sampler = SamplerParentBasedAlwaysOn
+	}
+	switch c.Exporter {
+	case ExporterOTLP, ExporterStdout, ExporterDatadog, ExporterNone:
+	case ExporterFile:
+		if c.FilePath == "" {
+			return fmt.Errorf("telemetry: exporter file requires %s", EnvFilePath)
+		}
+	default:
+		return fmt.Errorf("telemetry: unknown exporter %q", c.Exporter)
+	}
+	if c.Protocol != ProtocolGRPC && c.Protocol != ProtocolHTTP {
+		return fmt.Errorf("telemetry: unknown otlp protocol %q", c.Protocol)
+	}
+	if r := c.samplerRatio(); r < 0 || r > 1 {
+		return fmt.Errorf("telemetry: sampler ratio %v out of [0, 1]", r)
+	}
+	return nil
+}
+
+// samplerRatio ratio 采样的比例, 没有设置 SamplerArg 时为 1
+func (c *Config) samplerRatio() float64 {
+	if c.SamplerArg == nil {
+		return 1
+	}
+	return *c.SamplerArg
+}
@@ -0,0 +1,32 @@
+package telemetry
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/grpc"
+)
+
+// HTTPHandler 为每个请求创建 server span, 从请求头中提取上游的 trace 上下文
+func HTTPHandler(h http.Handler, operation string) http.Handler {
+	return otelhttp.NewHandler(h, operation)
+}
+
+// HTTPTransport 为每个请求创建 client span, 并把 trace 上下文注入请求头; base 为 nil 时使用 http.DefaultTransport
+func HTTPTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
+
+// GRPCServerOptions gRPC 服务端埋点, 传给 grpc.NewServer
+func GRPCServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler())}
+}
+
+// GRPCDialOptions gRPC 客户端埋点, 传给 grpc.Dial
+func GRPCDialOptions() []grpc.DialOption {
+	return []grpc.DialOption{grpc.WithStatsHandler(otelgrpc.NewClientHandler())}
+}
@@ -0,0 +1,206 @@
+// Package telemetry 统一的链路追踪初始化: 按配置或环境变量选择导出方式(OTLP gRPC/HTTP、stdout、
+// 文件、DataKit/datadog), 设置采样和资源属性, 并提供 HTTP 和 gRPC 的埋点
+//
+// 不论选择哪种导出方式, 业务代码都只使用 OpenTelemetry API (otel.Tracer); datadog 通过 dd-trace-go 的
+// OpenTelemetry 桥接实现. 初始化不会等待采集端连接, 采集端不可用时 span 在后台重试后丢弃, 不影响启动
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace/noop"
+	ddotel "gopkg.in/DataDog/dd-trace-go.v1/ddtrace/opentelemetry"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// ShutdownFunc 刷新还没有导出的 span 并关闭导出器
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup 按配置初始化全局 TracerProvider 和传播器(W3C tracecontext + baggage)
+func Setup(ctx context.Context, cfg Config) (ShutdownFunc, error) {
+	if err := cfg.normalize(); err != nil {
+		return nil, err
+	}
+	sampler, err := newSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	switch cfg.Exporter {
+	case ExporterNone:
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		return func(context.Context) error { return nil }, nil
+	case ExporterDatadog:
+		return setupDatadog(cfg), nil
+	}
+
+	exporter, closeOutput, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	res, err := newResource(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(res),
+		sdktrace.WithBatcher(exporter),
+	)
+	otel.SetTracerProvider(tp)
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closeOutput != nil {
+			err = errors.Join(err, closeOutput())
+		}
+		return err
+	}, nil
+}
+
+// newExporter closeOutput 用于关闭 file 导出时打开的文件
+func newExporter(ctx context.Context, cfg Config) (exporter sdktrace.SpanExporter, closeOutput func() error, err error) {
+	switch cfg.Exporter {
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(stdout), stdouttrace.WithPrettyPrint())
+	case ExporterFile:
+		var f *os.File
+		f, err = os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("telemetry: open %s: %w", cfg.FilePath, err)
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+		closeOutput = f.Close
+	case ExporterOTLP:
+		exporter, err = newOTLPExporter(ctx, cfg)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("telemetry: create %s exporter: %w", cfg.Exporter, err)
+	}
+	return exporter, closeOutput, nil
+}
+
+// stdout 测试时替换
+var stdout io.Writer = os.Stdout
+
+// newOTLPExporter 不使用 grpc.WithBlock, 连接在后台建立, 采集端不可用时不会阻塞启动
+func newOTLPExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	isURL := strings.Contains(cfg.Endpoint, "://")
+	if cfg.Protocol == ProtocolHTTP {
+		opts := []otlptracehttp.Option{otlptracehttp.WithTimeout(10 * time.Second)}
+		switch {
+		case isURL:
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		case cfg.Endpoint != "":
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithTimeout(10 * time.Second)}
+	switch {
+	case isURL:
+		opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.Endpoint))
+	case cfg.Endpoint != "":
+		opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	return otlptracegrpc.New(ctx, opts...)
+}
+
+func newResource(ctx context.Context, cfg Config) (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{semconv.ServiceNameKey.String(cfg.ServiceName)}
+	if cfg.ServiceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersionKey.String(cfg.ServiceVersion))
+	}
+	if cfg.Environment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironmentKey.String(cfg.Environment))
+	}
+	for k, v := range cfg.Attributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	res, err := resource.New(ctx,
+		resource.WithHost(),
+		resource.WithProcessRuntimeName(),
+		resource.WithProcessRuntimeVersion(),
+		resource.WithAttributes(attrs...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("telemetry: resource: %w", err)
+	}
+	return res, nil
+}
+
+func newSampler(cfg Config) (sdktrace.Sampler, error) {
+	ratio := cfg.samplerRatio()
+	switch cfg.Sampler {
+	case SamplerAlwaysOn:
+		return sdktrace.AlwaysSample(), nil
+	case SamplerAlwaysOff:
+		return sdktrace.NeverSample(), nil
+	case SamplerTraceIDRatio:
+		return sdktrace.TraceIDRatioBased(ratio), nil
+	case SamplerParentBasedAlwaysOn:
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case SamplerParentBasedAlwaysOff:
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case SamplerParentBasedTraceIDRatio:
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil
+	default:
+		return nil, fmt.Errorf("telemetry: unknown sampler %q", cfg.Sampler)
+	}
+}
+
+// setupDatadog 使用 dd-trace-go 发送到 DataKit; agent 地址为空时使用 DD_AGENT_HOST 等 datadog 环境变量
+// dd-trace-go 本身按父 span 的采样决定传播, 这里只把比例映射为 rate sampler
+func setupDatadog(cfg Config) ShutdownFunc {
+	opts := []tracer.StartOption{
+		tracer.WithService(cfg.ServiceName),
+		tracer.WithLogStartup(false),
+	}
+	if cfg.ServiceVersion != "" {
+		opts = append(opts, tracer.WithServiceVersion(cfg.ServiceVersion))
+	}
+	if cfg.Environment != "" {
+		opts = append(opts, tracer.WithEnv(cfg.Environment))
+	}
+	for k, v := range cfg.Attributes {
+		opts = append(opts, tracer.WithGlobalTag(k, v))
+	}
+	if cfg.Endpoint != "" {
+		opts = append(opts, tracer.WithAgentAddr(cfg.Endpoint))
+	}
+	switch cfg.Sampler {
+	case SamplerAlwaysOff, SamplerParentBasedAlwaysOff:
+		opts = append(opts, tracer.WithSampler(tracer.NewRateSampler(0)))
+	case SamplerTraceIDRatio, SamplerParentBasedTraceIDRatio:
+		if cfg.SamplerArg != nil {
+			opts = append(opts, tracer.WithSampler(tracer.NewRateSampler(*cfg.SamplerArg)))
+		}
+	}
+	tp := ddotel.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	return func(ctx context.Context) error {
+		return tp.Shutdown()
+	}
+}
@@ -0,0 +1,152 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvServiceName, "order-server")
+	t.Setenv(EnvResourceAttributes, "service.version=1.2.3,deployment.environment=test, team=trade")
+	t.Setenv(EnvExporter, "otlp")
+	t.Setenv(EnvProtocol, "http/protobuf")
+	t.Setenv(EnvEndpoint, "http://collector:4318")
+	t.Setenv(EnvSampler, "parentbased_traceidratio")
+	t.Setenv(EnvSamplerArg, "0.25")
+
+	cfg, err := ConfigFromEnv(Config{ServiceName: "default", Exporter: ExporterStdout, Attributes: map[string]string{"project": "demo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServiceName != "order-server" || cfg.ServiceVersion != "1.2.3" || cfg.Environment != "test" ||
+		cfg.Exporter != ExporterOTLP || cfg.Protocol != ProtocolHTTP || cfg.Endpoint != "http://collector:4318" ||
+		cfg.Sampler != SamplerParentBasedTraceIDRatio || cfg.SamplerArg == nil || *cfg.SamplerArg != 0.25 ||
+		cfg.Attributes["team"] != "trade" || cfg.Attributes["project"] != "demo" {
+		t.Fatalf("got %+v", cfg)
+	}
+
+	t.Setenv(EnvSamplerArg, "1.5")
+	if _, err := ConfigFromEnv(Config{}); err == nil {
+		t.Fatal("want ratio error")
+	}
+
+	// 设置为 0 是完全不采样, 没有设置才按 1 处理
+	t.Setenv(EnvSampler, SamplerTraceIDRatio)
+	for arg, want := range map[string]string{"0": "TraceIDRatioBased{0}", "": "AlwaysOnSampler"} {
+		t.Setenv(EnvSamplerArg, arg)
+		cfg, err := ConfigFromEnv(Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sampler, err := newSampler(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sampler.Description(); got != want {
+			t.Fatalf("sampler arg %q: got %s, want %s", arg, got, want)
+		}
+	}
+	t.Setenv(EnvSamplerArg, "")
+	t.Setenv(EnvExporter, "zipkin")
+	if _, err := ConfigFromEnv(Config{}); err == nil {
+		t.Fatal("want unknown exporter error")
+	}
+}
+
+func TestSetupFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	shutdown, err := Setup(context.Background(), Config{
+		ServiceName: "file-test",
+		Exporter:    ExporterFile,
+		FilePath:    path,
+		Sampler:     SamplerAlwaysOn,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := otel.Tracer("test").Start(context.Background(), "write-file")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`"Name":"write-file"`)) || !bytes.Contains(data, []byte("file-test")) {
+		t.Fatalf("got %s", data)
+	}
+}
+
+func TestSetupDoesNotBlockWhenCollectorIsDown(t *testing.T) {
+	start := time.Now()
+	shutdown, err := Setup(context.Background(), Config{
+		ServiceName: "offline",
+		Exporter:    ExporterOTLP,
+		Endpoint:    "127.0.0.1:1",
+		Insecure:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Setup took %v", elapsed)
+	}
+	_, span := otel.Tracer("test").Start(context.Background(), "dropped")
+	span.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	shutdown(ctx) // 导出失败的错误不重要, 只要不卡住
+}
+
+func TestParentBasedSamplingOverHTTP(t *testing.T) {
+	var out bytes.Buffer
+	stdout = &out
+	defer func() { stdout = os.Stdout }()
+
+	// 本服务自己不采样, 只跟随上游的采样决定
+	shutdown, err := Setup(context.Background(), Config{
+		ServiceName: "http-test",
+		Exporter:    ExporterStdout,
+		Sampler:     SamplerParentBasedAlwaysOff,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), "hello"))
+	defer srv.Close()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	for _, flags := range []string{"00", "01"} {
+		req, _ := http.NewRequest("GET", srv.URL, nil)
+		req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-"+flags)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := strings.Count(out.String(), `"Name": "hello"`); n != 1 {
+		t.Fatalf("exported %d spans, want 1 (only the sampled parent)\n%s", n, out.String())
+	}
+	if !strings.Contains(out.String(), traceID) {
+		t.Fatalf("span did not continue the upstream trace\n%s", out.String())
+	}
+}
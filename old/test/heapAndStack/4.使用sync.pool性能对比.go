@@ -5,6 +5,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"testGo/sync/pool/objpool"
 )
 
 type Message struct {
@@ -52,6 +54,27 @@ func releaseMessage(msg *Message) {
 	messagePool.Put(msg)
 }
 
+// 带类型的池版本, 重置逻辑写在 Reset 里, 不需要类型断言
+var typedMessagePool = objpool.New(objpool.Config[*Message]{
+	New: func() *Message {
+		return &Message{
+			Headers: make(map[string]string),
+			Data:    make([]byte, 1024),
+		}
+	},
+	Reset: func(msg *Message) {
+		clear(msg.Headers)
+		msg.Data = msg.Data[:0]
+	},
+})
+
+func createMessageWithTypedPool(id int) *Message {
+	msg := typedMessagePool.Get()
+	msg.ID = id
+	msg.Content = "Hello World"
+	return msg
+}
+
 // 基准测试
 func BenchmarkWithoutPool(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -69,6 +92,14 @@ func BenchmarkWithPool(b *testing.B) {
 	}
 }
 
+func BenchmarkWithTypedPool(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		msg := createMessageWithTypedPool(i)
+		_ = msg
+		typedMessagePool.Put(msg)
+	}
+}
+
 // func performanceComparison() {
 func main() {
 	fmt.Println("=== 性能对比 ===")
@@ -92,8 +123,18 @@ func main() {
 	}
 	withPoolTime := time.Since(start)
 
+	// 带类型的池测试
+	start = time.Now()
+	for i := 0; i < iterations; i++ {
+		msg := createMessageWithTypedPool(i)
+		_ = msg
+		typedMessagePool.Put(msg)
+	}
+	withTypedPoolTime := time.Since(start)
+
 	fmt.Printf("无池版本: %v\n", withoutPoolTime)
 	fmt.Printf("有池版本: %v\n", withPoolTime)
+	fmt.Printf("带类型的池: %v (%v)\n", withTypedPoolTime, typedMessagePool.Stats())
 	fmt.Printf("性能提升: %.2fx\n",
 		float64(withoutPoolTime)/float64(withPoolTime))
 }
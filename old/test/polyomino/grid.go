@@ -0,0 +1,160 @@
+package polyomino
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Grid 宽 width 的网格中拥有的格子, 行数由最大的格子编号决定
+type Grid struct {
+	width int
+	rows  int
+	owned map[int]bool
+	cells []int // 排好序的格子编号
+}
+
+// NewGrid width 为每行的格子数, cells 为拥有的格子编号(从 1 开始), 重复的编号忽略
+func NewGrid(width int, cells ...int) (*Grid, error) {
+	if width <= 0 {
+		return nil, fmt.Errorf("polyomino: invalid width %d", width)
+	}
+	g := &Grid{width: width, owned: make(map[int]bool, len(cells))}
+	for _, c := range cells {
+		if c < 1 {
+			return nil, fmt.Errorf("polyomino: invalid cell %d", c)
+		}
+		if !g.owned[c] {
+			g.owned[c] = true
+			g.cells = append(g.cells, c)
+		}
+		g.rows = max(g.rows, (c-1)/width+1)
+	}
+	slices.Sort(g.cells)
+	return g, nil
+}
+
+// Width 每行的格子数
+func (g *Grid) Width() int { return g.width }
+
+// Cells 拥有的格子, 从小到大
+func (g *Grid) Cells() []int { return slices.Clone(g.cells) }
+
+// Has 是否拥有格子 cell
+func (g *Grid) Has(cell int) bool { return g.owned[cell] }
+
+// Cell 第 row 行第 col 列(都从 0 开始)的格子编号
+func (g *Grid) Cell(row, col int) int { return row*g.width + col + 1 }
+
+// Pos 格子编号对应的行和列
+func (g *Grid) Pos(cell int) (row, col int) { return (cell - 1) / g.width, (cell - 1) % g.width }
+
+// Placement 形状在网格中的一个摆放
+type Placement struct {
+	Shape Shape // 摆放时的朝向
+	Cells []int // 占用的格子, 从小到大
+}
+
+// Find 找出 shapes 中任意一个形状的所有摆放, 形状按给定的朝向匹配, 需要旋转时传入 s.Rotations()...
+//
+// 结果按第一个格子排序, 同样的格子集合只出现一次
+func (g *Grid) Find(shapes ...Shape) []Placement {
+	var out []Placement
+	seen := map[string]bool{}
+	for _, s := range shapes {
+		s = s.Normalize()
+		if len(s) == 0 {
+			continue
+		}
+		for _, anchor := range g.cells {
+			cells, ok := g.place(s, anchor)
+			if !ok {
+				continue
+			}
+			key := fmt.Sprint(cells)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			out = append(out, Placement{Shape: s, Cells: cells})
+		}
+	}
+	slices.SortStableFunc(out, func(a, b Placement) int { return slices.Compare(a.Cells, b.Cells) })
+	return out
+}
+
+// First 第一个摆放, 相当于原来 check*LandShape 的结果
+func (g *Grid) First(shapes ...Shape) (Placement, bool) {
+	ps := g.Find(shapes...)
+	if len(ps) == 0 {
+		return Placement{}, false
+	}
+	return ps[0], true
+}
+
+// place 把形状的第一个格子(行优先)放在 anchor 上, 返回占用的格子;
+// 形状超出左右边界或者有格子不属于自己时 ok 为 false
+func (g *Grid) place(s Shape, anchor int) (cells []int, ok bool) {
+	ar, ac := g.Pos(anchor)
+	r0, c0 := ar-s[0].Row, ac-s[0].Col
+	cells = make([]int, len(s))
+	for i, p := range s {
+		r, c := r0+p.Row, c0+p.Col
+		if r < 0 || c < 0 || c >= g.width {
+			return nil, false
+		}
+		cell := g.Cell(r, c)
+		if !g.owned[cell] {
+			return nil, false
+		}
+		cells[i] = cell
+	}
+	return cells, true
+}
+
+// LargestRect 面积最大的全部拥有的矩形, 面积相同时取左上角最靠前的; 没有格子时 ok 为 false
+func (g *Grid) LargestRect() (p Placement, ok bool) {
+	var (
+		heights          = make([]int, g.width)
+		bestArea         int
+		bestTop, bestCol int
+		bestW, bestH     int
+	)
+	for r := 0; r < g.rows; r++ {
+		for c := range heights {
+			if g.owned[g.Cell(r, c)] {
+				heights[c]++
+			} else {
+				heights[c] = 0
+			}
+		}
+		// 以第 r 行为底的直方图最大矩形, 单调栈
+		var stack []int
+		for c := 0; c <= g.width; c++ {
+			h := 0
+			if c < g.width {
+				h = heights[c]
+			}
+			for len(stack) > 0 && heights[stack[len(stack)-1]] >= h {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				left := 0
+				if len(stack) > 0 {
+					left = stack[len(stack)-1] + 1
+				}
+				height, width := heights[top], c-left
+				area := height * width
+				t := r - height + 1
+				if area > bestArea || (area == bestArea && area > 0 && g.Cell(t, left) < g.Cell(bestTop, bestCol)) {
+					bestArea, bestTop, bestCol, bestW, bestH = area, t, left, width, height
+				}
+			}
+			stack = append(stack, c)
+		}
+	}
+	if bestArea == 0 {
+		return Placement{}, false
+	}
+	s := Rect(bestW, bestH)
+	cells, _ := g.place(s, g.Cell(bestTop, bestCol))
+	return Placement{Shape: s, Cells: cells}, true
+}
@@ -0,0 +1,105 @@
+package polyomino
+
+import "slices"
+
+// PackNodes Pack 回溯时最多搜索的节点数
+const PackNodes = 1_000_000
+
+// Pack 用 shapes 中的形状铺地块, 互不重叠, 返回摆放个数最多的一种方案;
+// 个数相同时取占用格子最多的. 形状按给定的朝向使用, 需要旋转时传入 s.Rotations()...
+//
+// 回溯求精确解, 最多搜索 PackNodes 个节点, 超出后返回已经找到的最好方案(不一定最优).
+// 存在铺满或接近铺满的方案时剪枝很快; 铺不满的地块(如奇数格子的矩形铺多米诺)
+// 几十个格子就可能超出, 需要知道结果是否最优时用 PackWithin
+func (g *Grid) Pack(shapes ...Shape) []Placement {
+	ps, _ := g.PackWithin(PackNodes, shapes...)
+	return ps
+}
+
+// PackWithin 同 Pack, 最多搜索 maxNodes 个节点, maxNodes <= 0 时不限;
+// exact 为 false 表示搜索被截断, 返回的是已经找到的最好方案.
+// 第一条搜索路径就是按格子顺序能放就放大形状的贪心解, 所以截断时结果不会比贪心差
+func (g *Grid) PackWithin(maxNodes int, shapes ...Shape) (ps []Placement, exact bool) {
+	all := g.Find(shapes...)
+	if len(all) == 0 {
+		return nil, true
+	}
+	// 按第一个格子分组: 回溯时最小的空格子要么被以它开头的摆放占用, 要么留空
+	byFirst := map[int][]Placement{}
+	minSize := len(all[0].Cells)
+	for _, p := range all {
+		byFirst[p.Cells[0]] = append(byFirst[p.Cells[0]], p)
+		minSize = min(minSize, len(p.Cells))
+	}
+	// 大的形状优先尝试, 尽早找到占用格子多的方案
+	for _, ps := range byFirst {
+		slices.SortStableFunc(ps, func(a, b Placement) int { return len(b.Cells) - len(a.Cells) })
+	}
+
+	pk := packer{cells: g.cells, byFirst: byFirst, minSize: minSize, used: map[int]bool{},
+		limited: maxNodes > 0, nodes: maxNodes}
+	pk.search(0, len(g.cells))
+	return pk.best, !pk.cut
+}
+
+type packer struct {
+	cells   []int
+	byFirst map[int][]Placement
+	minSize int
+	limited bool
+	nodes   int  // limited 时剩余可搜索的节点数
+	cut     bool // 节点数用完, 搜索被截断
+
+	used      map[int]bool
+	cur       []Placement
+	covered   int
+	best      []Placement
+	bestCells int
+}
+
+// search 从 cells[i] 开始继续铺, free 为 cells[i:] 中还没有占用的格子数
+func (pk *packer) search(i, free int) {
+	if pk.limited {
+		if pk.nodes == 0 {
+			pk.cut = true
+			return
+		}
+		pk.nodes--
+	}
+	if len(pk.cur) > len(pk.best) || (len(pk.cur) == len(pk.best) && pk.covered > pk.bestCells) {
+		pk.best, pk.bestCells = slices.Clone(pk.cur), pk.covered
+	}
+	for i < len(pk.cells) && pk.used[pk.cells[i]] {
+		i++
+	}
+	if i == len(pk.cells) {
+		return
+	}
+	// 剩下的格子全部用最小的形状铺满也超不过当前最好的方案
+	if bound := len(pk.cur) + free/pk.minSize; bound < len(pk.best) ||
+		(bound == len(pk.best) && pk.covered+free <= pk.bestCells) {
+		return
+	}
+
+	cell := pk.cells[i]
+	for _, p := range pk.byFirst[cell] {
+		if slices.ContainsFunc(p.Cells, func(c int) bool { return pk.used[c] }) {
+			continue
+		}
+		for _, c := range p.Cells {
+			pk.used[c] = true
+		}
+		pk.cur = append(pk.cur, p)
+		pk.covered += len(p.Cells)
+		pk.search(i+1, free-len(p.Cells))
+		pk.covered -= len(p.Cells)
+		pk.cur = pk.cur[:len(pk.cur)-1]
+		for _, c := range p.Cells {
+			delete(pk.used, c)
+		}
+	}
+	// cell 留空
+	pk.used[cell] = true
+	pk.search(i+1, free-1)
+	delete(pk.used, cell)
+}
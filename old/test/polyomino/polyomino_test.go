@@ -0,0 +1,266 @@
+package polyomino
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+func mustGrid(t *testing.T, width int, cells ...int) *Grid {
+	t.Helper()
+	g, err := NewGrid(width, cells...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func cellsOf(ps []Placement) [][]int {
+	out := make([][]int, len(ps))
+	for i, p := range ps {
+		out[i] = p.Cells
+	}
+	return out
+}
+
+func TestShape(t *testing.T) {
+	l := ParseShape(
+		"#.",
+		"#.",
+		"##",
+	)
+	if w, h := l.Size(); w != 2 || h != 3 {
+		t.Fatalf("size %dx%d", w, h)
+	}
+	if got := l.String(); got != "#.\n#.\n##" {
+		t.Fatalf("String %q", got)
+	}
+	if got := l.Rotations()[1].String(); got != "###\n#.." {
+		t.Fatalf("rotated 90 %q", got)
+	}
+
+	tests := []struct {
+		name                    string
+		shape                   Shape
+		rotations, orientations int
+	}{
+		{"square", Rect(2, 2), 1, 1},
+		{"I", Rect(3, 1), 2, 2},
+		{"L", l, 4, 8},
+		{"T", ParseShape("###", ".#."), 4, 4},
+		{"S", ParseShape(".##", "##."), 2, 4},
+	}
+	for _, tt := range tests {
+		if n := len(tt.shape.Rotations()); n != tt.rotations {
+			t.Errorf("%s rotations %d, want %d", tt.name, n, tt.rotations)
+		}
+		if n := len(tt.shape.Orientations()); n != tt.orientations {
+			t.Errorf("%s orientations %d, want %d", tt.name, n, tt.orientations)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	//  1  2  3  4
+	//  5  6  7  8
+	//  9 10 11 12
+	g := mustGrid(t, 4, 1, 2, 3, 5, 6, 7, 10, 11, 12)
+
+	got := cellsOf(g.Find(Rect(2, 2)))
+	want := [][]int{{1, 2, 5, 6}, {2, 3, 6, 7}, {6, 7, 10, 11}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Fatalf("2*2 = %v", got)
+	}
+	// 4 和 5 编号连续但不在同一行
+	g2 := mustGrid(t, 4, 4, 5)
+	if ps := g2.Find(Rect(2, 1).Rotations()...); len(ps) != 0 {
+		t.Fatalf("wrapped around the row: %v", cellsOf(ps))
+	}
+
+	// 非矩形: 所有朝向的 L
+	ls := g.Find(ParseShape("#.", "#.", "##").Orientations()...)
+	for _, p := range ls {
+		if len(p.Cells) != 4 {
+			t.Fatalf("bad placement %v", p)
+		}
+		for _, c := range p.Cells {
+			if !g.Has(c) {
+				t.Fatalf("placement %v uses unowned cell %d", p.Cells, c)
+			}
+		}
+	}
+	if !slices.ContainsFunc(ls, func(p Placement) bool { return slices.Equal(p.Cells, []int{2, 6, 10, 11}) }) {
+		t.Fatalf("missing L at 2,6,10,11: %v", cellsOf(ls))
+	}
+	if !slices.ContainsFunc(ls, func(p Placement) bool { return slices.Equal(p.Cells, []int{6, 10, 11, 12}) }) {
+		t.Fatalf("missing rotated L at 6,10,11,12: %v", cellsOf(ls))
+	}
+
+	first, ok := g.First(Rect(2, 3))
+	if !ok || !slices.Equal(first.Cells, []int{2, 3, 6, 7, 10, 11}) {
+		t.Fatalf("First 2*3 = %v %v", first.Cells, ok)
+	}
+	if _, ok := g.First(Rect(3, 2).Rotations()...); !ok {
+		t.Fatal("3*2 rotated should match 2*3")
+	}
+	if _, ok := g.First(Rect(4, 1)); ok {
+		t.Fatal("no full row owned")
+	}
+}
+
+// 和逐个格子检查矩形的朴素写法对比
+func TestFindRectBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for range 200 {
+		width := 3 + rng.Intn(3)
+		var cells []int
+		for c := 1; c <= width*4; c++ {
+			if rng.Intn(3) > 0 {
+				cells = append(cells, c)
+			}
+		}
+		g := mustGrid(t, width, cells...)
+		for _, size := range [][2]int{{1, 2}, {2, 1}, {2, 2}, {2, 3}, {3, 2}} {
+			w, h := size[0], size[1]
+			var want [][]int
+			for r := 0; r+h <= 4; r++ {
+				for c := 0; c+w <= width; c++ {
+					var rect []int
+					for dr := range h {
+						for dc := range w {
+							if g.Has(g.Cell(r+dr, c+dc)) {
+								rect = append(rect, g.Cell(r+dr, c+dc))
+							}
+						}
+					}
+					if len(rect) == w*h {
+						want = append(want, rect)
+					}
+				}
+			}
+			slices.SortFunc(want, slices.Compare)
+			if got := cellsOf(g.Find(Rect(w, h))); !slices.EqualFunc(got, want, slices.Equal) {
+				t.Fatalf("width %d cells %v rect %dx%d: got %v want %v", width, cells, w, h, got, want)
+			}
+		}
+	}
+}
+
+func TestLargestRect(t *testing.T) {
+	//  1  2  3  4  5
+	//  6  7  8  9 10
+	// 11 12 13 14 15
+	g := mustGrid(t, 5, 1, 2, 4, 6, 7, 8, 9, 11, 12, 13, 14, 15)
+	p, ok := g.LargestRect()
+	if !ok || !slices.Equal(p.Cells, []int{6, 7, 8, 9, 11, 12, 13, 14}) {
+		t.Fatalf("got %v %v", p.Cells, ok)
+	}
+	if w, h := p.Shape.Size(); w != 4 || h != 2 {
+		t.Fatalf("shape %dx%d", w, h)
+	}
+
+	// 面积相同取左上角靠前的
+	g = mustGrid(t, 4, 1, 5, 3, 4)
+	if p, _ := g.LargestRect(); !slices.Equal(p.Cells, []int{1, 5}) {
+		t.Fatalf("tie got %v", p.Cells)
+	}
+	if _, ok := mustGrid(t, 4).LargestRect(); ok {
+		t.Fatal("empty grid")
+	}
+}
+
+func TestPack(t *testing.T) {
+	//  1  2  3
+	//  4  5  6
+	// 1,2,4 放下后剩下的 3,5,6 要换一个朝向才放得下
+	g := mustGrid(t, 3, 1, 2, 3, 4, 5, 6)
+	got := g.Pack(ParseShape("##", "#.").Orientations()...)
+	if len(got) != 2 {
+		t.Fatalf("L trominoes on 2x3: %v", cellsOf(got))
+	}
+
+	rng := rand.New(rand.NewSource(2))
+	shapes := append(Rect(2, 1).Rotations(), ParseShape("##", "#.").Orientations()...)
+	for range 100 {
+		var cells []int
+		for c := 1; c <= 12; c++ {
+			if rng.Intn(4) > 0 {
+				cells = append(cells, c)
+			}
+		}
+		g := mustGrid(t, 4, cells...)
+		got := g.Pack(shapes...)
+		used := map[int]bool{}
+		for _, p := range got {
+			for _, c := range p.Cells {
+				if used[c] || !g.Has(c) {
+					t.Fatalf("cells %v: overlapping or unowned packing %v", cells, cellsOf(got))
+				}
+				used[c] = true
+			}
+		}
+		if want := bruteMaxPack(g.Find(shapes...), map[int]bool{}); len(got) != want {
+			t.Fatalf("cells %v: packed %d, want %d", cells, len(got), want)
+		}
+	}
+}
+
+func TestPackBudget(t *testing.T) {
+	// 9x9 铺不满多米诺, 最多 40 块; 精确回溯是指数级的, 要靠节点上限及时返回
+	var cells []int
+	for c := 1; c <= 81; c++ {
+		cells = append(cells, c)
+	}
+	g := mustGrid(t, 9, cells...)
+	start := time.Now()
+	got := g.Pack(Rect(2, 1).Rotations()...)
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("Pack took %v", d)
+	}
+	// 截断时至少是贪心解: 每行放 4 块横的
+	if len(got) < 36 || len(got) > 40 {
+		t.Fatalf("packed %d dominoes on 9x9", len(got))
+	}
+
+	if _, exact := g.PackWithin(1000, Rect(2, 1).Rotations()...); exact {
+		t.Fatal("search of 9x9 within 1000 nodes reported exact")
+	}
+	small := mustGrid(t, 3, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	if got, exact := small.PackWithin(0, Rect(2, 1).Rotations()...); !exact || len(got) != 4 {
+		t.Fatalf("3x3: packed %d exact %v", len(got), exact)
+	}
+}
+
+func bruteMaxPack(ps []Placement, used map[int]bool) int {
+	best := 0
+	for i, p := range ps {
+		if slices.ContainsFunc(p.Cells, func(c int) bool { return used[c] }) {
+			continue
+		}
+		for _, c := range p.Cells {
+			used[c] = true
+		}
+		best = max(best, 1+bruteMaxPack(ps[i+1:], used))
+		for _, c := range p.Cells {
+			delete(used, c)
+		}
+	}
+	return best
+}
+
+func TestNewGrid(t *testing.T) {
+	if _, err := NewGrid(0, 1); err == nil {
+		t.Fatal("want width error")
+	}
+	if _, err := NewGrid(3, 0); err == nil {
+		t.Fatal("want cell error")
+	}
+	g := mustGrid(t, 3, 5, 1, 5)
+	if !slices.Equal(g.Cells(), []int{1, 5}) {
+		t.Fatalf("cells %v", g.Cells())
+	}
+	if r, c := g.Pos(5); r != 1 || c != 1 || g.Cell(r, c) != 5 {
+		t.Fatalf("pos %d %d", r, c)
+	}
+}
@@ -0,0 +1,144 @@
+// Package polyomino 在网格地块中查找形状: 格子按行从 1 开始编号, 每行 width 个格子,
+// 和 testTwo.go 里 columnsNum 的编号方式一致:
+//
+//	1  2  3  4
+//	5  6  7  8
+//	9 10 11 12
+//
+// 形状可以是任意多联骨牌(包括 L、T 等非矩形), 支持旋转和镜像
+package polyomino
+
+import (
+	"slices"
+	"strings"
+)
+
+// Point 形状中的一个格子, 以形状左上角为 (0, 0)
+type Point struct {
+	Row, Col int
+}
+
+// Shape 格子的集合, 经过 Normalize 后按行优先排序且最小行列为 0
+type Shape []Point
+
+// Rect width 列 height 行的矩形, Rect(2, 3) 即 testTwo.go 里的 "2*3"
+func Rect(width, height int) Shape {
+	s := make(Shape, 0, width*height)
+	for r := 0; r < height; r++ {
+		for c := 0; c < width; c++ {
+			s = append(s, Point{r, c})
+		}
+	}
+	return s
+}
+
+// ParseShape 按行画出形状, '.' 和空格为空, 其他字符为格子:
+//
+//	ParseShape("#.", "#.", "##") // L 形
+func ParseShape(rows ...string) Shape {
+	var s Shape
+	for r, row := range rows {
+		for c, ch := range []rune(row) {
+			if ch != '.' && ch != ' ' {
+				s = append(s, Point{r, c})
+			}
+		}
+	}
+	return s.Normalize()
+}
+
+// Normalize 平移到最小行列为 0, 去重并按行优先排序
+func (s Shape) Normalize() Shape {
+	if len(s) == 0 {
+		return nil
+	}
+	minR, minC := s[0].Row, s[0].Col
+	for _, p := range s {
+		minR, minC = min(minR, p.Row), min(minC, p.Col)
+	}
+	out := make(Shape, len(s))
+	for i, p := range s {
+		out[i] = Point{p.Row - minR, p.Col - minC}
+	}
+	slices.SortFunc(out, comparePoint)
+	return slices.Compact(out)
+}
+
+// Size 宽(列数)和高(行数)
+func (s Shape) Size() (width, height int) {
+	for _, p := range s.Normalize() {
+		width, height = max(width, p.Col+1), max(height, p.Row+1)
+	}
+	return width, height
+}
+
+// Rotations 顺时针旋转 0/90/180/270 度后不重复的形状
+func (s Shape) Rotations() []Shape {
+	return s.orientations(false)
+}
+
+// Orientations 旋转加镜像后所有不重复的形状, 最多 8 种
+func (s Shape) Orientations() []Shape {
+	return s.orientations(true)
+}
+
+func (s Shape) orientations(mirror bool) []Shape {
+	var out []Shape
+	add := func(t Shape) {
+		t = t.Normalize()
+		if !slices.ContainsFunc(out, func(o Shape) bool { return slices.Equal(o, t) }) {
+			out = append(out, t)
+		}
+	}
+	cur := s
+	for range 4 {
+		add(cur)
+		if mirror {
+			add(cur.mirror())
+		}
+		cur = cur.rotate()
+	}
+	return out
+}
+
+// rotate 顺时针旋转 90 度
+func (s Shape) rotate() Shape {
+	out := make(Shape, len(s))
+	for i, p := range s {
+		out[i] = Point{p.Col, -p.Row}
+	}
+	return out
+}
+
+// mirror 左右翻转
+func (s Shape) mirror() Shape {
+	out := make(Shape, len(s))
+	for i, p := range s {
+		out[i] = Point{p.Row, -p.Col}
+	}
+	return out
+}
+
+// String 画出形状, 每行用 '\n' 分隔
+func (s Shape) String() string {
+	w, h := s.Size()
+	grid := make([][]byte, h)
+	for r := range grid {
+		grid[r] = []byte(strings.Repeat(".", w))
+	}
+	for _, p := range s.Normalize() {
+		grid[p.Row][p.Col] = '#'
+	}
+	lines := make([]string, h)
+	for r, row := range grid {
+		lines[r] = string(row)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func comparePoint(a, b Point) int {
+	if a.Row != b.Row {
+		return a.Row - b.Row
+	}
+	return a.Col - b.Col
+}
@@ -1,6 +1,10 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+
+	"testGo/test/polyomino"
+)
 
 func main() {
 
@@ -140,7 +144,7 @@ func main() {
 
 	// 是否存在2*2的格子
 	//for _, v := range list {
-	//	returnData := checkTwoToTwoLandShape(v, columnsNum)
+	//	returnData := checkLandShape(v, columnsNum, polyomino.Rect(2, 2))
 	//	if len(returnData) == 4 {
 	//		fmt.Println(v, "存在2*2的格子", returnData)
 	//	}
@@ -148,7 +152,7 @@ func main() {
 
 	// 是否存在2*3的格子
 	//for _, v := range list {
-	//	returnData := checkTwoToThreeLandShape(v, columnsNum)
+	//	returnData := checkLandShape(v, columnsNum, polyomino.Rect(2, 3))
 	//	if len(returnData) > 0 {
 	//		fmt.Println(v, "存在2*3的格子", returnData)
 	//	}
@@ -156,7 +160,7 @@ func main() {
 
 	// 是否存在3*2的格子
 	//for _, v := range list {
-	//	returnData := checkThreeToTwoLandShape(v, columnsNum)
+	//	returnData := checkLandShape(v, columnsNum, polyomino.Rect(3, 2))
 	//	if len(returnData) > 0 {
 	//		fmt.Println(v, "存在3*2的格子", returnData)
 	//	}
@@ -164,7 +168,7 @@ func main() {
 
 	// 是否存在1*2的格子
 	//for _, v := range list {
-	//	returnData := checkOneToTwoLandShape(v, columnsNum)
+	//	returnData := checkLandShape(v, columnsNum, polyomino.Rect(1, 2))
 	//	if len(returnData) > 0 {
 	//		fmt.Println(v, "存在1*2的格子", returnData)
 	//	}
@@ -172,153 +176,48 @@ func main() {
 
 	// 是否存在2*1的格子
 	for _, v := range list {
-		returnData := checkTwoToOneLandShape(v, columnsNum)
+		returnData := checkLandShape(v, columnsNum, polyomino.Rect(2, 1))
 		if len(returnData) > 0 {
 			fmt.Println(v, "存在2*1的格子", returnData)
 		}
 	}
-}
-
-// 是否存在2*2的形状
-func checkTwoToTwoLandShape(list []int32, columnsNum int32) []int32 {
-	boxNum := len(list)
-	returnData := make([]int32, 0)
-	for ik, iv := range list {
-		if ik+1 < boxNum {
-			// 一行是否存在 连续两个格子
-			if checkSameRowForTwoBox(iv, list[ik+1], columnsNum) {
-				// 如果一行存在连续的两个格子 判断下一行是否存在 连续两个格子
-				nextIv := iv + columnsNum
-				nextJv := list[ik+1] + columnsNum
 
-				isNextExist := 0
-				for jk := ik + 2; jk < boxNum; jk++ {
-					if list[jk] == nextIv || list[jk] == nextJv {
-						isNextExist++
-						if isNextExist == 2 {
-							returnData = append(returnData, iv, list[ik+1], nextIv, nextJv)
-							return returnData
-						}
-					}
-				}
-			}
+	// 是否存在任意朝向的L形
+	lShape := polyomino.ParseShape("#.", "#.", "##")
+	for _, v := range list {
+		returnData := checkLandShape(v, columnsNum, lShape.Orientations()...)
+		if len(returnData) > 0 {
+			fmt.Println(v, "存在L形的格子", returnData)
 		}
 	}
 
-	return returnData
-}
-
-// 是否存在2*3的形状
-func checkTwoToThreeLandShape(list []int32, columnsNum int32) []int32 {
-	boxNum := len(list)
-	returnData := make([]int32, 0)
-	for ik, iv := range list {
-		if ik+1 < boxNum {
-			// 一行是否存在 连续两个格子
-			if checkSameRowForTwoBox(iv, list[ik+1], columnsNum) {
-				// 如果一行存在连续的两个格子 判断下一行是否存在 连续两个格子
-				nextIv := iv + columnsNum
-				nextJv := list[ik+1] + columnsNum
-
-				isNextExist := 0
-				jk := ik + 2
-				for ; jk < boxNum; jk++ {
-					if list[jk] == nextIv || list[jk] == nextJv {
-						isNextExist++
-						if isNextExist == 2 {
-							break
-						}
-					}
-				}
-
-				// 第三行是否也存在
-				if isNextExist == 2 {
-					lastIv := nextIv + columnsNum
-					lastJv := nextJv + columnsNum
-
-					isLastExist := 0
-					for mk := jk + 1; mk < boxNum; mk++ {
-						if list[mk] == lastIv || list[mk] == lastJv {
-							isLastExist++
-							if isLastExist == 2 {
-								returnData = append(returnData, iv, list[ik+1], nextIv, nextJv, lastIv, lastJv)
-								return returnData
-							}
-						}
-					}
-				}
-			}
-		}
+	// 最大的矩形, 以及最多能放几块不重叠的2*1
+	grid, _ := polyomino.NewGrid(int(columnsNum), 1, 2, 3, 5, 6, 7, 10, 11, 12)
+	if p, ok := grid.LargestRect(); ok {
+		fmt.Println("最大矩形", p.Cells)
 	}
-
-	return returnData
-}
-
-// 是否存在3*2的形状
-func checkThreeToTwoLandShape(list []int32, columnsNum int32) []int32 {
-	boxNum := len(list)
-	returnData := make([]int32, 0)
-
-	for ik, iv := range list {
-		if ik+2 < boxNum {
-			// 一行是否存在 连续的三个格子
-			if checkSameRowForThreeBox(iv, list[ik+1], list[ik+2], columnsNum) {
-
-				// 如果一行存在连续的三个格子 判断下一行是否存在 连续三个格子
-				nextIv := iv + columnsNum
-				nextJv := list[ik+1] + columnsNum
-				nextKv := list[ik+2] + columnsNum
-				isNextExist := 0
-				jk := ik + 3
-				for ; jk < boxNum; jk++ {
-					if list[jk] == nextIv || list[jk] == nextJv || list[jk] == nextKv {
-						isNextExist++
-						if isNextExist == 3 {
-							returnData = append(returnData, iv, list[ik+1], list[ik+2], nextIv, nextJv, nextKv)
-							return returnData
-						}
-					}
-				}
-			}
-		}
+	for _, p := range grid.Pack(polyomino.Rect(2, 1).Rotations()...) {
+		fmt.Println("铺2*1", p.Cells)
 	}
-
-	return returnData
 }
 
-// 是否存在1*2的形状
-func checkOneToTwoLandShape(list []int32, columnsNum int32) []int32 {
-	boxNum := len(list)
-	returnData := make([]int32, 0)
-
-	for ik, iv := range list {
-		if ik+1 < boxNum {
-			// 一列是否存在 连续的两个格子
-			if checkSameColForTwoBox(iv, list[ik+1], columnsNum) {
-				returnData = append(returnData, iv, list[ik+1])
-				return returnData
-			}
-		}
+// 是否存在 shape 的形状, 返回第一个匹配的格子; 2*2、2*3 这类矩形用 polyomino.Rect(宽, 高),
+// 其他形状用 polyomino.ParseShape 画出来, 需要旋转时传入 shape.Rotations()...
+func checkLandShape(list []int32, columnsNum int32, shape ...polyomino.Shape) []int32 {
+	cells := make([]int, len(list))
+	for i, v := range list {
+		cells[i] = int(v)
 	}
-
-	return returnData
-}
-
-// 是否存在2*1的形状
-func checkTwoToOneLandShape(list []int32, columnsNum int32) []int32 {
-	boxNum := len(list)
 	returnData := make([]int32, 0)
-
-	for ik, iv := range list {
-		if ik+1 < boxNum {
-			// 一行是否存在 连续的两个格子
-			if checkSameRowForTwoBox(iv, list[ik+1], columnsNum) {
-				returnData = append(returnData, iv, list[ik+1])
-				return returnData
-			}
+	grid, err := polyomino.NewGrid(int(columnsNum), cells...)
+	if err != nil {
+		return returnData
+	}
+	if p, ok := grid.First(shape...); ok {
+		for _, c := range p.Cells {
+			returnData = append(returnData, int32(c))
 		}
 	}
-
 	return returnData
 }
 
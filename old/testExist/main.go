@@ -1,77 +1,103 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"math/rand"
-	"os"
-	"os/signal"
 	"sync"
 	"time"
+
+	"testGo/testExist/supervisor"
 )
 
 // Task ...
 type Task struct {
-	closed chan struct{}
 	wg     sync.WaitGroup
 	ticker *time.Ticker
 }
 
 func main() {
 	task := &Task{
-		closed: make(chan struct{}),
 		ticker: time.NewTicker(time.Second * 2),
 	}
 
-	c := make(chan os.Signal)
-	signal.Notify(c, os.Interrupt)
-
-	go task.Run()
-
-	// 主程序在监听`Ctrl+C`消息，收到之后，会把task任务stop
-	select {
-	case sig := <-c:
+	s := &supervisor.Supervisor{Logf: log.Printf}
+	s.Add(supervisor.Spec{Name: "task", Run: task.Run, Restart: supervisor.OnFailure, StopTimeout: 30 * time.Second})
+	s.Add(supervisor.Spec{Name: "flaky", Run: flaky, Restart: supervisor.OnFailure, MaxRestarts: 5})
+	s.Add(supervisor.Spec{Name: "health", Run: func(ctx context.Context) error { return report(ctx, s) }, Restart: supervisor.Always})
 
-		fmt.Printf("Got %s signal. Aborting...\n", sig)
-		task.Stop()
+	// 主程序在监听`Ctrl+C`和 SIGTERM 消息，收到之后，会把所有 worker stop
+	if err := s.RunUntilSignal(context.Background()); err != nil {
+		fmt.Println("stop:", err)
 	}
+	fmt.Println("all goroutine　done...")
 }
 
 // Run ...
-func (t *Task) Run() {
+func (t *Task) Run(ctx context.Context) error {
 	for {
 		select {
 		// 这里收到了退出消息之后，就不不会再调用handle了
-		case <-t.closed:
+		case <-ctx.Done():
 			fmt.Println("close .....")
-			return
+			// 在这里会等待所有的协程都退出
+			t.wg.Wait()
+			return ctx.Err()
 		case <-t.ticker.C:
 			t.wg.Add(1)
 			fmt.Println("add handle.....")
-			go handle(t)
+			go handle(ctx, t)
 		}
 	}
 }
 
-// Stop ...
-func (t *Task) Stop() {
-	fmt.Println("got close sig.....")
-	close(t.closed)
-	// 在这里会等待所有的协程都退出
-	t.wg.Wait()
-	fmt.Println("all goroutine　done...")
-}
-
-func handle(task *Task) {
+func handle(ctx context.Context, task *Task) {
 	defer task.wg.Done()
 	for i := 0; i < 5; i++ {
 		fmt.Print("#")
 		st := RandInt64(1, 5)
-		time.Sleep(time.Second * time.Duration(st))
+		select {
+		case <-ctx.Done():
+			fmt.Println()
+			return
+		case <-time.After(time.Second * time.Duration(st)):
+		}
 	}
 
 	fmt.Println()
 }
 
+// flaky 随机出错或 panic, 演示重启和退避
+func flaky(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Second * time.Duration(RandInt64(1, 5))):
+	}
+	if rand.Intn(2) == 0 {
+		panic("flaky worker panic")
+	}
+	return errors.New("flaky worker failed")
+}
+
+// report 定时打印 worker 的状态
+func report(ctx context.Context, s *supervisor.Supervisor) error {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			for _, h := range s.Health() {
+				fmt.Printf("\n%-8s %-10s restarts=%d err=%v\n", h.Name, h.State, h.Restarts, h.LastError)
+			}
+		}
+	}
+}
+
 // RandInt64 ...
 func RandInt64(min, max int64) int64 {
 	if min >= max || min == 0 || max == 0 {
@@ -0,0 +1,68 @@
+package supervisor
+
+import (
+	"fmt"
+	"time"
+)
+
+// State worker 的状态
+type State int
+
+const (
+	Idle       State = iota // 还没有启动
+	Running                 // 正在运行
+	Restarting              // 退出后等待重启
+	Stopping                // 已通知退出, 等待返回
+	Stopped                 // 正常结束
+	Failed                  // 出错结束, 或者超过了最多重启次数
+)
+
+func (s State) String() string {
+	switch s {
+	case Idle:
+		return "idle"
+	case Running:
+		return "running"
+	case Restarting:
+		return "restarting"
+	case Stopping:
+		return "stopping"
+	case Stopped:
+		return "stopped"
+	case Failed:
+		return "failed"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Health 一个 worker 的健康状态快照
+type Health struct {
+	Name        string
+	State       State
+	Since       time.Time     // 进入当前状态的时间
+	StartedAt   time.Time     // 最近一次启动的时间
+	Restarts    int           // 已经重启的次数
+	LastError   error         // 最近一次退出的错误, panic 时为 *PanicError
+	NextRestart time.Duration // Restarting 状态下的重启间隔
+}
+
+// Health 所有 worker 的状态, 按 Add 的顺序
+func (s *Supervisor) Health() []Health {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hs := make([]Health, len(s.workers))
+	for i, w := range s.workers {
+		hs[i] = w.health
+	}
+	return hs
+}
+
+// Healthy 所有 worker 都在运行
+func (s *Supervisor) Healthy() bool {
+	for _, h := range s.Health() {
+		if h.State != Running {
+			return false
+		}
+	}
+	return true
+}
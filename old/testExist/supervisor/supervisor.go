@@ -0,0 +1,345 @@
+// Package supervisor 管理一组长期运行的 worker: 按重启策略拉起退出的 worker, 失败重启带指数退避,
+// 捕获 panic, 通过 context 统一停止并给每个 worker 限定退出时间, 同时提供每个 worker 的健康状态
+//
+// 用法:
+//
+//	var s supervisor.Supervisor
+//	s.Add(supervisor.Spec{Name: "consumer", Run: consume, Restart: supervisor.OnFailure})
+//	err := s.RunUntilSignal(context.Background()) // SIGINT/SIGTERM 时停止
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"sync"
+	"syscall"
+	"time"
+
+	"testGo/time/timeutil"
+)
+
+var (
+	// ErrStopTimeout 停止时有 worker 没有在 StopTimeout 内退出
+	ErrStopTimeout = errors.New("supervisor: worker did not stop in time")
+	// ErrStarted Run 之后不能再 Add, 也不能重复 Run
+	ErrStarted = errors.New("supervisor: already started")
+)
+
+// Worker 一直运行到 ctx 取消为止, ctx 取消后应尽快返回
+type Worker func(ctx context.Context) error
+
+// Policy 重启策略
+type Policy int
+
+const (
+	Never     Policy = iota // 退出后不再启动
+	Always                  // 不管是否出错都重启
+	OnFailure               // 返回 error 或 panic 时重启
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Never:
+		return "never"
+	case Always:
+		return "always"
+	case OnFailure:
+		return "on-failure"
+	}
+	return fmt.Sprintf("Policy(%d)", int(p))
+}
+
+// Backoff 重启间隔: 从 Initial 开始每次乘以 Multiplier, 不超过 Max;
+// worker 一次运行超过 ResetAfter 说明已经恢复, 间隔重新从 Initial 开始
+type Backoff struct {
+	Initial    time.Duration // 默认 1s
+	Max        time.Duration // 默认 1min
+	Multiplier float64       // 默认 2
+	ResetAfter time.Duration // 默认等于 Max
+}
+
+func (b Backoff) withDefaults() Backoff {
+	if b.Initial <= 0 {
+		b.Initial = time.Second
+	}
+	if b.Max <= 0 {
+		b.Max = time.Minute
+	}
+	b.Max = max(b.Max, b.Initial)
+	if b.Multiplier < 1 {
+		b.Multiplier = 2
+	}
+	if b.ResetAfter <= 0 {
+		b.ResetAfter = b.Max
+	}
+	return b
+}
+
+// next 上次间隔为 prev, 本次运行了 ran 之后的重启间隔
+func (b Backoff) next(prev, ran time.Duration) time.Duration {
+	if prev == 0 || ran >= b.ResetAfter {
+		return b.Initial
+	}
+	return min(time.Duration(float64(prev)*b.Multiplier), b.Max)
+}
+
+// Spec 一个 worker 的配置
+type Spec struct {
+	Name        string
+	Run         Worker
+	Restart     Policy
+	Backoff     Backoff
+	MaxRestarts int           // 最多重启次数, 0 为不限; 超过后状态为 Failed
+	StopTimeout time.Duration // 停止时等待 worker 退出的时间, 默认 10s
+}
+
+// DefaultStopTimeout Spec.StopTimeout 的默认值
+const DefaultStopTimeout = 10 * time.Second
+
+// PanicError worker panic 时的错误, 带上 panic 的值和调用栈
+type PanicError struct {
+	Worker string
+	Value  any
+	Stack  []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("supervisor: worker %s panic: %v", e.Worker, e.Value)
+}
+
+// Supervisor 零值可用; Clock 为 nil 时使用系统时钟, Logf 为 nil 时不输出日志
+type Supervisor struct {
+	Clock timeutil.Clock
+	Logf  func(format string, args ...any)
+
+	mu      sync.Mutex
+	workers []*worker
+	names   map[string]bool
+	started bool
+}
+
+type worker struct {
+	spec   Spec
+	cancel context.CancelFunc
+	done   chan struct{}
+	health Health
+}
+
+// Add 注册 worker, 必须在 Run 之前调用
+func (s *Supervisor) Add(spec Spec) error {
+	if spec.Name == "" || spec.Run == nil {
+		return errors.New("supervisor: worker needs a name and a Run func")
+	}
+	if spec.StopTimeout <= 0 {
+		spec.StopTimeout = DefaultStopTimeout
+	}
+	spec.Backoff = spec.Backoff.withDefaults()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrStarted
+	}
+	if s.names[spec.Name] {
+		return fmt.Errorf("supervisor: duplicate worker %q", spec.Name)
+	}
+	if s.names == nil {
+		s.names = map[string]bool{}
+	}
+	s.names[spec.Name] = true
+	s.workers = append(s.workers, &worker{spec: spec, health: Health{Name: spec.Name, State: Idle}})
+	return nil
+}
+
+// Run 启动所有 worker, 直到 ctx 取消或者所有 worker 都不再重启时返回
+//
+// ctx 取消后同时通知所有 worker 退出, 每个 worker 最多等 StopTimeout; 有 worker 超时返回 ErrStopTimeout.
+// worker 自己全部结束时, 返回 Failed 状态的 worker 最后一次的错误
+func (s *Supervisor) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return ErrStarted
+	}
+	s.started = true
+	workers := s.workers
+	s.mu.Unlock()
+
+	allDone := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wctx, cancel := context.WithCancel(ctx)
+		w.cancel, w.done = cancel, make(chan struct{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(w.done)
+			s.supervise(wctx, w)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(allDone)
+	}()
+
+	select {
+	case <-allDone:
+		var errs []error
+		for _, h := range s.Health() {
+			if h.State == Failed {
+				errs = append(errs, fmt.Errorf("%s: %w", h.Name, h.LastError))
+			}
+		}
+		return errors.Join(errs...)
+	case <-ctx.Done():
+		return s.stop(workers)
+	}
+}
+
+// RunUntilSignal 同 Run, 收到 sigs 之一时停止, 不传 sigs 时为 SIGINT 和 SIGTERM
+func (s *Supervisor) RunUntilSignal(ctx context.Context, sigs ...os.Signal) error {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	// NotifyContext 内部用带缓冲的 channel, 不会漏掉信号
+	ctx, stop := signal.NotifyContext(ctx, sigs...)
+	defer stop()
+	return s.Run(ctx)
+}
+
+// stop 通知所有 worker 退出, 并发地等每个 worker 自己的 StopTimeout
+func (s *Supervisor) stop(workers []*worker) error {
+	s.logf("supervisor: stopping %d workers", len(workers))
+	var (
+		mu       sync.Mutex
+		timedOut []string
+		wg       sync.WaitGroup
+	)
+	for _, w := range workers {
+		s.update(w, func(h *Health) {
+			if h.State == Running || h.State == Restarting {
+				h.State, h.Since = Stopping, s.clock().Now()
+			}
+		})
+		w.cancel()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case <-w.done:
+			case <-s.clock().After(w.spec.StopTimeout):
+				s.logf("supervisor: worker %s did not stop within %v", w.spec.Name, w.spec.StopTimeout)
+				mu.Lock()
+				timedOut = append(timedOut, w.spec.Name)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(timedOut) > 0 {
+		return fmt.Errorf("%w: %v", ErrStopTimeout, timedOut)
+	}
+	return nil
+}
+
+// supervise 运行一个 worker 并按策略重启, ctx 取消后返回
+func (s *Supervisor) supervise(ctx context.Context, w *worker) {
+	spec := w.spec
+	var delay time.Duration
+	for {
+		start := s.clock().Now()
+		s.update(w, func(h *Health) {
+			h.State, h.Since, h.StartedAt = Running, start, start
+		})
+		err := call(ctx, spec)
+		ran := s.clock().Since(start)
+
+		if ctx.Err() != nil {
+			// 停止过程中返回的 context.Canceled 不算错误
+			if errors.Is(err, context.Canceled) {
+				err = nil
+			}
+			s.exit(w, Stopped, err)
+			return
+		}
+		if err != nil {
+			s.logf("supervisor: worker %s exited after %v: %v", spec.Name, ran, err)
+		}
+		if spec.Restart == Never || (spec.Restart == OnFailure && err == nil) {
+			state := Stopped
+			if err != nil {
+				state = Failed
+			}
+			s.exit(w, state, err)
+			return
+		}
+		if spec.MaxRestarts > 0 && s.restarts(w) >= spec.MaxRestarts {
+			s.logf("supervisor: worker %s reached %d restarts, giving up", spec.Name, spec.MaxRestarts)
+			if err == nil {
+				err = fmt.Errorf("supervisor: worker %s exited %d times", spec.Name, spec.MaxRestarts+1)
+			}
+			s.exit(w, Failed, err)
+			return
+		}
+
+		delay = spec.Backoff.next(delay, ran)
+		s.update(w, func(h *Health) {
+			h.State, h.Since, h.LastError, h.NextRestart = Restarting, s.clock().Now(), err, delay
+		})
+		select {
+		case <-ctx.Done():
+			s.exit(w, Stopped, err)
+			return
+		case <-s.clock().After(delay):
+		}
+		s.update(w, func(h *Health) { h.Restarts++ })
+	}
+}
+
+// call 运行一次 worker, panic 转成 *PanicError
+func call(ctx context.Context, spec Spec) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Worker: spec.Name, Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return spec.Run(ctx)
+}
+
+func (s *Supervisor) exit(w *worker, state State, err error) {
+	s.update(w, func(h *Health) {
+		h.State, h.Since, h.NextRestart = state, s.clock().Now(), 0
+		if err != nil {
+			h.LastError = err
+		}
+	})
+}
+
+func (s *Supervisor) restarts(w *worker) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return w.health.Restarts
+}
+
+func (s *Supervisor) update(w *worker, f func(*Health)) {
+	s.mu.Lock()
+	f(&w.health)
+	s.mu.Unlock()
+}
+
+func (s *Supervisor) clock() timeutil.Clock {
+	if s.Clock == nil {
+		return timeutil.System
+	}
+	return s.Clock
+}
+
+func (s *Supervisor) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
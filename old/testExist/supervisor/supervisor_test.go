@@ -0,0 +1,226 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"testGo/time/timeutil"
+)
+
+var errBoom = errors.New("boom")
+
+// eventually 等待其他 goroutine 推进到 cond 成立, 只用于同步, 计时都用假时钟
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func health(s *Supervisor, name string) Health {
+	for _, h := range s.Health() {
+		if h.Name == name {
+			return h
+		}
+	}
+	return Health{}
+}
+
+func start(s *Supervisor) (cancel context.CancelFunc, result chan error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	result = make(chan error, 1)
+	go func() { result <- s.Run(ctx) }()
+	return cancel, result
+}
+
+func TestOnFailureBackoff(t *testing.T) {
+	clock := timeutil.NewFake(time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC))
+	s := &Supervisor{Clock: clock}
+	runs := make(chan int, 10)
+	n := 0
+	s.Add(Spec{
+		Name:    "flaky",
+		Restart: OnFailure,
+		Backoff: Backoff{Initial: time.Second, Max: 3 * time.Second, Multiplier: 2, ResetAfter: time.Hour},
+		Run: func(ctx context.Context) error {
+			n++
+			runs <- n
+			if n <= 3 {
+				return errBoom
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+	cancel, result := start(s)
+
+	// 间隔 1s, 2s, 然后被 Max 限制在 3s
+	for i, delay := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		<-runs
+		eventually(t, "backoff", func() bool { return clock.Waiters() == 1 })
+		h := health(s, "flaky")
+		if h.State != Restarting || h.NextRestart != delay || !errors.Is(h.LastError, errBoom) || h.Restarts != i {
+			t.Fatalf("restart %d: %+v", i, h)
+		}
+		clock.Advance(delay - time.Nanosecond)
+		if h := health(s, "flaky"); h.State != Restarting {
+			t.Fatalf("restarted early: %+v", h)
+		}
+		clock.Advance(time.Nanosecond)
+	}
+	<-runs
+	eventually(t, "running", func() bool { return s.Healthy() })
+	if h := health(s, "flaky"); h.Restarts != 3 {
+		t.Fatalf("restarts %d", h.Restarts)
+	}
+
+	cancel()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+	if h := health(s, "flaky"); h.State != Stopped {
+		t.Fatalf("after stop: %+v", h)
+	}
+}
+
+func TestBackoffReset(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: 10 * time.Second, ResetAfter: time.Minute}.withDefaults()
+	d := b.next(0, 0)
+	d = b.next(d, time.Second)
+	d = b.next(d, time.Second)
+	if d != 4*time.Second {
+		t.Fatalf("got %v", d)
+	}
+	if d = b.next(d, time.Minute); d != time.Second {
+		t.Fatalf("reset got %v", d)
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	clock := timeutil.NewFake(time.Time{})
+	s := &Supervisor{Clock: clock}
+	counts := map[string]chan struct{}{}
+	add := func(name string, p Policy, err error) {
+		ch := make(chan struct{}, 10)
+		counts[name] = ch
+		s.Add(Spec{Name: name, Restart: p, Backoff: Backoff{Initial: time.Second}, Run: func(context.Context) error {
+			ch <- struct{}{}
+			return err
+		}})
+	}
+	add("never", Never, errBoom)
+	add("clean", OnFailure, nil)
+	add("always", Always, nil)
+	cancel, result := start(s)
+	defer cancel()
+
+	eventually(t, "first exits", func() bool {
+		return health(s, "never").State == Failed && health(s, "clean").State == Stopped && clock.Waiters() == 1
+	})
+	clock.Advance(time.Second)
+	eventually(t, "always restarted", func() bool { return len(counts["always"]) == 2 })
+	if len(counts["never"]) != 1 || len(counts["clean"]) != 1 {
+		t.Fatal("worker restarted against its policy")
+	}
+	cancel()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	s := &Supervisor{Clock: timeutil.NewFake(time.Time{})}
+	s.Add(Spec{Name: "panicky", Run: func(context.Context) error { panic("bad state") }})
+
+	err := s.Run(context.Background())
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Value != "bad state" || pe.Worker != "panicky" || len(pe.Stack) == 0 {
+		t.Fatalf("got %v", err)
+	}
+	if h := health(s, "panicky"); h.State != Failed || !errors.As(h.LastError, &pe) {
+		t.Fatalf("health %+v", h)
+	}
+}
+
+func TestMaxRestarts(t *testing.T) {
+	clock := timeutil.NewFake(time.Time{})
+	s := &Supervisor{Clock: clock}
+	s.Add(Spec{
+		Name:        "doomed",
+		Restart:     OnFailure,
+		MaxRestarts: 2,
+		Backoff:     Backoff{Initial: time.Second},
+		Run:         func(context.Context) error { return errBoom },
+	})
+	_, result := start(s)
+	for range 2 {
+		eventually(t, "backoff", func() bool { return clock.Waiters() == 1 })
+		clock.Advance(time.Minute)
+	}
+	if err := <-result; !errors.Is(err, errBoom) {
+		t.Fatalf("got %v", err)
+	}
+	if h := health(s, "doomed"); h.State != Failed || h.Restarts != 2 {
+		t.Fatalf("health %+v", h)
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	clock := timeutil.NewFake(time.Time{})
+	s := &Supervisor{Clock: clock}
+	release := make(chan struct{})
+	defer close(release)
+	s.Add(Spec{Name: "polite", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	s.Add(Spec{Name: "stubborn", StopTimeout: 5 * time.Second, Run: func(context.Context) error {
+		<-release // 不理会 ctx
+		return nil
+	}})
+	cancel, result := start(s)
+	eventually(t, "running", s.Healthy)
+
+	cancel()
+	eventually(t, "stop timers", func() bool { return clock.Waiters() == 2 && health(s, "polite").State == Stopped })
+	if h := health(s, "stubborn"); h.State != Stopping {
+		t.Fatalf("stubborn %+v", h)
+	}
+	clock.Advance(5 * time.Second)
+	err := <-result
+	if !errors.Is(err, ErrStopTimeout) {
+		t.Fatalf("got %v", err)
+	}
+	if want := "supervisor: worker did not stop in time: [stubborn]"; err.Error() != want {
+		t.Fatalf("got %q", err)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	var s Supervisor
+	run := func(context.Context) error { return nil }
+	if err := s.Add(Spec{Name: "a", Run: run}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(Spec{Name: "a", Run: run}); err == nil {
+		t.Fatal("want duplicate error")
+	}
+	if err := s.Add(Spec{Name: "b"}); err == nil {
+		t.Fatal("want missing Run error")
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(Spec{Name: "c", Run: run}); err != ErrStarted {
+		t.Fatalf("got %v", err)
+	}
+	if err := s.Run(context.Background()); err != ErrStarted {
+		t.Fatalf("got %v", err)
+	}
+}
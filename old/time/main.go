@@ -4,7 +4,8 @@ package main
 import (
 	"fmt"
 	"time"
-	_ "time/tzdata"
+
+	"testGo/time/timeutil"
 )
 
 func main() {
@@ -18,12 +19,14 @@ func main() {
 	t22 := t2.Unix()
 	fmt.Println("直接转，将[2024-06-03 00:00:00]转为时间戳", t22)
 	fmt.Println("直接转，将[2024-06-03 00:00:00]的时间戳再转为时间", time.Unix(t22, 0).Format(time.DateTime))
-}
 
-const (
-	TimeOffset = 8 * 3600  // 8 hour offset
-	HalfOffset = 12 * 3600 // Half-day hourly offset
-)
+	// 两种写法差 8 小时, 指定时区就没有歧义
+	t3, _ := timeutil.Parse(time.DateTime, "2024-06-03 00:00:00", timeutil.Shanghai)
+	fmt.Println("指定上海时区，将[2024-06-03 00:00:00]转为时间戳", t3.Unix())
+	fmt.Println("指定上海时区，时间戳再转为 UTC 时间", timeutil.Format(t3, time.DateTime, time.UTC))
+	fmt.Println("本周开始", timeutil.StartOfWeek(time.Now(), timeutil.Shanghai, time.Monday).Format(time.DateTime))
+	fmt.Println("距离月底", timeutil.Humanize(time.Until(timeutil.EndOfMonth(time.Now(), timeutil.Shanghai)), timeutil.Chinese))
+}
 
 // Get the current timestamp by Second
 func GetCurrentTimestampBySecond() int64 {
@@ -52,30 +55,33 @@ func GetCurrentTimestampByNano() int64 {
 
 // Get the current timestamp by Mill
 func GetCurrentTimestampByMill() int64 {
-	return time.Now().UnixNano() / 1e6
+	return time.Now().UnixMilli()
 }
 
-// Get the timestamp at 0 o'clock of the day
+// Get the timestamp at 0 o'clock of the day, Beijing time
 func GetCurDayZeroTimestamp() int64 {
-	timeStr := time.Now().Format("2006-01-02")
-	t, _ := time.Parse("2006-01-02", timeStr)
-	return t.Unix() - TimeOffset
+	return timeutil.StartOfDay(time.Now(), timeutil.Shanghai).Unix()
 }
 
-// Get the timestamp at 12 o'clock on the day
+// Get the timestamp at 12 o'clock on the day, Beijing time
 func GetCurDayHalfTimestamp() int64 {
-	return GetCurDayZeroTimestamp() + HalfOffset
-
+	return curDayHalf().Unix()
 }
 
 // Get the formatted time at 0 o'clock of the day, the format is "2006-01-02_00-00-00"
 func GetCurDayZeroTimeFormat() string {
-	return time.Unix(GetCurDayZeroTimestamp(), 0).Format("2006-01-02_15-04-05")
+	return timeutil.StartOfDay(time.Now(), timeutil.Shanghai).Format("2006-01-02_15-04-05")
 }
 
 // Get the formatted time at 12 o'clock of the day, the format is "2006-01-02_12-00-00"
 func GetCurDayHalfTimeFormat() string {
-	return time.Unix(GetCurDayZeroTimestamp()+HalfOffset, 0).Format("2006-01-02_15-04-05")
+	return curDayHalf().Format("2006-01-02_15-04-05")
+}
+
+// 北京时间当天 12 点, 和原来的 0 点加 8 小时偏移一样按北京时间算, 不随机器时区变化
+func curDayHalf() time.Time {
+	t := timeutil.StartOfDay(time.Now(), timeutil.Shanghai)
+	return time.Date(t.Year(), t.Month(), t.Day(), 12, 0, 0, 0, timeutil.Shanghai)
 }
 
 // TimeStringFormatTimeUnix convert string to unix timestamp
@@ -0,0 +1,173 @@
+package timeutil
+
+import (
+	"fmt"
+	"time"
+)
+
+// Date 不带时间和时区的日期
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// DateOf t 在 loc 中的日期
+func DateOf(t time.Time, loc *time.Location) Date {
+	y, m, d := t.In(loc).Date()
+	return Date{y, m, d}
+}
+
+// ParseDate 解析 2006-01-02
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return Date{}, fmt.Errorf("timeutil: parse date %q: %w", s, err)
+	}
+	return DateOf(t, time.UTC), nil
+}
+
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// In 这一天在 loc 中的 0 点
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// AddDays 加减天数, 自动跨月跨年
+func (d Date) AddDays(n int) Date {
+	return DateOf(time.Date(d.Year, d.Month, d.Day+n, 0, 0, 0, 0, time.UTC), time.UTC)
+}
+
+// Weekday 星期几
+func (d Date) Weekday() time.Weekday {
+	return d.In(time.UTC).Weekday()
+}
+
+// Calendar 工作日历: 周末、节假日和调休上班日, 按 Location 中的日期判断
+type Calendar struct {
+	Location *time.Location
+	weekend  map[time.Weekday]bool
+	holidays map[Date]string
+	workdays map[Date]string
+}
+
+// NewCalendar 周六周日为周末的日历
+func NewCalendar(loc *time.Location) *Calendar {
+	return &Calendar{
+		Location: loc,
+		weekend:  map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+		holidays: map[Date]string{},
+		workdays: map[Date]string{},
+	}
+}
+
+// SetWeekend 设置周末
+func (c *Calendar) SetWeekend(days ...time.Weekday) {
+	c.weekend = map[time.Weekday]bool{}
+	for _, d := range days {
+		c.weekend[d] = true
+	}
+}
+
+// AddHoliday 放假日, 包括落在工作日的法定节假日
+func (c *Calendar) AddHoliday(name string, dates ...Date) {
+	for _, d := range dates {
+		c.holidays[d] = name
+		delete(c.workdays, d)
+	}
+}
+
+// AddHolidayRange 连续放假 from ~ to, 包含两端
+func (c *Calendar) AddHolidayRange(name string, from, to Date) {
+	for d := from; !to.In(time.UTC).Before(d.In(time.UTC)); d = d.AddDays(1) {
+		c.AddHoliday(name, d)
+	}
+}
+
+// AddWorkday 调休上班日, 落在周末也要上班
+func (c *Calendar) AddWorkday(name string, dates ...Date) {
+	for _, d := range dates {
+		c.workdays[d] = name
+		delete(c.holidays, d)
+	}
+}
+
+// Holiday 节假日名字, 不是节假日时 ok 为 false
+func (c *Calendar) Holiday(t time.Time) (name string, ok bool) {
+	name, ok = c.holidays[DateOf(t, c.Location)]
+	return name, ok
+}
+
+// IsBusinessDay t 所在的日期是否上班
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	return c.isBusinessDate(DateOf(t, c.Location))
+}
+
+func (c *Calendar) isBusinessDate(d Date) bool {
+	if _, ok := c.workdays[d]; ok {
+		return true
+	}
+	if _, ok := c.holidays[d]; ok {
+		return false
+	}
+	return !c.weekend[d.Weekday()]
+}
+
+// maxScan 两个工作日之间最多隔的天数, 防止日历把每天都设成假日时死循环
+const maxScan = 3660
+
+// AddBusinessDays 加 n 个工作日, 保留时刻; n 为负数时往前; n 为 0 时原样返回
+func (c *Calendar) AddBusinessDays(t time.Time, n int) (time.Time, error) {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	d := DateOf(t, c.Location)
+	for gap := 0; n > 0; gap++ {
+		if gap > maxScan {
+			return time.Time{}, fmt.Errorf("timeutil: no business day within %d days of %s", maxScan, d)
+		}
+		d = d.AddDays(step)
+		if c.isBusinessDate(d) {
+			n, gap = n-1, -1
+		}
+	}
+	return c.withDate(t, d), nil
+}
+
+// NextBusinessDay t 当天或之后第一个工作日, 保留时刻
+func (c *Calendar) NextBusinessDay(t time.Time) (time.Time, error) {
+	d := DateOf(t, c.Location)
+	for i := 0; i <= maxScan; i++ {
+		if c.isBusinessDate(d) {
+			return c.withDate(t, d), nil
+		}
+		d = d.AddDays(1)
+	}
+	return time.Time{}, fmt.Errorf("timeutil: no business day within %d days of %s", maxScan, DateOf(t, c.Location))
+}
+
+// BusinessDaysBetween [from, to) 之间的工作日天数, to 早于 from 时为负数
+func (c *Calendar) BusinessDaysBetween(from, to time.Time) int {
+	a, b := DateOf(from, c.Location), DateOf(to, c.Location)
+	sign := 1
+	if b.In(time.UTC).Before(a.In(time.UTC)) {
+		a, b, sign = b, a, -1
+	}
+	n := 0
+	for d := a; d != b; d = d.AddDays(1) {
+		if c.isBusinessDate(d) {
+			n++
+		}
+	}
+	return sign * n
+}
+
+// withDate 把 t 的日期换成 d, 时刻不变
+func (c *Calendar) withDate(t time.Time, d Date) time.Time {
+	t = t.In(c.Location)
+	return time.Date(d.Year, d.Month, d.Day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), c.Location)
+}
@@ -0,0 +1,101 @@
+package timeutil
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Lang 描述时长使用的语言
+type Lang int
+
+const (
+	Chinese Lang = iota
+	English
+)
+
+type unit struct {
+	d       time.Duration
+	zh      string
+	en, ens string // 单数, 复数
+}
+
+var units = []unit{
+	{24 * time.Hour, "天", "day", "days"},
+	{time.Hour, "小时", "hour", "hours"},
+	{time.Minute, "分钟", "minute", "minutes"},
+	{time.Second, "秒", "second", "seconds"},
+	{time.Millisecond, "毫秒", "millisecond", "milliseconds"},
+}
+
+// Humanize 时长描述, 最多保留 2 个单位, 如 "1天3小时" / "1 day 3 hours"; 不足 1 毫秒为 "0秒" / "0 seconds"
+func Humanize(d time.Duration, lang Lang) string {
+	return HumanizeN(d, lang, 2)
+}
+
+// HumanizeN 最多保留 maxUnits 个非 0 单位, 较小的单位直接舍去; 负数时长加负号
+func HumanizeN(d time.Duration, lang Lang, maxUnits int) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	var parts []string
+	for _, u := range units {
+		if len(parts) >= max(maxUnits, 1) {
+			break
+		}
+		// 毫秒只在不足 1 秒时使用
+		if u.d == time.Millisecond && len(parts) > 0 {
+			break
+		}
+		n := int64(d / u.d)
+		if n == 0 {
+			continue
+		}
+		d -= time.Duration(n) * u.d
+		parts = append(parts, formatUnit(n, u, lang))
+	}
+	if len(parts) == 0 {
+		return formatUnit(0, units[3], lang)
+	}
+	if lang == English {
+		return sign + strings.Join(parts, " ")
+	}
+	return sign + strings.Join(parts, "")
+}
+
+func formatUnit(n int64, u unit, lang Lang) string {
+	if lang == English {
+		if n == 1 {
+			return "1 " + u.en
+		}
+		return fmt.Sprintf("%d %s", n, u.ens)
+	}
+	return fmt.Sprintf("%d%s", n, u.zh)
+}
+
+// Relative 相对 now 的描述, 如 "3分钟前" / "3 minutes ago", "2小时后" / "in 2 hours"; 1 秒以内为 "刚刚" / "just now"
+func Relative(t, now time.Time, lang Lang) string {
+	d := now.Sub(t)
+	past := d >= 0
+	if !past {
+		d = -d
+	}
+	if d < time.Second {
+		if lang == English {
+			return "just now"
+		}
+		return "刚刚"
+	}
+	s := HumanizeN(d, lang, 1)
+	switch {
+	case lang == English && past:
+		return s + " ago"
+	case lang == English:
+		return "in " + s
+	case past:
+		return s + "前"
+	default:
+		return s + "后"
+	}
+}
@@ -0,0 +1,42 @@
+package timeutil
+
+import "time"
+
+// 起止时间都按 loc 中的日历计算, 结束时间为下一个周期开始前 1 纳秒; 夏令时切换的日子也正确
+
+// StartOfDay t 在 loc 中当天的 0 点
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// EndOfDay t 在 loc 中当天的最后 1 纳秒
+func EndOfDay(t time.Time, loc *time.Location) time.Time {
+	start := StartOfDay(t, loc)
+	return time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, loc).Add(-time.Nanosecond)
+}
+
+// StartOfWeek t 所在周的第一天 0 点, weekStart 为一周的第一天, 国内一般是周一
+func StartOfWeek(t time.Time, loc *time.Location, weekStart time.Weekday) time.Time {
+	day := StartOfDay(t, loc)
+	offset := (int(day.Weekday()) - int(weekStart) + 7) % 7
+	return time.Date(day.Year(), day.Month(), day.Day()-offset, 0, 0, 0, 0, loc)
+}
+
+// EndOfWeek t 所在周的最后 1 纳秒
+func EndOfWeek(t time.Time, loc *time.Location, weekStart time.Weekday) time.Time {
+	start := StartOfWeek(t, loc, weekStart)
+	return time.Date(start.Year(), start.Month(), start.Day()+7, 0, 0, 0, 0, loc).Add(-time.Nanosecond)
+}
+
+// StartOfMonth t 所在月 1 日 0 点
+func StartOfMonth(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+}
+
+// EndOfMonth t 所在月的最后 1 纳秒
+func EndOfMonth(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc).Add(-time.Nanosecond)
+}
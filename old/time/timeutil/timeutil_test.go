@@ -0,0 +1,239 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+)
+
+var newYork = MustLoadLocation("America/New_York")
+
+func mustParse(t *testing.T, value string, loc *time.Location) time.Time {
+	t.Helper()
+	tm, err := Parse(time.DateTime, value, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tm
+}
+
+// main.go 里 time.Parse 和按本地时区解析差 8 小时的问题
+func TestParse(t *testing.T) {
+	tm := mustParse(t, "2024-06-03 00:00:00", Shanghai)
+	if tm.Unix() != 1717344000 {
+		t.Fatalf("unix %d", tm.Unix())
+	}
+	if got := Format(tm, time.DateTime, time.UTC); got != "2024-06-02 16:00:00" {
+		t.Fatalf("utc %s", got)
+	}
+	if got := Format(FromUnix(1717344000, Shanghai), time.DateTime, Shanghai); got != "2024-06-03 00:00:00" {
+		t.Fatalf("round trip %s", got)
+	}
+	if got := FromUnixMilli(1717344000123, time.UTC); got.Nanosecond() != 123e6 {
+		t.Fatalf("milli %v", got)
+	}
+
+	// 自带偏移的时间以偏移为准
+	tm, err := Parse(time.RFC3339, "2024-06-03T00:00:00Z", Shanghai)
+	if err != nil || tm.Unix() != 1717372800 {
+		t.Fatalf("rfc3339 %v %v", tm, err)
+	}
+	if _, err := Parse(time.DateTime, "2024-06-03", nil); err != ErrNilLocation {
+		t.Fatalf("got %v", err)
+	}
+	if _, err := ParseIn(time.DateTime, "2024-06-03 00:00:00", "Mars/Olympus"); err == nil {
+		t.Fatal("want unknown zone error")
+	}
+	if tm, err := ParseIn(time.DateTime, "2024-06-03 00:00:00", "Asia/Shanghai"); err != nil || tm.Unix() != 1717344000 {
+		t.Fatalf("ParseIn %v %v", tm, err)
+	}
+}
+
+func TestPeriods(t *testing.T) {
+	// 上海 6 月 3 日 01:00 在 UTC 还是 6 月 2 日, 按上海的日历计算
+	tm := mustParse(t, "2024-06-03 01:00:00", Shanghai).In(time.UTC)
+	tests := []struct {
+		name string
+		got  time.Time
+		want string
+	}{
+		{"StartOfDay", StartOfDay(tm, Shanghai), "2024-06-03 00:00:00"},
+		{"EndOfDay", EndOfDay(tm, Shanghai), "2024-06-03 23:59:59.999999999"},
+		{"StartOfWeek", StartOfWeek(tm, Shanghai, time.Monday), "2024-06-03 00:00:00"},
+		{"StartOfWeekSunday", StartOfWeek(tm, Shanghai, time.Sunday), "2024-06-02 00:00:00"},
+		{"EndOfWeek", EndOfWeek(tm, Shanghai, time.Monday), "2024-06-09 23:59:59.999999999"},
+		{"StartOfMonth", StartOfMonth(tm, Shanghai), "2024-06-01 00:00:00"},
+		{"EndOfMonth", EndOfMonth(tm, Shanghai), "2024-06-30 23:59:59.999999999"},
+		{"UTCDay", StartOfDay(tm, time.UTC), "2024-06-02 00:00:00"},
+		{"LeapFebruary", EndOfMonth(mustParse(t, "2024-02-10 00:00:00", Shanghai), Shanghai), "2024-02-29 23:59:59.999999999"},
+		{"December", EndOfMonth(mustParse(t, "2024-12-31 12:00:00", Shanghai), Shanghai), "2024-12-31 23:59:59.999999999"},
+	}
+	for _, tt := range tests {
+		if got := tt.got.Format("2006-01-02 15:04:05.999999999"); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// 夏令时开始的那天只有 23 小时
+	dst := mustParse(t, "2024-03-10 12:00:00", newYork)
+	if d := EndOfDay(dst, newYork).Sub(StartOfDay(dst, newYork)) + time.Nanosecond; d != 23*time.Hour {
+		t.Fatalf("dst day length %v", d)
+	}
+}
+
+func chinaCalendar(t *testing.T) *Calendar {
+	t.Helper()
+	c := NewCalendar(Shanghai)
+	// 2024 年国庆: 10 月 1 日 ~ 7 日放假, 9 月 29 日(周日)、10 月 12 日(周六)上班
+	from, _ := ParseDate("2024-10-01")
+	to, _ := ParseDate("2024-10-07")
+	c.AddHolidayRange("国庆节", from, to)
+	c.AddWorkday("国庆节调休", Date{2024, 9, 29}, Date{2024, 10, 12})
+	return c
+}
+
+func TestCalendar(t *testing.T) {
+	c := chinaCalendar(t)
+	day := func(s string) time.Time { return mustParse(t, s+" 09:30:00", Shanghai) }
+
+	for s, want := range map[string]bool{
+		"2024-09-27": true,  // 周五
+		"2024-09-28": false, // 周六
+		"2024-09-29": true,  // 周日调休
+		"2024-10-01": false,
+		"2024-10-07": false,
+		"2024-10-08": true,
+		"2024-10-12": true, // 周六调休
+		"2024-10-13": false,
+	} {
+		if got := c.IsBusinessDay(day(s)); got != want {
+			t.Errorf("IsBusinessDay(%s) = %v", s, got)
+		}
+	}
+	if name, ok := c.Holiday(day("2024-10-03")); !ok || name != "国庆节" {
+		t.Fatalf("Holiday = %q %v", name, ok)
+	}
+
+	// 9 月 30 日(周一)之后的第 1 个工作日是 10 月 8 日, 时刻不变
+	got, err := c.AddBusinessDays(day("2024-09-30"), 1)
+	if err != nil || !got.Equal(day("2024-10-08")) {
+		t.Fatalf("AddBusinessDays +1 = %v %v", got, err)
+	}
+	got, _ = c.AddBusinessDays(day("2024-10-08"), -2)
+	if !got.Equal(day("2024-09-29")) {
+		t.Fatalf("AddBusinessDays -2 = %v", got)
+	}
+	// n 很大时总天数超过 maxScan 也能算出来, 只有两个工作日之间隔太久才报错
+	got, err = NewCalendar(Shanghai).AddBusinessDays(day("2024-01-01"), 3000)
+	if err != nil || !got.Equal(day("2035-07-02")) {
+		t.Fatalf("AddBusinessDays +3000 = %v %v", got, err)
+	}
+	got, _ = c.NextBusinessDay(day("2024-10-05"))
+	if !got.Equal(day("2024-10-08")) {
+		t.Fatalf("NextBusinessDay = %v", got)
+	}
+	// 9 月 27 日 ~ 10 月 14 日: 27, 29, 30, 8, 9, 10, 11, 12 共 8 天
+	if n := c.BusinessDaysBetween(day("2024-09-27"), day("2024-10-14")); n != 8 {
+		t.Fatalf("BusinessDaysBetween = %d", n)
+	}
+	if n := c.BusinessDaysBetween(day("2024-10-14"), day("2024-09-27")); n != -8 {
+		t.Fatalf("reverse BusinessDaysBetween = %d", n)
+	}
+
+	// 按日历的时区判断日期: UTC 周五 20:00 在上海已经是周六
+	if c.IsBusinessDay(time.Date(2024, 9, 27, 20, 0, 0, 0, time.UTC)) {
+		t.Fatal("used UTC date instead of the calendar zone")
+	}
+
+	c.SetWeekend(time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday)
+	if _, err := c.NextBusinessDay(day("2025-01-01")); err == nil {
+		t.Fatal("want error for a calendar without business days")
+	}
+	if _, err := c.AddBusinessDays(day("2025-01-01"), 1); err == nil {
+		t.Fatal("want error from AddBusinessDays for a calendar without business days")
+	}
+}
+
+func TestHumanize(t *testing.T) {
+	tests := []struct {
+		d      time.Duration
+		zh, en string
+	}{
+		{0, "0秒", "0 seconds"},
+		{500 * time.Millisecond, "500毫秒", "500 milliseconds"},
+		{time.Second, "1秒", "1 second"},
+		{90 * time.Second, "1分钟30秒", "1 minute 30 seconds"},
+		{26*time.Hour + 3*time.Minute + 4*time.Second, "1天2小时", "1 day 2 hours"},
+		{24*time.Hour + 3*time.Minute, "1天3分钟", "1 day 3 minutes"},
+		{-2 * time.Hour, "-2小时", "-2 hours"},
+	}
+	for _, tt := range tests {
+		if got := Humanize(tt.d, Chinese); got != tt.zh {
+			t.Errorf("Humanize(%v, Chinese) = %q, want %q", tt.d, got, tt.zh)
+		}
+		if got := Humanize(tt.d, English); got != tt.en {
+			t.Errorf("Humanize(%v, English) = %q, want %q", tt.d, got, tt.en)
+		}
+	}
+	if got := HumanizeN(26*time.Hour+3*time.Minute+4*time.Second, Chinese, 4); got != "1天2小时3分钟4秒" {
+		t.Errorf("HumanizeN = %q", got)
+	}
+
+	now := mustParse(t, "2024-06-03 12:00:00", Shanghai)
+	for _, tt := range []struct {
+		t      time.Time
+		zh, en string
+	}{
+		{now.Add(-3*time.Minute - 10*time.Second), "3分钟前", "3 minutes ago"},
+		{now.Add(2 * time.Hour), "2小时后", "in 2 hours"},
+		{now.Add(-300 * time.Millisecond), "刚刚", "just now"},
+	} {
+		if got := Relative(tt.t, now, Chinese); got != tt.zh {
+			t.Errorf("Relative zh = %q, want %q", got, tt.zh)
+		}
+		if got := Relative(tt.t, now, English); got != tt.en {
+			t.Errorf("Relative en = %q, want %q", got, tt.en)
+		}
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	start := mustParse(t, "2024-06-03 12:00:00", Shanghai)
+	clock := NewFake(start)
+	var c Clock = clock
+
+	late := c.After(time.Minute)
+	early := c.After(time.Second)
+	if clock.Waiters() != 2 {
+		t.Fatalf("waiters %d", clock.Waiters())
+	}
+	clock.Advance(30 * time.Second)
+	select {
+	case at := <-early:
+		if !at.Equal(start.Add(30 * time.Second)) {
+			t.Fatalf("fired at %v", at)
+		}
+	default:
+		t.Fatal("early timer did not fire")
+	}
+	select {
+	case <-late:
+		t.Fatal("late timer fired too early")
+	default:
+	}
+	if c.Since(start) != 30*time.Second {
+		t.Fatalf("since %v", c.Since(start))
+	}
+
+	clock.Set(start) // 往回拨不触发
+	select {
+	case <-late:
+		t.Fatal("late timer fired after setting back")
+	default:
+	}
+	clock.Advance(time.Hour)
+	<-late
+	if clock.Waiters() != 0 {
+		t.Fatalf("waiters %d", clock.Waiters())
+	}
+	<-c.After(0)
+}
@@ -0,0 +1,62 @@
+package timeutil
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	_ "time/tzdata" // 没有系统时区数据库的容器里也能加载时区
+)
+
+// Shanghai 中国标准时间
+var Shanghai = MustLoadLocation("Asia/Shanghai")
+
+// ErrNilLocation 没有指定时区
+var ErrNilLocation = errors.New("timeutil: nil location")
+
+// MustLoadLocation 加载时区, 失败时 panic, 用于包级变量
+func MustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// Parse 按 loc 解析不带时区的时间; value 自带时区偏移时(如 RFC3339)以 value 为准
+//
+// time.Parse 把不带时区的时间当作 UTC, time.ParseInLocation(..., time.Local) 依赖机器配置,
+// 两者对 "2024-06-03 00:00:00" 会差 8 小时, 这里强制调用方给出时区
+func Parse(layout, value string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		return time.Time{}, ErrNilLocation
+	}
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timeutil: parse %q: %w", value, err)
+	}
+	return t, nil
+}
+
+// ParseIn 同 Parse, 时区用名字指定, 如 Asia/Shanghai
+func ParseIn(layout, value, zone string) (time.Time, error) {
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timeutil: %w", err)
+	}
+	return Parse(layout, value, loc)
+}
+
+// Format 转到 loc 后格式化
+func Format(t time.Time, layout string, loc *time.Location) string {
+	return t.In(loc).Format(layout)
+}
+
+// FromUnix 秒级时间戳转为 loc 中的时间
+func FromUnix(sec int64, loc *time.Location) time.Time {
+	return time.Unix(sec, 0).In(loc)
+}
+
+// FromUnixMilli 毫秒时间戳转为 loc 中的时间
+func FromUnixMilli(msec int64, loc *time.Location) time.Time {
+	return time.UnixMilli(msec).In(loc)
+}
@@ -0,0 +1,253 @@
+// Package coord 基于 go-zookeeper 的分布式协调: 可重入分布式锁、leader 选举和服务注册发现
+//
+// 锁和选举都使用临时顺序节点, 每个节点只监听排在自己前面的一个节点, 避免羊群效应;
+// 会话过期后临时节点被服务端删除, 锁通过 Lost 通知持有者, 选举和注册会在新会话中自动重来
+package coord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+	"github.com/gofrs/uuid"
+)
+
+// createSequential 连接断开后最多重试的次数和查找失败时的间隔
+const (
+	createRetries       = 3
+	createRetryInterval = 100 * time.Millisecond
+)
+
+var (
+	// ErrSessionExpired 等待过程中会话过期, 之前创建的临时节点已经不存在
+	ErrSessionExpired = errors.New("coord: zookeeper session expired")
+	// ErrNodeLost 自己创建的顺序节点不见了, 通常是会话过期或被人为删除
+	ErrNodeLost = errors.New("coord: sequential node lost")
+	// ErrNodeOwned 要注册的临时节点属于其他会话, 通常是同一地址的另一个实例还在运行
+	ErrNodeOwned = errors.New("coord: node owned by another session")
+)
+
+// Conn 用到的 *zk.Conn 方法, 测试时可以替换为内存实现
+type Conn interface {
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	Get(path string) ([]byte, *zk.Stat, error)
+	Set(path string, data []byte, version int32) (*zk.Stat, error)
+	Delete(path string, version int32) error
+	Exists(path string) (bool, *zk.Stat, error)
+	ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error)
+	Children(path string) ([]string, *zk.Stat, error)
+	ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error)
+	SessionID() int64
+	Close()
+}
+
+var _ Conn = (*zk.Conn)(nil)
+
+// Client 包装连接并跟踪会话状态
+type Client struct {
+	conn Conn
+	acl  []zk.ACL
+
+	mu      sync.Mutex
+	expired chan struct{} // 当前会话过期时关闭, 之后换成新的 channel
+	done    chan struct{}
+}
+
+// Dial 连接 zookeeper
+func Dial(servers []string, sessionTimeout time.Duration) (*Client, error) {
+	conn, events, err := zk.Connect(servers, sessionTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("connect zookeeper %v: %w", servers, err)
+	}
+	return NewClient(conn, events), nil
+}
+
+// NewClient events 为 zk.Connect 返回的会话事件, Client 负责消费
+func NewClient(conn Conn, events <-chan zk.Event) *Client {
+	c := &Client{
+		conn:    conn,
+		acl:     zk.WorldACL(zk.PermAll),
+		expired: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go c.loop(events)
+	return c
+}
+
+func (c *Client) loop(events <-chan zk.Event) {
+	for {
+		select {
+		case <-c.done:
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if ev.Type == zk.EventSession && ev.State == zk.StateExpired {
+				c.mu.Lock()
+				close(c.expired)
+				c.expired = make(chan struct{})
+				c.mu.Unlock()
+			}
+		}
+	}
+}
+
+// Conn 底层连接
+func (c *Client) Conn() Conn {
+	return c.conn
+}
+
+// session 当前会话过期时关闭的 channel, 在创建临时节点之前获取
+func (c *Client) session() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.expired
+}
+
+// Close 关闭连接, 本连接创建的临时节点随之删除
+func (c *Client) Close() {
+	c.mu.Lock()
+	select {
+	case <-c.done:
+		c.mu.Unlock()
+		return
+	default:
+		close(c.done)
+	}
+	c.mu.Unlock()
+	c.conn.Close()
+}
+
+// EnsurePath 逐级创建持久节点, 已经存在的忽略
+func (c *Client) EnsurePath(path string) error {
+	node := ""
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		node += "/" + part
+		_, err := c.conn.Create(node, nil, 0, c.acl)
+		if err != nil && !errors.Is(err, zk.ErrNodeExists) {
+			return fmt.Errorf("create %s: %w", node, err)
+		}
+	}
+	return nil
+}
+
+// createSequential 在 dir 下创建临时顺序节点, 返回节点名(不含目录)
+//
+// 节点名是 prefix + guid + "-" + 序号: 连接在服务端建好节点之后断开时客户端收不到节点名,
+// 重试前先按 guid 在 dir 下查找, 找到就直接用, 不会留下没人认领的节点把锁和选举卡住
+func (c *Client) createSequential(dir, prefix string, data []byte) (string, error) {
+	if err := c.EnsurePath(dir); err != nil {
+		return "", err
+	}
+	guid, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	protected := prefix + guid.String() + "-"
+	var lastErr error
+	for i := 0; i <= createRetries; i++ {
+		if lastErr != nil {
+			// 上一次创建结果未知, 先找一遍; 查找失败时不能再建, 否则可能多出一个孤儿节点
+			children, _, err := c.conn.Children(dir)
+			if err != nil {
+				lastErr = err
+				time.Sleep(createRetryInterval)
+				continue
+			}
+			for _, child := range children {
+				if strings.HasPrefix(child, protected) {
+					return child, nil
+				}
+			}
+		}
+		path, err := c.conn.Create(dir+"/"+protected, data, zk.FlagEphemeral|zk.FlagSequence, c.acl)
+		if err == nil {
+			return path[strings.LastIndex(path, "/")+1:], nil
+		}
+		if !errors.Is(err, zk.ErrConnectionClosed) {
+			return "", fmt.Errorf("create %s/%s: %w", dir, prefix, err)
+		}
+		lastErr = err
+	}
+	return "", fmt.Errorf("create %s/%s: %w", dir, prefix, lastErr)
+}
+
+// sortedChildren dir 下以 prefix 开头的节点, 按序号从小到大排列
+func (c *Client) sortedChildren(dir, prefix string) ([]string, error) {
+	children, _, err := c.conn.Children(dir)
+	if err != nil {
+		return nil, err
+	}
+	nodes := children[:0]
+	for _, child := range children {
+		if strings.HasPrefix(child, prefix) && sequence(child) >= 0 {
+			nodes = append(nodes, child)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return sequence(nodes[i]) < sequence(nodes[j]) })
+	return nodes, nil
+}
+
+// sequence 顺序节点末尾的 10 位序号, 格式不对时返回 -1
+func sequence(node string) int64 {
+	if len(node) < 10 {
+		return -1
+	}
+	n, err := strconv.ParseInt(node[len(node)-10:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// waitFirst 阻塞到 node 成为 dir 下序号最小的节点, 期间只监听前一个节点
+func (c *Client) waitFirst(ctx context.Context, session <-chan struct{}, dir, prefix, node string) error {
+	for {
+		nodes, err := c.sortedChildren(dir, prefix)
+		if err != nil {
+			return err
+		}
+		idx := slices.Index(nodes, node)
+		if idx < 0 {
+			return ErrNodeLost
+		}
+		if idx == 0 {
+			return nil
+		}
+
+		exists, _, watch, err := c.conn.ExistsW(dir + "/" + nodes[idx-1])
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-session:
+			return ErrSessionExpired
+		case ev := <-watch:
+			if ev.Err != nil {
+				return ev.Err
+			}
+		}
+	}
+}
+
+// deleteNode 删除节点, 节点已经不存在时不报错
+func (c *Client) deleteNode(path string) error {
+	err := c.conn.Delete(path, -1)
+	if err != nil && !errors.Is(err, zk.ErrNoNode) {
+		return fmt.Errorf("delete %s: %w", path, err)
+	}
+	return nil
+}
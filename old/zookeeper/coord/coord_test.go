@@ -0,0 +1,380 @@
+package coord
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/resolver"
+)
+
+func newTestClient(t *testing.T, s *memServer) (*Client, *memConn) {
+	t.Helper()
+	conn, events := s.connect()
+	c := NewClient(conn, events)
+	t.Cleanup(c.Close)
+	return c, conn
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLockMutualExclusion(t *testing.T) {
+	s := newMemServer()
+	var holders, maxHolders atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		c, _ := newTestClient(t, s)
+		l := NewLock(c, "/locks/order", nil)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				if err := l.Lock(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+				if n := holders.Add(1); n > maxHolders.Load() {
+					maxHolders.Store(n)
+				}
+				time.Sleep(time.Millisecond)
+				holders.Add(-1)
+				if err := l.Unlock(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if maxHolders.Load() != 1 {
+		t.Fatalf("%d holders at the same time", maxHolders.Load())
+	}
+}
+
+func TestLockReentrant(t *testing.T) {
+	s := newMemServer()
+	c, _ := newTestClient(t, s)
+	l := NewLock(c, "/locks/order", []byte("a"))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := l.Lock(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if data, ok, err := l.Holder(); err != nil || !ok || string(data) != "a" {
+		t.Fatalf("holder %q %v %v", data, ok, err)
+	}
+
+	other, _ := newTestClient(t, s)
+	ctx2, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancel()
+	if err := NewLock(other, "/locks/order", nil).Lock(ctx2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want deadline exceeded, got %v", err)
+	}
+	// 超时的排队节点已经删除
+	if children, _, _ := c.Conn().Children("/locks/order"); len(children) != 1 {
+		t.Fatalf("children %v", children)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := l.Unlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Unlock(); !errors.Is(err, ErrNotLocked) {
+		t.Fatalf("want ErrNotLocked, got %v", err)
+	}
+	if _, ok, _ := l.Holder(); ok {
+		t.Fatal("lock still held")
+	}
+}
+
+func TestLockLostCreateReply(t *testing.T) {
+	s := newMemServer()
+	c, conn := newTestClient(t, s)
+	s.mu.Lock()
+	conn.lostReplies = 1
+	s.mu.Unlock()
+	l := NewLock(c, "/locks/order", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// 应答丢失的节点按 guid 找回, 不会再多建一个排在后面等自己
+	if err := l.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if children, _, _ := c.Conn().Children("/locks/order"); len(children) != 1 {
+		t.Fatalf("children %v", children)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	other, _ := newTestClient(t, s)
+	lo := NewLock(other, "/locks/order", nil)
+	if err := lo.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := lo.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLockLostOnSessionExpiry(t *testing.T) {
+	s := newMemServer()
+	a, aConn := newTestClient(t, s)
+	b, _ := newTestClient(t, s)
+	la := NewLock(a, "/locks/job", nil)
+	lb := NewLock(b, "/locks/job", nil)
+	ctx := context.Background()
+
+	if err := la.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	acquired := make(chan error, 1)
+	go func() { acquired <- lb.Lock(ctx) }()
+
+	lost := la.Lost()
+	aConn.expire()
+	select {
+	case <-lost:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Lost not closed after session expiry")
+	}
+	if err := <-acquired; err != nil {
+		t.Fatal(err)
+	}
+	if err := la.Unlock(); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("want ErrLockLost, got %v", err)
+	}
+	if err := lb.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestElectionFailover(t *testing.T) {
+	s := newMemServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var leading []string
+	var conns = map[string]*memConn{}
+	var elections []*Election
+	var wg sync.WaitGroup
+	for _, id := range []string{"a", "b"} {
+		c, conn := newTestClient(t, s)
+		conns[id] = conn
+		e := NewElection(c, "/election/order", id)
+		e.RetryInterval = 10 * time.Millisecond
+		elections = append(elections, e)
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			e.Run(ctx, func(ctx context.Context) {
+				mu.Lock()
+				leading = append(leading, id)
+				mu.Unlock()
+				<-ctx.Done()
+			})
+		}(id)
+		// 保证 a 先参选
+		waitFor(t, id+" campaign", func() bool {
+			children, _, _ := c.Conn().Children("/election/order")
+			return len(children) == len(elections)
+		})
+	}
+	history := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(leading)
+	}
+
+	waitFor(t, "a leads", func() bool { return slices.Equal(history(), []string{"a"}) })
+	if leader, err := elections[1].Leader(); err != nil || leader != "a" {
+		t.Fatalf("leader %q %v", leader, err)
+	}
+
+	conns["a"].expire()
+	waitFor(t, "b takes over", func() bool { return slices.Equal(history(), []string{"a", "b"}) })
+	// a 重新参选后排在 b 后面
+	waitFor(t, "a campaigns again", func() bool {
+		children, _, _ := conns["b"].Children("/election/order")
+		return len(children) == 2
+	})
+	if leader, err := elections[0].Leader(); err != nil || leader != "b" {
+		t.Fatalf("leader %q %v", leader, err)
+	}
+
+	cancel()
+	wg.Wait()
+	if _, err := elections[0].Leader(); !errors.Is(err, ErrNoLeader) {
+		t.Fatalf("want ErrNoLeader, got %v", err)
+	}
+}
+
+func TestElectionResign(t *testing.T) {
+	s := newMemServer()
+	c, _ := newTestClient(t, s)
+	e := NewElection(c, "/election/once", "a")
+	if err := e.Run(context.Background(), func(context.Context) {}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Leader(); !errors.Is(err, ErrNoLeader) {
+		t.Fatalf("want ErrNoLeader after resign, got %v", err)
+	}
+}
+
+// nextUpdate 从 Watch 读取下一次更新
+func nextUpdate(t *testing.T, updates <-chan []string) []string {
+	t.Helper()
+	select {
+	case addrs := <-updates:
+		return addrs
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for registry update")
+		return nil
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	s := newMemServer()
+	watcher, _ := newTestClient(t, s)
+	registry := NewRegistry(watcher, "/services")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 服务目录还不存在
+	updates := registry.Watch(ctx, "order")
+	if got := nextUpdate(t, updates); len(got) != 0 {
+		t.Fatalf("got %v", got)
+	}
+
+	c1, conn1 := newTestClient(t, s)
+	r1 := NewRegistry(c1, "/services")
+	r1.RetryInterval = 10 * time.Millisecond
+	deregister1, err := r1.Register("order", "127.0.0.1:50052")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nextUpdate(t, updates); !slices.Equal(got, []string{"127.0.0.1:50052"}) {
+		t.Fatalf("got %v", got)
+	}
+
+	c2, _ := newTestClient(t, s)
+	deregister2, err := NewRegistry(c2, "/services").Register("order", "127.0.0.1:50053")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nextUpdate(t, updates); !slices.Equal(got, []string{"127.0.0.1:50052", "127.0.0.1:50053"}) {
+		t.Fatalf("got %v", got)
+	}
+
+	// 会话过期后实例重新注册
+	conn1.expire()
+	waitFor(t, "re-register", func() bool {
+		_, st, err := watcher.Conn().Get("/services/order/127.0.0.1:50052")
+		return err == nil && st.EphemeralOwner == conn1.session
+	})
+
+	if err := deregister2(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "deregister", func() bool {
+		addrs, _ := registry.Lookup("order")
+		return slices.Equal(addrs, []string{"127.0.0.1:50052"})
+	})
+	if err := deregister1(); err != nil {
+		t.Fatal(err)
+	}
+	if addrs, err := registry.Lookup("order"); err != nil || len(addrs) != 0 {
+		t.Fatalf("got %v %v", addrs, err)
+	}
+}
+
+// 崩溃重启前的旧会话还没过期时不能删掉它的节点, 也不能注册失败, 等旧会话过期后自动注册
+func TestRegistryStaleNode(t *testing.T) {
+	s := newMemServer()
+	const path = "/services/order/127.0.0.1:50052"
+	old, oldConn := newTestClient(t, s)
+	if _, err := NewRegistry(old, "/services").Register("order", "127.0.0.1:50052"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 旧进程崩溃, 会话还在; 新进程用同一地址注册
+	c, conn := newTestClient(t, s)
+	r := NewRegistry(c, "/services")
+	r.RetryInterval = 10 * time.Millisecond
+	deregister, err := r.Register("order", "127.0.0.1:50052")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, st, err := c.Conn().Get(path); err != nil || st.EphemeralOwner != oldConn.session {
+		t.Fatalf("node of the old session was replaced: %+v, %v", st, err)
+	}
+
+	// 旧会话过期后由后台重试注册上
+	oldConn.Close()
+	waitFor(t, "register after the old session expired", func() bool {
+		_, st, err := c.Conn().Get(path)
+		return err == nil && st.EphemeralOwner == conn.session
+	})
+	if err := deregister(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, _ := c.Conn().Exists(path); ok {
+		t.Fatal("node still exists after deregister")
+	}
+}
+
+type fakeClientConn struct {
+	resolver.ClientConn
+	states chan resolver.State
+}
+
+func (f *fakeClientConn) UpdateState(s resolver.State) error {
+	f.states <- s
+	return nil
+}
+
+func TestResolver(t *testing.T) {
+	s := newMemServer()
+	c, _ := newTestClient(t, s)
+	registry := NewRegistry(c, "/services")
+	if _, err := registry.Register("order", "127.0.0.1:50052"); err != nil {
+		t.Fatal(err)
+	}
+
+	cc := &fakeClientConn{states: make(chan resolver.State, 4)}
+	target := resolver.Target{}
+	target.URL.Scheme, target.URL.Path = Scheme, "/order"
+	r, err := NewResolverBuilder(registry).Build(target, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	select {
+	case state := <-cc.states:
+		if len(state.Addresses) != 1 || state.Addresses[0].Addr != "127.0.0.1:50052" {
+			t.Fatalf("got %+v", state)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no state")
+	}
+}
@@ -0,0 +1,104 @@
+package coord
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+const electionPrefix = "n_"
+
+// ErrNoLeader 选举目录下没有候选者
+var ErrNoLeader = errors.New("coord: no leader")
+
+// Election leader 选举, 序号最小的候选节点为 leader
+type Election struct {
+	c   *Client
+	dir string
+	id  string
+
+	// RetryInterval 创建节点失败或会话过期后重新参选的间隔, 默认 1 秒
+	RetryInterval time.Duration
+}
+
+// NewElection dir 为选举目录, id 为本候选者的标识(一般是地址), 写入候选节点供 Leader 查询
+func NewElection(c *Client, dir, id string) *Election {
+	return &Election{c: c, dir: dir, id: id, RetryInterval: time.Second}
+}
+
+// Run 参选, 当选后调用 lead; 失去 leader 身份时(会话过期或节点被删除)取消 lead 的 ctx,
+// 等 lead 返回后重新参选
+//
+// lead 自己返回时主动让出 leader, Run 返回 nil; ctx 结束时 Run 返回 ctx.Err()
+func (e *Election) Run(ctx context.Context, lead func(ctx context.Context)) error {
+	for {
+		resigned, err := e.campaign(ctx, lead)
+		if resigned || ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("[coord] election %s: %v, campaign again", e.dir, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.RetryInterval):
+		}
+	}
+}
+
+// campaign 参选一次, lead 自己返回时 resigned 为 true
+func (e *Election) campaign(ctx context.Context, lead func(ctx context.Context)) (resigned bool, err error) {
+	session := e.c.session()
+	node, err := e.c.createSequential(e.dir, electionPrefix, []byte(e.id))
+	if err != nil {
+		return false, err
+	}
+	path := e.dir + "/" + node
+	defer e.c.deleteNode(path)
+
+	if err := e.c.waitFirst(ctx, session, e.dir, electionPrefix, node); err != nil {
+		return false, err
+	}
+	exists, _, watch, err := e.c.conn.ExistsW(path)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, ErrNodeLost
+	}
+
+	leadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(leadCtx)
+	}()
+
+	select {
+	case <-done:
+		return true, nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-session:
+		err = ErrSessionExpired
+	case <-watch:
+		// 自己的节点被删除或数据变化, 都按失去 leader 处理
+		err = ErrNodeLost
+	}
+	cancel()
+	<-done
+	return false, err
+}
+
+// Leader 当前 leader 的 id, 没有 leader 时返回 ErrNoLeader
+func (e *Election) Leader() (string, error) {
+	data, ok, err := firstData(e.c, e.dir, electionPrefix)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrNoLeader
+	}
+	return string(data), nil
+}
@@ -0,0 +1,149 @@
+package coord
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/go-zookeeper/zk"
+)
+
+const lockPrefix = "lock-"
+
+var (
+	// ErrNotLocked 对没有持有的锁调用 Unlock
+	ErrNotLocked = errors.New("coord: unlock of unlocked lock")
+	// ErrLockLost 持有锁期间会话过期, 锁已经被释放
+	ErrLockLost = errors.New("coord: lock lost because the session expired")
+)
+
+// Lock 可重入的分布式锁
+//
+// 重入以 *Lock 为单位: 同一个 *Lock 已经持有时再次 Lock 只增加计数, 需要同样次数的 Unlock 才真正释放;
+// 不同的 *Lock (不论是否在同一进程) 之间互斥
+type Lock struct {
+	c    *Client
+	dir  string
+	data []byte
+
+	acquire sync.Mutex // 同一个 *Lock 同时只有一个 goroutine 去 zookeeper 排队
+
+	mu    sync.Mutex
+	node  string
+	depth int
+	lost  <-chan struct{}
+}
+
+// NewLock dir 为锁目录, 例如 /locks/order; data 写入排队节点, 方便排查谁持有锁
+func NewLock(c *Client, dir string, data []byte) *Lock {
+	return &Lock{c: c, dir: dir, data: data}
+}
+
+// Lock 阻塞直到获得锁或 ctx 结束
+func (l *Lock) Lock(ctx context.Context) error {
+	if l.reenter() {
+		return nil
+	}
+	l.acquire.Lock()
+	defer l.acquire.Unlock()
+	// 排队期间其他 goroutine 可能已经拿到了
+	if l.reenter() {
+		return nil
+	}
+
+	session := l.c.session()
+	node, err := l.c.createSequential(l.dir, lockPrefix, l.data)
+	if err != nil {
+		return err
+	}
+	if err := l.c.waitFirst(ctx, session, l.dir, lockPrefix, node); err != nil {
+		if delErr := l.c.deleteNode(l.dir + "/" + node); delErr != nil {
+			return errors.Join(err, delErr)
+		}
+		return err
+	}
+
+	l.mu.Lock()
+	l.node, l.depth, l.lost = node, 1, session
+	l.mu.Unlock()
+	return nil
+}
+
+// reenter 已经持有且会话没有过期时增加计数
+func (l *Lock) reenter() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.depth == 0 {
+		return false
+	}
+	select {
+	case <-l.lost:
+		// 会话已经过期, 旧节点不再有效, 重新排队
+		l.node, l.depth = "", 0
+		return false
+	default:
+	}
+	l.depth++
+	return true
+}
+
+// Unlock 计数减到 0 时删除节点释放锁; 持有期间会话过期返回 ErrLockLost
+func (l *Lock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.depth == 0 {
+		return ErrNotLocked
+	}
+	l.depth--
+	if l.depth > 0 {
+		return nil
+	}
+	node := l.node
+	l.node = ""
+	select {
+	case <-l.lost:
+		return ErrLockLost
+	default:
+	}
+	return l.c.deleteNode(l.dir + "/" + node)
+}
+
+// Lost 持有期间会话过期时关闭, 持有者应该停止受锁保护的操作; 没有持有锁时返回 nil
+func (l *Lock) Lost() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.depth == 0 {
+		return nil
+	}
+	return l.lost
+}
+
+// Holder 当前持有锁的节点数据, 没有人持有时 ok 为 false
+func (l *Lock) Holder() (data []byte, ok bool, err error) {
+	return firstData(l.c, l.dir, lockPrefix)
+}
+
+// firstData dir 下序号最小的节点的数据
+func firstData(c *Client, dir, prefix string) ([]byte, bool, error) {
+	for {
+		nodes, err := c.sortedChildren(dir, prefix)
+		if err != nil {
+			if errors.Is(err, zk.ErrNoNode) {
+				return nil, false, nil
+			}
+			return nil, false, err
+		}
+		if len(nodes) == 0 {
+			return nil, false, nil
+		}
+		data, _, err := c.conn.Get(dir + "/" + nodes[0])
+		if errors.Is(err, zk.ErrNoNode) {
+			// 刚好被删除, 重新读
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		return data, true, nil
+	}
+}
@@ -0,0 +1,286 @@
+package coord
+
+import (
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// memServer 内存中的 zookeeper 替身, 支持临时/顺序节点、一次性 watch 和会话过期
+type memServer struct {
+	mu            sync.Mutex
+	nodes         map[string]*memNode
+	dataWatches   map[string][]memWatch
+	childWatches  map[string][]memWatch
+	nextSessionID int64
+}
+
+type memNode struct {
+	data     []byte
+	version  int32
+	owner    int64 // 临时节点所属会话
+	children map[string]bool
+	seq      int32
+}
+
+type memWatch struct {
+	session int64
+	ch      chan zk.Event
+}
+
+func newMemServer() *memServer {
+	return &memServer{
+		nodes:        map[string]*memNode{"/": {children: map[string]bool{}}},
+		dataWatches:  map[string][]memWatch{},
+		childWatches: map[string][]memWatch{},
+	}
+}
+
+// connect 新建会话, 返回连接和会话事件
+func (s *memServer) connect() (*memConn, <-chan zk.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextSessionID++
+	c := &memConn{s: s, session: s.nextSessionID, events: make(chan zk.Event, 16)}
+	c.events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+	return c, c.events
+}
+
+func (s *memServer) fire(watches map[string][]memWatch, p string, ev zk.Event) {
+	for _, w := range watches[p] {
+		w.ch <- ev
+	}
+	delete(watches, p)
+}
+
+// removeSession 删除会话的临时节点, 会话上没有触发的 watch 收到 EventNotWatching
+func (s *memServer) removeSession(session int64) {
+	for p, n := range s.nodes {
+		if n.owner == session {
+			s.delete(p)
+		}
+	}
+	for _, watches := range []map[string][]memWatch{s.dataWatches, s.childWatches} {
+		for p, ws := range watches {
+			kept := ws[:0]
+			for _, w := range ws {
+				if w.session == session {
+					w.ch <- zk.Event{Type: zk.EventNotWatching, State: zk.StateDisconnected, Path: p, Err: zk.ErrSessionExpired}
+				} else {
+					kept = append(kept, w)
+				}
+			}
+			watches[p] = kept
+		}
+	}
+}
+
+func (s *memServer) delete(p string) {
+	delete(s.nodes, p)
+	parent := path.Dir(p)
+	delete(s.nodes[parent].children, path.Base(p))
+	s.fire(s.dataWatches, p, zk.Event{Type: zk.EventNodeDeleted, Path: p})
+	s.fire(s.childWatches, p, zk.Event{Type: zk.EventNodeDeleted, Path: p})
+	s.fire(s.childWatches, parent, zk.Event{Type: zk.EventNodeChildrenChanged, Path: parent})
+}
+
+type memConn struct {
+	s       *memServer
+	session int64
+	closed  bool
+	events  chan zk.Event
+	// lostReplies 大于 0 时 Create 建好顺序节点后仍返回 ErrConnectionClosed, 模拟应答在断线时丢失
+	lostReplies int
+}
+
+// expire 模拟会话过期: 临时节点被删除, 然后像 go-zookeeper 一样以新会话重连
+func (c *memConn) expire() {
+	s := c.s
+	s.mu.Lock()
+	s.removeSession(c.session)
+	s.nextSessionID++
+	c.session = s.nextSessionID
+	s.mu.Unlock()
+	c.events <- zk.Event{Type: zk.EventSession, State: zk.StateExpired}
+	c.events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+}
+
+func (c *memConn) SessionID() int64 {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	return c.session
+}
+
+func (c *memConn) check() error {
+	if c.closed {
+		return zk.ErrClosing
+	}
+	return nil
+}
+
+func stat(n *memNode) *zk.Stat {
+	return &zk.Stat{Version: n.version, EphemeralOwner: n.owner, NumChildren: int32(len(n.children))}
+}
+
+func (c *memConn) Create(p string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := c.check(); err != nil {
+		return "", err
+	}
+	parent, ok := s.nodes[path.Dir(p)]
+	if !ok {
+		return "", zk.ErrNoNode
+	}
+	if parent.owner != 0 {
+		return "", zk.ErrNoChildrenForEphemerals
+	}
+	if flags&zk.FlagSequence != 0 {
+		p = fmt.Sprintf("%s%010d", p, parent.seq)
+		parent.seq++
+	}
+	if _, ok := s.nodes[p]; ok {
+		return "", zk.ErrNodeExists
+	}
+	n := &memNode{data: data, children: map[string]bool{}}
+	if flags&zk.FlagEphemeral != 0 {
+		n.owner = c.session
+	}
+	s.nodes[p] = n
+	parent.children[path.Base(p)] = true
+	s.fire(s.dataWatches, p, zk.Event{Type: zk.EventNodeCreated, Path: p})
+	s.fire(s.childWatches, path.Dir(p), zk.Event{Type: zk.EventNodeChildrenChanged, Path: path.Dir(p)})
+	if flags&zk.FlagSequence != 0 && c.lostReplies > 0 {
+		c.lostReplies--
+		return "", zk.ErrConnectionClosed
+	}
+	return p, nil
+}
+
+func (c *memConn) Get(p string) ([]byte, *zk.Stat, error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	if err := c.check(); err != nil {
+		return nil, nil, err
+	}
+	n, ok := c.s.nodes[p]
+	if !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+	return n.data, stat(n), nil
+}
+
+func (c *memConn) Set(p string, data []byte, version int32) (*zk.Stat, error) {
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	n, ok := s.nodes[p]
+	if !ok {
+		return nil, zk.ErrNoNode
+	}
+	if version != -1 && version != n.version {
+		return nil, zk.ErrBadVersion
+	}
+	n.data = data
+	n.version++
+	s.fire(s.dataWatches, p, zk.Event{Type: zk.EventNodeDataChanged, Path: p})
+	return stat(n), nil
+}
+
+func (c *memConn) Delete(p string, version int32) error {
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := c.check(); err != nil {
+		return err
+	}
+	n, ok := s.nodes[p]
+	if !ok {
+		return zk.ErrNoNode
+	}
+	if version != -1 && version != n.version {
+		return zk.ErrBadVersion
+	}
+	if len(n.children) > 0 {
+		return zk.ErrNotEmpty
+	}
+	s.delete(p)
+	return nil
+}
+
+func (c *memConn) Exists(p string) (bool, *zk.Stat, error) {
+	ok, st, _, err := c.exists(p, false)
+	return ok, st, err
+}
+
+func (c *memConn) ExistsW(p string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	return c.exists(p, true)
+}
+
+func (c *memConn) exists(p string, watch bool) (bool, *zk.Stat, <-chan zk.Event, error) {
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := c.check(); err != nil {
+		return false, nil, nil, err
+	}
+	var ch chan zk.Event
+	if watch {
+		ch = make(chan zk.Event, 1)
+		s.dataWatches[p] = append(s.dataWatches[p], memWatch{session: c.session, ch: ch})
+	}
+	n, ok := s.nodes[p]
+	if !ok {
+		return false, nil, ch, nil
+	}
+	return true, stat(n), ch, nil
+}
+
+func (c *memConn) Children(p string) ([]string, *zk.Stat, error) {
+	children, st, _, err := c.children(p, false)
+	return children, st, err
+}
+
+func (c *memConn) ChildrenW(p string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	return c.children(p, true)
+}
+
+func (c *memConn) children(p string, watch bool) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := c.check(); err != nil {
+		return nil, nil, nil, err
+	}
+	n, ok := s.nodes[p]
+	if !ok {
+		return nil, nil, nil, zk.ErrNoNode
+	}
+	var ch chan zk.Event
+	if watch {
+		ch = make(chan zk.Event, 1)
+		s.childWatches[p] = append(s.childWatches[p], memWatch{session: c.session, ch: ch})
+	}
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	return names, stat(n), ch, nil
+}
+
+func (c *memConn) Close() {
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	s.removeSession(c.session)
+}
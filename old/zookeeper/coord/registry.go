@@ -0,0 +1,206 @@
+package coord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// Registry 服务注册发现, 实例注册为 root/<service>/<addr> 临时节点
+type Registry struct {
+	c    *Client
+	root string
+
+	// RetryInterval 会话过期后重新注册、监听出错后重新监听的间隔, 默认 1 秒
+	RetryInterval time.Duration
+}
+
+// NewRegistry root 为注册根目录, 例如 /services
+func NewRegistry(c *Client, root string) *Registry {
+	return &Registry{c: c, root: root, RetryInterval: time.Second}
+}
+
+func (r *Registry) servicePath(service string) string {
+	return r.root + "/" + service
+}
+
+// Register 注册实例, 会话过期后在新会话中自动重新注册; 调用返回的 deregister 注销
+// 节点还属于其他会话时(通常是崩溃重启前的旧进程, 它的会话要等超时才过期)不返回错误,
+// 后台每隔 RetryInterval 重试, 直到旧会话过期、节点被服务端删除
+func (r *Registry) Register(service, addr string) (deregister func() error, err error) {
+	dir := r.servicePath(service)
+	path := dir + "/" + addr
+	if err := r.c.EnsurePath(dir); err != nil {
+		return nil, err
+	}
+	session := r.c.session()
+	err = r.create(path, addr)
+	if err != nil && !errors.Is(err, ErrNodeOwned) {
+		return nil, err
+	}
+	pending := err != nil
+	if pending {
+		log.Printf("[coord] %v, retry every %v", err, r.RetryInterval)
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			for pending {
+				select {
+				case <-stop:
+					return
+				case <-time.After(r.RetryInterval):
+				}
+				session = r.c.session()
+				err := r.create(path, addr)
+				if err != nil {
+					log.Printf("[coord] %v", err)
+					continue
+				}
+				log.Printf("[coord] registered %s", path)
+				pending = false
+			}
+			select {
+			case <-stop:
+				return
+			case <-session:
+			}
+			// 旧会话的临时节点已经被删除, 在新会话中重新创建
+			session = r.c.session()
+			if err := r.create(path, addr); err != nil {
+				log.Printf("[coord] re-register %s: %v", path, err)
+				pending = true
+				continue
+			}
+			log.Printf("[coord] re-registered %s after session expired", path)
+		}
+	}()
+
+	var once sync.Once
+	return func() error {
+		once.Do(func() {
+			close(stop)
+			<-stopped
+			// 注册失败(节点属于同地址的其他实例)时没有自己的节点要删除
+			if err = r.deleteOwn(path); errors.Is(err, ErrNodeOwned) {
+				err = nil
+			}
+		})
+		return err
+	}, nil
+}
+
+// create 创建实例节点; 节点已经存在且属于当前会话时先删掉再创建,
+// 属于其他会话时返回 ErrNodeOwned, 不能把同地址的另一个实例从服务发现里删掉
+func (r *Registry) create(path, addr string) error {
+	_, err := r.c.conn.Create(path, []byte(addr), zk.FlagEphemeral, r.c.acl)
+	if errors.Is(err, zk.ErrNodeExists) {
+		if err := r.deleteOwn(path); err != nil {
+			return fmt.Errorf("register %s: %w", path, err)
+		}
+		_, err = r.c.conn.Create(path, []byte(addr), zk.FlagEphemeral, r.c.acl)
+	}
+	if err != nil {
+		return fmt.Errorf("register %s: %w", path, err)
+	}
+	return nil
+}
+
+// deleteOwn 删除当前会话创建的节点, 节点不存在时忽略; 按版本删除, 避免删掉期间被别人重建的节点
+func (r *Registry) deleteOwn(path string) error {
+	_, st, err := r.c.conn.Get(path)
+	if errors.Is(err, zk.ErrNoNode) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get %s: %w", path, err)
+	}
+	if st.EphemeralOwner != r.c.conn.SessionID() {
+		return fmt.Errorf("%w: %s owned by session %#x", ErrNodeOwned, path, st.EphemeralOwner)
+	}
+	if err := r.c.conn.Delete(path, st.Version); err != nil && !errors.Is(err, zk.ErrNoNode) {
+		return fmt.Errorf("delete %s: %w", path, err)
+	}
+	return nil
+}
+
+// Lookup 当前注册的实例地址, 按地址排序; 服务不存在时返回空
+func (r *Registry) Lookup(service string) ([]string, error) {
+	addrs, _, err := r.c.conn.Children(r.servicePath(service))
+	if errors.Is(err, zk.ErrNoNode) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(addrs)
+	return addrs, nil
+}
+
+// Watch 实例列表变化时发送最新的完整列表, 开始时先发送一次; ctx 结束后关闭 channel
+// 接收方处理慢时只保留最新的列表
+func (r *Registry) Watch(ctx context.Context, service string) <-chan []string {
+	out := make(chan []string, 1)
+	dir := r.servicePath(service)
+	go func() {
+		defer close(out)
+		var last []string
+		first := true
+		for {
+			addrs, watch, err := r.watchOnce(dir)
+			if err != nil {
+				log.Printf("[coord] watch %s: %v", dir, err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(r.RetryInterval):
+				}
+				continue
+			}
+			slices.Sort(addrs)
+			if first || !slices.Equal(addrs, last) {
+				first = false
+				last = addrs
+				select {
+				case <-out:
+				default:
+				}
+				out <- addrs
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-watch:
+			}
+		}
+	}()
+	return out
+}
+
+// watchOnce 服务目录不存在时监听目录的创建
+func (r *Registry) watchOnce(dir string) ([]string, <-chan zk.Event, error) {
+	addrs, _, watch, err := r.c.conn.ChildrenW(dir)
+	if !errors.Is(err, zk.ErrNoNode) {
+		return addrs, watch, err
+	}
+	exists, _, watch, err := r.c.conn.ExistsW(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if exists {
+		// 刚好被创建, 马上重新读取
+		ch := make(chan zk.Event, 1)
+		ch <- zk.Event{Type: zk.EventNodeCreated, Path: dir}
+		return nil, ch, nil
+	}
+	return nil, watch, nil
+}
@@ -0,0 +1,56 @@
+package coord
+
+import (
+	"context"
+
+	"google.golang.org/grpc/resolver"
+)
+
+// Scheme gRPC 目标地址 zk:///<service> 使用注册中心解析
+const Scheme = "zk"
+
+// ResolverBuilder 从 Registry 解析 gRPC 服务地址, 实例上下线时更新连接
+//
+//	conn, err := grpc.Dial("zk:///order", grpc.WithResolvers(coord.NewResolverBuilder(registry)), ...)
+type ResolverBuilder struct {
+	registry *Registry
+}
+
+// NewResolverBuilder 通过 grpc.WithResolvers 传给 grpc.Dial, 或者用 resolver.Register 全局注册
+func NewResolverBuilder(r *Registry) *ResolverBuilder {
+	return &ResolverBuilder{registry: r}
+}
+
+func (b *ResolverBuilder) Scheme() string { return Scheme }
+
+func (b *ResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := b.registry.Watch(ctx, target.Endpoint())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for addrs := range updates {
+			state := resolver.State{Addresses: make([]resolver.Address, len(addrs))}
+			for i, addr := range addrs {
+				state.Addresses[i] = resolver.Address{Addr: addr}
+			}
+			if err := cc.UpdateState(state); err != nil {
+				cc.ReportError(err)
+			}
+		}
+	}()
+	return &zkResolver{cancel: cancel, done: done}, nil
+}
+
+type zkResolver struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// ResolveNow 地址由 watch 推送, 不需要主动解析
+func (*zkResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (r *zkResolver) Close() {
+	r.cancel()
+	<-r.done
+}
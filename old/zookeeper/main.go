@@ -2,24 +2,88 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
 	// "github.com/samuel/go-zookeeper/zk"
 	"github.com/go-zookeeper/zk"
-	"time"
+
+	"testGo/zookeeper/coord"
+)
+
+var (
+	servers = flag.String("servers", "127.0.0.1:2181", "zookeeper 地址, 逗号分隔")
+	mode    = flag.String("mode", "crud", "crud: 节点增删改查; lock: 抢锁; elect: 参与选举")
+	id      = flag.String("id", "", "锁和选举中标识自己, 默认 hostname-pid")
 )
 
 func main() {
-	conn, _, err := zk.Connect([]string{"127.0.0.1:2181"}, 5*time.Second)
+	flag.Parse()
+	if *id == "" {
+		host, _ := os.Hostname()
+		*id = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+
+	client, err := coord.Dial(strings.Split(*servers, ","), 5*time.Second)
 	if err != nil {
-		panic(err)
+		log.Fatal(err)
 	}
+	defer client.Close()
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	switch *mode {
+	case "crud":
+		err = crud(client.Conn())
+	case "lock":
+		err = lock(ctx, client)
+	case "elect":
+		err = coord.NewElection(client, "/election/demo", *id).Run(ctx, func(ctx context.Context) {
+			log.Println(*id, "became leader")
+			<-ctx.Done()
+			log.Println(*id, "lost leadership")
+		})
+	default:
+		err = fmt.Errorf("unknown mode %q", *mode)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatal(err)
+	}
+}
+
+func lock(ctx context.Context, client *coord.Client) error {
+	l := coord.NewLock(client, "/locks/demo", []byte(*id))
+	if err := l.Lock(ctx); err != nil {
+		return err
+	}
+	log.Println(*id, "holds /locks/demo, Ctrl+C to release")
+	select {
+	case <-ctx.Done():
+	case <-l.Lost():
+		log.Println("session expired, lock lost")
+	}
+	return l.Unlock()
+}
+
+func crud(conn coord.Conn) error {
 	// 1.验证根是否存在
-	if hasRoot, _, _ := conn.Exists("/root1"); !hasRoot {
+	hasRoot, _, err := conn.Exists("/root1")
+	if err != nil {
+		return fmt.Errorf("exists /root1: %w", err)
+	}
+	if !hasRoot {
 		// 2.新增根
-		_, err = conn.Create("/root1", []byte("root_content"), 0, zk.WorldACL(zk.PermAll))
-		if err != nil {
-			fmt.Println("failed add root, info: ", err.Error())
+		if _, err = conn.Create("/root1", []byte("root_content"), 0, zk.WorldACL(zk.PermAll)); err != nil {
+			return fmt.Errorf("failed add root: %w", err)
 		}
 		fmt.Println("add /root1 success")
 	}
@@ -27,34 +91,32 @@ func main() {
 	// 3.查询根
 	data, stat, err := conn.Get("/root1")
 	if err != nil {
-		fmt.Println("failed get root, info: ", err.Error())
+		return fmt.Errorf("failed get root: %w", err)
 	}
 	fmt.Println("text: ", string(data), stat.Version)
 
-	// 4.修改根
+	// 4.修改根, 版本号不一致时说明被其他人改过
 	if _, err = conn.Set("/root1", []byte("update text"), stat.Version); err != nil {
-		fmt.Println("failed update root")
+		return fmt.Errorf("failed update root: %w", err)
 	}
 
 	// 5.设置子节点(必须要有根/父节点)
-	if _, err = conn.Create("/root1/subnode", []byte("node_text"), 0, zk.WorldACL(zk.PermAll)); err != nil {
-		fmt.Println("failed add subnode, info: ", err.Error())
+	if _, err = conn.Create("/root1/subnode", []byte("node_text"), 0, zk.WorldACL(zk.PermAll)); err != nil && !errors.Is(err, zk.ErrNodeExists) {
+		return fmt.Errorf("failed add subnode: %w", err)
 	}
 	// 6.获取子节点列表
 	childNodes, _, err := conn.Children("/root1")
 	if err != nil {
-		fmt.Println("failed get node list, info: ", err.Error())
-	} else {
-		fmt.Println("node list: ", childNodes)
+		return fmt.Errorf("failed get node list: %w", err)
 	}
+	fmt.Println("node list: ", childNodes)
 
-	// 6.删除根(必须先查后删, 删完子才能删父节点)
-	_, stat, _ = conn.Get("/root1/subnode")
-	if err := conn.Delete("/root1/subnode", stat.Version); err != nil {
-		fmt.Println("falied delete node, info: ", stat.Version, err.Error())
+	// 7.删除根(删完子才能删父节点), 版本号 -1 表示不检查版本
+	if err := conn.Delete("/root1/subnode", -1); err != nil {
+		return fmt.Errorf("failed delete node: %w", err)
 	}
-	_, stat, _ = conn.Get("/root1")
-	if err := conn.Delete("/root1", stat.Version); err != nil {
-		fmt.Println("falied delete root, info: ", stat.Version, err.Error())
+	if err := conn.Delete("/root1", -1); err != nil {
+		return fmt.Errorf("failed delete root: %w", err)
 	}
+	return nil
 }